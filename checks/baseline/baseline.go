@@ -0,0 +1,244 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package baseline records the diagnostics reported by a check in a baseline file and suppresses the diagnostics that
+// are recorded in a baseline, which allows checks to be adopted incrementally on existing code bases: the violations
+// that exist when the baseline is written are accepted, while new violations are still reported.
+//
+// A diagnostic is identified by the name of the check that reported it, its file (relative to the base directory), its
+// rule ID and a hash of its message and of the content of the line on which it occurs. The line number is not part of
+// the key, so diagnostics in a baseline remain suppressed when the lines around them are added or removed.
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/diagnostic"
+)
+
+const (
+	// FlagName is the name of the flag that specifies the baseline file used to suppress diagnostics.
+	FlagName = "baseline"
+	// WriteFlagName is the name of the flag that specifies the file to which the baseline is written.
+	WriteFlagName = "write-baseline"
+)
+
+const version = 1
+
+// Entry is a diagnostic recorded in a baseline file.
+type Entry struct {
+	Check string `json:"check"`
+	File  string `json:"file"`
+	Rule  string `json:"rule,omitempty"`
+	Hash  string `json:"hash"`
+	// Count is the number of diagnostics with this key.
+	Count int `json:"count"`
+}
+
+type baselineFile struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+type key struct {
+	check, file, rule, hash string
+}
+
+// Baseline is a set of recorded diagnostics.
+type Baseline struct {
+	baseDir string
+	counts  map[key]int
+	// lines of the files read to compute hashes, keyed by path
+	lines map[string][]string
+}
+
+// New returns a baseline that records the provided diagnostics. The files of the diagnostics are recorded relative to
+// baseDir.
+func New(baseDir string, diags []diagnostic.Diagnostic) *Baseline {
+	b := newBaseline(baseDir)
+	for _, d := range diags {
+		b.counts[b.key(d)]++
+	}
+	return b
+}
+
+// Load reads the baseline in the file at the provided path. The files of the recorded diagnostics are resolved relative
+// to baseDir.
+func Load(path, baseDir string) (*Baseline, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read baseline %s", path)
+	}
+	var f baselineFile
+	if err := json.Unmarshal(bytes, &f); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal baseline %s", path)
+	}
+	if f.Version != version {
+		return nil, errors.Errorf("unsupported version %d in baseline %s: must be %d", f.Version, path, version)
+	}
+	b := newBaseline(baseDir)
+	for _, e := range f.Entries {
+		b.counts[key{check: e.Check, file: e.File, rule: e.Rule, hash: e.Hash}] += e.Count
+	}
+	return b, nil
+}
+
+func newBaseline(baseDir string) *Baseline {
+	return &Baseline{
+		baseDir: baseDir,
+		counts:  make(map[key]int),
+		lines:   make(map[string][]string),
+	}
+}
+
+// Write writes the baseline to the file at the provided path. The entries are sorted so that the content of the file
+// is stable.
+func (b *Baseline) Write(path string) error {
+	f := baselineFile{
+		Version: version,
+		Entries: []Entry{},
+	}
+	for k, count := range b.counts {
+		if count == 0 {
+			continue
+		}
+		f.Entries = append(f.Entries, Entry{
+			Check: k.check,
+			File:  k.file,
+			Rule:  k.rule,
+			Hash:  k.hash,
+			Count: count,
+		})
+	}
+	sort.Slice(f.Entries, func(i, j int) bool {
+		ei, ej := f.Entries[i], f.Entries[j]
+		if ei.Check != ej.Check {
+			return ei.Check < ej.Check
+		}
+		if ei.File != ej.File {
+			return ei.File < ej.File
+		}
+		if ei.Rule != ej.Rule {
+			return ei.Rule < ej.Rule
+		}
+		return ei.Hash < ej.Hash
+	})
+	bytes, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal baseline")
+	}
+	if err := ioutil.WriteFile(path, append(bytes, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write baseline %s", path)
+	}
+	return nil
+}
+
+// Suppress returns true if the provided diagnostic is recorded in the baseline. Every recorded diagnostic suppresses at
+// most one diagnostic: if a diagnostic is recorded once and now occurs twice, only one of the occurrences is
+// suppressed.
+func (b *Baseline) Suppress(d diagnostic.Diagnostic) bool {
+	k := b.key(d)
+	if b.counts[k] == 0 {
+		return false
+	}
+	b.counts[k]--
+	return true
+}
+
+// Filter returns the provided diagnostics that are not suppressed by the baseline.
+func (b *Baseline) Filter(diags []diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	var filtered []diagnostic.Diagnostic
+	for _, d := range diags {
+		if !b.Suppress(d) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+func (b *Baseline) key(d diagnostic.Diagnostic) key {
+	file := d.File
+	if filepath.IsAbs(file) && b.baseDir != "" {
+		if rel, err := filepath.Rel(b.baseDir, file); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			file = rel
+		}
+	}
+	sum := sha256.Sum256([]byte(d.Message + "\n" + b.line(d)))
+	return key{
+		check: d.CheckName,
+		file:  filepath.ToSlash(file),
+		rule:  d.RuleID,
+		hash:  fmt.Sprintf("%x", sum[:8]),
+	}
+}
+
+// line returns the content of the line on which the provided diagnostic occurs with leading and trailing whitespace
+// removed. Returns an empty string if the line cannot be read.
+func (b *Baseline) line(d diagnostic.Diagnostic) string {
+	if d.File == "" || d.Line <= 0 {
+		return ""
+	}
+	file := d.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(b.baseDir, file)
+	}
+	lines, ok := b.lines[file]
+	if !ok {
+		if bytes, err := ioutil.ReadFile(file); err == nil {
+			lines = strings.Split(string(bytes), "\n")
+		}
+		b.lines[file] = lines
+	}
+	if d.Line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[d.Line-1])
+}
+
+// Options specifies the baseline files used by a check.
+type Options struct {
+	// Path is the path to the baseline file used to suppress diagnostics. No diagnostics are suppressed if empty.
+	Path string
+	// WritePath is the path to the file to which the baseline for the diagnostics is written. No baseline is written if
+	// empty.
+	WritePath string
+}
+
+// Apply applies the options to the provided diagnostics and returns the diagnostics that should be reported. If
+// WritePath is set, the baseline for the diagnostics is written and no diagnostics are returned. Otherwise, if Path is
+// set, the diagnostics that are suppressed by the baseline are removed.
+func (o Options) Apply(baseDir string, diags []diagnostic.Diagnostic) ([]diagnostic.Diagnostic, error) {
+	if o.WritePath != "" {
+		if err := New(baseDir, diags).Write(o.WritePath); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if o.Path == "" {
+		return diags, nil
+	}
+	b, err := Load(o.Path, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return b.Filter(diags), nil
+}
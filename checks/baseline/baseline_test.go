@@ -0,0 +1,138 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baseline_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+)
+
+func TestApply(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	fooFile := path.Join(tmpDir, "foo.go")
+	err = ioutil.WriteFile(fooFile, []byte("package foo\n\nvar _ = a\nvar _ = a\nvar _ = b\n"), 0644)
+	require.NoError(t, err)
+
+	diags := []diagnostic.Diagnostic{
+		{File: fooFile, Line: 3, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: a"},
+		{File: fooFile, Line: 4, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: a"},
+		{File: fooFile, Line: 5, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: b"},
+	}
+	baselineFile := path.Join(tmpDir, "baseline.json")
+	got, err := baseline.Options{WritePath: baselineFile}.Apply(tmpDir, diags)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = baseline.Options{Path: baselineFile}.Apply(tmpDir, diags)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	// shift the lines, add another occurrence of a recorded diagnostic and add a new diagnostic
+	err = ioutil.WriteFile(fooFile, []byte("package foo\n\nimport \"fmt\"\n\nvar _ = a\nvar _ = a\nvar _ = a\nvar _ = b\nvar _ = c\n"), 0644)
+	require.NoError(t, err)
+	diags = []diagnostic.Diagnostic{
+		{File: fooFile, Line: 3, Col: 8, CheckName: "compiles", RuleID: "type", Message: `"fmt" imported and not used`},
+		{File: fooFile, Line: 5, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: a"},
+		{File: fooFile, Line: 6, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: a"},
+		{File: fooFile, Line: 7, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: a"},
+		{File: fooFile, Line: 8, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: b"},
+		{File: fooFile, Line: 9, Col: 9, CheckName: "compiles", RuleID: "type", Message: "undefined: c"},
+	}
+	got, err = baseline.Options{Path: baselineFile}.Apply(tmpDir, diags)
+	require.NoError(t, err)
+	assert.Equal(t, []diagnostic.Diagnostic{diags[0], diags[3], diags[5]}, got)
+
+	// diagnostics reported by other checks are not suppressed
+	other := diags[4]
+	other.CheckName = "nobadfuncs"
+	got, err = baseline.Options{Path: baselineFile}.Apply(tmpDir, []diagnostic.Diagnostic{other})
+	require.NoError(t, err)
+	assert.Equal(t, []diagnostic.Diagnostic{other}, got)
+
+	got, err = baseline.Options{}.Apply(tmpDir, diags)
+	require.NoError(t, err)
+	assert.Equal(t, diags, got)
+}
+
+func TestWriteIsRelativeAndSorted(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	baselineFile := path.Join(tmpDir, "baseline.json")
+	err = baseline.New(tmpDir, []diagnostic.Diagnostic{
+		{File: path.Join(tmpDir, "foo/foo.go"), Line: 1, CheckName: "extimport", RuleID: "external-import", Message: "imports external package github.com/org/ext"},
+		{File: "bar/bar.go", Line: 1, CheckName: "importalias", RuleID: "inconsistent-alias", Message: `uses alias "bar" to import package "fmt". Use alias "foo" instead.`},
+		{File: path.Join(tmpDir, "foo/foo.go"), Line: 1, CheckName: "extimport", RuleID: "external-import", Message: "imports external package github.com/org/ext"},
+	}).Write(baselineFile)
+	require.NoError(t, err)
+
+	bytes, err := ioutil.ReadFile(baselineFile)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+    "version": 1,
+    "entries": [
+        {
+            "check": "extimport",
+            "file": "foo/foo.go",
+            "rule": "external-import",
+            "hash": "321519ecd3f1592c",
+            "count": 2
+        },
+        {
+            "check": "importalias",
+            "file": "bar/bar.go",
+            "rule": "inconsistent-alias",
+            "hash": "230f4895ce3987a9",
+            "count": 1
+        }
+    ]
+}
+`, string(bytes))
+}
+
+func TestLoadInvalidVersion(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	baselineFile := path.Join(tmpDir, "baseline.json")
+	err = ioutil.WriteFile(baselineFile, []byte(`{"version": 2, "entries": []}`), 0644)
+	require.NoError(t, err)
+
+	_, err = baseline.Load(baselineFile, tmpDir)
+	assert.EqualError(t, err, "unsupported version 2 in baseline "+baselineFile+": must be 1")
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type Checks struct {
+	// Parallel specifies whether the checks should be run in parallel. If false, the checks are run one at a time in
+	// the order in which they are listed by CheckNames.
+	Parallel bool `yaml:"parallel" json:"parallel"`
+
+	// Checks is a map from the name of a check to its configuration. Only the checks in this map are run. The
+	// supported checks are those returned by CheckNames.
+	Checks map[string]Check `yaml:"checks" json:"checks"`
+}
+
+type Check struct {
+	// Command is the command that is used to run the check. If empty, the name of the check is used. Commands that
+	// do not contain a path separator are resolved using the PATH environment variable.
+	Command string `yaml:"command" json:"command"`
+
+	// Config is the path to the configuration file for the check relative to the project directory. Only supported by
+	// compiles, gogenerate, golicense, nobadfuncs, outparamcheck and ptimports.
+	Config string `yaml:"config" json:"config"`
+
+	// Pkgs are the packages that should be checked. If empty, nobadfuncs, outparamcheck and ptimports check "./..."
+	// and all of the other checks check all of the packages in the project directory.
+	Pkgs []string `yaml:"pkgs" json:"pkgs"`
+
+	// Args are additional arguments that are provided to the check before the packages.
+	Args []string `yaml:"args" json:"args"`
+}
+
+// CheckNames returns the names of the supported checks in the order in which they are run.
+func CheckNames() []string {
+	return []string{
+		"extimport",
+		"novendor",
+		"importalias",
+		"compiles",
+		"nobadfuncs",
+		"outparamcheck",
+		"golicense",
+		"gogenerate",
+		"ptimports",
+	}
+}
+
+// SortedNames returns the names of the configured checks in the order in which they are run.
+func (c Checks) SortedNames() []string {
+	var names []string
+	for _, name := range CheckNames() {
+		if _, ok := c.Checks[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Validate returns an error if the configuration specifies checks that are not supported.
+func (c Checks) Validate() error {
+	supported := make(map[string]struct{})
+	for _, name := range CheckNames() {
+		supported[name] = struct{}{}
+	}
+	var unknown []string
+	for name := range c.Checks {
+		if _, ok := supported[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return errors.Errorf("unknown checks %v: supported checks are %v", unknown, CheckNames())
+	}
+	return nil
+}
+
+func Load(configPath string) (Checks, error) {
+	yml, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return Checks{}, errors.Wrapf(err, "failed to read file %s", configPath)
+	}
+	return LoadFromString(string(yml))
+}
+
+func LoadFromString(ymlContent string) (Checks, error) {
+	cfg := Checks{}
+	if err := yaml.Unmarshal([]byte(ymlContent), &cfg); err != nil {
+		return Checks{}, errors.Wrapf(err, "failed to unmarshal YML %s", ymlContent)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Checks{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"fmt"
+
+	"github.com/palantir/checks/checks/config"
+)
+
+func Example() {
+	yml := `
+parallel: true
+checks:
+  compiles:
+    args: ["--tags", "integration"]
+  nobadfuncs:
+    config: nobadfuncs.json
+  golicense:
+    config: godel/config/license.yml
+`
+	cfg, err := config.LoadFromString(yml)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%q\n", fmt.Sprintf("%+v", cfg))
+	fmt.Println(cfg.SortedNames())
+	// Output: "{Parallel:true Checks:map[compiles:{Command: Config: Pkgs:[] Args:[--tags integration]} golicense:{Command: Config:godel/config/license.yml Pkgs:[] Args:[]} nobadfuncs:{Command: Config:nobadfuncs.json Pkgs:[] Args:[]}]}"
+	// [compiles nobadfuncs golicense]
+}
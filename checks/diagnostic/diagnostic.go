@@ -0,0 +1,325 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diagnostic defines the structured representation of the problems found by checks and the formats in which
+// they are printed. Checks that report problems at source positions convert them to diagnostics so that the output of
+// every check can be consumed in the same way.
+package diagnostic
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+const (
+	// FormatText prints every diagnostic on its own line in the form "file:line:col: message".
+	FormatText = "text"
+	// FormatJSON prints the diagnostics as a JSON array.
+	FormatJSON = "json"
+	// FormatCheckstyle prints the diagnostics as a Checkstyle XML report.
+	FormatCheckstyle = "checkstyle"
+	// FormatGitHub prints every diagnostic as a GitHub Actions workflow command that creates an annotation.
+	FormatGitHub = "github"
+)
+
+// Formats returns the supported output formats.
+func Formats() []string {
+	return []string{FormatText, FormatJSON, FormatCheckstyle, FormatGitHub}
+}
+
+// ValidateFormat returns an error if the provided format is not a supported output format.
+func ValidateFormat(format string) error {
+	for _, currFormat := range Formats() {
+		if format == currFormat {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid format %q: must be one of %v", format, Formats())
+}
+
+// Diagnostic is a single problem found by a check.
+type Diagnostic struct {
+	// File is the path to the file in which the problem was found. May be empty if the problem is not specific to a
+	// file.
+	File string `json:"file"`
+	// Line is the 1-based line of the problem. 0 if unknown.
+	Line int `json:"line"`
+	// Col is the 1-based column of the problem. 0 if unknown.
+	Col int `json:"col"`
+	// CheckName is the name of the check that found the problem.
+	CheckName string `json:"check"`
+	// RuleID identifies the kind of problem within the check.
+	RuleID string `json:"ruleId,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message"`
+	// Severity is the severity of the problem.
+	Severity Severity `json:"severity"`
+	// Hint is an optional suggestion for how to fix the problem.
+	Hint string `json:"hint,omitempty"`
+	// DocURL is an optional URL of documentation that describes the problem. It is only included in the JSON format.
+	DocURL string `json:"docUrl,omitempty"`
+}
+
+// New returns an error diagnostic for the provided position.
+func New(pos token.Position, checkName, ruleID, msg string) Diagnostic {
+	return Diagnostic{
+		File:      pos.Filename,
+		Line:      pos.Line,
+		Col:       pos.Column,
+		CheckName: checkName,
+		RuleID:    ruleID,
+		Message:   msg,
+		Severity:  SeverityError,
+	}
+}
+
+// Position returns the position of the diagnostic in the form "file:line:col". The line and column are omitted if
+// they are unknown, which matches the format of token.Position.
+func (d Diagnostic) Position() string {
+	s := d.File
+	if d.Line > 0 {
+		if s != "" {
+			s += ":"
+		}
+		s += strconv.Itoa(d.Line)
+		if d.Col != 0 {
+			s += ":" + strconv.Itoa(d.Col)
+		}
+	}
+	return s
+}
+
+// String returns the text representation of the diagnostic, which is of the form "file:line:col: message". Only the
+// message is returned if the diagnostic does not have a position.
+func (d Diagnostic) String() string {
+	if pos := d.Position(); pos != "" {
+		return pos + ": " + d.Message
+	}
+	return d.Message
+}
+
+// Sort sorts the provided diagnostics by file, line and column. The relative order of diagnostics at the same position
+// is preserved.
+func Sort(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Col < diags[j].Col
+	})
+}
+
+// Print writes the provided diagnostics to the provided writer in the provided format.
+func Print(w io.Writer, format string, diags []Diagnostic) error {
+	switch format {
+	case FormatText:
+		return PrintText(w, diags)
+	case FormatJSON:
+		return PrintJSON(w, diags)
+	case FormatCheckstyle:
+		return PrintCheckstyle(w, diags)
+	case FormatGitHub:
+		return PrintGitHub(w, diags)
+	default:
+		return ValidateFormat(format)
+	}
+}
+
+// PrintText writes every diagnostic to the provided writer on its own line. The hint of a diagnostic (if any) is
+// written on the following line, indented by a tab.
+func PrintText(w io.Writer, diags []Diagnostic) error {
+	for _, d := range diags {
+		out := d.String()
+		if d.Hint != "" {
+			out += "\n\thint: " + d.Hint
+		}
+		if _, err := fmt.Fprintln(w, out); err != nil {
+			return errors.Wrapf(err, "failed to write diagnostic")
+		}
+	}
+	return nil
+}
+
+// PrintJSON writes the provided diagnostics to the provided writer as a JSON array. An empty array is written if there
+// are no diagnostics.
+func PrintJSON(w io.Writer, diags []Diagnostic) error {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	out, err := json.MarshalIndent(diags, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal diagnostics")
+	}
+	if _, err := fmt.Fprintln(w, string(out)); err != nil {
+		return errors.Wrapf(err, "failed to write diagnostics")
+	}
+	return nil
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// PrintCheckstyle writes the provided diagnostics to the provided writer as a Checkstyle XML report. The diagnostics
+// are grouped by file in the order in which each file first appears. The source of each error is the name of the check
+// followed by the rule ID (if any), separated by a period.
+func PrintCheckstyle(w io.Writer, diags []Diagnostic) error {
+	report := checkstyleReport{
+		Version: "5.0",
+	}
+	fileIndices := make(map[string]int)
+	for _, d := range diags {
+		idx, ok := fileIndices[d.File]
+		if !ok {
+			idx = len(report.Files)
+			fileIndices[d.File] = idx
+			report.Files = append(report.Files, checkstyleFile{
+				Name: d.File,
+			})
+		}
+		source := d.CheckName
+		if d.RuleID != "" {
+			source += "." + d.RuleID
+		}
+		report.Files[idx].Errors = append(report.Files[idx].Errors, checkstyleError{
+			Line:     d.Line,
+			Column:   d.Col,
+			Severity: string(d.Severity),
+			Message:  d.Message,
+			Source:   source,
+		})
+	}
+	out, err := xml.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal checkstyle report")
+	}
+	if _, err := fmt.Fprintf(w, "%s%s\n", xml.Header, out); err != nil {
+		return errors.Wrapf(err, "failed to write checkstyle report")
+	}
+	return nil
+}
+
+// githubCommands are the GitHub Actions workflow commands used for each severity.
+var githubCommands = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "notice",
+}
+
+// PrintGitHub writes every diagnostic to the provided writer as a GitHub Actions workflow command of the form
+// "::error file=<file>,line=<line>,col=<col>,title=<title>::<message>", which is shown as an annotation on the
+// corresponding line of a pull request. The command is determined by the severity ("error", "warning" or "notice"),
+// the title is the name of the check followed by the rule ID (if any), separated by a period, and the hint (if any) is
+// appended to the message on a separate line. Absolute file paths within the working directory are written relative
+// to it because GitHub resolves the paths of annotations relative to the root of the repository.
+func PrintGitHub(w io.Writer, diags []Diagnostic) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get working directory")
+	}
+	for _, d := range diags {
+		command, ok := githubCommands[d.Severity]
+		if !ok {
+			command = githubCommands[SeverityError]
+		}
+
+		var props []string
+		if d.File != "" {
+			file := d.File
+			if filepath.IsAbs(file) {
+				if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+					file = filepath.ToSlash(rel)
+				}
+			}
+			props = append(props, "file="+escapeGitHubProperty(file))
+		}
+		if d.Line > 0 {
+			props = append(props, "line="+strconv.Itoa(d.Line))
+			if d.Col > 0 {
+				props = append(props, "col="+strconv.Itoa(d.Col))
+			}
+		}
+		title := d.CheckName
+		if d.RuleID != "" {
+			title += "." + d.RuleID
+		}
+		if title != "" {
+			props = append(props, "title="+escapeGitHubProperty(title))
+		}
+
+		msg := d.Message
+		if d.Hint != "" {
+			msg += "\nhint: " + d.Hint
+		}
+
+		out := "::" + command
+		if len(props) > 0 {
+			out += " " + strings.Join(props, ",")
+		}
+		out += "::" + escapeGitHubData(msg)
+		if _, err := fmt.Fprintln(w, out); err != nil {
+			return errors.Wrapf(err, "failed to write diagnostic")
+		}
+	}
+	return nil
+}
+
+// escapeGitHubData escapes the characters in the provided message of a workflow command that would otherwise be
+// interpreted by GitHub Actions.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes the characters in the provided property value of a workflow command that would
+// otherwise be interpreted by GitHub Actions.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
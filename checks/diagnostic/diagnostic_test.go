@@ -0,0 +1,190 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diagnostic_test
+
+import (
+	"bytes"
+	"go/token"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/diagnostic"
+)
+
+func TestString(t *testing.T) {
+	for i, currCase := range []struct {
+		diag diagnostic.Diagnostic
+		want string
+	}{
+		{
+			diag: diagnostic.New(token.Position{Filename: "foo.go", Line: 3, Column: 5}, "check", "", "message"),
+			want: "foo.go:3:5: message",
+		},
+		{
+			diag: diagnostic.New(token.Position{Filename: "foo.go", Line: 3}, "check", "", "message"),
+			want: "foo.go:3: message",
+		},
+		{
+			diag: diagnostic.New(token.Position{Filename: "foo.go"}, "check", "", "message"),
+			want: "foo.go: message",
+		},
+		{
+			diag: diagnostic.New(token.Position{}, "check", "", "message"),
+			want: "message",
+		},
+	} {
+		assert.Equal(t, currCase.want, currCase.diag.String(), "Case %d", i)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	diags := []diagnostic.Diagnostic{
+		diagnostic.New(token.Position{Filename: "foo.go", Line: 3, Column: 5}, "extimport", "external-import", `imports external package "github.com/bar"`),
+		diagnostic.New(token.Position{Filename: "bar.go", Line: 1}, "compiles", "", "undefined: x"),
+		diagnostic.New(token.Position{Filename: "foo.go", Line: 7, Column: 2}, "extimport", "external-import", "imports external package github.com/baz"),
+	}
+
+	for i, currCase := range []struct {
+		format string
+		diags  []diagnostic.Diagnostic
+		want   string
+	}{
+		{
+			format: diagnostic.FormatText,
+			diags:  diags,
+			want: `foo.go:3:5: imports external package "github.com/bar"
+bar.go:1: undefined: x
+foo.go:7:2: imports external package github.com/baz
+`,
+		},
+		{
+			format: diagnostic.FormatText,
+			want:   "",
+		},
+		{
+			format: diagnostic.FormatText,
+			diags: []diagnostic.Diagnostic{
+				{File: "foo.go", Line: 3, Col: 5, Message: "imports external package github.com/bar", Hint: "vendor github.com/bar"},
+			},
+			want: "foo.go:3:5: imports external package github.com/bar\n\thint: vendor github.com/bar\n",
+		},
+		{
+			format: diagnostic.FormatJSON,
+			diags:  diags[1:2],
+			want: `[
+    {
+        "file": "bar.go",
+        "line": 1,
+        "col": 0,
+        "check": "compiles",
+        "message": "undefined: x",
+        "severity": "error"
+    }
+]
+`,
+		},
+		{
+			format: diagnostic.FormatJSON,
+			want:   "[]\n",
+		},
+		{
+			format: diagnostic.FormatCheckstyle,
+			diags:  diags,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0">
+    <file name="foo.go">
+        <error line="3" column="5" severity="error" message="imports external package &#34;github.com/bar&#34;" source="extimport.external-import"></error>
+        <error line="7" column="2" severity="error" message="imports external package github.com/baz" source="extimport.external-import"></error>
+    </file>
+    <file name="bar.go">
+        <error line="1" severity="error" message="undefined: x" source="compiles"></error>
+    </file>
+</checkstyle>
+`,
+		},
+		{
+			format: diagnostic.FormatCheckstyle,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0"></checkstyle>
+`,
+		},
+		{
+			format: diagnostic.FormatGitHub,
+			diags:  diags,
+			want: `::error file=foo.go,line=3,col=5,title=extimport.external-import::imports external package "github.com/bar"
+::error file=bar.go,line=1,title=compiles::undefined: x
+::error file=foo.go,line=7,col=2,title=extimport.external-import::imports external package github.com/baz
+`,
+		},
+		{
+			format: diagnostic.FormatGitHub,
+			diags: []diagnostic.Diagnostic{
+				{File: "dir,a:b.go", Line: 3, CheckName: "importalias", Message: "100% inconsistent\nalias", Severity: diagnostic.SeverityWarning, Hint: "use foo"},
+				{CheckName: "novendor", Message: "unused", Severity: diagnostic.SeverityInfo},
+			},
+			want: "::warning file=dir%2Ca%3Ab.go,line=3,title=importalias::100%25 inconsistent%0Aalias%0Ahint: use foo\n" +
+				"::notice title=novendor::unused\n",
+		},
+		{
+			format: diagnostic.FormatGitHub,
+			want:   "",
+		},
+	} {
+		buf := &bytes.Buffer{}
+		err := diagnostic.Print(buf, currCase.format, currCase.diags)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, buf.String(), "Case %d", i)
+	}
+}
+
+func TestPrintInvalidFormat(t *testing.T) {
+	err := diagnostic.Print(&bytes.Buffer{}, "xml", nil)
+	assert.EqualError(t, err, `invalid format "xml": must be one of [text json checkstyle github]`)
+}
+
+func TestPrintGitHubRelativePaths(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	err = diagnostic.PrintGitHub(buf, []diagnostic.Diagnostic{
+		diagnostic.New(token.Position{Filename: path.Join(wd, "foo", "foo.go"), Line: 1}, "compiles", "type", "undefined: x"),
+		diagnostic.New(token.Position{Filename: "/outside/bar.go", Line: 2}, "compiles", "type", "undefined: y"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `::error file=foo/foo.go,line=1,title=compiles.type::undefined: x
+::error file=/outside/bar.go,line=2,title=compiles.type::undefined: y
+`, buf.String())
+}
+
+func TestSort(t *testing.T) {
+	diags := []diagnostic.Diagnostic{
+		{File: "b.go", Line: 1, Col: 1, Message: "1"},
+		{File: "a.go", Line: 2, Col: 1, Message: "2"},
+		{File: "a.go", Line: 1, Col: 4, Message: "3"},
+		{File: "a.go", Line: 1, Col: 4, Message: "4"},
+		{File: "a.go", Line: 1, Col: 2, Message: "5"},
+	}
+	diagnostic.Sort(diags)
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.Message)
+	}
+	assert.Equal(t, []string{"5", "3", "4", "2", "1"}, got)
+}
@@ -0,0 +1,174 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PreCommit is the type of the hook that runs the checks on the staged files before every commit.
+	PreCommit = "pre-commit"
+	// PrePush is the type of the hook that runs the checks on the entire project before every push.
+	PrePush = "pre-push"
+)
+
+// marker is the line that identifies hooks that were installed by Install.
+const marker = "# Installed by \"checks hook install\"."
+
+// Types returns the supported types of hooks.
+func Types() []string {
+	return []string{PreCommit, PrePush}
+}
+
+// Script returns the content of the hook of the provided type that runs the checks in the provided project directory
+// (relative to the root of the repository) using the provided command and configuration file (relative to the project
+// directory). The pre-commit hook runs the checks with "--staged", while the pre-push hook runs all of the checks. When
+// the checks fail, the hook explains how to skip them.
+func Script(hookType, command, projectDir, configPath string) (string, error) {
+	var args, desc, gitCmd string
+	switch hookType {
+	case PreCommit:
+		args, desc, gitCmd = " --staged", "on the files that are staged for commit", "git commit --no-verify"
+	case PrePush:
+		args, desc, gitCmd = "", "before pushing", "git push --no-verify"
+	default:
+		return "", errors.Errorf("invalid hook type %q: must be one of %v", hookType, Types())
+	}
+	cdDir, configDesc := `"$(git rev-parse --show-toplevel)"`, configPath
+	if projectDir != "." {
+		cdDir += "/" + shellQuote(projectDir)
+	}
+	if !path.IsAbs(configPath) {
+		configDesc = path.Join(projectDir, configPath)
+	}
+	return fmt.Sprintf(`#!/bin/sh
+%s
+# Runs the checks configured in %s %s.
+# The checks can be skipped once using "%s".
+cd %s || exit 1
+if ! %s --config %s%s; then
+	echo >&2
+	echo 'The %s checks failed. Fix the problems reported above or run "%s" to skip the checks.' >&2
+	exit 1
+fi
+`, marker, configDesc, desc, gitCmd, cdDir, shellQuote(command), shellQuote(configPath), args, hookType, gitCmd), nil
+}
+
+// Install writes the hook of the provided type for the git repository that contains projectDir and returns the path of
+// the hook. The hook runs the checks in projectDir and the configuration file path is resolved relative to projectDir.
+// A hook that was not installed by Install is only replaced if force is true.
+func Install(projectDir, hookType, command, configPath string, force bool) (string, error) {
+	rootDir, err := git(projectDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	hooksDir, err := git(projectDir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(projectDir, hooksDir)
+	}
+	relProjectDir, err := filepath.Rel(rootDir, projectDir)
+	if err != nil || strings.HasPrefix(relProjectDir, "..") {
+		return "", errors.Errorf("%s is not in the repository %s", projectDir, rootDir)
+	}
+	if filepath.IsAbs(configPath) {
+		if relPath, err := filepath.Rel(projectDir, configPath); err == nil && !strings.HasPrefix(relPath, "..") {
+			configPath = relPath
+		}
+	}
+
+	script, err := Script(hookType, command, filepath.ToSlash(relProjectDir), filepath.ToSlash(configPath))
+	if err != nil {
+		return "", err
+	}
+	hookPath := filepath.Join(hooksDir, hookType)
+	if existing, err := ioutil.ReadFile(hookPath); err == nil && !force && !bytes.Contains(existing, []byte(marker)) {
+		return "", errors.Errorf("%s already exists and was not installed by checks: use --force to replace it", hookPath)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to create directory %s", hooksDir)
+	}
+	if err := ioutil.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to write %s", hookPath)
+	}
+	// the mode provided to WriteFile is not applied to existing files
+	if err := os.Chmod(hookPath, 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to make %s executable", hookPath)
+	}
+	return hookPath, nil
+}
+
+// StagedGoFiles returns the paths (relative to dir) of the Go files in dir that are staged for commit in the git
+// repository that contains dir. Files that are staged for deletion are not returned.
+func StagedGoFiles(dir string) ([]string, error) {
+	output, err := git(dir, "-c", "core.quotePath=false", "diff", "--cached", "--name-only", "--relative", "--diff-filter=ACMR", "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// ChangedFiles returns the paths (relative to dir) of the files in dir that differ from the provided ref in the git
+// repository that contains dir as reported by "git diff --name-only <ref>". Files that were deleted are included, but
+// untracked files are not.
+func ChangedFiles(dir, ref string) ([]string, error) {
+	output, err := git(dir, "-c", "core.quotePath=false", "diff", "--name-only", "--relative", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// git runs git with the provided arguments in dir and returns its output without surrounding whitespace.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// shellQuote returns the provided string quoted for use as a single word in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,194 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/hook"
+)
+
+func TestScript(t *testing.T) {
+	got, err := hook.Script(hook.PreCommit, "/usr/local/bin/checks", ".", "config/checks.yml")
+	require.NoError(t, err)
+	assert.Equal(t, `#!/bin/sh
+# Installed by "checks hook install".
+# Runs the checks configured in config/checks.yml on the files that are staged for commit.
+# The checks can be skipped once using "git commit --no-verify".
+cd "$(git rev-parse --show-toplevel)" || exit 1
+if ! '/usr/local/bin/checks' --config 'config/checks.yml' --staged; then
+	echo >&2
+	echo 'The pre-commit checks failed. Fix the problems reported above or run "git commit --no-verify" to skip the checks.' >&2
+	exit 1
+fi
+`, got)
+
+	// the checks are run in the project directory
+	got, err = hook.Script(hook.PrePush, "checks", "sub/project", "checks.yml")
+	require.NoError(t, err)
+	assert.Equal(t, `#!/bin/sh
+# Installed by "checks hook install".
+# Runs the checks configured in sub/project/checks.yml before pushing.
+# The checks can be skipped once using "git push --no-verify".
+cd "$(git rev-parse --show-toplevel)"/'sub/project' || exit 1
+if ! 'checks' --config 'checks.yml'; then
+	echo >&2
+	echo 'The pre-push checks failed. Fix the problems reported above or run "git push --no-verify" to skip the checks.' >&2
+	exit 1
+fi
+`, got)
+
+	_, err = hook.Script("post-commit", "checks", ".", "checks.yml")
+	assert.EqualError(t, err, `invalid hook type "post-commit": must be one of [pre-commit pre-push]`)
+}
+
+func TestInstall(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+	gitCmd(t, tmpDir, "init")
+
+	subDir := filepath.Join(tmpDir, "sub")
+	err = os.Mkdir(subDir, 0755)
+	require.NoError(t, err)
+
+	// the hook runs the checks in the project directory, relative to which the configuration path is resolved
+	hookPath, err := hook.Install(subDir, hook.PrePush, "checks", filepath.Join(subDir, "checks.yml"), false)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, ".git", "hooks", "pre-push"), hookPath)
+	content, err := ioutil.ReadFile(hookPath)
+	require.NoError(t, err)
+	want, err := hook.Script(hook.PrePush, "checks", "sub", "checks.yml")
+	require.NoError(t, err)
+	assert.Equal(t, want, string(content))
+	fi, err := os.Stat(hookPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+
+	// the hook runs in the project directory when git runs it from the root of the repository
+	recordScript := filepath.Join(tmpDir, "record.sh")
+	err = ioutil.WriteFile(recordScript, []byte("#!/bin/sh\npwd > '"+filepath.Join(tmpDir, "hook-dir")+"'\ntest -f \"$2\"\n"), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(subDir, "checks.yml"), nil, 0644)
+	require.NoError(t, err)
+	_, err = hook.Install(subDir, hook.PrePush, recordScript, "checks.yml", false)
+	require.NoError(t, err)
+	cmd := exec.Command(hookPath)
+	cmd.Dir = tmpDir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	hookDir, err := ioutil.ReadFile(filepath.Join(tmpDir, "hook-dir"))
+	require.NoError(t, err)
+	assert.Equal(t, subDir+"\n", string(hookDir))
+
+	// hooks installed by checks are replaced
+	_, err = hook.Install(tmpDir, hook.PrePush, "checks", "checks.yml", false)
+	require.NoError(t, err)
+
+	// other hooks are only replaced if forced
+	preCommitPath := filepath.Join(tmpDir, ".git", "hooks", "pre-commit")
+	err = ioutil.WriteFile(preCommitPath, []byte("#!/bin/sh\nexit 0\n"), 0755)
+	require.NoError(t, err)
+	_, err = hook.Install(tmpDir, hook.PreCommit, "checks", "checks.yml", false)
+	assert.EqualError(t, err, preCommitPath+" already exists and was not installed by checks: use --force to replace it")
+	_, err = hook.Install(tmpDir, hook.PreCommit, "checks", "checks.yml", true)
+	require.NoError(t, err)
+}
+
+func TestStagedGoFiles(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+	gitCmd(t, tmpDir, "init")
+
+	for _, currFile := range []string{"foo.go", "bar/bar.go", "bar/README.md", "baz/baz.go"} {
+		err := os.MkdirAll(filepath.Join(tmpDir, filepath.Dir(currFile)), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(tmpDir, currFile), []byte("package foo\n"), 0644)
+		require.NoError(t, err)
+	}
+	gitCmd(t, tmpDir, "add", "foo.go", "bar")
+
+	files, err := hook.StagedGoFiles(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar/bar.go", "foo.go"}, files)
+
+	// paths are relative to the provided directory and limited to it
+	files, err = hook.StagedGoFiles(filepath.Join(tmpDir, "bar"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar.go"}, files)
+
+	files, err = hook.StagedGoFiles(filepath.Join(tmpDir, "baz"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, files)
+}
+
+func TestChangedFiles(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+	gitCmd(t, tmpDir, "init")
+	gitCmd(t, tmpDir, "config", "user.email", "test@example.com")
+	gitCmd(t, tmpDir, "config", "user.name", "test")
+
+	for _, currFile := range []string{"foo.go", "bar/bar.go", "bar/README.md", "baz/baz.go"} {
+		err := os.MkdirAll(filepath.Join(tmpDir, filepath.Dir(currFile)), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(tmpDir, currFile), []byte("package foo\n"), 0644)
+		require.NoError(t, err)
+	}
+	gitCmd(t, tmpDir, "add", ".")
+	gitCmd(t, tmpDir, "commit", "-m", "initial")
+
+	// modified, deleted and staged files are changed, but untracked files are not
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "bar", "README.md"), []byte("updated\n"), 0644)
+	require.NoError(t, err)
+	err = os.Remove(filepath.Join(tmpDir, "baz", "baz.go"))
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "bar", "new.go"), []byte("package bar\n"), 0644)
+	require.NoError(t, err)
+	gitCmd(t, tmpDir, "add", "bar/new.go")
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "untracked.go"), []byte("package foo\n"), 0644)
+	require.NoError(t, err)
+
+	files, err := hook.ChangedFiles(tmpDir, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar/README.md", "bar/new.go", "baz/baz.go"}, files)
+
+	// paths are relative to the provided directory and limited to it
+	files, err = hook.ChangedFiles(filepath.Join(tmpDir, "bar"), "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md", "new.go"}, files)
+
+	_, err = hook.ChangedFiles(tmpDir, "nonexistent-ref")
+	assert.Error(t, err)
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v failed: %s", args, string(output))
+}
@@ -0,0 +1,159 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loader imports Go packages using a build context that is configured in the same way by every check. Checks
+// that analyze packages using go/build expose the GOOS, GOARCH and build tags of the context as flags so that the code
+// for a particular platform or set of build tags can be analyzed regardless of the platform on which the check runs.
+package loader
+
+import (
+	"fmt"
+	"go/build"
+	"strings"
+)
+
+const (
+	// GOOSFlagName is the name of the flag that specifies the target operating system of the build context.
+	GOOSFlagName = "goos"
+	// GOARCHFlagName is the name of the flag that specifies the target architecture of the build context.
+	GOARCHFlagName = "goarch"
+	// TagsFlagName is the name of the flag that specifies the comma-separated build tags of the build context.
+	TagsFlagName = "tags"
+)
+
+// KnownOS and KnownArch are the values of GOOS and GOARCH that are recognized in build constraints.
+var (
+	KnownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js", "linux", "nacl", "netbsd",
+		"openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+	}
+	KnownArch = []string{
+		"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips", "mipsle", "mips64",
+		"mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le", "riscv", "riscv64", "s390", "s390x", "sparc",
+		"sparc64", "wasm",
+	}
+)
+
+// Options configures the build context used to import packages.
+type Options struct {
+	// GOOS is the target operating system. If empty, the operating system of build.Default is used.
+	GOOS string
+	// GOARCH is the target architecture. If empty, the architecture of build.Default is used.
+	GOARCH string
+	// Tags is a comma-separated set of build tags. Tags are interpreted as described by ContextForTags.
+	Tags string
+	// UseAllFiles specifies that all Go files should be considered regardless of their build constraints. Checks that
+	// analyze every file in a package by default set this to true. It is ignored if GOOS, GOARCH or Tags are specified,
+	// which allows the build constraints to be applied even for such checks.
+	UseAllFiles bool
+}
+
+// Overridden returns true if any of GOOS, GOARCH or Tags are specified.
+func (o Options) Overridden() bool {
+	return o.GOOS != "" || o.GOARCH != "" || strings.TrimSpace(o.Tags) != ""
+}
+
+// Context returns the build context for the options, which is based on build.Default. The tags are applied before
+// GOOS and GOARCH, so GOOS and GOARCH take precedence over tags that name an operating system or architecture.
+func (o Options) Context() build.Context {
+	ctx := ContextForTags(build.Default, o.Tags)
+	if o.GOOS != "" {
+		ctx.GOOS = o.GOOS
+	}
+	if o.GOARCH != "" {
+		ctx.GOARCH = o.GOARCH
+	}
+	ctx.UseAllFiles = o.UseAllFiles && !o.Overridden()
+	return ctx
+}
+
+// ContextForTags returns a copy of the provided build context configured for the provided comma-separated set of tags.
+// Tags that name an operating system or architecture set GOOS or GOARCH respectively, "cgo" enables cgo and all other
+// tags are added to the build tags of the context.
+func ContextForTags(base build.Context, tagSet string) build.Context {
+	ctx := base
+	ctx.BuildTags = append([]string{}, base.BuildTags...)
+	for _, currTag := range strings.Split(tagSet, ",") {
+		currTag = strings.TrimSpace(currTag)
+		switch {
+		case currTag == "":
+			continue
+		case contains(KnownOS, currTag):
+			ctx.GOOS = currTag
+		case contains(KnownArch, currTag):
+			ctx.GOARCH = currTag
+		case currTag == "cgo":
+			ctx.CgoEnabled = true
+		default:
+			ctx.BuildTags = append(ctx.BuildTags, currTag)
+		}
+	}
+	return ctx
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Loader imports packages using a build context.
+type Loader struct {
+	ctx build.Context
+}
+
+// New returns a loader that uses the build context for the provided options.
+func New(opts Options) *Loader {
+	return &Loader{
+		ctx: opts.Context(),
+	}
+}
+
+// Context returns a copy of the build context used by the loader.
+func (l *Loader) Context() build.Context {
+	ctx := l.ctx
+	ctx.BuildTags = append([]string{}, l.ctx.BuildTags...)
+	return ctx
+}
+
+// Import imports the package with the provided import path from the provided source directory using the build context
+// of the loader. It behaves in the same manner as build.Context.Import.
+func (l *Loader) Import(path, srcDir string, mode build.ImportMode) (*build.Package, error) {
+	return l.ctx.Import(path, srcDir, mode)
+}
+
+// MatchFile returns true if the file with the provided name in the provided directory matches the build context of the
+// loader. It behaves in the same manner as build.Context.MatchFile.
+func (l *Loader) MatchFile(dir, name string) (bool, error) {
+	return l.ctx.MatchFile(dir, name)
+}
+
+// String returns a description of the build context of the loader, which identifies the files that are considered
+// when importing packages.
+func (l *Loader) String() string {
+	if l.ctx.UseAllFiles {
+		return "all files"
+	}
+	s := fmt.Sprintf("%s/%s", l.ctx.GOOS, l.ctx.GOARCH)
+	if l.ctx.CgoEnabled {
+		s += " cgo"
+	}
+	if len(l.ctx.BuildTags) > 0 {
+		s += " tags=" + strings.Join(l.ctx.BuildTags, ",")
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loader_test
+
+import (
+	"go/build"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/loader"
+)
+
+func TestOptionsContext(t *testing.T) {
+	for i, currCase := range []struct {
+		opts            loader.Options
+		wantGOOS        string
+		wantGOARCH      string
+		wantTags        []string
+		wantUseAllFiles bool
+	}{
+		{
+			opts:       loader.Options{},
+			wantGOOS:   build.Default.GOOS,
+			wantGOARCH: build.Default.GOARCH,
+		},
+		{
+			opts:            loader.Options{UseAllFiles: true},
+			wantGOOS:        build.Default.GOOS,
+			wantGOARCH:      build.Default.GOARCH,
+			wantUseAllFiles: true,
+		},
+		{
+			opts:       loader.Options{GOOS: "windows", GOARCH: "arm64", UseAllFiles: true},
+			wantGOOS:   "windows",
+			wantGOARCH: "arm64",
+		},
+		{
+			opts:       loader.Options{Tags: "integration, plan9,386", UseAllFiles: true},
+			wantGOOS:   "plan9",
+			wantGOARCH: "386",
+			wantTags:   []string{"integration"},
+		},
+		{
+			opts:       loader.Options{GOOS: "darwin", Tags: "linux"},
+			wantGOOS:   "darwin",
+			wantGOARCH: build.Default.GOARCH,
+		},
+	} {
+		ctx := currCase.opts.Context()
+		assert.Equal(t, currCase.wantGOOS, ctx.GOOS, "Case %d", i)
+		assert.Equal(t, currCase.wantGOARCH, ctx.GOARCH, "Case %d", i)
+		assert.Equal(t, append(append([]string{}, build.Default.BuildTags...), currCase.wantTags...), ctx.BuildTags, "Case %d", i)
+		assert.Equal(t, currCase.wantUseAllFiles, ctx.UseAllFiles, "Case %d", i)
+	}
+}
+
+func TestLoaderImport(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{
+		"foo.go":         "package foo\n",
+		"foo_windows.go": "package foo\n",
+		"foo_tagged.go":  "// +build tagged\n\npackage foo\n",
+		"ignored.go":     "// +build ignore\n\npackage main\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(path.Join(tmpDir, name), []byte(content), 0644))
+	}
+
+	for i, currCase := range []struct {
+		opts    loader.Options
+		want    []string
+		wantErr bool
+	}{
+		{
+			opts: loader.Options{GOOS: "linux"},
+			want: []string{"foo.go"},
+		},
+		{
+			opts: loader.Options{GOOS: "windows", Tags: "tagged"},
+			want: []string{"foo.go", "foo_tagged.go", "foo_windows.go"},
+		},
+		{
+			// all files are considered, so the files in package main are included
+			opts:    loader.Options{UseAllFiles: true},
+			wantErr: true,
+		},
+	} {
+		pkg, err := loader.New(currCase.opts).Import(".", tmpDir, build.ImportComment)
+		if currCase.wantErr {
+			_, ok := err.(*build.MultiplePackageError)
+			assert.True(t, ok, "Case %d: expected MultiplePackageError, was %v", i, err)
+			continue
+		}
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, pkg.GoFiles, "Case %d", i)
+	}
+}
+
+func TestLoaderString(t *testing.T) {
+	assert.Equal(t, "all files", loader.New(loader.Options{UseAllFiles: true}).String())
+	assert.Regexp(t, `^linux/amd64( cgo)?$`, loader.New(loader.Options{GOOS: "linux", GOARCH: "amd64"}).String())
+	assert.Equal(t, "linux/amd64 cgo tags=integration", loader.New(loader.Options{GOOS: "linux", GOARCH: "amd64", Tags: "cgo,integration"}).String())
+}
@@ -0,0 +1,234 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/errorstringer"
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/flag"
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/config"
+	"github.com/palantir/checks/checks/hook"
+	"github.com/palantir/checks/checks/runner"
+)
+
+const (
+	configFlagName   = "config"
+	parallelFlagName = "parallel"
+	formatFlagName   = "format"
+	stagedFlagName   = "staged"
+	sinceFlagName    = "since"
+	typeFlagName     = "type"
+	commandFlagName  = "command"
+	forceFlagName    = "force"
+	profileFlagName  = "profile-out"
+)
+
+const (
+	textFormat = "text"
+	jsonFormat = "json"
+)
+
+var (
+	configFlag = flag.StringFlag{
+		Name:  configFlagName,
+		Value: "checks.yml",
+		Usage: "path to the configuration file that specifies the checks to run (relative to the working directory)",
+	}
+	parallelFlag = flag.BoolFlag{
+		Name:  parallelFlagName,
+		Usage: "run the checks in parallel (overrides the value in the configuration file)",
+	}
+	formatFlag = flag.StringFlag{
+		Name:  formatFlagName,
+		Value: textFormat,
+		Usage: "format of the output. Must be 'text' or 'json'",
+	}
+	stagedFlag = flag.BoolFlag{
+		Name:  stagedFlagName,
+		Usage: "only check the Go files that are staged for commit with the checks that support checking individual files (golicense, importalias and ptimports)",
+	}
+	sinceFlag = flag.StringFlag{
+		Name: sinceFlagName,
+		Usage: "only check the files that changed since the provided git ref with the checks that support incremental " +
+			"operation (golicense, importalias, nobadfuncs and ptimports) and only run novendor and extimport if Go " +
+			"files or vendored files changed",
+	}
+	profileFlag = flag.StringFlag{
+		Name:  profileFlagName,
+		Usage: "path to which a pprof profile of the wall-clock duration and peak memory usage of each check is written (view using 'go tool pprof')",
+	}
+)
+
+func main() {
+	app := cli.NewApp(cli.DebugHandler(errorstringer.SingleStack))
+	app.Flags = append(app.Flags,
+		configFlag,
+		parallelFlag,
+		formatFlag,
+		stagedFlag,
+		sinceFlag,
+		profileFlag,
+	)
+	app.Subcommands = []cli.Command{
+		hookCommand(),
+	}
+	app.Action = func(ctx cli.Context) error {
+		format := ctx.String(formatFlagName)
+		if format != textFormat && format != jsonFormat {
+			return errors.Errorf("invalid format %q: must be %q or %q", format, textFormat, jsonFormat)
+		}
+
+		wd, err := dirs.GetwdEvalSymLinks()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get working directory")
+		}
+		cfgPath := ctx.String(configFlagName)
+		if !filepath.IsAbs(cfgPath) {
+			cfgPath = filepath.Join(wd, cfgPath)
+		}
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		if ctx.Bool(parallelFlagName) {
+			cfg.Parallel = true
+		}
+
+		if ctx.Bool(stagedFlagName) && ctx.Has(sinceFlagName) {
+			return errors.Errorf("--%s and --%s cannot be used together", stagedFlagName, sinceFlagName)
+		}
+
+		var results []runner.Result
+		if ctx.Has(sinceFlagName) {
+			since := ctx.String(sinceFlagName)
+			changed, err := hook.ChangedFiles(wd, since)
+			if err != nil {
+				return err
+			}
+			if len(changed) == 0 {
+				if format == textFormat {
+					fmt.Fprintf(ctx.App.Stdout, "No files changed since %s\n", since)
+				}
+				return nil
+			}
+			if results, err = runner.RunChanged(wd, cfg, changed); err != nil {
+				return err
+			}
+		} else {
+			var files []string
+			if ctx.Bool(stagedFlagName) {
+				if files, err = hook.StagedGoFiles(wd); err != nil {
+					return err
+				}
+				if len(files) == 0 {
+					if format == textFormat {
+						fmt.Fprintln(ctx.App.Stdout, "No Go files are staged for commit")
+					}
+					return nil
+				}
+			}
+			if results, err = runner.RunFiles(wd, cfg, files); err != nil {
+				return err
+			}
+		}
+		if profilePath := ctx.String(profileFlagName); profilePath != "" {
+			if err := writeProfile(profilePath, results); err != nil {
+				return err
+			}
+		}
+		switch format {
+		case jsonFormat:
+			if err := runner.PrintJSON(results, ctx.App.Stdout); err != nil {
+				return err
+			}
+		default:
+			runner.PrintText(results, ctx.App.Stdout)
+			fmt.Fprintln(ctx.App.Stdout)
+			if err := runner.PrintTimings(results, ctx.App.Stdout); err != nil {
+				return err
+			}
+		}
+		if !runner.NewReport(results).Passed {
+			// output has already been printed, so return empty error
+			return fmt.Errorf("")
+		}
+		return nil
+	}
+	os.Exit(app.Run(os.Args))
+}
+
+// writeProfile writes the pprof profile of the provided results to the file at the provided path.
+func writeProfile(profilePath string, results []runner.Result) (rErr error) {
+	f, err := os.Create(profilePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create profile %s", profilePath)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && rErr == nil {
+			rErr = errors.Wrapf(err, "failed to close profile %s", profilePath)
+		}
+	}()
+	return runner.WriteProfile(results, f)
+}
+
+func hookCommand() cli.Command {
+	return cli.Command{
+		Name:  "hook",
+		Usage: "Manage the git hooks that run the checks",
+		Subcommands: []cli.Command{
+			{
+				Name: "install",
+				Usage: "Install a git hook that runs the checks in the configuration file. The pre-commit hook only checks " +
+					"the staged files with the checks that support checking individual files",
+				Flags: []flag.Flag{
+					configFlag,
+					flag.StringFlag{
+						Name:  typeFlagName,
+						Value: hook.PreCommit,
+						Usage: "type of the hook. Must be 'pre-commit' or 'pre-push'",
+					},
+					flag.StringFlag{
+						Name:  commandFlagName,
+						Value: "checks",
+						Usage: "command that the hook uses to run the checks",
+					},
+					flag.BoolFlag{
+						Name:  forceFlagName,
+						Usage: "replace an existing hook that was not installed by checks",
+					},
+				},
+				Action: func(ctx cli.Context) error {
+					wd, err := dirs.GetwdEvalSymLinks()
+					if err != nil {
+						return errors.Wrapf(err, "failed to get working directory")
+					}
+					hookPath, err := hook.Install(wd, ctx.String(typeFlagName), ctx.String(commandFlagName), ctx.String(configFlagName), ctx.Bool(forceFlagName))
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(ctx.App.Stdout, "Installed %s hook at %s\n", ctx.String(typeFlagName), hookPath)
+					return nil
+				},
+			},
+		},
+	}
+}
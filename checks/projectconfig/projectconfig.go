@@ -0,0 +1,74 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package projectconfig loads the configuration that is shared by the checks of a project, which allows settings such
+// as the files and directories that should not be checked to be declared once rather than in the configuration of
+// every check.
+package projectconfig
+
+import (
+	"io/ioutil"
+	"regexp"
+
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// FlagName is the name of the flag that specifies the path to the project configuration file.
+const FlagName = "project-config"
+
+// ProjectConfig is the configuration that is shared by the checks of a project.
+type ProjectConfig struct {
+	// Exclude matches the files and directories (relative to the project directory) that should not be considered by
+	// any of the checks that support the project configuration, such as generated code, snapshots of third-party code
+	// and test data.
+	Exclude matcher.NamesPathsCfg `yaml:"exclude" json:"exclude"`
+}
+
+// ExcludeMatcher returns the matcher for the files and directories that are excluded by the configuration. Returns nil
+// if the configuration does not exclude anything.
+func (c ProjectConfig) ExcludeMatcher() matcher.Matcher {
+	if c.Exclude.Empty() {
+		return nil
+	}
+	return c.Exclude.Matcher()
+}
+
+// Load returns the project configuration in the YAML file at the provided path. Returns an empty configuration if the
+// path is empty.
+func Load(configPath string) (ProjectConfig, error) {
+	if configPath == "" {
+		return ProjectConfig{}, nil
+	}
+	yml, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return ProjectConfig{}, errors.Wrapf(err, "failed to read file %s", configPath)
+	}
+	return LoadFromString(string(yml))
+}
+
+// LoadFromString returns the project configuration in the provided YAML content.
+func LoadFromString(ymlContent string) (ProjectConfig, error) {
+	var cfg ProjectConfig
+	if err := yaml.Unmarshal([]byte(ymlContent), &cfg); err != nil {
+		return ProjectConfig{}, errors.Wrapf(err, "failed to unmarshal YML %s", ymlContent)
+	}
+	for _, currName := range cfg.Exclude.Names {
+		if _, err := regexp.Compile(currName); err != nil {
+			return ProjectConfig{}, errors.Wrapf(err, "invalid exclude name %q", currName)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projectconfig_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/projectconfig"
+)
+
+func TestLoadFromString(t *testing.T) {
+	cfg, err := projectconfig.LoadFromString(`exclude:
+  names:
+    - ".*\\.pb\\.go"
+    - testdata
+  paths:
+    - third_party/snapshot
+`)
+	require.NoError(t, err)
+
+	exclude := cfg.ExcludeMatcher()
+	require.NotNil(t, exclude)
+	for _, currPath := range []string{
+		"foo/foo.pb.go",
+		"foo/testdata",
+		"foo/testdata/bar.go",
+		"third_party/snapshot",
+		"third_party/snapshot/foo.go",
+	} {
+		assert.True(t, exclude.Match(currPath), "expected %s to be excluded", currPath)
+	}
+	for _, currPath := range []string{
+		"foo/foo.go",
+		"third_party/other/foo.go",
+	} {
+		assert.False(t, exclude.Match(currPath), "expected %s not to be excluded", currPath)
+	}
+}
+
+func TestLoadEmpty(t *testing.T) {
+	cfg, err := projectconfig.Load("")
+	require.NoError(t, err)
+	assert.Nil(t, cfg.ExcludeMatcher())
+}
+
+func TestLoadInvalidName(t *testing.T) {
+	_, err := projectconfig.LoadFromString(`exclude:
+  names:
+    - "[invalid"
+`)
+	assert.EqualError(t, err, "invalid exclude name \"[invalid\": error parsing regexp: missing closing ]: `[invalid`")
+}
@@ -0,0 +1,165 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WriteProfile writes a gzip-compressed pprof profile with a sample for every one of the provided results to the
+// provided writer (see https://github.com/google/pprof/blob/master/proto/profile.proto for the format). The sample of a
+// check is attributed to a function with the name of the check and has the wall-clock duration of the check and its
+// peak memory usage as values and the number of packages it checked as a label, so the checks that dominate the time
+// of a run can be identified using "go tool pprof".
+func WriteProfile(results []Result, w io.Writer) error {
+	strs := newStringTable()
+	wall, nanoseconds := strs.index("wall"), strs.index("nanoseconds")
+	maxMemory, bytesUnit := strs.index("max_memory"), strs.index("bytes")
+	packages := strs.index("packages")
+
+	p := &protoBuffer{}
+	p.message(1, func(m *protoBuffer) {
+		m.int64Field(1, wall)
+		m.int64Field(2, nanoseconds)
+	})
+	p.message(1, func(m *protoBuffer) {
+		m.int64Field(1, maxMemory)
+		m.int64Field(2, bytesUnit)
+	})
+	for i, result := range results {
+		id := int64(i + 1)
+		p.message(2, func(m *protoBuffer) {
+			m.packedField(1, []int64{id})
+			m.packedField(2, []int64{int64(result.Duration), result.MaxMemory})
+			m.message(3, func(l *protoBuffer) {
+				l.int64Field(1, packages)
+				l.int64Field(3, int64(result.Packages))
+				l.int64Field(4, packages)
+			})
+		})
+	}
+	for i := range results {
+		id := int64(i + 1)
+		p.message(4, func(m *protoBuffer) {
+			m.int64Field(1, id)
+			m.message(4, func(l *protoBuffer) {
+				l.int64Field(1, id)
+			})
+		})
+	}
+	for i, result := range results {
+		id := int64(i + 1)
+		name := strs.index(result.Check)
+		p.message(5, func(m *protoBuffer) {
+			m.int64Field(1, id)
+			m.int64Field(2, name)
+			m.int64Field(3, name)
+			m.int64Field(4, strs.index(strings.Join(result.Command, " ")))
+		})
+	}
+	for _, str := range strs.strs {
+		p.bytesField(6, []byte(str))
+	}
+	p.message(11, func(m *protoBuffer) {
+		m.int64Field(1, wall)
+		m.int64Field(2, nanoseconds)
+	})
+	p.int64Field(14, wall)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(p.Bytes()); err != nil {
+		return errors.Wrapf(err, "failed to write profile")
+	}
+	if err := gz.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write profile")
+	}
+	return nil
+}
+
+// stringTable is the string table of a profile. The first string is always the empty string.
+type stringTable struct {
+	strs    []string
+	indices map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{
+		strs: []string{""},
+		indices: map[string]int64{
+			"": 0,
+		},
+	}
+}
+
+// index returns the index of the provided string in the table, adding it if it is not present.
+func (t *stringTable) index(str string) int64 {
+	if idx, ok := t.indices[str]; ok {
+		return idx
+	}
+	idx := int64(len(t.strs))
+	t.strs = append(t.strs, str)
+	t.indices[str] = idx
+	return idx
+}
+
+// protoBuffer encodes protocol buffer messages.
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		_ = b.WriteByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	_ = b.WriteByte(byte(x))
+}
+
+// int64Field encodes a varint field. Fields with a value of 0 are omitted.
+func (b *protoBuffer) int64Field(field int, x int64) {
+	if x == 0 {
+		return
+	}
+	b.varint(uint64(field)<<3 | 0)
+	b.varint(uint64(x))
+}
+
+// bytesField encodes a length-delimited field.
+func (b *protoBuffer) bytesField(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	_, _ = b.Write(data)
+}
+
+// packedField encodes a packed repeated varint field.
+func (b *protoBuffer) packedField(field int, xs []int64) {
+	packed := &protoBuffer{}
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	b.bytesField(field, packed.Bytes())
+}
+
+// message encodes an embedded message field whose content is written by the provided function.
+func (b *protoBuffer) message(field int, write func(m *protoBuffer)) {
+	m := &protoBuffer{}
+	write(m)
+	b.bytesField(field, m.Bytes())
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Report is the combined report for a set of checks.
+type Report struct {
+	// Passed is true if all of the checks passed.
+	Passed bool `json:"passed"`
+	// Checks are the results of the checks.
+	Checks []Result `json:"checks"`
+}
+
+// NewReport returns the report for the provided results.
+func NewReport(results []Result) Report {
+	report := Report{
+		Passed: true,
+		Checks: results,
+	}
+	for _, result := range results {
+		if !result.Passed() {
+			report.Passed = false
+		}
+	}
+	return report
+}
+
+// PrintJSON writes the report for the provided results to the provided writer as JSON.
+func PrintJSON(results []Result, w io.Writer) error {
+	out, err := json.MarshalIndent(NewReport(results), "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal report")
+	}
+	if _, err := fmt.Fprintln(w, string(out)); err != nil {
+		return errors.Wrapf(err, "failed to write report")
+	}
+	return nil
+}
+
+// PrintText writes the output of each of the provided results to the provided writer followed by a summary of the
+// checks that failed.
+func PrintText(results []Result, w io.Writer) {
+	var failed []string
+	for _, result := range results {
+		fmt.Fprintf(w, "Running %s...\n", result.Check)
+		fmt.Fprint(w, result.Output)
+		if result.Output != "" && !strings.HasSuffix(result.Output, "\n") {
+			fmt.Fprintln(w)
+		}
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(w, "Failed to run %s: %s\n", result.Check, result.Error)
+			failed = append(failed, result.Check)
+		case result.ExitCode != 0:
+			fmt.Fprintf(w, "Finished %s: failed with exit code %d\n", result.Check, result.ExitCode)
+			failed = append(failed, result.Check)
+		default:
+			fmt.Fprintf(w, "Finished %s\n", result.Check)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed: %s\n", len(failed), len(results), strings.Join(failed, ", "))
+	}
+}
+
+// PrintTimings writes a table with the wall-clock duration, number of packages and peak memory usage of each of the
+// provided results to the provided writer.
+func PrintTimings(results []Result, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tDURATION\tPACKAGES\tMAX MEMORY")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%v\t%d\t%s\n", result.Check, result.Duration.Round(time.Millisecond), result.Packages, formatBytes(result.MaxMemory))
+	}
+	return tw.Flush()
+}
+
+// formatBytes returns the provided number of bytes in mebibytes or "-" if it is 0.
+func formatBytes(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
@@ -0,0 +1,355 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/config"
+	"github.com/palantir/checks/checks/vendorutil"
+)
+
+// Result is the result of running a single check.
+type Result struct {
+	// Check is the name of the check.
+	Check string `json:"check"`
+	// Command is the command line used to run the check.
+	Command []string `json:"command"`
+	// ExitCode is the exit code of the check. -1 if the check could not be run.
+	ExitCode int `json:"exitCode"`
+	// Output is the combined standard output and standard error of the check.
+	Output string `json:"output"`
+	// Error is the reason that the check could not be run, if any.
+	Error string `json:"error,omitempty"`
+	// Duration is the wall-clock duration of the check.
+	Duration time.Duration `json:"durationNanos"`
+	// Packages is the number of packages in the project directory that are checked by the check.
+	Packages int `json:"packages"`
+	// MaxMemory is the peak resident set size of the process of the check in bytes. 0 if it could not be determined.
+	MaxMemory int64 `json:"maxMemoryBytes"`
+}
+
+// Passed returns true if the check ran and exited with a status of 0.
+func (r Result) Passed() bool {
+	return r.Error == "" && r.ExitCode == 0
+}
+
+// Run runs the checks specified by the provided configuration in projectDir and returns their results in the order
+// returned by cfg.SortedNames. If cfg.Parallel is true, the checks are run concurrently. A check that fails or that
+// cannot be run does not prevent the other checks from running. An error is only returned if the configuration is
+// invalid.
+func Run(projectDir string, cfg config.Checks) ([]Result, error) {
+	return RunFiles(projectDir, cfg, nil)
+}
+
+// RunFiles runs the checks specified by the provided configuration in the same manner as Run. If files is non-nil, the
+// checks that can check individual files (golicense, importalias and ptimports) only check the provided Go files (paths
+// relative to projectDir) instead of their configured packages: golicense and ptimports check the files and
+// importalias checks the packages that contain them. Files in vendor directories are ignored and such checks are not
+// run at all if none of the files apply to them. All of the other checks check the entire project.
+func RunFiles(projectDir string, cfg config.Checks, files []string) ([]Result, error) {
+	return run(projectDir, cfg, files, nil)
+}
+
+// RunChanged runs the checks specified by the provided configuration for the provided changed files (paths relative to
+// projectDir, which may include files that are not Go files and files that were deleted). The checks that can check
+// individual files check the changed files that exist in the same manner as RunFiles. nobadfuncs only checks the
+// packages that are affected by the changed Go files (using its "--changed-only" mode with the changed files provided
+// on standard input) and is not run if no Go files changed. novendor and extimport, which check the entire project, are
+// only run if a Go file or a file in a vendor directory changed. All of the other checks check the entire project.
+func RunChanged(projectDir string, cfg config.Checks, changed []string) ([]Result, error) {
+	files := []string{}
+	for _, file := range changed {
+		if _, err := os.Stat(filepath.Join(projectDir, file)); err == nil {
+			files = append(files, file)
+		}
+	}
+	return run(projectDir, cfg, files, changed)
+}
+
+// run runs the checks specified by the provided configuration. If files is non-nil, the checks that can check
+// individual files only check the provided files as described by RunFiles. If changed is non-nil, the checks that
+// support incremental operation are provided the changed files and the checks whose results cannot be affected by the
+// changed files are not run as described by RunChanged.
+func run(projectDir string, cfg config.Checks, files, changed []string) ([]Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var names []string
+	var cmds [][]string
+	var stdins []string
+	var pkgCounts []int
+	for _, name := range cfg.SortedNames() {
+		check := cfg.Checks[name]
+		var stdin string
+		if files != nil {
+			if args, ok := fileArgs(name, files); ok {
+				if len(args) == 0 {
+					continue
+				}
+				check.Pkgs = args
+			}
+		}
+		if changed != nil {
+			switch name {
+			case "nobadfuncs":
+				changedGoFiles := goFiles(changed)
+				if len(changedGoFiles) == 0 {
+					continue
+				}
+				check.Args = append([]string{"--changed-only", "--changed-files", "-"}, check.Args...)
+				stdin = strings.Join(changedGoFiles, "\n") + "\n"
+			case "extimport", "novendor":
+				if !projectFilesChanged(changed) {
+					continue
+				}
+			}
+		}
+		cmd, err := commandLine(projectDir, name, check)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		cmds = append(cmds, cmd)
+		stdins = append(stdins, stdin)
+		pkgCounts = append(pkgCounts, pkgCount(projectDir, check.Pkgs))
+	}
+
+	results := make([]Result, len(names))
+	if !cfg.Parallel {
+		for i, name := range names {
+			results[i] = runCheck(projectDir, name, cmds[i], stdins[i])
+			results[i].Packages = pkgCounts[i]
+		}
+		return results, nil
+	}
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = runCheck(projectDir, name, cmds[i], stdins[i])
+			results[i].Packages = pkgCounts[i]
+		}(i, name)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// goFiles returns the provided files that are Go files.
+func goFiles(files []string) []string {
+	var out []string
+	for _, file := range files {
+		if filepath.Ext(file) == ".go" {
+			out = append(out, file)
+		}
+	}
+	return out
+}
+
+// projectFilesChanged returns true if any of the provided changed files can affect the result of a check of the
+// imports of the entire project, which is the case if it is a Go file or a file in a vendor directory.
+func projectFilesChanged(changed []string) bool {
+	for _, file := range changed {
+		file = filepath.ToSlash(filepath.Clean(file))
+		if filepath.Ext(file) == ".go" || strings.HasPrefix(file, "vendor/") || strings.Contains(file, "/vendor/") {
+			return true
+		}
+	}
+	return false
+}
+
+// pkgCount returns the number of packages in projectDir that are checked by a check that is provided the packages pkgs
+// (all of the packages in projectDir if empty). Arguments that end in "/..." match the directories that contain Go
+// files in the directory and its subdirectories (vendor, testdata and hidden directories are skipped), arguments that
+// are Go files match the directory that contains them and other arguments match the directory with the provided path
+// if it contains Go files. Paths are relative to projectDir and import paths are not counted.
+func pkgCount(projectDir string, pkgs []string) int {
+	if len(pkgs) == 0 {
+		pkgs = []string{"./..."}
+	}
+	dirs := make(map[string]struct{})
+	for _, pkg := range pkgs {
+		recursive := pkg == "..." || strings.HasSuffix(pkg, "/...")
+		if recursive {
+			pkg = strings.TrimSuffix(strings.TrimSuffix(pkg, "..."), "/")
+		}
+		pkgPath := filepath.FromSlash(pkg)
+		if !filepath.IsAbs(pkgPath) {
+			pkgPath = filepath.Join(projectDir, pkgPath)
+		}
+		switch {
+		case filepath.Ext(pkgPath) == ".go":
+			dirs[filepath.Dir(pkgPath)] = struct{}{}
+		case recursive:
+			_ = filepath.Walk(pkgPath, func(currPath string, info os.FileInfo, err error) error {
+				if err != nil || !info.IsDir() {
+					return nil
+				}
+				name := info.Name()
+				if currPath != pkgPath && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				if hasGoFiles(currPath) {
+					dirs[currPath] = struct{}{}
+				}
+				return nil
+			})
+		case hasGoFiles(pkgPath):
+			dirs[pkgPath] = struct{}{}
+		}
+	}
+	return len(dirs)
+}
+
+// hasGoFiles returns true if the provided directory contains Go files.
+func hasGoFiles(dir string) bool {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() && filepath.Ext(fileInfo.Name()) == ".go" {
+			return true
+		}
+	}
+	return false
+}
+
+// fileArgs returns the arguments that make the check with the provided name check only the provided files. Returns
+// false if the check cannot check individual files.
+func fileArgs(name string, files []string) ([]string, bool) {
+	var goFiles []string
+	for _, file := range files {
+		file = filepath.ToSlash(filepath.Clean(file))
+		if filepath.Ext(file) != ".go" || vendorutil.IsVendoredBy(path.Dir(file), "") {
+			continue
+		}
+		goFiles = append(goFiles, file)
+	}
+
+	switch name {
+	case "golicense", "ptimports":
+		return goFiles, true
+	case "importalias":
+		dirs := make(map[string]struct{})
+		for _, file := range goFiles {
+			dirs["./"+filepath.ToSlash(filepath.Dir(file))] = struct{}{}
+		}
+		var pkgs []string
+		for dir := range dirs {
+			if dir == "./." {
+				dir = "."
+			}
+			pkgs = append(pkgs, dir)
+		}
+		sort.Strings(pkgs)
+		return pkgs, true
+	default:
+		return nil, false
+	}
+}
+
+// commandLine returns the command line used to run the check with the provided name and configuration.
+func commandLine(projectDir, name string, check config.Check) ([]string, error) {
+	command := check.Command
+	if command == "" {
+		command = name
+	}
+	var args []string
+	switch name {
+	case "golicense", "gogenerate":
+		args = append(args, "--verify")
+	case "ptimports":
+		args = append(args, "-c")
+	}
+	if check.Config != "" {
+		switch name {
+		case "compiles", "golicense", "gogenerate":
+			args = append(args, "--config", check.Config)
+		case "nobadfuncs":
+			// nobadfuncs takes the content of its configuration rather than a path
+			cfgPath := check.Config
+			if !filepath.IsAbs(cfgPath) {
+				cfgPath = filepath.Join(projectDir, cfgPath)
+			}
+			content, err := ioutil.ReadFile(cfgPath)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read configuration for %s", name)
+			}
+			args = append(args, "--config", string(content))
+		case "outparamcheck":
+			args = append(args, "-config", "@"+check.Config)
+		case "ptimports":
+			args = append(args, "-config", check.Config)
+		default:
+			return nil, errors.Errorf("%s does not support a configuration file", name)
+		}
+	}
+	args = append(args, check.Args...)
+	pkgs := check.Pkgs
+	if len(pkgs) == 0 {
+		switch name {
+		case "nobadfuncs", "outparamcheck", "ptimports":
+			pkgs = []string{"./..."}
+		}
+	}
+	args = append(args, pkgs...)
+	return append([]string{command}, args...), nil
+}
+
+// runCheck runs the check with the provided name using the provided command line in projectDir. If stdin is non-empty,
+// it is provided to the check on standard input.
+func runCheck(projectDir, name string, cmdLine []string, stdin string) Result {
+	result := Result{
+		Check:   name,
+		Command: cmdLine,
+	}
+	output := &bytes.Buffer{}
+	cmd := exec.Command(cmdLine[0], cmdLine[1:]...)
+	cmd.Dir = projectDir
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	if cmd.ProcessState != nil {
+		result.MaxMemory = maxRSS(cmd.ProcessState)
+	}
+	result.Output = output.String()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+			result.Error = err.Error()
+		}
+	}
+	return result
+}
@@ -0,0 +1,360 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/config"
+)
+
+func TestCommandLine(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	err = ioutil.WriteFile(path.Join(tmpDir, "nobadfuncs.json"), []byte(`{"func os.Exit(int)": ""}`), 0644)
+	require.NoError(t, err)
+
+	for i, currCase := range []struct {
+		name  string
+		check config.Check
+		want  []string
+	}{
+		{
+			name:  "extimport",
+			check: config.Check{},
+			want:  []string{"extimport"},
+		},
+		{
+			name: "compiles",
+			check: config.Check{
+				Command: "/usr/local/bin/compiles",
+				Config:  "compiles.yml",
+				Args:    []string{"--tags", "integration"},
+				Pkgs:    []string{"foo"},
+			},
+			want: []string{"/usr/local/bin/compiles", "--config", "compiles.yml", "--tags", "integration", "foo"},
+		},
+		{
+			name: "nobadfuncs",
+			check: config.Check{
+				Config: "nobadfuncs.json",
+			},
+			want: []string{"nobadfuncs", "--config", `{"func os.Exit(int)": ""}`, "./..."},
+		},
+		{
+			name: "outparamcheck",
+			check: config.Check{
+				Config: "outparamcheck.yml",
+				Args:   []string{"-infer"},
+			},
+			want: []string{"outparamcheck", "-config", "@outparamcheck.yml", "-infer", "./..."},
+		},
+		{
+			name: "golicense",
+			check: config.Check{
+				Config: "license.yml",
+			},
+			want: []string{"golicense", "--verify", "--config", "license.yml"},
+		},
+		{
+			name:  "gogenerate",
+			check: config.Check{},
+			want:  []string{"gogenerate", "--verify"},
+		},
+		{
+			name: "ptimports",
+			check: config.Check{
+				Config: "ptimports.yml",
+			},
+			want: []string{"ptimports", "-c", "-config", "ptimports.yml", "./..."},
+		},
+	} {
+		got, err := commandLine(tmpDir, currCase.name, currCase.check)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, got, "Case %d", i)
+	}
+
+	_, err = commandLine(tmpDir, "novendor", config.Check{Config: "novendor.yml"})
+	assert.EqualError(t, err, "novendor does not support a configuration file")
+}
+
+func TestRun(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	for _, parallel := range []bool{false, true} {
+		cfg := config.Checks{
+			Parallel: parallel,
+			Checks: map[string]config.Check{
+				"compiles": {
+					Command: "sh",
+					Args:    []string{"-c", "echo compile error; exit 3"},
+				},
+				"extimport": {
+					Command: "sh",
+					Args:    []string{"-c", "pwd"},
+				},
+				"novendor": {
+					Command: path.Join(tmpDir, "nonexistent"),
+				},
+			},
+		}
+		results, err := Run(tmpDir, cfg)
+		require.NoError(t, err, "parallel: %v", parallel)
+		require.Equal(t, 3, len(results), "parallel: %v", parallel)
+
+		assert.Equal(t, "extimport", results[0].Check, "parallel: %v", parallel)
+		assert.Equal(t, 0, results[0].ExitCode, "parallel: %v", parallel)
+		assert.Equal(t, tmpDir+"\n", results[0].Output, "parallel: %v", parallel)
+		assert.True(t, results[0].Passed(), "parallel: %v", parallel)
+		assert.True(t, results[0].Duration > 0, "parallel: %v", parallel)
+
+		assert.Equal(t, "novendor", results[1].Check, "parallel: %v", parallel)
+		assert.Equal(t, -1, results[1].ExitCode, "parallel: %v", parallel)
+		assert.NotEqual(t, "", results[1].Error, "parallel: %v", parallel)
+		assert.False(t, results[1].Passed(), "parallel: %v", parallel)
+
+		assert.Equal(t, "compiles", results[2].Check, "parallel: %v", parallel)
+		assert.Equal(t, 3, results[2].ExitCode, "parallel: %v", parallel)
+		assert.Equal(t, "compile error\n", results[2].Output, "parallel: %v", parallel)
+		assert.False(t, results[2].Passed(), "parallel: %v", parallel)
+
+		assert.False(t, NewReport(results).Passed, "parallel: %v", parallel)
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	echoCmd := path.Join(tmpDir, "echo.sh")
+	err = ioutil.WriteFile(echoCmd, []byte("#!/bin/sh\necho \"$@\"\n"), 0755)
+	require.NoError(t, err)
+	// prints its arguments and fails like "ptimports -c" does if any files are not formatted
+	failCmd := path.Join(tmpDir, "fail.sh")
+	err = ioutil.WriteFile(failCmd, []byte("#!/bin/sh\necho \"$@\"\nexit 1\n"), 0755)
+	require.NoError(t, err)
+	cfg := config.Checks{
+		Checks: map[string]config.Check{
+			"extimport": {
+				Command: echoCmd,
+			},
+			"importalias": {
+				Command: echoCmd,
+			},
+			"golicense": {
+				Command: echoCmd,
+				Pkgs:    []string{"foo.go"},
+			},
+			"ptimports": {
+				Command: failCmd,
+			},
+		},
+	}
+	results, err := RunFiles(tmpDir, cfg, []string{"foo/foo.go", "foo/foo_test.go", "main.go", "vendor/dep/dep.go", "README.md"})
+	require.NoError(t, err)
+	require.Equal(t, 4, len(results))
+
+	// project-level checks are run normally
+	assert.Equal(t, "extimport", results[0].Check)
+	assert.Equal(t, "\n", results[0].Output)
+	assert.True(t, results[0].Passed())
+
+	assert.Equal(t, "importalias", results[1].Check)
+	assert.Equal(t, ". ./foo\n", results[1].Output)
+
+	assert.Equal(t, "golicense", results[2].Check)
+	assert.Equal(t, "--verify foo/foo.go foo/foo_test.go main.go\n", results[2].Output)
+
+	// the exit code of ptimports determines whether it passed
+	assert.Equal(t, "ptimports", results[3].Check)
+	assert.Equal(t, "-c foo/foo.go foo/foo_test.go main.go\n", results[3].Output)
+	assert.Equal(t, 1, results[3].ExitCode)
+
+	// checks that can check individual files are not run if no files apply to them
+	results, err = RunFiles(tmpDir, cfg, []string{"vendor/dep/dep.go"})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, "extimport", results[0].Check)
+}
+
+func TestRunChanged(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	// prints its arguments followed by its standard input
+	echoCmd := path.Join(tmpDir, "echo.sh")
+	err = ioutil.WriteFile(echoCmd, []byte("#!/bin/sh\necho \"$@\"\ncat\n"), 0755)
+	require.NoError(t, err)
+	for _, currFile := range []string{"foo/foo.go", "main.go", "README.md"} {
+		err := os.MkdirAll(path.Join(tmpDir, path.Dir(currFile)), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path.Join(tmpDir, currFile), []byte("package foo\n"), 0644)
+		require.NoError(t, err)
+	}
+	cfg := config.Checks{
+		Checks: map[string]config.Check{
+			"compiles": {
+				Command: echoCmd,
+			},
+			"extimport": {
+				Command: echoCmd,
+			},
+			"nobadfuncs": {
+				Command: echoCmd,
+				Args:    []string{"--format", "json"},
+			},
+			"novendor": {
+				Command: echoCmd,
+			},
+			"ptimports": {
+				Command: echoCmd,
+			},
+		},
+	}
+
+	// "bar/bar.go" was deleted, so it is only provided to nobadfuncs
+	results, err := RunChanged(tmpDir, cfg, []string{"bar/bar.go", "foo/foo.go", "README.md"})
+	require.NoError(t, err)
+	require.Equal(t, 5, len(results))
+
+	assert.Equal(t, "extimport", results[0].Check)
+	assert.Equal(t, "novendor", results[1].Check)
+	assert.Equal(t, "compiles", results[2].Check)
+	assert.Equal(t, "\n", results[2].Output)
+
+	assert.Equal(t, "nobadfuncs", results[3].Check)
+	assert.Equal(t, "--changed-only --changed-files - --format json ./...\nbar/bar.go\nfoo/foo.go\n", results[3].Output)
+
+	assert.Equal(t, "ptimports", results[4].Check)
+	assert.Equal(t, "-c foo/foo.go\n", results[4].Output)
+
+	// only the whole-project checks that can be affected by vendored files are run if only vendored files changed
+	results, err = RunChanged(tmpDir, cfg, []string{"vendor/github.com/org/dep/LICENSE"})
+	require.NoError(t, err)
+	var checks []string
+	for _, result := range results {
+		checks = append(checks, result.Check)
+	}
+	assert.Equal(t, []string{"extimport", "novendor", "compiles"}, checks)
+
+	// whole-project import checks are not run if no Go or vendored files changed
+	results, err = RunChanged(tmpDir, cfg, []string{"README.md"})
+	require.NoError(t, err)
+	checks = nil
+	for _, result := range results {
+		checks = append(checks, result.Check)
+	}
+	assert.Equal(t, []string{"compiles"}, checks)
+}
+
+func TestPkgCount(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	for _, file := range []string{
+		"main.go",
+		"foo/foo.go",
+		"foo/bar/bar.go",
+		"foo/README.md",
+		"baz/README.md",
+		"vendor/dep/dep.go",
+		"foo/testdata/data.go",
+		".hidden/hidden.go",
+	} {
+		err := os.MkdirAll(path.Join(tmpDir, path.Dir(file)), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path.Join(tmpDir, file), []byte("package foo\n"), 0644)
+		require.NoError(t, err)
+	}
+
+	for i, currCase := range []struct {
+		pkgs []string
+		want int
+	}{
+		{nil, 3},
+		{[]string{"./..."}, 3},
+		{[]string{"./foo/..."}, 2},
+		{[]string{"./foo", "./baz"}, 1},
+		{[]string{"foo/foo.go", "foo/foo_test.go", "main.go"}, 2},
+		{[]string{"./foo/...", "./foo/bar"}, 2},
+		{[]string{"github.com/org/project"}, 0},
+	} {
+		assert.Equal(t, currCase.want, pkgCount(tmpDir, currCase.pkgs), "Case %d", i)
+	}
+}
+
+func TestPrintTimings(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := PrintTimings([]Result{
+		{Check: "extimport", Duration: 1234567 * time.Microsecond, Packages: 12, MaxMemory: 50 << 20},
+		{Check: "novendor", ExitCode: -1, Error: "executable file not found"},
+	}, buf)
+	require.NoError(t, err)
+	assert.Equal(t, `CHECK      DURATION  PACKAGES  MAX MEMORY
+extimport  1.235s    12        50.0 MiB
+novendor   0s        0         -
+`, buf.String())
+}
+
+func TestWriteProfile(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteProfile([]Result{
+		{Check: "compiles", Command: []string{"compiles"}, Duration: 3 * time.Second, Packages: 42, MaxMemory: 300 << 20},
+	}, buf)
+	require.NoError(t, err)
+
+	gz, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	for _, str := range []string{"wall", "nanoseconds", "max_memory", "bytes", "packages", "compiles"} {
+		assert.Contains(t, string(content), str)
+	}
+}
+
+func TestPrintText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	PrintText([]Result{
+		{Check: "extimport", Output: ""},
+		{Check: "compiles", ExitCode: 1, Output: "foo.go:1:1: error"},
+		{Check: "novendor", ExitCode: -1, Error: "executable file not found"},
+	}, buf)
+	assert.Equal(t, `Running extimport...
+Finished extimport
+Running compiles...
+foo.go:1:1: error
+Finished compiles: failed with exit code 1
+Running novendor...
+Failed to run novendor: executable file not found
+2 of 3 checks failed: compiles, novendor
+`, buf.String())
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vendorutil provides functions for working with the import paths and directories of vendored packages. Paths
+// are considered to be vendored if one of their elements is "vendor", which is how the go tool resolves vendored
+// packages.
+package vendorutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// vendorDirName is the name of vendor directories.
+const vendorDirName = "vendor"
+
+// SplitVendorPath splits the provided slash-separated path after its innermost "vendor" element and returns the path up
+// to and including the element and the path that follows it. For example, "foo/vendor/inner/vendor/github.com/org/lib"
+// is split into "foo/vendor/inner/vendor" and "github.com/org/lib". If the path does not contain a "vendor" element,
+// the first return value is empty and the second is the provided path.
+func SplitVendorPath(p string) (string, string) {
+	parts := strings.Split(p, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == vendorDirName {
+			return strings.Join(parts[:i+1], "/"), strings.Join(parts[i+1:], "/")
+		}
+	}
+	return "", p
+}
+
+// StripVendorPrefix returns the provided slash-separated import path without the path of the innermost vendor directory
+// that contains it. For example, both "github.com/org/project/vendor/github.com/org/lib" and
+// "vendor/github.com/org/lib" become "github.com/org/lib". Paths that do not contain a "vendor" element are returned
+// unmodified.
+func StripVendorPrefix(importPath string) string {
+	_, stripped := SplitVendorPath(importPath)
+	return stripped
+}
+
+// IsVendoredBy returns true if pkg is in a vendor directory within root. Both may be import paths or file system paths
+// (in which case both must be absolute or both must be relative). If root is empty, returns true if pkg is in any
+// vendor directory. Returns false if pkg is not within root.
+func IsVendoredBy(pkg, root string) bool {
+	pkg = filepath.ToSlash(pkg)
+	if root != "" {
+		root = strings.TrimSuffix(filepath.ToSlash(root), "/")
+		if !strings.HasPrefix(pkg, root+"/") {
+			return false
+		}
+		pkg = pkg[len(root)+1:]
+	}
+	for _, part := range strings.Split(pkg, "/") {
+		if part == vendorDirName {
+			return true
+		}
+	}
+	return false
+}
+
+// VendorDirsUnder returns the paths of the vendor directories in root and its subdirectories in lexical order. Vendor
+// directories within vendor directories are not returned and hidden directories are not searched.
+func VendorDirsUnder(root string) ([]string, error) {
+	var vendorDirs []string
+	if err := filepath.Walk(root, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || currPath == root {
+			return nil
+		}
+		switch name := info.Name(); {
+		case name == vendorDirName:
+			vendorDirs = append(vendorDirs, currPath)
+			return filepath.SkipDir
+		case strings.HasPrefix(name, "."):
+			return filepath.SkipDir
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return vendorDirs, nil
+}
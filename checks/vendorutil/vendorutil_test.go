@@ -0,0 +1,118 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vendorutil_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/vendorutil"
+)
+
+func TestSplitVendorPath(t *testing.T) {
+	for i, currCase := range []struct {
+		in         string
+		wantVendor string
+		wantPath   string
+	}{
+		{"github.com/org/project/vendor/github.com/org/lib", "github.com/org/project/vendor", "github.com/org/lib"},
+		{"foo/bar/vendor/inner/vendor/github.com/org/repo", "foo/bar/vendor/inner/vendor", "github.com/org/repo"},
+		{"vendor/github.com/org/lib", "vendor", "github.com/org/lib"},
+		{"github.com/org/lib", "", "github.com/org/lib"},
+		{"github.com/org/govendor/lib", "", "github.com/org/govendor/lib"},
+		{"github.com/org/project/vendor", "github.com/org/project/vendor", ""},
+	} {
+		gotVendor, gotPath := vendorutil.SplitVendorPath(currCase.in)
+		assert.Equal(t, currCase.wantVendor, gotVendor, "Case %d", i)
+		assert.Equal(t, currCase.wantPath, gotPath, "Case %d", i)
+	}
+}
+
+func TestStripVendorPrefix(t *testing.T) {
+	for i, currCase := range []struct {
+		in   string
+		want string
+	}{
+		{"github.com/org/project/vendor/github.com/org/lib", "github.com/org/lib"},
+		{"github.com/org/project/vendor/a/vendor/b", "b"},
+		{"vendor/github.com/org/lib", "github.com/org/lib"},
+		{"github.com/org/lib", "github.com/org/lib"},
+		{"github.com/org/vendored/lib", "github.com/org/vendored/lib"},
+		{"fmt", "fmt"},
+	} {
+		assert.Equal(t, currCase.want, vendorutil.StripVendorPrefix(currCase.in), "Case %d", i)
+	}
+}
+
+func TestIsVendoredBy(t *testing.T) {
+	for i, currCase := range []struct {
+		pkg  string
+		root string
+		want bool
+	}{
+		{"github.com/org/project/vendor/github.com/org/lib", "github.com/org/project", true},
+		{"github.com/org/project/vendor/github.com/org/lib", "github.com/org/project/", true},
+		{"github.com/org/project/foo/vendor/github.com/org/lib", "github.com/org/project", true},
+		{"github.com/org/project/vendor", "github.com/org/project", true},
+		{"github.com/org/project/foo", "github.com/org/project", false},
+		{"github.com/org/project/govendor/foo", "github.com/org/project", false},
+		{"github.com/org/other/vendor/github.com/org/lib", "github.com/org/project", false},
+		{"github.com/org/project-other/vendor/lib", "github.com/org/project", false},
+		{"/go/src/github.com/org/project/vendor/lib", "/go/src/github.com/org/project", true},
+		{"/go/src/github.com/org/vendor/project/foo", "/go/src/github.com/org/vendor/project", false},
+		{"github.com/org/project/vendor/lib", "", true},
+		{"vendor/lib", "", true},
+		{"github.com/org/lib", "", false},
+	} {
+		assert.Equal(t, currCase.want, vendorutil.IsVendoredBy(currCase.pkg, currCase.root), "Case %d", i)
+	}
+}
+
+func TestVendorDirsUnder(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	for _, dir := range []string{
+		"vendor/github.com/org/lib/vendor/github.com/org/nested",
+		"foo/vendor/github.com/org/lib",
+		"foo/bar",
+		".hidden/vendor/github.com/org/lib",
+		"vendored/lib",
+	} {
+		err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755)
+		require.NoError(t, err)
+	}
+	// files named "vendor" are not vendor directories
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "foo", "bar", "vendor"), []byte("vendor"), 0644)
+	require.NoError(t, err)
+
+	got, err := vendorutil.VendorDirsUnder(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(tmpDir, "foo", "vendor"),
+		filepath.Join(tmpDir, "vendor"),
+	}, got)
+
+	got, err = vendorutil.VendorDirsUnder(filepath.Join(tmpDir, "foo", "bar"))
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/diagnostic"
+)
+
+// Classes of type-checking errors that can be disabled. Errors in these classes are reported by the type-checker (and
+// rejected by the compiler), but some projects consider them style issues that are enforced by other checks.
+const (
+	// unusedVariableClass is the class of errors for local variables that are declared but not used.
+	unusedVariableClass = "unused-variable"
+	// unusedImportClass is the class of errors for imports that are not used.
+	unusedImportClass = "unused-import"
+	// impossibleAssertionClass is the class of errors for type assertions and type switch cases that can never
+	// succeed because the type does not implement the interface.
+	impossibleAssertionClass = "impossible-assertion"
+)
+
+// errorClasses returns the classes of type-checking errors that can be disabled.
+func errorClasses() []string {
+	return []string{unusedVariableClass, unusedImportClass, impossibleAssertionClass}
+}
+
+// errorClassRegexps matches the messages of the errors in each class. The type-checker does not export error codes, so
+// errors are classified by their message. Both the current and the older forms of the messages are matched.
+var errorClassRegexps = map[string]*regexp.Regexp{
+	unusedVariableClass:      regexp.MustCompile(`^(declared (and|but) not used: \S+|\S+ declared (and|but) not used)`),
+	unusedImportClass:        regexp.MustCompile(`^".+" imported (as \S+ )?(and|but) not used`),
+	impossibleAssertionClass: regexp.MustCompile(`^impossible type (assertion|switch case)`),
+}
+
+// validateDisabledClasses returns an error if any of the provided classes is not a class of type-checking errors that
+// can be disabled.
+func validateDisabledClasses(classes []string) error {
+	for _, currClass := range classes {
+		if _, ok := errorClassRegexps[currClass]; !ok {
+			return errors.Errorf("invalid error class %q: must be one of %v", currClass, errorClasses())
+		}
+	}
+	return nil
+}
+
+// filterDisabledClasses returns the provided diagnostics without the type-checking errors (including the errors in
+// dependencies) that are in one of the provided classes.
+func filterDisabledClasses(diags []diagnostic.Diagnostic, disabled []string) []diagnostic.Diagnostic {
+	if len(disabled) == 0 {
+		return diags
+	}
+	var out []diagnostic.Diagnostic
+	for _, currDiag := range diags {
+		if currDiag.RuleID == typeRule || currDiag.RuleID == dependencyRule {
+			if isInClasses(currDiag.Message, disabled) {
+				continue
+			}
+		}
+		out = append(out, currDiag)
+	}
+	return out
+}
+
+func isInClasses(msg string, classes []string) bool {
+	for _, currClass := range classes {
+		if errorClassRegexps[currClass].MatchString(msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -16,77 +16,428 @@ package main
 
 import (
 	"fmt"
+	"go/build"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/nmiyake/pkg/errorstringer"
 	"github.com/palantir/pkg/cli"
 	"github.com/palantir/pkg/cli/flag"
+	"github.com/palantir/pkg/matcher"
 	"github.com/palantir/pkg/pkgpath"
 	"github.com/pkg/errors"
-	"golang.org/x/tools/go/loader"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/projectconfig"
+)
+
+const (
+	checkName  = "compiles"
+	syntaxRule = "syntax"
+	typeRule   = "type"
+	// packageClauseRule is the rule for test files whose package clause does not match the package in their directory
+	packageClauseRule = "package-clause"
+	// dependencyRule is the rule for errors in packages outside of the project that are imported by project packages
+	dependencyRule = "dependency"
 )
 
 func main() {
-	const pkgsFlagName = "pkgs"
+	const (
+		pkgsFlagName             = "pkgs"
+		configFlagName           = "config"
+		excludeFlagName          = "exclude"
+		excludeGeneratedFlagName = "exclude-generated"
+		dependencyErrsFlagName   = "dependency-errors"
+		disableFlagName          = "disable"
+		parallelismFlagName      = "parallelism"
+		formatFlagName           = "format"
+		watchFlagName            = "watch"
+		noColorFlagName          = "no-color"
+	)
 	app := cli.NewApp(cli.DebugHandler(errorstringer.SingleStack))
-	app.Flags = append(app.Flags, flag.StringSlice{
-		Name:  pkgsFlagName,
-		Usage: "paths to the packages to check",
-	})
+	app.Flags = append(app.Flags,
+		flag.StringFlag{
+			Name:  configFlagName,
+			Usage: "path to a YAML configuration file that specifies the packages to exclude",
+		},
+		flag.StringFlag{
+			Name:  projectconfig.FlagName,
+			Usage: "path to a project configuration file whose exclude section specifies additional packages to exclude",
+		},
+		flag.StringFlag{
+			Name: excludeFlagName,
+			Usage: "glob matching the paths (relative to the working directory) of packages that should not be " +
+				"checked. Can be specified multiple times",
+		},
+		flag.BoolFlag{
+			Name:  excludeGeneratedFlagName,
+			Usage: "do not check packages in which every Go file is generated",
+		},
+		flag.BoolFlag{
+			Name: dependencyErrsFlagName,
+			Usage: "also report errors in the packages outside of the project that are imported by project packages " +
+				"along with the project packages that import them",
+		},
+		flag.StringFlag{
+			Name: disableFlagName,
+			Usage: "class of type-checking errors that should not be reported: 'unused-variable', 'unused-import' or " +
+				"'impossible-assertion'. Can be specified multiple times",
+		},
+		flag.StringFlag{
+			Name:  loader.GOOSFlagName,
+			Usage: "target operating system to use when type-checking",
+		},
+		flag.StringFlag{
+			Name:  loader.GOARCHFlagName,
+			Usage: "target architecture to use when type-checking",
+		},
+		flag.StringFlag{
+			Name: loader.TagsFlagName,
+			Usage: "comma-separated set of build tags to use when type-checking. Can be specified multiple times, in " +
+				"which case the packages are type-checked once for each set of tags. Tags that are operating systems " +
+				"or architectures set GOOS or GOARCH",
+		},
+		flag.IntFlag{
+			Name:  parallelismFlagName,
+			Value: runtime.NumCPU(),
+			Usage: "maximum number of packages to type-check concurrently",
+		},
+		flag.StringFlag{
+			Name:  formatFlagName,
+			Value: diagnostic.FormatText,
+			Usage: "format of the output. Must be 'text', 'json', 'checkstyle' or 'github'",
+		},
+		flag.BoolFlag{
+			Name:  noColorFlagName,
+			Usage: "do not colorize the text output. The output is only colorized when it is written to a terminal",
+		},
+		flag.BoolFlag{
+			Name: watchFlagName,
+			Usage: "keep running and re-check the packages whose files change (and the packages that import them) " +
+				"whenever Go files in the working directory change, printing the errors that were resolved or introduced",
+		},
+		flag.StringFlag{
+			Name:  baseline.FlagName,
+			Usage: "path to a baseline file: errors recorded in the baseline are not reported",
+		},
+		flag.StringFlag{
+			Name:  baseline.WriteFlagName,
+			Usage: "path to which a baseline file that records the current errors is written",
+		},
+		flag.StringSlice{
+			Name:  pkgsFlagName,
+			Usage: "paths to the packages to check (paths that end in '/...' match the packages in the directory and its subdirectories)",
+		},
+	)
 	app.Action = func(ctx cli.Context) error {
 		wd, err := dirs.GetwdEvalSymLinks()
 		if err != nil {
 			return errors.Wrapf(err, "Failed to get working directory")
 		}
-		return doCompiles(wd, ctx.Slice(pkgsFlagName), ctx.App.Stdout)
+		cfg, err := loadConfig(ctx.String(configFlagName))
+		if err != nil {
+			return err
+		}
+		projectCfg, err := projectconfig.Load(ctx.String(projectconfig.FlagName))
+		if err != nil {
+			return err
+		}
+		cfg.Exclude.Add(projectCfg.Exclude)
+		for _, currPath := range ctx.StringSlice(excludeFlagName) {
+			if currPath != "" {
+				cfg.Exclude.Paths = append(cfg.Exclude.Paths, currPath)
+			}
+		}
+		if ctx.Bool(excludeGeneratedFlagName) {
+			cfg.ExcludeGenerated = true
+		}
+		if ctx.Bool(dependencyErrsFlagName) {
+			cfg.DependencyErrors = true
+		}
+		for _, currClass := range ctx.StringSlice(disableFlagName) {
+			if currClass != "" {
+				cfg.Disable = append(cfg.Disable, currClass)
+			}
+		}
+		var tagSets []string
+		if goos := ctx.String(loader.GOOSFlagName); goos != "" {
+			cfg.GOOS = goos
+		}
+		if goarch := ctx.String(loader.GOARCHFlagName); goarch != "" {
+			cfg.GOARCH = goarch
+		}
+		for _, currTagSet := range ctx.StringSlice(loader.TagsFlagName) {
+			if currTagSet != "" {
+				tagSets = append(tagSets, currTagSet)
+			}
+		}
+		bl := baseline.Options{
+			Path:      ctx.String(baseline.FlagName),
+			WritePath: ctx.String(baseline.WriteFlagName),
+		}
+		if ctx.Bool(watchFlagName) {
+			if format := ctx.String(formatFlagName); format != diagnostic.FormatText {
+				return errors.Errorf("--%s cannot be used with format %q", watchFlagName, format)
+			}
+			return doWatch(wd, ctx.Slice(pkgsFlagName), cfg, tagSets, ctx.Int(parallelismFlagName), bl, ctx.App.Stdout, nil)
+		}
+		color := !ctx.Bool(noColorFlagName) && isTerminal(ctx.App.Stdout)
+		return doCompiles(wd, ctx.Slice(pkgsFlagName), cfg, tagSets, ctx.Int(parallelismFlagName), ctx.String(formatFlagName), color, bl, ctx.App.Stdout)
 	}
 	os.Exit(app.Run(os.Args))
 }
 
-func doCompiles(projectDir string, pkgPaths []string, w io.Writer) error {
+// doCompiles type-checks the packages with the provided paths (or all of the packages in projectDir if no paths are
+// provided) along with their tests. Paths that end in "/..." are patterns that are resolved using pkgPathsForArgs. When
+// the packages are listed from projectDir, the packages excluded by cfg are not checked. Packages are type-checked
+// concurrently, with at most parallelism packages being type-checked at once; a package is only type-checked once all
+// of the project packages it imports have been checked. The packages are type-checked for the GOOS and GOARCH specified
+// by cfg (or those of the default build context). If tag sets are provided, the packages are type-checked once for each
+// comma-separated set of tags and every error is annotated with the tag sets for which it occurred. Type-checking
+// errors in the classes disabled by cfg are not reported. The errors that are not suppressed by the baseline are
+// printed to w as diagnostics in the provided format. In the text format, the errors are grouped by package and are
+// colorized if color is true.
+func doCompiles(projectDir string, pkgPaths []string, cfg config, tagSets []string, parallelism int, format string, color bool, bl baseline.Options, w io.Writer) error {
+	if err := diagnostic.ValidateFormat(format); err != nil {
+		return err
+	}
+	if err := validateDisabledClasses(cfg.Disable); err != nil {
+		return err
+	}
+
+	gopathSrc, err := gopathSrcDir(projectDir)
+	if err != nil {
+		return err
+	}
+
+	baseCtx := loader.Options{
+		GOOS:   cfg.GOOS,
+		GOARCH: cfg.GOARCH,
+	}.Context()
+
+	if pkgPaths, err = pkgPathsForArgs(projectDir, gopathSrc, baseCtx, cfg, pkgPaths); err != nil {
+		return err
+	}
+
+	ctxTagSets := tagSets
+	if len(ctxTagSets) == 0 {
+		ctxTagSets = []string{""}
+	}
+
+	// errors in the order in which they were first encountered along with the tag sets for which they occurred
+	var errs []diagnostic.Diagnostic
+	errTagSets := make(map[string][]string)
+	for _, currTagSet := range ctxTagSets {
+		ctx := loader.ContextForTags(baseCtx, currTagSet)
+		checker := newTypeChecker(&ctx, cfg.DependencyErrors)
+		units, err := checker.units(pkgPaths, projectDir)
+		if err != nil {
+			return err
+		}
+		checker.check(units, parallelism)
+
+		// errors are recorded in package order followed by the errors in dependencies (if requested). The non-test
+		// files of a package are type-checked both as part of the package and as part of its test variant, so errors
+		// are de-duplicated.
+		var unitErrs []diagnostic.Diagnostic
+		for _, currUnit := range units {
+			unitErrs = append(unitErrs, currUnit.errs...)
+		}
+		unitErrs = append(unitErrs, checker.dependencyErrs(units)...)
+		unitErrs = filterDisabledClasses(unitErrs, cfg.Disable)
+		for _, currErr := range unitErrs {
+			key := currErr.String()
+			prevTagSets, ok := errTagSets[key]
+			if !ok {
+				errs = append(errs, currErr)
+			}
+			if len(prevTagSets) == 0 || prevTagSets[len(prevTagSets)-1] != currTagSet {
+				errTagSets[key] = append(prevTagSets, currTagSet)
+			}
+		}
+	}
+
+	errs, err = bl.Apply(projectDir, errs)
+	if err != nil {
+		return err
+	}
+	if len(tagSets) > 0 {
+		for i, currErr := range errs {
+			errs[i].Message = fmt.Sprintf("%s [tags: %s]", currErr.Message, strings.Join(errTagSets[currErr.String()], "; "))
+		}
+	}
+	if format == diagnostic.FormatText {
+		if err := printGrouped(w, errs, gopathSrc, color); err != nil {
+			return err
+		}
+	} else if err := diagnostic.Print(w, format, errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		// return blank error if any errors were encountered. Errors are printed to the writer in the proper format so
+		// no need to create any other output.
+		return fmt.Errorf("")
+	}
+	return nil
+}
+
+// gopathSrcDir returns the "src" directory of the GOPATH. Returns an error if projectDir is not an absolute path in it.
+func gopathSrcDir(projectDir string) (string, error) {
 	if !path.IsAbs(projectDir) {
-		return fmt.Errorf("projectDir must be an absolute path: %v", projectDir)
+		return "", fmt.Errorf("projectDir must be an absolute path: %v", projectDir)
 	}
 
 	gopath := os.Getenv("GOPATH")
 	if gopath == "" {
-		return fmt.Errorf("GOPATH environment variable must be set")
+		return "", fmt.Errorf("GOPATH environment variable must be set")
 	}
 
-	if relPath, err := filepath.Rel(path.Join(gopath, "src"), projectDir); err != nil || strings.HasPrefix(relPath, "../") {
-		return fmt.Errorf("Project directory %v must be a subdirectory of $GOPATH/src (%v)", projectDir, path.Join(gopath, "src"))
+	gopathSrc := path.Join(gopath, "src")
+	if relPath, err := filepath.Rel(gopathSrc, projectDir); err != nil || strings.HasPrefix(relPath, "../") {
+		return "", fmt.Errorf("Project directory %v must be a subdirectory of $GOPATH/src (%v)", projectDir, gopathSrc)
 	}
+	return gopathSrc, nil
+}
 
-	if len(pkgPaths) == 0 {
-		pkgs, err := pkgpath.PackagesInDir(projectDir, pkgpath.DefaultGoPkgExcludeMatcher())
-		if err != nil {
-			return fmt.Errorf("Failed to list packages: %v", err)
+// pkgPathsForArgs returns the paths of the packages that should be checked for the provided arguments. If no arguments
+// are provided, the packages returned by projectPkgPaths are returned. An argument that ends in "/..." (or that is
+// "...") is a pattern that matches the packages returned by projectPkgPaths that are in the directory before the "/..."
+// or in one of its subdirectories: the directory is resolved relative to projectDir if it is "." or starts with "./" or
+// "../" and is interpreted as an import path otherwise. Returns an error if a pattern does not match any packages.
+// Other arguments are returned unchanged.
+func pkgPathsForArgs(projectDir, gopathSrc string, ctx build.Context, cfg config, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return projectPkgPaths(projectDir, gopathSrc, ctx, cfg)
+	}
+
+	var projectPkgs []string
+	var pkgPaths []string
+	seen := make(map[string]struct{})
+	for _, currArg := range args {
+		if currArg != "..." && !strings.HasSuffix(currArg, "/...") {
+			if _, ok := seen[currArg]; !ok {
+				seen[currArg] = struct{}{}
+				pkgPaths = append(pkgPaths, currArg)
+			}
+			continue
+		}
+		if projectPkgs == nil {
+			var err error
+			if projectPkgs, err = projectPkgPaths(projectDir, gopathSrc, ctx, cfg); err != nil {
+				return nil, err
+			}
 		}
 
-		pkgPaths, err = pkgs.Paths(pkgpath.GoPathSrcRelative)
-		if err != nil {
-			return fmt.Errorf("Failed to convert package paths: %v", err)
+		dir := strings.TrimSuffix(strings.TrimSuffix(currArg, "..."), "/")
+		switch {
+		case path.IsAbs(dir):
+		case dir == "" || dir == "." || dir == ".." || strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "../"):
+			dir = path.Join(projectDir, dir)
+		default:
+			dir = path.Join(gopathSrc, dir)
+		}
+
+		matched := false
+		for _, currPkgPath := range projectPkgs {
+			if pkgDir := path.Join(gopathSrc, currPkgPath); pkgDir != dir && !strings.HasPrefix(pkgDir, dir+"/") {
+				continue
+			}
+			matched = true
+			if _, ok := seen[currPkgPath]; !ok {
+				seen[currPkgPath] = struct{}{}
+				pkgPaths = append(pkgPaths, currPkgPath)
+			}
+		}
+		if !matched {
+			return nil, errors.Errorf("pattern %s did not match any packages", currArg)
 		}
 	}
+	return pkgPaths, nil
+}
 
-	cfg := loader.Config{}
-	for _, currPkgPath := range pkgPaths {
-		cfg.ImportWithTests(currPkgPath)
+// projectPkgPaths returns the import paths of the packages in projectDir that should be checked, which are the packages
+// that match the provided build context and that are not excluded by cfg.
+func projectPkgPaths(projectDir, gopathSrc string, ctx build.Context, cfg config) ([]string, error) {
+	exclude := pkgpath.DefaultGoPkgExcludeMatcher()
+	if !cfg.Exclude.Empty() {
+		exclude = matcher.Any(exclude, cfg.Exclude.Matcher())
 	}
-	cfg.TypeChecker.Error = func(e error) {
-		fmt.Fprintln(w, e)
+	pkgPaths, err := pkgPathsInDir(projectDir, gopathSrc, ctx, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list packages: %v", err)
+	}
+	if !cfg.ExcludeGenerated {
+		return pkgPaths, nil
 	}
 
-	if _, err := cfg.Load(); err != nil {
-		// return blank error if any errors were encountered during load. Load function prints errors to writer
-		// in proper format as they are encountered so no need to create any other output.
-		return fmt.Errorf("")
+	var nonGenerated []string
+	for _, currPkgPath := range pkgPaths {
+		generated, err := isGeneratedPkg(path.Join(gopathSrc, currPkgPath))
+		if err != nil {
+			return nil, err
+		}
+		if !generated {
+			nonGenerated = append(nonGenerated, currPkgPath)
+		}
 	}
+	return nonGenerated, nil
+}
 
-	return nil
+// pkgPathsInDir returns the import paths (relative to gopathSrc) of the directories rooted at projectDir that contain
+// Go files that match the provided build context. Paths (relative to projectDir) of directories and files that match
+// exclude are skipped. The package clauses of the files are not examined, so directories that contain files whose
+// package clauses do not match are listed and the mismatch is reported when the package is type-checked.
+func pkgPathsInDir(projectDir, gopathSrc string, ctx build.Context, exclude matcher.Matcher) ([]string, error) {
+	var pkgPaths []string
+	if err := filepath.Walk(projectDir, func(currPath string, currInfo os.FileInfo, err error) error {
+		currRelPath, relErr := filepath.Rel(projectDir, currPath)
+		if relErr != nil {
+			return relErr
+		}
+		if exclude != nil && exclude.Match(currRelPath) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !currInfo.IsDir() {
+			return nil
+		}
+
+		fileInfos, err := ioutil.ReadDir(currPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read directory %s", currPath)
+		}
+		for _, currFileInfo := range fileInfos {
+			if currFileInfo.IsDir() || !strings.HasSuffix(currFileInfo.Name(), ".go") {
+				continue
+			}
+			if exclude != nil && exclude.Match(path.Join(currRelPath, currFileInfo.Name())) {
+				continue
+			}
+			if match, _ := ctx.MatchFile(currPath, currFileInfo.Name()); !match {
+				continue
+			}
+			pkgPath, err := filepath.Rel(gopathSrc, currPath)
+			if err != nil {
+				return err
+			}
+			pkgPaths = append(pkgPaths, filepath.ToSlash(pkgPath))
+			break
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Strings(pkgPaths)
+	return pkgPaths, nil
 }
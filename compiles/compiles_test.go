@@ -17,15 +17,22 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/build"
 	"io/ioutil"
 	"os"
+	"path"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/pkg/matcher"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
 )
 
 func TestCompilesPassCases(t *testing.T) {
@@ -65,6 +72,47 @@ func TestCompilesPassCases(t *testing.T) {
 				},
 			},
 		},
+		{
+			files: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+				import "{{index . "bar/bar.go"}}"
+				func Foo() string {
+					return bar.Bar()
+				}`,
+				},
+				{
+					RelPath: "foo/helper_test.go",
+					Src: `package foo
+				func Helper() string {
+					return Foo()
+				}`,
+				},
+				{
+					RelPath: "foo/foo_test.go",
+					Src: `package foo_test
+				import "testing"
+				import "{{index . "foo/foo.go"}}"
+				func TestFoo(t *testing.T) {
+					_ = foo.Helper()
+				}`,
+				},
+				{
+					RelPath: "bar/bar.go",
+					Src: `package bar
+				import "{{index . "baz/baz.go"}}"
+				func Bar() string {
+					return baz.Baz
+				}`,
+				},
+				{
+					RelPath: "baz/baz.go",
+					Src: `package baz
+				const Baz = "baz"`,
+				},
+			},
+		},
 	}
 
 	for i, currCase := range cases {
@@ -75,8 +123,53 @@ func TestCompilesPassCases(t *testing.T) {
 		_, err = gofiles.Write(projectDir, currCase.files)
 		require.NoError(t, err)
 
-		err = doCompiles(projectDir, nil, &buf)
-		require.NoError(t, err, "Case %d: %v", i, buf.String())
+		for _, parallelism := range []int{1, runtime.NumCPU()} {
+			err = doCompiles(projectDir, nil, config{}, nil, parallelism, diagnostic.FormatText, false, baseline.Options{}, &buf)
+			require.NoError(t, err, "Case %d: parallelism %d: %v", i, parallelism, buf.String())
+		}
+	}
+}
+
+// Packages outside of the type-checked packages that import type-checked packages must be type-checked against the
+// packages of the project rather than against another copy of them.
+func TestCompilesDependencyImportsProjectPkg(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "b/b.go",
+			Src: `package b
+				type T struct{ X int }
+				func Use(t T) int { return t.X }`,
+		},
+		{
+			RelPath: "gen/gen.go",
+			Src: `package gen
+				import "{{index . "b/b.go"}}"
+				func Make() b.T { return b.T{} }`,
+		},
+		{
+			RelPath: "a/a.go",
+			Src: `package a
+				import "{{index . "b/b.go"}}"
+				import "{{index . "gen/gen.go"}}"
+				var _ = b.Use(gen.Make())`,
+		},
+	})
+	require.NoError(t, err)
+
+	for _, parallelism := range []int{1, runtime.NumCPU()} {
+		buf := bytes.Buffer{}
+		err = doCompiles(projectDir, []string{"./a/...", "./b/..."}, config{}, nil, parallelism, diagnostic.FormatText, false, baseline.Options{}, &buf)
+		require.NoError(t, err, "parallelism %d: %v", parallelism, buf.String())
 	}
 }
 
@@ -109,9 +202,10 @@ func TestCompilesErrorCases(t *testing.T) {
 			},
 			want: func(files map[string]gofiles.GoFile) string {
 				lines := []string{
-					files["foo/foo.go"].Path + `:3:13: no result values expected`,
-					files["bar/bar.go"].Path + `:2:12: "fmt" imported but not used`,
+					"# " + files["bar/bar.go"].ImportPath,
 					files["bar/bar.go"].Path + `:2:12: "fmt" imported but not used`,
+					"# " + files["foo/foo.go"].ImportPath,
+					files["foo/foo.go"].Path + `:3:13: no result values expected`,
 					"",
 				}
 				return strings.Join(lines, "\n")
@@ -140,6 +234,7 @@ func TestCompilesErrorCases(t *testing.T) {
 			},
 			want: func(files map[string]gofiles.GoFile) string {
 				lines := []string{
+					"# " + files["foo/foo.go"].ImportPath,
 					files["foo/foo_test.go"].Path + `:7:6: bar declared but not used`,
 					"",
 				}
@@ -169,6 +264,7 @@ func TestCompilesErrorCases(t *testing.T) {
 			},
 			want: func(files map[string]gofiles.GoFile) string {
 				lines := []string{
+					"# " + files["foo/foo.go"].ImportPath,
 					files["foo/foo_test.go"].Path + `:7:6: bar declared but not used`,
 					"",
 				}
@@ -185,9 +281,645 @@ func TestCompilesErrorCases(t *testing.T) {
 		files, err := gofiles.Write(projectDir, currCase.files)
 		require.NoError(t, err)
 
-		err = doCompiles(projectDir, nil, &buf)
+		err = doCompiles(projectDir, nil, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
 		require.Error(t, err, fmt.Sprintf("Case %d", i))
 
 		assert.Equal(t, currCase.want(files), buf.String(), "Case %d", i)
 	}
 }
+
+func TestCompilesPackageClauseMismatch(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+				func Foo() string {
+					return "Foo"
+				}`,
+		},
+		{
+			RelPath: "foo/a_test.go",
+			Src: `package bar
+				import "testing"
+				func TestBar(t *testing.T) {}`,
+		},
+		{
+			RelPath: "foo/foo_test.go",
+			Src: `package foo_test
+				import (
+					"testing"
+					"{{index . "foo/foo.go"}}"
+				)
+				func TestFoo(t *testing.T) {
+					_ = foo.Foo()
+				}`,
+		},
+		{
+			RelPath: "foo/other_test.go",
+			Src:     `package other_test`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = doCompiles(projectDir, nil, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+	require.EqualError(t, err, "")
+
+	want := strings.Join([]string{
+		"# " + files["foo/foo.go"].ImportPath,
+		files["foo/a_test.go"].Path + ":1:9: test file declares package bar, but the package in its directory is foo: expected package foo or foo_test",
+		files["foo/other_test.go"].Path + ":1:9: test file declares package other_test, but the package in its directory is foo: expected package foo or foo_test",
+		"",
+	}, "\n")
+	assert.Equal(t, want, buf.String())
+}
+
+func TestCompilesTagSets(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+				var _ = undefinedAll`,
+		},
+		{
+			RelPath: "foo/foo_linux.go",
+			Src: `package foo
+				func Platform() string { return "linux" }`,
+		},
+		{
+			RelPath: "foo/foo_darwin.go",
+			Src: `package foo
+				func Platform() string { return undefinedDarwin }`,
+		},
+		{
+			RelPath: "foo/foo_integration.go",
+			Src: `//go:build integration
+
+				package foo
+				var _ = undefinedIntegration`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = doCompiles(projectDir, nil, config{}, []string{"linux", "darwin,integration"}, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+	require.Error(t, err)
+
+	lines := []string{
+		"# " + files["foo/foo.go"].ImportPath,
+		files["foo/foo.go"].Path + `:2:13: undefined: undefinedAll [tags: linux; darwin,integration]`,
+		files["foo/foo_integration.go"].Path + `:4:13: undefined: undefinedIntegration [tags: darwin,integration]`,
+		files["foo/foo_darwin.go"].Path + `:2:37: undefined: undefinedDarwin [tags: darwin,integration]`,
+		"",
+	}
+	assert.Equal(t, strings.Join(lines, "\n"), buf.String())
+}
+
+func TestCompilesDependencyErrors(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "project/foo/foo.go",
+			Src: `package foo
+				import "{{index . "deps/broken/broken.go"}}"
+				var _ = broken.Broken`,
+		},
+		{
+			RelPath: "project/bar/bar.go",
+			Src: `package bar
+				import "{{index . "deps/mid/mid.go"}}"
+				var _ = mid.Mid`,
+		},
+		{
+			RelPath: "project/baz/baz.go",
+			Src:     `package baz`,
+		},
+		{
+			RelPath: "deps/mid/mid.go",
+			Src: `package mid
+				import "{{index . "deps/broken/broken.go"}}"
+				var Mid = broken.Broken`,
+		},
+		{
+			RelPath: "deps/broken/broken.go",
+			Src: `package broken
+				var Broken = undefinedBroken`,
+		},
+	})
+	require.NoError(t, err)
+	projectDir := path.Join(tmpDir, "project")
+
+	buf := bytes.Buffer{}
+	err = doCompiles(projectDir, nil, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+	require.NoError(t, err, buf.String())
+
+	err = doCompiles(projectDir, nil, config{
+		DependencyErrors: true,
+	}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+	require.EqualError(t, err, "")
+
+	want := fmt.Sprintf(`# %s
+%s:2:18: undefined: undefinedBroken (in dependency %s imported by %s, %s)
+`, files["deps/broken/broken.go"].ImportPath, files["deps/broken/broken.go"].Path, files["deps/broken/broken.go"].ImportPath, files["project/bar/bar.go"].ImportPath, files["project/foo/foo.go"].ImportPath)
+	assert.Equal(t, want, buf.String())
+}
+
+func TestCompilesExclude(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+				var _ = undefinedFoo`,
+		},
+		{
+			RelPath: "fixtures/broken/broken.go",
+			Src: `package broken
+				var _ = undefinedFixture`,
+		},
+		{
+			RelPath: "mock_gen/mock.go",
+			Src: `package mock_gen
+				var _ = undefinedMock`,
+		},
+		{
+			RelPath: "generated/generated.go",
+			Src: `// Code generated by protoc-gen-go. DO NOT EDIT.
+
+				package generated
+				var _ = undefinedGenerated`,
+		},
+		{
+			RelPath: "generated/other.go",
+			Src: `// This file was generated by a tool.
+				package generated`,
+		},
+		{
+			RelPath: "partial/partial.go",
+			Src: `// Code generated by stringer. DO NOT EDIT.
+
+				package partial
+				var _ = undefinedPartial`,
+		},
+		{
+			RelPath: "partial/handwritten.go",
+			Src:     `package partial`,
+		},
+	})
+	require.NoError(t, err)
+
+	cfgFile := path.Join(projectDir, "compiles.yml")
+	err = ioutil.WriteFile(cfgFile, []byte(`exclude:
+  names:
+    - ".+_gen"
+  paths:
+    - "fixtures"
+exclude-generated: true
+`), 0644)
+	require.NoError(t, err)
+
+	cfg, err := loadConfig(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, config{
+		Exclude: matcher.NamesPathsCfg{
+			Names: []string{".+_gen"},
+			Paths: []string{"fixtures"},
+		},
+		ExcludeGenerated: true,
+	}, cfg)
+
+	buf := bytes.Buffer{}
+	err = doCompiles(projectDir, nil, cfg, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+	require.Error(t, err)
+
+	lines := []string{
+		"# " + files["foo/foo.go"].ImportPath,
+		files["foo/foo.go"].Path + `:2:13: undefined: undefinedFoo`,
+		"# " + files["partial/partial.go"].ImportPath,
+		files["partial/partial.go"].Path + `:4:13: undefined: undefinedPartial`,
+		"",
+	}
+	assert.Equal(t, strings.Join(lines, "\n"), buf.String())
+}
+
+// Excluded packages that import checked packages and are imported by checked packages are type-checked against the
+// checked packages.
+func TestCompilesExcludeImportsCheckedPkg(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "b/b.go",
+			Src: `package b
+				type T struct{ X int }
+				func Use(t T) int { return t.X }`,
+		},
+		{
+			RelPath: "gen/gen.go",
+			Src: `// Code generated by a tool. DO NOT EDIT.
+
+				package gen
+				import "{{index . "b/b.go"}}"
+				func Make() b.T { return b.T{} }`,
+		},
+		{
+			RelPath: "a/a.go",
+			Src: `package a
+				import "{{index . "b/b.go"}}"
+				import "{{index . "gen/gen.go"}}"
+				var _ = b.Use(gen.Make())`,
+		},
+	})
+	require.NoError(t, err)
+
+	for i, cfg := range []config{
+		{ExcludeGenerated: true},
+		{Exclude: matcher.NamesPathsCfg{Paths: []string{"gen"}}},
+	} {
+		buf := bytes.Buffer{}
+		err = doCompiles(projectDir, []string{"./..."}, cfg, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+		require.NoError(t, err, "Case %d: %v", i, buf.String())
+	}
+}
+
+func TestCompilesJSON(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+				var _ = undefinedFoo`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = doCompiles(projectDir, nil, config{}, nil, runtime.NumCPU(), diagnostic.FormatJSON, false, baseline.Options{}, &buf)
+	require.Error(t, err)
+
+	want := fmt.Sprintf(`[
+    {
+        "file": "%s",
+        "line": 2,
+        "col": 13,
+        "check": "compiles",
+        "ruleId": "type",
+        "message": "undefined: undefinedFoo",
+        "severity": "error"
+    }
+]
+`, files["foo/foo.go"].Path)
+	assert.Equal(t, want, buf.String())
+
+	buf = bytes.Buffer{}
+	err = doCompiles(projectDir, []string{files["bar/bar.go"].ImportPath}, config{}, nil, runtime.NumCPU(), diagnostic.FormatJSON, false, baseline.Options{}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestCompilesPkgPatterns(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "api/api.go",
+			Src:     `package api`,
+		},
+		{
+			RelPath: "api/v1/v1.go",
+			Src: `package v1
+				var _ = undefinedV1`,
+		},
+		{
+			RelPath: "apiclient/apiclient.go",
+			Src: `package apiclient
+				var _ = undefinedAPIClient`,
+		},
+		{
+			RelPath: "internal/internal.go",
+			Src: `package internal
+				var _ = undefinedInternal`,
+		},
+		{
+			RelPath: "a/a.go",
+			Src: `package a
+				import "{{index . "b/b.go"}}"
+				import "{{index . "gen/gen.go"}}"
+				var _ = b.Use(gen.Make())`,
+		},
+		{
+			RelPath: "b/b.go",
+			Src: `package b
+				type T struct{ X int }
+				func Use(t T) int { return t.X }`,
+		},
+		{
+			RelPath: "gen/gen.go",
+			Src: `package gen
+				import "{{index . "b/b.go"}}"
+				func Make() b.T { return b.T{} }`,
+		},
+	})
+	require.NoError(t, err)
+	apiImportPath := files["api/api.go"].ImportPath
+
+	for i, currCase := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"./api/..."}, []string{"api/v1/v1.go:2:13: undefined: undefinedV1"}},
+		{[]string{apiImportPath + "/..."}, []string{"api/v1/v1.go:2:13: undefined: undefinedV1"}},
+		{[]string{"./api/...", "./internal"}, []string{"api/v1/v1.go:2:13: undefined: undefinedV1", "internal/internal.go:2:13: undefined: undefinedInternal"}},
+		{[]string{"./..."}, []string{"api/v1/v1.go:2:13: undefined: undefinedV1", "apiclient/apiclient.go:2:13: undefined: undefinedAPIClient", "internal/internal.go:2:13: undefined: undefinedInternal"}},
+	} {
+		buf := bytes.Buffer{}
+		err = doCompiles(projectDir, currCase.args, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+		require.Error(t, err, "Case %d", i)
+
+		var want []string
+		for _, currWant := range currCase.want {
+			want = append(want, "# "+path.Join(path.Dir(apiImportPath), path.Dir(currWant)), path.Join(projectDir, currWant))
+		}
+		assert.Equal(t, strings.Join(want, "\n")+"\n", buf.String(), "Case %d", i)
+	}
+
+	// gen is not matched by the patterns, but imports a package that is
+	buf := bytes.Buffer{}
+	err = doCompiles(projectDir, []string{"./a/...", "./b/..."}, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+	require.NoError(t, err, buf.String())
+
+	err = doCompiles(projectDir, []string{"./missing/..."}, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, ioutil.Discard)
+	assert.EqualError(t, err, "pattern ./missing/... did not match any packages")
+}
+
+func TestCompilesBaseline(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+				var _ = undefinedFoo`,
+		},
+	})
+	require.NoError(t, err)
+
+	baselineFile := path.Join(tmpDir, "baseline.json")
+	buf := bytes.Buffer{}
+	err = doCompiles(projectDir, nil, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{WritePath: baselineFile}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	// the recorded error is suppressed even though its line changes
+	err = ioutil.WriteFile(files["foo/foo.go"].Path, []byte("package foo\n\nvar _ = undefinedBar\n\nvar _ = undefinedFoo\n"), 0644)
+	require.NoError(t, err)
+	err = doCompiles(projectDir, nil, config{}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{Path: baselineFile}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "# "+files["foo/foo.go"].ImportPath+"\n"+files["foo/foo.go"].Path+":3:9: undefined: undefinedBar\n", buf.String())
+}
+
+func TestCompilesDisable(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import "fmt"
+
+type I interface{ M() }
+
+type T struct{}
+
+func Foo(i I) {
+	x := 1
+	_ = i.(T)
+	undefinedFoo()
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+	projectDir := path.Dir(path.Dir(files["foo/foo.go"].Path))
+	fooPath := files["foo/foo.go"].Path
+
+	unusedImportErr := fmt.Sprintf(`%s:3:8: "fmt" imported and not used`, fooPath)
+	unusedVarErr := fmt.Sprintf(`%s:10:2: declared and not used: x`, fooPath)
+	impossibleAssertionErr := fmt.Sprintf(`%s:11:6: impossible type assertion: i.(T)`, fooPath)
+	undefinedErr := fmt.Sprintf(`%s:12:2: undefined: undefinedFoo`, fooPath)
+
+	for i, currCase := range []struct {
+		disable []string
+		want    []string
+		notWant []string
+	}{
+		{
+			want: []string{unusedImportErr, unusedVarErr, impossibleAssertionErr, undefinedErr},
+		},
+		{
+			disable: []string{unusedImportClass},
+			want:    []string{unusedVarErr, impossibleAssertionErr, undefinedErr},
+			notWant: []string{unusedImportErr},
+		},
+		{
+			disable: []string{unusedVariableClass, unusedImportClass, impossibleAssertionClass},
+			want:    []string{undefinedErr},
+			notWant: []string{unusedImportErr, unusedVarErr, impossibleAssertionErr},
+		},
+	} {
+		buf := bytes.Buffer{}
+		err = doCompiles(projectDir, nil, config{Disable: currCase.disable}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &buf)
+		require.EqualError(t, err, "", "Case %d", i)
+		for _, currWant := range currCase.want {
+			assert.Contains(t, buf.String(), currWant, "Case %d", i)
+		}
+		for _, currNotWant := range currCase.notWant {
+			assert.NotContains(t, buf.String(), currNotWant, "Case %d", i)
+		}
+	}
+
+	err = doCompiles(projectDir, nil, config{Disable: []string{"unused-label"}}, nil, runtime.NumCPU(), diagnostic.FormatText, false, baseline.Options{}, &bytes.Buffer{})
+	assert.EqualError(t, err, `invalid error class "unused-label": must be one of [unused-variable unused-import impossible-assertion]`)
+}
+
+func TestWatchCheckerIncremental(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "project/foo/foo.go",
+			Src: `package foo
+				var Foo = 1`,
+		},
+		{
+			RelPath: "project/bar/bar.go",
+			Src: `package bar
+				import "{{index . "project/foo/foo.go"}}"
+				var Bar = foo.Foo`,
+		},
+		{
+			RelPath: "project/baz/baz.go",
+			Src:     `package baz`,
+		},
+		{
+			RelPath: "project/baz/baz_test.go",
+			Src:     `package baz`,
+		},
+	})
+	require.NoError(t, err)
+	projectDir := path.Join(tmpDir, "project")
+	gopathSrc, err := gopathSrcDir(projectDir)
+	require.NoError(t, err)
+
+	checker := &watchChecker{
+		projectDir: projectDir,
+		gopathSrc:  gopathSrc,
+		ctx:        build.Default,
+	}
+	errs, nChecked, nUnits, err := checker.run(nil, runtime.NumCPU())
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Equal(t, 4, nChecked)
+	assert.Equal(t, 4, nUnits)
+
+	// nothing changed, so nothing is re-checked
+	_, nChecked, _, err = checker.run(nil, runtime.NumCPU())
+	require.NoError(t, err)
+	assert.Equal(t, 0, nChecked)
+
+	// foo and bar (which imports foo) are re-checked
+	err = ioutil.WriteFile(files["project/foo/foo.go"].Path, []byte("package foo\n\nvar Foo = undefinedFoo\n"), 0644)
+	require.NoError(t, err)
+	errs, nChecked, _, err = checker.run(map[string]struct{}{
+		path.Join(projectDir, "foo"): {},
+	}, runtime.NumCPU())
+	require.NoError(t, err)
+	assert.Equal(t, 2, nChecked)
+	require.Equal(t, 1, len(errs))
+	assert.Equal(t, files["project/foo/foo.go"].Path+":3:11: undefined: undefinedFoo", errs[0].String())
+
+	// a change in a directory without a project package causes all packages to be re-checked
+	errs, nChecked, _, err = checker.run(map[string]struct{}{
+		path.Join(tmpDir, "other"): {},
+	}, runtime.NumCPU())
+	require.NoError(t, err)
+	assert.Equal(t, 4, nChecked)
+	assert.Equal(t, 1, len(errs))
+}
+
+func TestPrintGrouped(t *testing.T) {
+	diags := []diagnostic.Diagnostic{
+		{File: "/go/src/github.com/org/project/foo/foo.go", Line: 3, Col: 11, Message: "undefined: undefinedFoo", Severity: diagnostic.SeverityError},
+		{File: "/go/src/github.com/org/project/bar/bar.go", Line: 2, Col: 5, Message: "undefined: undefinedBar", Severity: diagnostic.SeverityError},
+		{File: "/go/src/github.com/org/project/foo/foo_test.go", Line: 7, Col: 2, Message: "undefined: undefinedFooTest", Severity: diagnostic.SeverityWarning},
+		{File: "/other/baz/baz.go", Line: 1, Col: 1, Message: "undefined: undefinedBaz", Severity: diagnostic.SeverityError},
+		{Message: "failed to import", Severity: diagnostic.SeverityError},
+	}
+
+	buf := bytes.Buffer{}
+	err := printGrouped(&buf, diags, "/go/src", false)
+	require.NoError(t, err)
+	assert.Equal(t, `failed to import
+# github.com/org/project/foo
+/go/src/github.com/org/project/foo/foo.go:3:11: undefined: undefinedFoo
+/go/src/github.com/org/project/foo/foo_test.go:7:2: undefined: undefinedFooTest
+# github.com/org/project/bar
+/go/src/github.com/org/project/bar/bar.go:2:5: undefined: undefinedBar
+# /other/baz
+/other/baz/baz.go:1:1: undefined: undefinedBaz
+`, buf.String())
+
+	buf.Reset()
+	err = printGrouped(&buf, diags[2:3], "/go/src", true)
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[1m# github.com/org/project/foo\x1b[0m\n"+
+		"\x1b[36m/go/src/github.com/org/project/foo/foo_test.go:7:2\x1b[0m: \x1b[33mundefined: undefinedFooTest\x1b[0m\n", buf.String())
+
+	buf.Reset()
+	err = printGrouped(&buf, nil, "/go/src", true)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+}
@@ -0,0 +1,122 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type config struct {
+	// Exclude matches the package directories (relative to the project directory) that should not be checked when
+	// packages are determined by listing the packages in the project directory.
+	Exclude matcher.NamesPathsCfg `yaml:"exclude" json:"exclude"`
+
+	// ExcludeGenerated specifies whether packages in which every Go file is generated should not be checked. A file
+	// is considered to be generated if a comment before its package clause contains a line of the form
+	// "// Code generated ... DO NOT EDIT." or contains the phrase "generated by".
+	ExcludeGenerated bool `yaml:"exclude-generated" json:"exclude-generated"`
+
+	// DependencyErrors specifies whether errors in the packages outside of the project that are imported by project
+	// packages should be reported along with the project packages that import them.
+	DependencyErrors bool `yaml:"dependency-errors" json:"dependency-errors"`
+
+	// Disable specifies the classes of type-checking errors that should not be reported ("unused-variable",
+	// "unused-import" or "impossible-assertion").
+	Disable []string `yaml:"disable" json:"disable"`
+
+	// GOOS and GOARCH specify the target operating system and architecture used when type-checking. If empty, the
+	// operating system and architecture of the default build context are used.
+	GOOS   string `yaml:"goos" json:"goos"`
+	GOARCH string `yaml:"goarch" json:"goarch"`
+}
+
+var (
+	codeGeneratedRegexp = regexp.MustCompile(`^Code generated .* DO NOT EDIT\.$`)
+	generatedByRegexp   = regexp.MustCompile(`(?i)\bgenerated by\b`)
+)
+
+// loadConfig returns the configuration in the YAML file at the provided path. Returns an empty configuration if the
+// path is empty.
+func loadConfig(configPath string) (config, error) {
+	var cfg config
+	if configPath == "" {
+		return cfg, nil
+	}
+	yml, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return config{}, errors.Wrapf(err, "failed to read file %s", configPath)
+	}
+	if err := yaml.Unmarshal(yml, &cfg); err != nil {
+		return config{}, errors.Wrapf(err, "failed to unmarshal YML %s", string(yml))
+	}
+	for _, currName := range cfg.Exclude.Names {
+		if _, err := regexp.Compile(currName); err != nil {
+			return config{}, errors.Wrapf(err, "invalid exclude name %q", currName)
+		}
+	}
+	if err := validateDisabledClasses(cfg.Disable); err != nil {
+		return config{}, err
+	}
+	return cfg, nil
+}
+
+// isGeneratedPkg returns true if the provided directory contains at least one Go file and all of the Go files in it are
+// generated.
+func isGeneratedPkg(dir string) (bool, error) {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read directory %s", dir)
+	}
+	goFiles := 0
+	for _, currFileInfo := range fileInfos {
+		if currFileInfo.IsDir() || !strings.HasSuffix(currFileInfo.Name(), ".go") {
+			continue
+		}
+		goFiles++
+		if !isGeneratedFile(path.Join(dir, currFileInfo.Name())) {
+			return false, nil
+		}
+	}
+	return goFiles > 0, nil
+}
+
+func isGeneratedFile(filename string) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		// files that cannot be parsed are not considered to be generated: the error is reported when the package is
+		// type-checked
+		return false
+	}
+	for _, currGroup := range file.Comments {
+		if currGroup.Pos() >= file.Package {
+			break
+		}
+		for _, currLine := range strings.Split(currGroup.Text(), "\n") {
+			if codeGeneratedRegexp.MatchString(currLine) || generatedByRegexp.MatchString(currLine) {
+				return true
+			}
+		}
+	}
+	return false
+}
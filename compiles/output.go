@@ -0,0 +1,138 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/diagnostic"
+)
+
+// ANSI escape sequences used to colorize the text output.
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorCyan   = "\x1b[36m"
+)
+
+// isTerminal returns true if the provided writer is a file that is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// printGrouped writes the provided diagnostics to w in the text format grouped by the package that contains the file
+// of each diagnostic. Every group is preceded by a header of the form "# <package>", where the package is the import
+// path of the directory of the file (relative to gopathSrc) or the directory itself if it is not in gopathSrc. Groups
+// are written in the order in which their first diagnostic occurs and diagnostics that do not have a file are written
+// before the groups. If color is true, the headers, positions and messages are colorized using ANSI escape sequences.
+func printGrouped(w io.Writer, diags []diagnostic.Diagnostic, gopathSrc string, color bool) error {
+	var pkgs []string
+	pkgDiags := make(map[string][]diagnostic.Diagnostic)
+	for _, currDiag := range diags {
+		pkg := diagPkg(currDiag, gopathSrc)
+		if _, ok := pkgDiags[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+		pkgDiags[pkg] = append(pkgDiags[pkg], currDiag)
+	}
+
+	var lines []string
+	if noPkgDiags, ok := pkgDiags[""]; ok {
+		for _, currDiag := range noPkgDiags {
+			lines = append(lines, diagText(currDiag, color))
+		}
+	}
+	for _, pkg := range pkgs {
+		if pkg == "" {
+			continue
+		}
+		lines = append(lines, colorize("# "+pkg, colorBold, color))
+		for _, currDiag := range pkgDiags[pkg] {
+			lines = append(lines, diagText(currDiag, color))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(lines, "\n")); err != nil {
+		return errors.Wrapf(err, "failed to write diagnostics")
+	}
+	return nil
+}
+
+// diagPkg returns the package that contains the file of the provided diagnostic. Returns an empty string if the
+// diagnostic does not have a file.
+func diagPkg(d diagnostic.Diagnostic, gopathSrc string) string {
+	if d.File == "" {
+		return ""
+	}
+	dir := filepath.Dir(d.File)
+	if relPath, err := filepath.Rel(gopathSrc, dir); err == nil && relPath != ".." && !strings.HasPrefix(relPath, "../") {
+		return filepath.ToSlash(relPath)
+	}
+	return dir
+}
+
+// diagText returns the text representation of the provided diagnostic, which is the same as that written by
+// diagnostic.PrintText. If color is true, the position is colorized and the message is colorized based on its
+// severity.
+func diagText(d diagnostic.Diagnostic, color bool) string {
+	msg := colorize(d.Message, severityColor(d.Severity), color)
+	out := msg
+	if pos := d.Position(); pos != "" {
+		out = colorize(pos, colorCyan, color) + ": " + msg
+	}
+	if d.Hint != "" {
+		out += "\n\thint: " + d.Hint
+	}
+	return out
+}
+
+// severityColor returns the color used for messages of the provided severity.
+func severityColor(severity diagnostic.Severity) string {
+	switch severity {
+	case diagnostic.SeverityWarning:
+		return colorYellow
+	case diagnostic.SeverityInfo:
+		return colorBlue
+	default:
+		return colorRed
+	}
+}
+
+// colorize returns s wrapped in the provided color if enabled is true and s otherwise.
+func colorize(s, color string, enabled bool) string {
+	if !enabled {
+		return s
+	}
+	return color + s + colorReset
+}
@@ -0,0 +1,663 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/diagnostic"
+)
+
+// checkUnit is a set of files that is type-checked as a single package. Every package in the project has a unit for
+// its non-test files and may have additional units for the package augmented with its in-package test files and for
+// its external test package.
+type checkUnit struct {
+	name  string
+	path  string
+	dir   string
+	files []string
+	// units within the project that are imported by this unit, keyed by import path
+	deps map[string]*checkUnit
+	// units within the project that are imported by the packages outside of the project that this unit imports, keyed
+	// by import path. The packages outside of the project are type-checked against these units, so they must be
+	// type-checked before this unit.
+	indirectDeps map[string]*checkUnit
+	// canonical import paths of the packages outside of the project that are imported by this unit
+	extImports map[string]struct{}
+
+	done chan struct{}
+	pkg  *types.Package
+	errs []diagnostic.Diagnostic
+}
+
+// typeChecker type-checks the units of a project concurrently. A unit is type-checked once all of the units it imports
+// have been type-checked. Packages outside of the project are type-checked from source on demand.
+type typeChecker struct {
+	ctx           *build.Context
+	fset          *token.FileSet
+	recordDepErrs bool
+
+	srcImporter *sourceImporter
+}
+
+// newTypeChecker returns a type checker that uses the provided build context. If recordDepErrs is true, the errors in
+// the packages outside of the project that are type-checked from source are recorded so that they can be reported
+// using dependencyErrs.
+func newTypeChecker(ctx *build.Context, recordDepErrs bool) *typeChecker {
+	fset := token.NewFileSet()
+	return &typeChecker{
+		ctx:           ctx,
+		fset:          fset,
+		recordDepErrs: recordDepErrs,
+		srcImporter:   newSourceImporter(ctx, fset, recordDepErrs),
+	}
+}
+
+// resetSourceImporter discards the packages that were type-checked from source so that they are type-checked again when
+// they are next imported. The project packages that imports resolve to are retained.
+func (c *typeChecker) resetSourceImporter() {
+	units := c.srcImporter.units
+	c.srcImporter = newSourceImporter(c.ctx, c.fset, c.recordDepErrs)
+	c.srcImporter.units = units
+}
+
+// units returns the units for the packages with the provided import paths in order. The dependencies of each unit on
+// other units in the returned slice are resolved.
+func (c *typeChecker) units(pkgPaths []string, srcDir string) ([]*checkUnit, error) {
+	var units []*checkUnit
+	baseUnits := make(map[string]*checkUnit)
+	testUnits := make(map[string]*checkUnit)
+	type unitImports struct {
+		unit    *checkUnit
+		imports []string
+		xtest   bool
+	}
+	var allImports []unitImports
+
+	for _, currPkgPath := range pkgPaths {
+		bp, err := c.ctx.Import(currPkgPath, srcDir, 0)
+		var clauseErrs []diagnostic.Diagnostic
+		if err != nil {
+			if _, ok := err.(*build.NoGoError); ok {
+				continue
+			}
+			if _, ok := err.(*build.MultiplePackageError); !ok {
+				return nil, errors.Wrapf(err, "failed to import package %s", currPkgPath)
+			}
+			// the package clauses of the files in the directory do not match. If only the package clauses of test files
+			// are wrong, report the test files and check the package without them.
+			var ok bool
+			if clauseErrs, ok = c.removeMismatchedTestFiles(bp); !ok {
+				return nil, errors.Wrapf(err, "failed to import package %s", currPkgPath)
+			}
+		}
+
+		srcFiles := absPaths(bp.Dir, bp.GoFiles, bp.CgoFiles)
+		base := newCheckUnit(bp.ImportPath, bp.ImportPath, bp.Dir, srcFiles)
+		base.errs = clauseErrs
+		baseUnits[bp.ImportPath] = base
+		units = append(units, base)
+		allImports = append(allImports, unitImports{unit: base, imports: bp.Imports})
+
+		if len(bp.TestGoFiles) > 0 {
+			test := newCheckUnit(bp.ImportPath+" [test]", bp.ImportPath, bp.Dir, append(srcFiles, absPaths(bp.Dir, bp.TestGoFiles)...))
+			testUnits[bp.ImportPath] = test
+			units = append(units, test)
+			allImports = append(allImports, unitImports{unit: test, imports: append(append([]string{}, bp.Imports...), bp.TestImports...)})
+		}
+		if len(bp.XTestGoFiles) > 0 {
+			xtest := newCheckUnit(bp.ImportPath+"_test", bp.ImportPath+"_test", bp.Dir, absPaths(bp.Dir, bp.XTestGoFiles))
+			units = append(units, xtest)
+			allImports = append(allImports, unitImports{unit: xtest, imports: bp.XTestImports, xtest: true})
+		}
+	}
+
+	// units reachable from each package outside of the project
+	reachable := make(map[string]map[string]*checkUnit)
+	for _, currUnitImports := range allImports {
+		for _, currImport := range currUnitImports.imports {
+			if currImport == "C" || currImport == "unsafe" {
+				continue
+			}
+			importPath := c.resolve(currImport, currUnitImports.unit.dir)
+			dep, ok := baseUnits[importPath]
+			if !ok {
+				indirect, err := c.reachableUnits(currImport, currUnitImports.unit.dir, baseUnits, reachable, []string{currUnitImports.unit.name})
+				if err != nil {
+					return nil, err
+				}
+				for indirectPath, indirectDep := range indirect {
+					currUnitImports.unit.indirectDeps[indirectPath] = indirectDep
+				}
+				continue
+			}
+			if test, ok := testUnits[importPath]; ok && currUnitImports.xtest && importPath+"_test" == currUnitImports.unit.path {
+				// external test package imports the package augmented with its in-package tests
+				dep = test
+			}
+			currUnitImports.unit.deps[importPath] = dep
+		}
+	}
+
+	if err := verifyNoCycles(units); err != nil {
+		return nil, err
+	}
+	c.srcImporter.units = baseUnits
+	return units, nil
+}
+
+// reachableUnits returns the base units of the project packages that are imported, either directly or through other
+// packages outside of the project, by the package outside of the project with the provided import declared in a file in
+// srcDir. If the import resolves to a project package, its unit is returned. Packages in the standard library cannot
+// import project packages and are not traversed. memo stores the result for every traversed package and stack contains
+// the packages that are currently being traversed, which is used to report import cycles: packages in a cycle would
+// wait on each other indefinitely when they are type-checked from source.
+func (c *typeChecker) reachableUnits(importPath, srcDir string, baseUnits map[string]*checkUnit, memo map[string]map[string]*checkUnit, stack []string) (map[string]*checkUnit, error) {
+	bp, err := c.ctx.Import(importPath, srcDir, build.FindOnly)
+	if err != nil {
+		// import errors are reported when the importing unit is type-checked
+		return nil, nil
+	}
+	if unit, ok := baseUnits[bp.ImportPath]; ok {
+		return map[string]*checkUnit{bp.ImportPath: unit}, nil
+	}
+	if bp.Goroot {
+		return nil, nil
+	}
+	if units, ok := memo[bp.ImportPath]; ok {
+		if units == nil {
+			return nil, fmt.Errorf("import cycle not allowed: %s", strings.Join(append(stack, bp.ImportPath), " -> "))
+		}
+		return units, nil
+	}
+	// mark package as in progress to detect cycles
+	memo[bp.ImportPath] = nil
+
+	units := make(map[string]*checkUnit)
+	if pkg, err := c.ctx.Import(importPath, srcDir, 0); err == nil {
+		for _, currImport := range pkg.Imports {
+			if currImport == "C" || currImport == "unsafe" {
+				continue
+			}
+			importUnits, err := c.reachableUnits(currImport, pkg.Dir, baseUnits, memo, append(stack, bp.ImportPath))
+			if err != nil {
+				return nil, err
+			}
+			for unitPath, unit := range importUnits {
+				units[unitPath] = unit
+			}
+		}
+	}
+	memo[bp.ImportPath] = units
+	return units, nil
+}
+
+// removeMismatchedTestFiles removes the test files of the provided package whose package clause is neither the name of
+// the package declared by its non-test files nor that name with a "_test" suffix. Returns a diagnostic for each of the
+// removed files. Returns false if the non-test files of the package do not declare exactly one package, in which case
+// the package is not modified.
+func (c *typeChecker) removeMismatchedTestFiles(bp *build.Package) ([]diagnostic.Diagnostic, bool) {
+	pkgNames := make(map[string]struct{})
+	for _, currFile := range absPaths(bp.Dir, bp.GoFiles, bp.CgoFiles) {
+		file, err := parser.ParseFile(c.fset, currFile, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return nil, false
+		}
+		pkgNames[file.Name.Name] = struct{}{}
+	}
+	if len(pkgNames) != 1 {
+		return nil, false
+	}
+	var pkgName string
+	for currName := range pkgNames {
+		pkgName = currName
+	}
+
+	var diags []diagnostic.Diagnostic
+	filter := func(testFiles []string) ([]string, bool) {
+		var valid []string
+		for _, currFile := range testFiles {
+			file, err := parser.ParseFile(c.fset, filepath.Join(bp.Dir, currFile), nil, parser.PackageClauseOnly)
+			if err != nil {
+				return nil, false
+			}
+			if currName := file.Name.Name; currName != pkgName && currName != pkgName+"_test" {
+				msg := fmt.Sprintf("test file declares package %s, but the package in its directory is %s: expected package %s or %s_test", currName, pkgName, pkgName, pkgName)
+				diags = append(diags, diagnostic.New(c.fset.Position(file.Name.Pos()), checkName, packageClauseRule, msg))
+				continue
+			}
+			valid = append(valid, currFile)
+		}
+		return valid, true
+	}
+	testGoFiles, ok := filter(bp.TestGoFiles)
+	if !ok {
+		return nil, false
+	}
+	xTestGoFiles, ok := filter(bp.XTestGoFiles)
+	if !ok {
+		return nil, false
+	}
+	bp.TestGoFiles = testGoFiles
+	bp.XTestGoFiles = xTestGoFiles
+	return diags, true
+}
+
+// check type-checks the provided units using at most parallelism concurrent type-checks.
+func (c *typeChecker) check(units []*checkUnit, parallelism int) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, currUnit := range units {
+		wg.Add(1)
+		go func(unit *checkUnit) {
+			defer wg.Done()
+			defer close(unit.done)
+			for _, dep := range unit.deps {
+				<-dep.done
+			}
+			for _, dep := range unit.indirectDeps {
+				<-dep.done
+			}
+			sem <- struct{}{}
+			defer func() {
+				<-sem
+			}()
+			c.checkUnit(unit)
+		}(currUnit)
+	}
+	wg.Wait()
+}
+
+func (c *typeChecker) checkUnit(unit *checkUnit) {
+	var files []*ast.File
+	for _, currFile := range unit.files {
+		file, err := parser.ParseFile(c.fset, currFile, nil, 0)
+		if err != nil {
+			if errList, ok := err.(scanner.ErrorList); ok {
+				for _, currErr := range errList {
+					unit.errs = append(unit.errs, diagnostic.New(currErr.Pos, checkName, syntaxRule, currErr.Msg))
+				}
+			} else {
+				unit.errs = append(unit.errs, errDiagnostic(err, syntaxRule))
+			}
+			continue
+		}
+		files = append(files, file)
+	}
+
+	cfg := types.Config{
+		Importer:    &unitImporter{checker: c, unit: unit},
+		FakeImportC: true,
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				unit.errs = append(unit.errs, diagnostic.New(typeErr.Fset.Position(typeErr.Pos), checkName, typeRule, typeErr.Msg))
+				return
+			}
+			unit.errs = append(unit.errs, errDiagnostic(err, typeRule))
+		},
+	}
+	// errors are recorded by the Error function
+	unit.pkg, _ = cfg.Check(unit.path, c.fset, files, nil)
+}
+
+// errDiagnostic returns a diagnostic without a position whose message is the provided error.
+func errDiagnostic(err error, ruleID string) diagnostic.Diagnostic {
+	return diagnostic.New(token.Position{}, checkName, ruleID, err.Error())
+}
+
+// resolve returns the canonical import path for the provided import declared in a file in srcDir, which takes vendor
+// directories into account. Returns the provided import path if it cannot be resolved.
+func (c *typeChecker) resolve(importPath, srcDir string) string {
+	bp, err := c.ctx.Import(importPath, srcDir, build.FindOnly)
+	if err != nil {
+		return importPath
+	}
+	return bp.ImportPath
+}
+
+// unitImporter imports packages for a unit. Packages that are part of the project are provided by the units that have
+// already been type-checked; all other packages are type-checked from source.
+type unitImporter struct {
+	checker *typeChecker
+	unit    *checkUnit
+}
+
+func (i *unitImporter) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, i.unit.dir, 0)
+}
+
+func (i *unitImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	if dep, ok := i.unit.deps[i.checker.resolve(path, dir)]; ok {
+		if dep.pkg == nil {
+			return nil, errors.Errorf("failed to type-check %s", dep.name)
+		}
+		return dep.pkg, nil
+	}
+	i.unit.extImports[i.checker.resolve(path, dir)] = struct{}{}
+	return i.checker.srcImporter.ImportFrom(path, dir, mode)
+}
+
+// dependencyErrs returns the errors in the packages outside of the project that were imported by the provided units.
+// The message of each error identifies the package in which it occurred and the project packages that import that
+// package, either directly or through other packages outside of the project. Returns nil if the type checker does not
+// record the errors in dependencies.
+func (c *typeChecker) dependencyErrs(units []*checkUnit) []diagnostic.Diagnostic {
+	if c.srcImporter.errs == nil {
+		return nil
+	}
+
+	// project packages that import each dependency
+	importers := make(map[string]map[string]struct{})
+	for _, currUnit := range units {
+		visited := make(map[string]struct{})
+		var visit func(pkgPath string)
+		visit = func(pkgPath string) {
+			if _, ok := visited[pkgPath]; ok {
+				return
+			}
+			visited[pkgPath] = struct{}{}
+			if importers[pkgPath] == nil {
+				importers[pkgPath] = make(map[string]struct{})
+			}
+			importers[pkgPath][currUnit.path] = struct{}{}
+			for _, currImport := range c.srcImporter.imports[pkgPath] {
+				visit(currImport)
+			}
+		}
+		for currImport := range currUnit.extImports {
+			visit(currImport)
+		}
+	}
+
+	var depPaths []string
+	for depPath := range c.srcImporter.errs {
+		depPaths = append(depPaths, depPath)
+	}
+	sort.Strings(depPaths)
+
+	var diags []diagnostic.Diagnostic
+	for _, depPath := range depPaths {
+		var importerPaths []string
+		for importerPath := range importers[depPath] {
+			importerPaths = append(importerPaths, importerPath)
+		}
+		sort.Strings(importerPaths)
+		for _, currErr := range c.srcImporter.errs[depPath] {
+			currErr.Message = fmt.Sprintf("%s (in dependency %s imported by %s)", currErr.Message, depPath, strings.Join(importerPaths, ", "))
+			diags = append(diags, currErr)
+		}
+	}
+	return diags
+}
+
+// sourceImporter imports packages by type-checking them from source using its build context. Imports of project
+// packages resolve to the packages of their units, which must have been type-checked before they are imported, so that
+// every project package has a single package object. Errors in imported packages do not cause imports to fail, but are
+// recorded if errs is non-nil. It is safe for concurrent use: packages are type-checked concurrently, but each package
+// is type-checked at most once.
+type sourceImporter struct {
+	ctx  *build.Context
+	fset *token.FileSet
+	// base units of the project packages keyed by import path
+	units map[string]*checkUnit
+
+	// guards the fields below
+	mu sync.Mutex
+	// packages that are type-checked or being type-checked from source keyed by canonical import path
+	pkgs map[string]*sourcePkg
+	// import paths of the project packages that were imported by packages type-checked from source
+	unitImports map[string]struct{}
+	// if non-nil, the syntax and type errors in imported packages outside of the standard library keyed by canonical
+	// import path
+	errs map[string][]diagnostic.Diagnostic
+	// if errs is non-nil, the canonical import paths of the packages imported by each imported package
+	imports map[string][]string
+}
+
+// sourcePkg is the result of type-checking a package from source. done is closed once pkg and err are set.
+type sourcePkg struct {
+	done chan struct{}
+	pkg  *types.Package
+	err  error
+}
+
+func newSourceImporter(ctx *build.Context, fset *token.FileSet, recordErrs bool) *sourceImporter {
+	i := &sourceImporter{
+		ctx:         ctx,
+		fset:        fset,
+		pkgs:        make(map[string]*sourcePkg),
+		unitImports: make(map[string]struct{}),
+	}
+	if recordErrs {
+		i.errs = make(map[string][]diagnostic.Diagnostic)
+		i.imports = make(map[string][]string)
+	}
+	return i
+}
+
+func (i *sourceImporter) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, "", 0)
+}
+
+func (i *sourceImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	return i.importPkg(path, dir, nil)
+}
+
+// importsUnit returns true if a package that was type-checked from source imports the project package with the provided
+// import path.
+func (i *sourceImporter) importsUnit(pkgPath string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	_, ok := i.unitImports[pkgPath]
+	return ok
+}
+
+// importPkg imports the package with the provided import path declared in a file in dir. stack contains the canonical
+// import paths of the packages whose type-check led to this import.
+func (i *sourceImporter) importPkg(path, dir string, stack []string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	bp, err := i.ctx.Import(path, dir, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to import %s", path)
+	}
+	if unit, ok := i.units[bp.ImportPath]; ok {
+		<-unit.done
+		i.mu.Lock()
+		i.unitImports[bp.ImportPath] = struct{}{}
+		i.mu.Unlock()
+		if unit.pkg == nil {
+			return nil, errors.Errorf("failed to type-check %s", unit.name)
+		}
+		return unit.pkg, nil
+	}
+	for _, currPath := range stack {
+		if currPath == bp.ImportPath {
+			return nil, errors.Errorf("import cycle via %s", bp.ImportPath)
+		}
+	}
+
+	i.mu.Lock()
+	pkg, ok := i.pkgs[bp.ImportPath]
+	if !ok {
+		pkg = &sourcePkg{
+			done: make(chan struct{}),
+		}
+		i.pkgs[bp.ImportPath] = pkg
+	}
+	i.mu.Unlock()
+	if ok {
+		<-pkg.done
+		return pkg.pkg, pkg.err
+	}
+	defer close(pkg.done)
+	pkg.pkg, pkg.err = i.check(bp, append(stack, bp.ImportPath))
+	return pkg.pkg, pkg.err
+}
+
+// check type-checks the provided package from source. stack contains the canonical import paths of the packages whose
+// type-check led to this one, including the provided package.
+func (i *sourceImporter) check(bp *build.Package, stack []string) (*types.Package, error) {
+	// errors are not recorded for packages in the standard library
+	recordErrs := i.errs != nil && !bp.Goroot
+	if recordErrs {
+		var imports []string
+		for _, currImport := range bp.Imports {
+			if currImport == "C" || currImport == "unsafe" {
+				continue
+			}
+			if importBp, err := i.ctx.Import(currImport, bp.Dir, build.FindOnly); err == nil {
+				imports = append(imports, importBp.ImportPath)
+			}
+		}
+		i.mu.Lock()
+		i.imports[bp.ImportPath] = imports
+		i.mu.Unlock()
+	}
+
+	var files []*ast.File
+	for _, currFile := range absPaths(bp.Dir, bp.GoFiles, bp.CgoFiles) {
+		file, err := parser.ParseFile(i.fset, currFile, nil, 0)
+		if err != nil {
+			if recordErrs {
+				i.recordErr(bp.ImportPath, err)
+			}
+			return nil, errors.Wrapf(err, "failed to parse %s", currFile)
+		}
+		files = append(files, file)
+	}
+	cfg := types.Config{
+		Importer:    stackImporter{importer: i, stack: stack},
+		FakeImportC: true,
+		// errors in imported packages do not fail the import: the goal is to obtain the type information needed to
+		// check the project
+		Error: func(err error) {
+			if recordErrs {
+				i.recordErr(bp.ImportPath, err)
+			}
+		},
+	}
+	pkg, _ := cfg.Check(bp.ImportPath, i.fset, files, nil)
+	return pkg, nil
+}
+
+// recordErr records the provided error for the package with the provided import path.
+func (i *sourceImporter) recordErr(pkgPath string, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	switch err := err.(type) {
+	case scanner.ErrorList:
+		for _, currErr := range err {
+			i.errs[pkgPath] = append(i.errs[pkgPath], diagnostic.New(currErr.Pos, checkName, dependencyRule, currErr.Msg))
+		}
+	case types.Error:
+		i.errs[pkgPath] = append(i.errs[pkgPath], diagnostic.New(err.Fset.Position(err.Pos), checkName, dependencyRule, err.Msg))
+	default:
+		i.errs[pkgPath] = append(i.errs[pkgPath], errDiagnostic(err, dependencyRule))
+	}
+}
+
+// stackImporter imports the dependencies of a package that is being type-checked from source using a sourceImporter.
+type stackImporter struct {
+	importer *sourceImporter
+	// canonical import paths of the packages whose type-check led to this import, used to detect cycles
+	stack []string
+}
+
+func (i stackImporter) Import(path string) (*types.Package, error) {
+	return i.importer.importPkg(path, "", i.stack)
+}
+
+func (i stackImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	return i.importer.importPkg(path, dir, i.stack)
+}
+
+func newCheckUnit(name, path, dir string, files []string) *checkUnit {
+	return &checkUnit{
+		name:  name,
+		path:  path,
+		dir:   dir,
+		files: files,
+		deps:  make(map[string]*checkUnit),
+		done:  make(chan struct{}),
+
+		indirectDeps: make(map[string]*checkUnit),
+		extImports:   make(map[string]struct{}),
+	}
+}
+
+func absPaths(dir string, fileSets ...[]string) []string {
+	var paths []string
+	for _, currFiles := range fileSets {
+		for _, currFile := range currFiles {
+			paths = append(paths, filepath.Join(dir, currFile))
+		}
+	}
+	return paths
+}
+
+// verifyNoCycles returns an error if the dependencies between the provided units contain a cycle. Units in a cycle
+// would wait on each other indefinitely.
+func verifyNoCycles(units []*checkUnit) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*checkUnit]int)
+	var visit func(unit *checkUnit, stack []string) error
+	visit = func(unit *checkUnit, stack []string) error {
+		switch state[unit] {
+		case visiting:
+			return fmt.Errorf("import cycle not allowed: %s", strings.Join(append(stack, unit.name), " -> "))
+		case visited:
+			return nil
+		}
+		state[unit] = visiting
+		for _, deps := range []map[string]*checkUnit{unit.deps, unit.indirectDeps} {
+			for _, dep := range deps {
+				if err := visit(dep, append(stack, unit.name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[unit] = visited
+		return nil
+	}
+	for _, currUnit := range units {
+		if err := visit(currUnit, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
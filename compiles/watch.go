@@ -0,0 +1,319 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+	"github.com/palantir/checks/checks/loader"
+)
+
+// watchDebounce is the amount of time to wait after a change for further changes before re-checking. Editors and tools
+// often write multiple files (or the same file multiple times) in quick succession.
+const watchDebounce = 250 * time.Millisecond
+
+// doWatch type-checks the packages with the provided paths (or all of the packages in projectDir if no paths are
+// provided) in the same manner as doCompiles and then watches projectDir, re-checking packages whenever Go files in it
+// change. The type-checked packages are kept in memory, so only the packages in the directories that changed and the
+// project packages that import them (directly or indirectly) are re-checked. After every run, the errors that were
+// resolved (prefixed with "-") and the errors that were introduced (prefixed with "+") since the previous run are
+// printed to w followed by a summary line. Errors that prevent a run (such as import cycles) are printed and the
+// previous result is retained. At most one tag set may be provided and the baseline may not be written. Runs until the
+// stop channel is closed (or forever if it is nil).
+func doWatch(projectDir string, pkgPaths []string, cfg config, tagSets []string, parallelism int, bl baseline.Options, w io.Writer, stop <-chan struct{}) error {
+	if err := validateDisabledClasses(cfg.Disable); err != nil {
+		return err
+	}
+	if len(tagSets) > 1 {
+		return errors.New("only one set of tags can be specified in watch mode")
+	}
+	if bl.WritePath != "" {
+		return errors.New("a baseline cannot be written in watch mode")
+	}
+	gopathSrc, err := gopathSrcDir(projectDir)
+	if err != nil {
+		return err
+	}
+
+	ctx := loader.Options{
+		GOOS:   cfg.GOOS,
+		GOARCH: cfg.GOARCH,
+	}.Context()
+	if len(tagSets) == 1 {
+		ctx = loader.ContextForTags(ctx, tagSets[0])
+	}
+	checker := &watchChecker{
+		projectDir: projectDir,
+		gopathSrc:  gopathSrc,
+		pkgPaths:   pkgPaths,
+		cfg:        cfg,
+		ctx:        ctx,
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file watcher")
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+	if err := addWatches(watcher, projectDir); err != nil {
+		return err
+	}
+
+	var prev []string
+	changedDirs := make(map[string]struct{})
+	run := func() {
+		start := time.Now()
+		errs, nChecked, nUnits, err := checker.run(changedDirs, parallelism)
+		if err == nil {
+			errs, err = bl.Apply(projectDir, errs)
+		}
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+			return
+		}
+		changedDirs = make(map[string]struct{})
+
+		curr := make([]string, len(errs))
+		for i, currErr := range errs {
+			curr[i] = currErr.String()
+		}
+		for _, line := range errsDelta(prev, curr) {
+			fmt.Fprintln(w, line)
+		}
+		fmt.Fprintf(w, "%d errors (type-checked %d of %d packages in %v)\n", len(curr), nChecked, nUnits, time.Since(start).Round(time.Millisecond))
+		prev = curr
+	}
+	run()
+
+	var rerun <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			switch filepath.Ext(event.Name) {
+			case ".go":
+				changedDirs[filepath.Dir(event.Name)] = struct{}{}
+			case "":
+				// directories that are created are watched and directories that are removed no longer contain packages,
+				// which is determined when the packages are listed
+				if event.Op&fsnotify.Create != 0 {
+					if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+						if err := addWatches(watcher, event.Name); err != nil {
+							fmt.Fprintf(w, "error: %v\n", err)
+						}
+					}
+				}
+			default:
+				continue
+			}
+			rerun = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(w, "error: %v\n", err)
+		case <-rerun:
+			rerun = nil
+			run()
+		}
+	}
+}
+
+// addWatches adds the provided directory and all of its subdirectories (except for hidden directories) to the watcher.
+func addWatches(watcher *fsnotify.Watcher, rootDir string) error {
+	return filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// directory may have been removed since it was listed
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != rootDir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return errors.Wrapf(err, "failed to watch %s", path)
+		}
+		return nil
+	})
+}
+
+// watchChecker type-checks the packages of a project repeatedly, retaining the result of each run so that units that
+// are not affected by the changes since the previous run do not need to be type-checked again.
+type watchChecker struct {
+	projectDir string
+	gopathSrc  string
+	// if empty or if it contains patterns, the packages are listed from the project directory on every run
+	pkgPaths []string
+	cfg      config
+	ctx      build.Context
+
+	checker *typeChecker
+	// units of the previous run keyed by name
+	prevUnits map[string]*checkUnit
+}
+
+// run type-checks the units that are stale given the directories in which Go files changed since the previous run and
+// returns the errors in all of the units (de-duplicated and without the disabled classes), the number of units that
+// were type-checked and the total number of units. If a Go file changed in a directory that does not contain a unit
+// (for example, a vendored dependency) or a stale unit is imported by a package outside of the project, packages
+// outside of the project that were type-checked from source may be out of date, so all of the units are type-checked.
+func (c *watchChecker) run(changedDirs map[string]struct{}, parallelism int) ([]diagnostic.Diagnostic, int, int, error) {
+	pkgPaths, err := pkgPathsForArgs(c.projectDir, c.gopathSrc, c.ctx, c.cfg, c.pkgPaths)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if c.checker == nil {
+		c.checker = newTypeChecker(&c.ctx, c.cfg.DependencyErrors)
+	}
+	units, err := c.checker.units(pkgPaths, c.projectDir)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	unitDirs := make(map[string]struct{})
+	for _, currUnit := range units {
+		unitDirs[currUnit.dir] = struct{}{}
+	}
+	reset := false
+	for currDir := range changedDirs {
+		if _, ok := unitDirs[currDir]; !ok {
+			reset = true
+			break
+		}
+	}
+	stale := staleUnits(units, c.prevUnits, changedDirs)
+	for currUnit, isStale := range stale {
+		if isStale && c.checker.srcImporter.importsUnit(currUnit.path) {
+			// packages outside of the project that were type-checked against the previous package of the unit are
+			// out of date
+			reset = true
+			break
+		}
+	}
+	if reset {
+		c.checker.resetSourceImporter()
+		c.prevUnits = nil
+		stale = staleUnits(units, nil, changedDirs)
+	}
+	var toCheck []*checkUnit
+	for _, currUnit := range units {
+		if stale[currUnit] {
+			toCheck = append(toCheck, currUnit)
+			continue
+		}
+		prevUnit := c.prevUnits[currUnit.name]
+		currUnit.pkg = prevUnit.pkg
+		currUnit.errs = prevUnit.errs
+		currUnit.extImports = prevUnit.extImports
+		close(currUnit.done)
+	}
+	c.checker.check(toCheck, parallelism)
+
+	c.prevUnits = make(map[string]*checkUnit, len(units))
+	for _, currUnit := range units {
+		c.prevUnits[currUnit.name] = currUnit
+	}
+
+	var unitErrs []diagnostic.Diagnostic
+	for _, currUnit := range units {
+		unitErrs = append(unitErrs, currUnit.errs...)
+	}
+	unitErrs = append(unitErrs, c.checker.dependencyErrs(units)...)
+	var errs []diagnostic.Diagnostic
+	seen := make(map[string]struct{})
+	for _, currErr := range filterDisabledClasses(unitErrs, c.cfg.Disable) {
+		if _, ok := seen[currErr.String()]; ok {
+			continue
+		}
+		seen[currErr.String()] = struct{}{}
+		errs = append(errs, currErr)
+	}
+	return errs, len(toCheck), len(units), nil
+}
+
+// staleUnits returns the units that must be type-checked: units that did not exist in the previous run, units whose set
+// of files changed or that are in one of the changed directories and units that import a stale unit.
+func staleUnits(units []*checkUnit, prevUnits map[string]*checkUnit, changedDirs map[string]struct{}) map[*checkUnit]bool {
+	stale := make(map[*checkUnit]bool)
+	var visit func(unit *checkUnit) bool
+	visit = func(unit *checkUnit) bool {
+		if isStale, ok := stale[unit]; ok {
+			return isStale
+		}
+		prevUnit, ok := prevUnits[unit.name]
+		_, changed := changedDirs[unit.dir]
+		isStale := !ok || changed || strings.Join(prevUnit.files, "\n") != strings.Join(unit.files, "\n")
+		for _, dep := range unit.deps {
+			if visit(dep) {
+				isStale = true
+			}
+		}
+		stale[unit] = isStale
+		return isStale
+	}
+	for _, currUnit := range units {
+		visit(currUnit)
+	}
+	return stale
+}
+
+// errsDelta returns the lines that are in prev but not in curr prefixed with "- " and the lines that are in curr but
+// not in prev prefixed with "+ ", sorted by content (ignoring the prefix).
+func errsDelta(prev, curr []string) []string {
+	prevSet := make(map[string]struct{}, len(prev))
+	for _, line := range prev {
+		prevSet[line] = struct{}{}
+	}
+	currSet := make(map[string]struct{}, len(curr))
+	for _, line := range curr {
+		currSet[line] = struct{}{}
+	}
+
+	var delta []string
+	for line := range prevSet {
+		if _, ok := currSet[line]; !ok {
+			delta = append(delta, "- "+line)
+		}
+	}
+	for line := range currSet {
+		if _, ok := prevSet[line]; !ok {
+			delta = append(delta, "+ "+line)
+		}
+	}
+	sort.Slice(delta, func(i, j int) bool {
+		return delta[i][2:] < delta[j][2:]
+	})
+	return delta
+}
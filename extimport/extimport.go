@@ -17,11 +17,13 @@ package main
 import (
 	"fmt"
 	"go/build"
+	"go/parser"
 	"go/token"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -29,14 +31,40 @@ import (
 	"github.com/nmiyake/pkg/errorstringer"
 	"github.com/palantir/pkg/cli"
 	"github.com/palantir/pkg/cli/flag"
+	"github.com/palantir/pkg/matcher"
 	"github.com/palantir/pkg/pkgpath"
 	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/projectconfig"
+	"github.com/palantir/checks/checks/vendorutil"
+)
+
+const (
+	pkgsFlagName          = "pkgs"
+	listFlagName          = "list"
+	suggestVendorFlagName = "suggest-vendor"
+	allFlagName           = "all"
+	formatFlagName        = "format"
+	projectDirFlagName    = "project-dir"
+	discoverRootsFlagName = "discover-roots"
+	stdPrefixFlagName     = "std-prefix"
+	warnStdPrefixFlagName = "warn-std-prefix"
+	internalFlagName      = "internal"
+	warnOnlyFlagName      = "warn-only"
+	maxViolationsFlagName = "max-violations"
+	allPlatformsFlagName  = "all-platforms"
+	ignoreGenFlagName     = "ignore-generated"
+	warnGenFlagName       = "warn-generated"
 )
 
 const (
-	pkgsFlagName = "pkgs"
-	listFlagName = "list"
-	allFlagName  = "all"
+	checkName          = "extimport"
+	externalImportRule = "external-import"
+	// foreignInternalRule is the rule for imports of internal packages of other projects
+	foreignInternalRule = "foreign-internal-import"
 )
 
 var (
@@ -49,33 +77,257 @@ var (
 		Alias: "l",
 		Usage: "print external dependencies one per line",
 	}
+	suggestVendorFlag = flag.BoolFlag{
+		Name:  suggestVendorFlagName,
+		Usage: "print the directory under vendor/ that must exist for every external dependency and where it can be copied from",
+	}
 	allFlag = flag.BoolFlag{
 		Name:  allFlagName,
 		Alias: "a",
 		Usage: "list all external dependencies, including those multiple levels deep",
 	}
+	formatFlag = flag.StringFlag{
+		Name:  formatFlagName,
+		Value: diagnostic.FormatText,
+		Usage: "format of the output for external imports. Must be 'text', 'json', 'checkstyle' or 'github'. Must be 'text' when listing external dependencies",
+	}
+	projectDirFlag = flag.StringFlag{
+		Name: projectDirFlagName,
+		Usage: "path (relative to the working directory) to the root of a project to check. Can be specified multiple " +
+			"times, in which case imports are considered external relative to the project that contains them",
+	}
+	discoverRootsFlag = flag.BoolFlag{
+		Name:  discoverRootsFlagName,
+		Usage: "check every project in the working directory, where a project is a directory that contains a vendor directory or a go.mod file",
+	}
+	baselineFlag = flag.StringFlag{
+		Name:  baseline.FlagName,
+		Usage: "path to a baseline file: external imports recorded in the baseline are not reported",
+	}
+	writeBaselineFlag = flag.StringFlag{
+		Name:  baseline.WriteFlagName,
+		Usage: "path to which a baseline file that records the current external imports is written",
+	}
+	stdPrefixFlag = flag.StringFlag{
+		Name: stdPrefixFlagName,
+		Usage: "import path prefix (for example, \"golang.org/x/\") of external packages that are treated like standard " +
+			"library packages and are not reported. Can be specified multiple times",
+	}
+	warnStdPrefixFlag = flag.BoolFlag{
+		Name:  warnStdPrefixFlagName,
+		Usage: "report imports of external packages that match a standard prefix as warnings rather than ignoring them",
+	}
+	internalFlag = flag.BoolFlag{
+		Name: internalFlagName,
+		Usage: "also report imports of internal packages of other projects, which only compile because of how the " +
+			"packages are vendored",
+	}
+	ignoreGenFlag = flag.BoolFlag{
+		Name:  ignoreGenFlagName,
+		Usage: "do not report external imports in generated files",
+	}
+	warnGenFlag = flag.BoolFlag{
+		Name:  warnGenFlagName,
+		Usage: "report external imports in generated files as warnings rather than errors",
+	}
+	warnOnlyFlag = flag.BoolFlag{
+		Name:  warnOnlyFlagName,
+		Usage: "print external imports without failing the check",
+	}
+	maxViolationsFlag = flag.IntFlag{
+		Name:  maxViolationsFlagName,
+		Usage: "maximum number of external imports that are tolerated before the check fails",
+	}
+	projectConfigFlag = flag.StringFlag{
+		Name:  projectconfig.FlagName,
+		Usage: "path to a project configuration file whose exclude section specifies packages to exclude",
+	}
+	goosFlag = flag.StringFlag{
+		Name:  loader.GOOSFlagName,
+		Usage: "target operating system used to determine the files of packages (default is the current operating system)",
+	}
+	goarchFlag = flag.StringFlag{
+		Name:  loader.GOARCHFlagName,
+		Usage: "target architecture used to determine the files of packages (default is the current architecture)",
+	}
+	tagsFlag = flag.StringFlag{
+		Name:  loader.TagsFlagName,
+		Usage: "comma-separated build tags used to determine the files of packages",
+	}
+	allPlatformsFlag = flag.BoolFlag{
+		Name: allPlatformsFlagName,
+		Usage: "check the imports of all Go files regardless of their build constraints, so that external imports in " +
+			"files for other platforms or tags are reported",
+	}
 )
 
 func main() {
 	app := cli.NewApp(cli.DebugHandler(errorstringer.SingleStack))
 	app.Flags = append(app.Flags,
 		listFlag,
+		suggestVendorFlag,
 		allFlag,
+		formatFlag,
+		projectDirFlag,
+		discoverRootsFlag,
+		baselineFlag,
+		writeBaselineFlag,
+		stdPrefixFlag,
+		warnStdPrefixFlag,
+		internalFlag,
+		ignoreGenFlag,
+		warnGenFlag,
+		warnOnlyFlag,
+		maxViolationsFlag,
+		projectConfigFlag,
+		goosFlag,
+		goarchFlag,
+		tagsFlag,
+		allPlatformsFlag,
 		pkgsFlag,
 	)
 	app.Action = func(ctx cli.Context) error {
+		loaderOpts := loader.Options{
+			GOOS:        ctx.String(loader.GOOSFlagName),
+			GOARCH:      ctx.String(loader.GOARCHFlagName),
+			Tags:        ctx.String(loader.TagsFlagName),
+			UseAllFiles: ctx.Bool(allPlatformsFlagName),
+		}
+		if loaderOpts.UseAllFiles && loaderOpts.Overridden() {
+			return errors.Errorf("--%s cannot be specified with --%s, --%s or --%s", allPlatformsFlagName, loader.GOOSFlagName, loader.GOARCHFlagName, loader.TagsFlagName)
+		}
 		wd, err := dirs.GetwdEvalSymLinks()
 		if err != nil {
 			return errors.Wrapf(err, "Failed to get working directory")
 		}
-		return doExtimport(wd, ctx.Slice(pkgsFlagName), ctx.Bool(listFlagName), ctx.Bool(allFlagName), ctx.App.Stdout)
+		var projectDirs []string
+		for _, currDir := range ctx.StringSlice(projectDirFlagName) {
+			if currDir == "" {
+				continue
+			}
+			if !filepath.IsAbs(currDir) {
+				currDir = path.Join(wd, currDir)
+			}
+			projectDirs = append(projectDirs, path.Clean(currDir))
+		}
+		if ctx.Bool(discoverRootsFlagName) {
+			if len(projectDirs) > 0 {
+				return errors.Errorf("--%s and --%s cannot be specified together", projectDirFlagName, discoverRootsFlagName)
+			}
+			if projectDirs, err = discoverRoots(wd); err != nil {
+				return err
+			}
+			if len(projectDirs) == 0 {
+				return errors.Errorf("no project roots found in %s", wd)
+			}
+		}
+		projectCfg, err := projectconfig.Load(ctx.String(projectconfig.FlagName))
+		if err != nil {
+			return err
+		}
+		std := stdPrefixes{
+			warn: ctx.Bool(warnStdPrefixFlagName),
+		}
+		for _, currPrefix := range ctx.StringSlice(stdPrefixFlagName) {
+			if currPrefix != "" {
+				std.prefixes = append(std.prefixes, currPrefix)
+			}
+		}
+		generated := generatedFiles{
+			ignore: ctx.Bool(ignoreGenFlagName),
+			warn:   ctx.Bool(warnGenFlagName),
+		}
+		if generated.ignore && generated.warn {
+			return errors.Errorf("--%s and --%s cannot be specified together", ignoreGenFlagName, warnGenFlagName)
+		}
+		policy := failurePolicy{
+			warnOnly:      ctx.Bool(warnOnlyFlagName),
+			maxViolations: ctx.Int(maxViolationsFlagName),
+		}
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		return doExtimport(loader.New(loaderOpts), wd, extimportOptions{
+			projectDirs:   projectDirs,
+			pkgPaths:      ctx.Slice(pkgsFlagName),
+			exclude:       projectCfg.ExcludeMatcher(),
+			std:           std,
+			generated:     generated,
+			checkInternal: ctx.Bool(internalFlagName),
+			list:          ctx.Bool(listFlagName),
+			suggestVendor: ctx.Bool(suggestVendorFlagName),
+			all:           ctx.Bool(allFlagName),
+			format:        ctx.String(formatFlagName),
+			baseline: baseline.Options{
+				Path:      ctx.String(baseline.FlagName),
+				WritePath: ctx.String(baseline.WriteFlagName),
+			},
+			policy: policy,
+		}, ctx.App.Stdout)
 	}
 	os.Exit(app.Run(os.Args))
 }
 
-func doExtimport(projectDir string, pkgPaths []string, list, all bool, w io.Writer) error {
-	if !path.IsAbs(projectDir) {
-		return errors.Errorf("projectDir %s must be an absolute path", projectDir)
+// extimportOptions configures the packages that are checked by doExtimport and how the results are reported.
+type extimportOptions struct {
+	// projectDirs are the absolute paths of the project directories. If empty, the base directory is the only project
+	// directory.
+	projectDirs []string
+	// pkgPaths are the paths of the packages to check, relative to the project directory. Paths can only be provided
+	// if there is a single project directory and can be patterns that end in "/..." (see expandPkgPaths). If empty, all
+	// of the packages in each project directory are checked.
+	pkgPaths []string
+	// exclude matches the paths (relative to the base directory) of the packages that are not checked when the
+	// packages are listed from the project directories. May be nil.
+	exclude matcher.Matcher
+	// std are the prefixes of external packages that are ignored or reported as warnings.
+	std stdPrefixes
+	// generated specifies whether external imports in generated files are ignored or reported as warnings.
+	generated generatedFiles
+	// checkInternal specifies whether imports of internal packages of other projects are reported as well (see
+	// foreignInternalRoot).
+	checkInternal bool
+	// list specifies whether the external packages are printed one per line instead of the external imports being
+	// printed as diagnostics.
+	list bool
+	// suggestVendor specifies whether the vendor directory that would need to exist for each external package is
+	// printed instead of the external imports (see printVendorSuggestions).
+	suggestVendor bool
+	// all specifies whether the external dependencies of the external packages are included when the external
+	// packages are listed or vendor suggestions are printed.
+	all bool
+	// format is the format in which the diagnostics are printed.
+	format string
+	// baseline suppresses the diagnostics recorded in a baseline file. The files in the baseline are relative to the
+	// base directory.
+	baseline baseline.Options
+	// policy determines whether the external imports (or external packages, if they are listed) cause an error to be
+	// returned.
+	policy failurePolicy
+}
+
+// doExtimport uses the provided loader to check the packages of the project directories specified by the provided
+// options for imports of packages outside of the project directory that contains them. The results for all of the
+// project directories are aggregated into a single report that is written to w.
+func doExtimport(l *loader.Loader, baseDir string, opts extimportOptions, w io.Writer) error {
+	if err := diagnostic.ValidateFormat(opts.format); err != nil {
+		return err
+	}
+	if opts.list && opts.suggestVendor {
+		return errors.Errorf("--%s and --%s cannot be specified together", listFlagName, suggestVendorFlagName)
+	}
+	if (opts.list || opts.suggestVendor) && opts.format != diagnostic.FormatText {
+		return errors.Errorf("format %q is not supported when listing external dependencies", opts.format)
+	}
+	if (opts.list || opts.suggestVendor) && opts.baseline != (baseline.Options{}) {
+		return errors.Errorf("baselines are not supported when listing external dependencies")
+	}
+	projectDirs := opts.projectDirs
+	if len(projectDirs) == 0 {
+		projectDirs = []string{baseDir}
+	}
+	if len(projectDirs) > 1 && len(opts.pkgPaths) > 0 {
+		return errors.Errorf("packages cannot be specified when checking multiple project directories")
 	}
 
 	gopath := os.Getenv("GOPATH")
@@ -83,32 +335,123 @@ func doExtimport(projectDir string, pkgPaths []string, list, all bool, w io.Writ
 		return errors.Errorf("GOPATH environment variable must be set")
 	}
 
-	if relPath, err := filepath.Rel(path.Join(gopath, "src"), projectDir); err != nil || strings.HasPrefix(relPath, "../") {
-		return errors.Wrapf(err, "Project directory %s must be a subdirectory of $GOPATH/src (%s)", projectDir, path.Join(gopath, "src"))
+	violations := 0
+	var diags []diagnostic.Diagnostic
+	printedPkgs := make(map[string]bool)
+	for _, projectDir := range projectDirs {
+		if !path.IsAbs(projectDir) {
+			return errors.Errorf("projectDir %s must be an absolute path", projectDir)
+		}
+		if relPath, err := filepath.Rel(path.Join(gopath, "src"), projectDir); err != nil || strings.HasPrefix(relPath, "../") {
+			return errors.Wrapf(err, "Project directory %s must be a subdirectory of $GOPATH/src (%s)", projectDir, path.Join(gopath, "src"))
+		}
+
+		projectExclude := matcher.PathLiteral(excludedRoots(projectDir, projectDirs)...)
+		if opts.exclude != nil {
+			projectExclude = matcher.Any(projectExclude, baseRelMatcher(baseDir, projectDir, opts.exclude))
+		}
+		externalPkgs, projectDiags, err := checkProject(l, projectDir, opts.pkgPaths, projectExclude, opts.std, opts.generated, opts.checkInternal && !opts.list && !opts.suggestVendor, opts.list, (opts.list || opts.suggestVendor) && opts.all, w, printedPkgs)
+		if err != nil {
+			return err
+		}
+		diags = append(diags, projectDiags...)
+		violations += len(externalPkgs)
+		if opts.suggestVendor {
+			relProjectDir, err := filepath.Rel(baseDir, projectDir)
+			if err != nil {
+				relProjectDir = projectDir
+			}
+			printVendorSuggestions(l, relProjectDir, externalPkgs, w)
+		}
+	}
+
+	if !opts.list && !opts.suggestVendor {
+		diags, err := opts.baseline.Apply(baseDir, diags)
+		if err != nil {
+			return err
+		}
+		if len(diags) > 0 || opts.format != diagnostic.FormatText {
+			if err := diagnostic.Print(w, opts.format, diags); err != nil {
+				return err
+			}
+		}
+		// warnings are reported but do not count as violations
+		violations = 0
+		for _, currDiag := range diags {
+			if currDiag.Severity == diagnostic.SeverityError {
+				violations++
+			}
+		}
+	}
+	return opts.policy.check(violations)
+}
+
+// failurePolicy determines whether the violations found by the check cause it to fail. The zero value fails the check
+// if there are any violations.
+type failurePolicy struct {
+	// warnOnly specifies whether violations are printed without ever failing the check.
+	warnOnly bool
+	// maxViolations is the number of violations that are tolerated before the check fails, which allows the number of
+	// violations in legacy projects to be ratcheted down gradually.
+	maxViolations int
+}
+
+func (p failurePolicy) validate() error {
+	if p.maxViolations < 0 {
+		return errors.Errorf("--%s must be non-negative: %d", maxViolationsFlagName, p.maxViolations)
+	}
+	if p.warnOnly && p.maxViolations > 0 {
+		return errors.Errorf("--%s and --%s cannot be specified together", warnOnlyFlagName, maxViolationsFlagName)
 	}
+	return nil
+}
+
+// check returns an error if the provided number of violations causes the check to fail. The violations have already
+// been printed, so the error is blank unless more violations than the tolerated maximum were found.
+func (p failurePolicy) check(violations int) error {
+	if p.warnOnly || violations <= p.maxViolations {
+		return nil
+	}
+	if p.maxViolations > 0 {
+		return errors.Errorf("found %d external imports, which exceeds the maximum of %d", violations, p.maxViolations)
+	}
+	return fmt.Errorf("")
+}
 
+// checkProject checks the packages with the provided paths (or all of the packages in projectDir other than those
+// matched by exclude if no paths are provided) for external imports and returns the external packages that are
+// imported along with the diagnostics for the external imports. If list is true, the external packages are printed to
+// w as they are found instead of being returned as diagnostics. If followExternal is true, the external packages are
+// checked as well so that all external dependencies (even those multiple levels deep) are returned. If checkInternal is
+// true, diagnostics are also returned for the imports of internal packages of other projects in the project packages.
+func checkProject(l *loader.Loader, projectDir string, pkgPaths []string, exclude matcher.Matcher, std stdPrefixes, generated generatedFiles, checkInternal, list, followExternal bool, w io.Writer, printedPkgs map[string]bool) ([]string, []diagnostic.Diagnostic, error) {
 	if len(pkgPaths) == 0 {
-		pkgs, err := pkgpath.PackagesInDir(projectDir, pkgpath.DefaultGoPkgExcludeMatcher())
+		pkgs, err := pkgpath.PackagesInDir(projectDir, matcher.Any(pkgpath.DefaultGoPkgExcludeMatcher(), exclude))
 		if err != nil {
-			return errors.Wrapf(err, "Failed to list packages")
+			return nil, nil, errors.Wrapf(err, "Failed to list packages")
 		}
 
 		pkgPaths, err = pkgs.Paths(pkgpath.Relative)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to convert package paths")
+			return nil, nil, errors.Wrapf(err, "Failed to convert package paths")
+		}
+	} else {
+		var err error
+		if pkgPaths, err = expandPkgPaths(projectDir, pkgPaths, exclude); err != nil {
+			return nil, nil, err
 		}
 	}
 
 	internalPkgs := make(map[string]bool)
 	externalPkgs := make(map[string][]string)
-	printedPkgs := make(map[string]bool)
 
 	type pkgWithSrc struct {
 		pkg string
 		src string
 	}
 
-	externalImportsExist := false
+	var diags []diagnostic.Diagnostic
+	var allExternalPkgs []string
 	pkgsToProcess := make([]pkgWithSrc, len(pkgPaths))
 	for i, pkgPath := range pkgPaths {
 		pkgsToProcess[i] = pkgWithSrc{
@@ -125,15 +468,15 @@ func doExtimport(projectDir string, pkgPaths []string, list, all bool, w io.Writ
 		}
 		processedPkgs[currPkg] = true
 
-		externalPkgs, err := checkImports(currPkg.pkg, currPkg.src, projectDir, internalPkgs, externalPkgs, w, list, printedPkgs)
+		// only the imports of the project packages themselves are checked for internal packages of other projects
+		checkPkgInternal := checkInternal && currPkg.pkg == "./."
+		externalPkgs, pkgDiags, err := checkImports(l, currPkg.pkg, currPkg.src, projectDir, std, generated, checkPkgInternal, internalPkgs, externalPkgs, w, list, printedPkgs)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to check imports for %v", currPkg)
-		} else if len(externalPkgs) == 0 {
-			continue
+			return nil, nil, errors.Wrapf(err, "Failed to check imports for %v", currPkg)
 		}
-
-		externalImportsExist = true
-		if list && all {
+		diags = append(diags, pkgDiags...)
+		allExternalPkgs = append(allExternalPkgs, externalPkgs...)
+		if followExternal {
 			// when run in "list all" mode, process all external packages as well so that all
 			// external dependencies (even those multiple levels deep) are listed
 			for _, currExternalPkg := range externalPkgs {
@@ -147,24 +490,164 @@ func doExtimport(projectDir string, pkgPaths []string, list, all bool, w io.Writ
 			}
 		}
 	}
+	return allExternalPkgs, diags, nil
+}
 
-	if externalImportsExist {
-		return fmt.Errorf("")
+// expandPkgPaths returns the provided package paths (which are relative to projectDir) with every pattern expanded. A
+// path that ends in "/..." (or that is "...") is a pattern that matches the package in the directory before the "/..."
+// and all of the packages in its subdirectories, following the conventions of the go tool: vendor directories, hidden
+// directories, directories named "testdata" and directories that start with an underscore are skipped, as are the
+// directories matched by exclude. Returns an error if a pattern is not within projectDir or does not match any
+// packages. Other paths are returned unchanged.
+func expandPkgPaths(projectDir string, pkgPaths []string, exclude matcher.Matcher) ([]string, error) {
+	var projectPkgs []string
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, currPath := range pkgPaths {
+		if currPath != "..." && !strings.HasSuffix(currPath, "/...") {
+			if !seen[currPath] {
+				seen[currPath] = true
+				expanded = append(expanded, currPath)
+			}
+			continue
+		}
+
+		dir := path.Clean(strings.TrimSuffix(strings.TrimSuffix(currPath, "..."), "/"))
+		if path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			return nil, errors.Errorf("pattern %s is not within the project directory %s", currPath, projectDir)
+		}
+		if projectPkgs == nil {
+			pkgs, err := pkgpath.PackagesInDir(projectDir, matcher.Any(pkgpath.DefaultGoPkgExcludeMatcher(), matcher.Name("vendor"), exclude))
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to list packages")
+			}
+			if projectPkgs, err = pkgs.Paths(pkgpath.Relative); err != nil {
+				return nil, errors.Wrapf(err, "Failed to convert package paths")
+			}
+			sort.Strings(projectPkgs)
+		}
+
+		matched := false
+		for _, currPkgPath := range projectPkgs {
+			if pkgDir := path.Clean(currPkgPath); dir != "." && pkgDir != dir && !strings.HasPrefix(pkgDir, dir+"/") {
+				continue
+			}
+			matched = true
+			if !seen[currPkgPath] {
+				seen[currPkgPath] = true
+				expanded = append(expanded, currPkgPath)
+			}
+		}
+		if !matched {
+			return nil, errors.Errorf("pattern %s did not match any packages", currPath)
+		}
 	}
+	return expanded, nil
+}
 
-	return nil
+// excludedRoots returns the paths (relative to projectDir) of the provided project directories that are within
+// projectDir. The packages in these directories belong to those projects rather than to projectDir.
+func excludedRoots(projectDir string, projectDirs []string) []string {
+	var excluded []string
+	for _, currDir := range projectDirs {
+		if rel, err := filepath.Rel(projectDir, currDir); err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, "../") {
+			excluded = append(excluded, filepath.ToSlash(rel))
+		}
+	}
+	return excluded
+}
+
+// baseRelMatcher returns a matcher for paths relative to projectDir that matches the paths for which the provided
+// matcher matches the same path relative to baseDir. If projectDir is not within baseDir, paths are matched unmodified.
+func baseRelMatcher(baseDir, projectDir string, m matcher.Matcher) matcher.Matcher {
+	rel, err := filepath.Rel(baseDir, projectDir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return m
+	}
+	return prefixMatcher{
+		prefix:  filepath.ToSlash(rel),
+		matcher: m,
+	}
+}
+
+// prefixMatcher matches a path if the path joined to prefix is matched by matcher.
+type prefixMatcher struct {
+	prefix  string
+	matcher matcher.Matcher
+}
+
+func (m prefixMatcher) Match(relPath string) bool {
+	return m.matcher.Match(path.Join(m.prefix, relPath))
+}
+
+// discoverRoots returns the directories within baseDir (including baseDir itself) that are the roots of Go projects.
+// A directory is considered to be a project root if it contains a "vendor" directory or a "go.mod" file. Vendor
+// directories, "testdata" directories and hidden directories are not searched.
+func discoverRoots(baseDir string) ([]string, error) {
+	var roots []string
+	if err := filepath.Walk(baseDir, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if currPath != baseDir && (info.Name() == "vendor" || info.Name() == "testdata" || strings.HasPrefix(info.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		for _, marker := range []string{"vendor", "go.mod"} {
+			if _, err := os.Stat(path.Join(currPath, marker)); err == nil {
+				roots = append(roots, currPath)
+				break
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to discover project roots in %s", baseDir)
+	}
+	return roots, nil
+}
+
+// printVendorSuggestions prints a line for every provided external package (in sorted order and without duplicates)
+// that specifies the directory under the "vendor" directory of the project that would need to exist for the package to
+// be resolved within the project. The vendor directory is prefixed by the provided project directory unless it is ".".
+// If the package can be found in the GOPATH using the provided loader, the line also specifies the directory that can
+// be copied to the vendor directory.
+func printVendorSuggestions(l *loader.Loader, projectDir string, externalPkgs []string, w io.Writer) {
+	seen := make(map[string]struct{})
+	var sortedPkgs []string
+	for _, currPkg := range externalPkgs {
+		if _, ok := seen[currPkg]; ok {
+			continue
+		}
+		seen[currPkg] = struct{}{}
+		sortedPkgs = append(sortedPkgs, currPkg)
+	}
+	sort.Strings(sortedPkgs)
+
+	for _, currPkg := range sortedPkgs {
+		vendorDir := path.Join(projectDir, "vendor", currPkg)
+		if pkg, err := l.Import(currPkg, "", build.FindOnly); err == nil {
+			fmt.Fprintf(w, "%s: copy from %s\n", vendorDir, pkg.Dir)
+		} else {
+			fmt.Fprintf(w, "%s: not found in GOPATH\n", vendorDir)
+		}
+	}
 }
 
 // checkImports returns any external imports for the package "pkg". Does so by getting the "import" statements in all of
 // the .go files (including tests) in the directory and then resolving the imports using standard Go rules assuming that
 // the resolution occurs in "srcDir" (this is done so that special directories like "vendor" and "internal" are handled
 // correctly). An import is considered external if its resolved location is outside of the directory tree of
-// "projectRootDir".
-func checkImports(pkgPath, srcDir, projectRootDir string, internalPkgs map[string]bool, externalPkgs map[string][]string, w io.Writer, list bool, printedPkgs map[string]bool) ([]string, error) {
+// "projectRootDir". If list is true, the external packages are printed to w as they are found; otherwise, a diagnostic
+// is returned for every external import. Diagnostics for imports of external packages that match the standard prefixes
+// are warnings. Imports in generated files are skipped or reported as warnings as specified by generated. If
+// checkInternal is true, a diagnostic is also returned for every import of an internal package of another project.
+func checkImports(l *loader.Loader, pkgPath, srcDir, projectRootDir string, std stdPrefixes, generated generatedFiles, checkInternal bool, internalPkgs map[string]bool, externalPkgs map[string][]string, w io.Writer, list bool, printedPkgs map[string]bool) ([]string, []diagnostic.Diagnostic, error) {
 	// get all imports in package
-	pkg, err := build.Import(pkgPath, srcDir, build.ImportComment)
+	pkg, err := importPkg(l, pkgPath, srcDir)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to import package %s using srcDir %s", pkgPath, srcDir)
+		return nil, nil, errors.Wrapf(err, "Failed to import package %s using srcDir %s", pkgPath, srcDir)
 	}
 	importsToCheck := make(map[string][]token.Position)
 	addImportPosToMap(importsToCheck, pkg.ImportPos)
@@ -172,14 +655,29 @@ func checkImports(pkgPath, srcDir, projectRootDir string, internalPkgs map[strin
 	addImportPosToMap(importsToCheck, pkg.XTestImportPos)
 
 	var externalPkgsFound []string
+	var diags []diagnostic.Diagnostic
 	// check imports for each file in the package
 	sortedFiles, fileToImports := fileToImportsMap(importsToCheck)
 	for _, currFile := range sortedFiles {
+		isGenerated := (generated.ignore || generated.warn) && isGeneratedFile(currFile)
+		if isGenerated && generated.ignore {
+			continue
+		}
 		// check each import in the file
 		for _, currImportLine := range fileToImports[currFile] {
-			chain, err := getExternalImport(currImportLine.name, srcDir, projectRootDir, internalPkgs, externalPkgs)
+			if checkInternal {
+				diag, ok, err := foreignInternalImport(l, pkg, currFile, currImportLine, srcDir)
+				if err != nil {
+					return nil, nil, err
+				}
+				if ok {
+					diags = append(diags, diag)
+				}
+			}
+
+			chain, err := getExternalImport(l, currImportLine.name, srcDir, projectRootDir, std, internalPkgs, externalPkgs)
 			if err != nil {
-				return nil, errors.Wrapf(err, "isExternalImport failed for %s", currImportLine)
+				return nil, nil, errors.Wrapf(err, "isExternalImport failed for %s", currImportLine)
 			}
 
 			if len(chain) > 0 {
@@ -191,23 +689,51 @@ func checkImports(pkgPath, srcDir, projectRootDir string, internalPkgs map[strin
 					}
 					printedPkgs[externalPkg] = true
 				} else {
-					msg := fmt.Sprintf("%v:%v:%v: imports external package %v", currFile, currImportLine.pos.Line, currImportLine.pos.Column, externalPkg)
+					msg := fmt.Sprintf("imports external package %v", externalPkg)
 					if len(chain) > 1 {
 						msg += fmt.Sprintf(" transitively via %v", strings.Join(chain[:len(chain)-1], " -> "))
 					}
-					fmt.Fprintln(w, msg)
+					pos := currImportLine.pos
+					pos.Filename = currFile
+					diag := diagnostic.New(pos, checkName, externalImportRule, msg)
+					if len(chain) > 1 {
+						diag.Hint = cutPointHint(l, pkg, chain, projectRootDir)
+					}
+					if std.match(externalPkg) {
+						diag.Message += " (matches a standard prefix)"
+						diag.Severity = diagnostic.SeverityWarning
+					}
+					if isGenerated {
+						diag.Message += " (in generated file)"
+						diag.Severity = diagnostic.SeverityWarning
+					}
+					diags = append(diags, diag)
 				}
 			}
 		}
 	}
-	return externalPkgsFound, nil
+	return externalPkgsFound, diags, nil
+}
+
+// importPkg imports the package with the provided import path from the provided source directory using the provided
+// loader. If the loader considers all Go files regardless of their build constraints, a directory that contains files
+// of multiple packages (for example, a program in a file with an "ignore" build constraint next to a library) is not an
+// error: the returned package has the imports of all of the files.
+func importPkg(l *loader.Loader, pkgPath, srcDir string) (*build.Package, error) {
+	pkg, err := l.Import(pkgPath, srcDir, build.ImportComment)
+	if _, ok := err.(*build.MultiplePackageError); ok && l.Context().UseAllFiles {
+		return pkg, nil
+	}
+	return pkg, err
 }
 
 // getExternalImport takes an import and returns the chain to the external import if the import is external and nil
 // otherwise. Assumes that the import occurs in a package in "srcDir". The import is considered external if its resolved
-// path is not a subdirectory of the project root.
-func getExternalImport(importPkgPath, srcDir, projectRoot string, internalPkgs map[string]bool, externalPkgs map[string][]string) ([]string, error) {
-	if !strings.Contains(importPkgPath, ".") || internalPkgs[importPkgPath] {
+// path is not a subdirectory of the project root. Unless the standard prefixes are reported as warnings, packages that
+// match them are treated as standard packages. Otherwise, a chain to an external package that does not match them is
+// returned in preference to one that does.
+func getExternalImport(l *loader.Loader, importPkgPath, srcDir, projectRoot string, std stdPrefixes, internalPkgs map[string]bool, externalPkgs map[string][]string) ([]string, error) {
+	if !strings.Contains(importPkgPath, ".") || internalPkgs[importPkgPath] || (!std.warn && std.match(importPkgPath)) {
 		// if package is a standard package or known to be internal, return empty
 		return nil, nil
 	} else if chain, ok := externalPkgs[importPkgPath]; ok {
@@ -215,7 +741,7 @@ func getExternalImport(importPkgPath, srcDir, projectRoot string, internalPkgs m
 		return chain, nil
 	}
 
-	pkg, err := build.Import(importPkgPath, srcDir, build.ImportComment)
+	pkg, err := importPkg(l, importPkgPath, srcDir)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to import package %s", importPkgPath)
 	}
@@ -231,24 +757,159 @@ func getExternalImport(importPkgPath, srcDir, projectRoot string, internalPkgs m
 	// imported package using its source directory (required because this import may have its own internal or vendor
 	// directories).
 	sort.Strings(pkg.Imports)
+	var stdChain []string
 	for _, currImport := range pkg.Imports {
-		chain, err := getExternalImport(currImport, pkg.Dir, projectRoot, internalPkgs, externalPkgs)
+		chain, err := getExternalImport(l, currImport, pkg.Dir, projectRoot, std, internalPkgs, externalPkgs)
 		if err != nil {
 			return nil, errors.Wrapf(err, "isExternalImport failed for %v", currImport)
 		}
 		// if any import is external, this import is external
 		if len(chain) > 0 {
 			currChain := append([]string{importPkgPath}, chain...)
+			if std.match(chain[len(chain)-1]) {
+				// keep looking for a chain to a package that does not match a standard prefix
+				if stdChain == nil {
+					stdChain = currChain
+				}
+				continue
+			}
 			externalPkgs[importPkgPath] = currChain
 			return currChain, nil
 		}
 	}
+	if stdChain != nil {
+		externalPkgs[importPkgPath] = stdChain
+		return stdChain, nil
+	}
 
 	// if all checks pass, mark this package as internal and return false
 	internalPkgs[importPkgPath] = true
 	return nil, nil
 }
 
+// foreignInternalImport returns a diagnostic if the provided import of the provided package (which is in srcDir) in the
+// provided file imports an internal package of another project. Returns false if the import is not such an import.
+func foreignInternalImport(l *loader.Loader, pkg *build.Package, file string, imp importLine, srcDir string) (diagnostic.Diagnostic, bool, error) {
+	if !strings.Contains(imp.name, ".") {
+		// standard packages are not checked
+		return diagnostic.Diagnostic{}, false, nil
+	}
+	importedPkg, err := l.Import(imp.name, srcDir, build.FindOnly)
+	if err != nil {
+		return diagnostic.Diagnostic{}, false, errors.Wrapf(err, "Failed to import package %s", imp.name)
+	}
+	root, ok := foreignInternalRoot(pkg.ImportPath, importedPkg.ImportPath)
+	if !ok {
+		return diagnostic.Diagnostic{}, false, nil
+	}
+	pos := imp.pos
+	pos.Filename = file
+	diag := diagnostic.New(pos, checkName, foreignInternalRule, fmt.Sprintf("imports package %s, which is internal to %s", imp.name, vendorutil.StripVendorPrefix(root)))
+	diag.Hint = fmt.Sprintf("use the public API of %s instead: the import only compiles because of how the package is vendored and will break when the dependency is updated", vendorutil.StripVendorPrefix(root))
+	return diag, true, nil
+}
+
+// foreignInternalRoot returns the root of the tree of packages that may import the package with the provided resolved
+// import path if the package is internal (that is, the import path up to its last "internal" element) and the package
+// with the provided import path is not in that tree. Returns false otherwise. Import paths of vendored packages include
+// the path of the vendor directory, so a vendored internal package can only be imported by the packages of the
+// vendored project.
+func foreignInternalRoot(importerPath, importedPath string) (string, bool) {
+	var root string
+	switch {
+	case strings.HasSuffix(importedPath, "/internal"):
+		root = strings.TrimSuffix(importedPath, "/internal")
+	case strings.Contains(importedPath, "/internal/"):
+		root = importedPath[:strings.LastIndex(importedPath, "/internal/")]
+	default:
+		return "", false
+	}
+	if importerPath == root || strings.HasPrefix(importerPath, root+"/") {
+		return "", false
+	}
+	return root, true
+}
+
+// stdPrefixes are the import path prefixes of external packages that are treated like standard library packages (for
+// example, "golang.org/x/" for projects that intentionally do not vendor the x/ repositories).
+type stdPrefixes struct {
+	prefixes []string
+	// warn specifies whether imports of external packages that match the prefixes are reported as warnings (which do
+	// not cause the check to fail) rather than ignored.
+	warn bool
+}
+
+// match returns true if the provided import path starts with one of the prefixes.
+func (s stdPrefixes) match(importPath string) bool {
+	for _, currPrefix := range s.prefixes {
+		if strings.HasPrefix(importPath, currPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedFiles specifies how external imports in generated files are handled. A file is generated if a comment before
+// its package clause contains a line of the form "// Code generated ... DO NOT EDIT.". Fixing such imports requires
+// changing the generator rather than the file.
+type generatedFiles struct {
+	// ignore specifies whether imports in generated files are not checked.
+	ignore bool
+	// warn specifies whether external imports in generated files are reported as warnings (which do not cause the check
+	// to fail) rather than errors. Has no effect when the external packages are listed.
+	warn bool
+}
+
+var codeGeneratedRegexp = regexp.MustCompile(`^Code generated .* DO NOT EDIT\.$`)
+
+// isGeneratedFile returns true if the file with the provided name has the standard header of generated files. Files
+// that cannot be parsed are not considered to be generated.
+func isGeneratedFile(filename string) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+	for _, currGroup := range file.Comments {
+		if currGroup.Pos() >= file.Package {
+			break
+		}
+		for _, currLine := range strings.Split(currGroup.Text(), "\n") {
+			if codeGeneratedRegexp.MatchString(currLine) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cutPointHint returns a hint for fixing the provided transitive external import chain of the provided package. The
+// hint identifies the project-owned (non-vendored) package closest to the external package along the chain, which is
+// the point at which the chain can be cut: either the external package is vendored or that package is changed so that
+// it no longer imports the next package in the chain. Returns an empty string if the chain cannot be resolved.
+func cutPointHint(l *loader.Loader, pkg *build.Package, chain []string, projectRootDir string) string {
+	cut, next := pkg.ImportPath, chain[0]
+	srcDir := pkg.Dir
+	for i, currImport := range chain[:len(chain)-1] {
+		currPkg, err := l.Import(currImport, srcDir, build.FindOnly)
+		if err != nil {
+			return ""
+		}
+		srcDir = currPkg.Dir
+		if !vendorutil.IsVendoredBy(currPkg.Dir, projectRootDir) {
+			cut, next = currImport, chain[i+1]
+		}
+	}
+	externalPkg := chain[len(chain)-1]
+	hint := fmt.Sprintf("vendor %s or move %s so that it no longer imports %s", externalPkg, cut, next)
+	if next == externalPkg {
+		hint = fmt.Sprintf("vendor %s or move %s so that it no longer imports it", externalPkg, cut)
+	}
+	if importer := chain[len(chain)-2]; importer != cut {
+		hint += fmt.Sprintf(" (%s is required by vendored package %s)", externalPkg, importer)
+	}
+	return hint
+}
+
 func addImportPosToMap(dst, src map[string][]token.Position) {
 	for k, v := range src {
 		dst[k] = v
@@ -16,10 +16,12 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -27,6 +29,11 @@ import (
 	"github.com/nmiyake/pkg/gofiles"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/projectconfig"
 )
 
 func TestExtimport(t *testing.T) {
@@ -294,6 +301,7 @@ func TestExtimport(t *testing.T) {
 			verify: func(files map[string]gofiles.GoFile, got string, err error, caseNum int, caseName string) {
 				require.Error(t, err, fmt.Sprintf("Case %d (%s)", caseNum, caseName))
 				want := fmt.Sprintf("%s:1:22: imports external package %s transitively via %s -> %s\n", files["foo/foo.go"].Path, files["ext/ext.go"].ImportPath, files["foo/vendor/github.com/org/product/bar/bar.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath)
+				want += fmt.Sprintf("\thint: vendor %s or move %s so that it no longer imports %s (%s is required by vendored package %s)\n", files["ext/ext.go"].ImportPath, files["foo/foo.go"].ImportPath, files["foo/vendor/github.com/org/product/bar/bar.go"].ImportPath, files["ext/ext.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath)
 				assert.Equal(t, want, got, "Case %d (%s)", caseNum, caseName)
 			},
 			listOutput: func(files map[string]gofiles.GoFile) []string {
@@ -405,7 +413,9 @@ func TestExtimport(t *testing.T) {
 			verify: func(files map[string]gofiles.GoFile, got string, err error, caseNum int, caseName string) {
 				require.Error(t, err, fmt.Sprintf("Case %d (%s)", caseNum, caseName))
 				want := fmt.Sprintf("%s:1:22: imports external package %s transitively via %s\n", files["foo/foo.go"].Path, files["ext/ext.go"].ImportPath, files["foo/vendor/github.com/org/product/bar/bar.go"].ImportPath)
+				want += fmt.Sprintf("\thint: vendor %s or move %s so that it no longer imports %s (%s is required by vendored package %s)\n", files["ext/ext.go"].ImportPath, files["foo/foo.go"].ImportPath, files["foo/vendor/github.com/org/product/bar/bar.go"].ImportPath, files["ext/ext.go"].ImportPath, files["foo/vendor/github.com/org/product/bar/bar.go"].ImportPath)
 				want += fmt.Sprintf("%s:1:59: imports external package %s transitively via %s\n", files["foo/foo.go"].Path, files["ext/ext.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath)
+				want += fmt.Sprintf("\thint: vendor %s or move %s so that it no longer imports %s (%s is required by vendored package %s)\n", files["ext/ext.go"].ImportPath, files["foo/foo.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath, files["ext/ext.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath)
 				assert.Equal(t, want, got, "Case %d (%s)", caseNum, caseName)
 			},
 			listOutput: func(files map[string]gofiles.GoFile) []string {
@@ -414,6 +424,36 @@ func TestExtimport(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "hint identifies the nearest project package that imports the external package",
+			getArgs: func(projectDir string) (string, []string) {
+				return path.Join(projectDir, "foo"), []string{"./."}
+			},
+			files: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src:     `package main; import "{{index . "foo/bar/bar.go"}}";`,
+				},
+				{
+					RelPath: "foo/bar/bar.go",
+					Src:     `package bar; import "{{index . "foo/vendor/github.com/org/product/baz/baz.go"}}";`,
+				},
+				{
+					RelPath: "foo/vendor/github.com/org/product/baz/baz.go",
+					Src:     `package baz; import "{{index . "ext/ext.go"}}";`,
+				},
+				{
+					RelPath: "ext/ext.go",
+					Src:     `package ext`,
+				},
+			},
+			verify: func(files map[string]gofiles.GoFile, got string, err error, caseNum int, caseName string) {
+				require.Error(t, err, fmt.Sprintf("Case %d (%s)", caseNum, caseName))
+				want := fmt.Sprintf("%s:1:22: imports external package %s transitively via %s -> %s\n", files["foo/foo.go"].Path, files["ext/ext.go"].ImportPath, files["foo/bar/bar.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath)
+				want += fmt.Sprintf("\thint: vendor %s or move %s so that it no longer imports %s (%s is required by vendored package %s)\n", files["ext/ext.go"].ImportPath, files["foo/bar/bar.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath, files["ext/ext.go"].ImportPath, files["foo/vendor/github.com/org/product/baz/baz.go"].ImportPath)
+				assert.Equal(t, want, got, "Case %d (%s)", caseNum, caseName)
+			},
+		},
 	}
 
 	for i, currCase := range cases {
@@ -426,12 +466,19 @@ func TestExtimport(t *testing.T) {
 		dir, args := currCase.getArgs(currTmpDir)
 
 		buf := bytes.Buffer{}
-		doMainErr := doExtimport(dir, args, false, false, &buf)
+		doMainErr := doExtimport(loader.New(loader.Options{}), dir, extimportOptions{
+			pkgPaths: args,
+			format:   diagnostic.FormatText,
+		}, &buf)
 		currCase.verify(files, buf.String(), doMainErr, i, currCase.name)
 
 		if currCase.listOutput != nil {
 			buf := bytes.Buffer{}
-			_ = doExtimport(dir, args, true, false, &buf)
+			_ = doExtimport(loader.New(loader.Options{}), dir, extimportOptions{
+				pkgPaths: args,
+				list:     true,
+				format:   diagnostic.FormatText,
+			}, &buf)
 			assert.Equal(t, strings.Join(currCase.listOutput(files), "\n")+"\n", buf.String(), "Case %d (%s)", i, currCase.name)
 
 			listAllOutputFunc := currCase.listAllOutput
@@ -439,8 +486,686 @@ func TestExtimport(t *testing.T) {
 				listAllOutputFunc = currCase.listOutput
 			}
 			buf = bytes.Buffer{}
-			_ = doExtimport(dir, args, true, true, &buf)
+			_ = doExtimport(loader.New(loader.Options{}), dir, extimportOptions{
+				pkgPaths: args,
+				list:     true,
+				all:      true,
+				format:   diagnostic.FormatText,
+			}, &buf)
 			assert.Equal(t, strings.Join(listAllOutputFunc(files), "\n")+"\n", buf.String(), "Case %d (%s)", i, currCase.name)
 		}
 	}
 }
+
+func TestExtimportJSON(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package main; import "{{index . "ext/ext.go"}}";`,
+		},
+		{
+			RelPath: "ext/ext.go",
+			Src:     `package ext`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), path.Join(tmpDir, "foo"), extimportOptions{
+		pkgPaths: []string{"./."},
+		format:   diagnostic.FormatJSON,
+	}, &buf)
+	require.Error(t, err)
+
+	want := fmt.Sprintf(`[
+    {
+        "file": "%s",
+        "line": 1,
+        "col": 22,
+        "check": "extimport",
+        "ruleId": "external-import",
+        "message": "imports external package %s",
+        "severity": "error"
+    }
+]
+`, files["foo/foo.go"].Path, files["ext/ext.go"].ImportPath)
+	assert.Equal(t, want, buf.String())
+
+	err = doExtimport(loader.New(loader.Options{}), path.Join(tmpDir, "foo"), extimportOptions{
+		pkgPaths: []string{"./."},
+		list:     true,
+		format:   diagnostic.FormatJSON,
+	}, &buf)
+	assert.EqualError(t, err, `format "json" is not supported when listing external dependencies`)
+}
+
+func TestExtimportBaseline(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package main; import "{{index . "ext/ext.go"}}";`,
+		},
+		{
+			RelPath: "foo/bar/bar.go",
+			Src:     `package bar`,
+		},
+		{
+			RelPath: "ext/ext.go",
+			Src:     `package ext`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+	baselineFile := path.Join(tmpDir, "baseline.json")
+
+	buf := bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		format:   diagnostic.FormatText,
+		baseline: baseline.Options{WritePath: baselineFile},
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		format:   diagnostic.FormatText,
+		baseline: baseline.Options{Path: baselineFile},
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	// new external import is reported
+	err = ioutil.WriteFile(path.Join(projectDir, "bar", "bar.go"), []byte(fmt.Sprintf("package bar\n\nimport %q\n", files["ext/ext.go"].ImportPath)), 0644)
+	require.NoError(t, err)
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		format:   diagnostic.FormatText,
+		baseline: baseline.Options{Path: baselineFile},
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, fmt.Sprintf("%s:3:8: imports external package %s\n", path.Join(projectDir, "bar", "bar.go"), files["ext/ext.go"].ImportPath), buf.String())
+}
+
+func TestExtimportFailurePolicy(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package main; import "{{index . "ext/ext.go"}}"; import "{{index . "ext/other/other.go"}}";`,
+		},
+		{
+			RelPath: "ext/ext.go",
+			Src:     `package ext`,
+		},
+		{
+			RelPath: "ext/other/other.go",
+			Src:     `package other`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+	for i, currCase := range []struct {
+		policy  failurePolicy
+		wantErr string
+	}{
+		{failurePolicy{}, ""},
+		{failurePolicy{warnOnly: true}, "<nil>"},
+		{failurePolicy{maxViolations: 1}, "found 2 external imports, which exceeds the maximum of 1"},
+		{failurePolicy{maxViolations: 2}, "<nil>"},
+	} {
+		buf := bytes.Buffer{}
+		err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+			format: diagnostic.FormatText,
+			policy: currCase.policy,
+		}, &buf)
+		assert.Equal(t, currCase.wantErr, fmt.Sprint(err), "Case %d", i)
+		// violations are printed regardless of whether they cause the check to fail
+		assert.Equal(t, 2, strings.Count(buf.String(), "imports external package"), "Case %d", i)
+	}
+
+	assert.EqualError(t, failurePolicy{maxViolations: -1}.validate(), "--max-violations must be non-negative: -1")
+	assert.EqualError(t, failurePolicy{warnOnly: true, maxViolations: 1}.validate(), "--warn-only and --max-violations cannot be specified together")
+}
+
+func TestExtimportSuggestVendor(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package main; import "{{index . "ext/ext.go"}}"; import "{{index . "foo/bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "foo/bar/bar.go",
+			Src:     `package bar; import "{{index . "ext/ext.go"}}";`,
+		},
+		{
+			RelPath: "ext/ext.go",
+			Src:     `package ext; import "{{index . "other/other.go"}}";`,
+		},
+		{
+			RelPath: "other/other.go",
+			Src:     `package other`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+	extDir := path.Dir(files["ext/ext.go"].Path)
+	otherDir := path.Dir(files["other/other.go"].Path)
+
+	buf := bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		suggestVendor: true,
+		format:        diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, fmt.Sprintf("vendor/%s: copy from %s\n", files["ext/ext.go"].ImportPath, extDir), buf.String())
+
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		suggestVendor: true,
+		all:           true,
+		format:        diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	want := fmt.Sprintf("vendor/%s: copy from %s\n", files["ext/ext.go"].ImportPath, extDir)
+	want += fmt.Sprintf("vendor/%s: copy from %s\n", files["other/other.go"].ImportPath, otherDir)
+	assert.Equal(t, want, buf.String())
+
+	buf = bytes.Buffer{}
+	printVendorSuggestions(loader.New(loader.Options{}), ".", []string{"github.com/org/missing"}, &buf)
+	assert.Equal(t, "vendor/github.com/org/missing: not found in GOPATH\n", buf.String())
+
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		list:          true,
+		suggestVendor: true,
+		format:        diagnostic.FormatText,
+	}, &buf)
+	assert.EqualError(t, err, "--list and --suggest-vendor cannot be specified together")
+}
+
+func TestExtimportMultipleProjects(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import "{{index . "foo/vendor/github.com/org/lib/lib.go"}}";`,
+		},
+		{
+			RelPath: "foo/vendor/github.com/org/lib/lib.go",
+			Src:     `package lib`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import "{{index . "foo/foo.go"}}";`,
+		},
+		{
+			RelPath: "bar/go.mod",
+			Src:     `module bar`,
+		},
+	})
+	require.NoError(t, err)
+
+	// project directories are discovered based on the presence of a vendor directory or go.mod file
+	roots, err := discoverRoots(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{path.Join(tmpDir, "bar"), path.Join(tmpDir, "foo")}, roots)
+
+	// foo is internal to its own project but external to bar
+	buf := bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), tmpDir, extimportOptions{
+		projectDirs: roots,
+		format:      diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, fmt.Sprintf("%s:1:21: imports external package %s\n", files["bar/bar.go"].Path, files["foo/foo.go"].ImportPath), buf.String())
+
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), tmpDir, extimportOptions{
+		projectDirs: []string{path.Join(tmpDir, "foo")},
+		format:      diagnostic.FormatText,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), tmpDir, extimportOptions{
+		projectDirs:   roots,
+		suggestVendor: true,
+		format:        diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, fmt.Sprintf("bar/vendor/%s: copy from %s\n", files["foo/foo.go"].ImportPath, path.Dir(files["foo/foo.go"].Path)), buf.String())
+
+	// paths excluded by the project configuration are relative to the base directory
+	projectCfg, err := projectconfig.LoadFromString(`exclude:
+  paths:
+    - "bar"
+`)
+	require.NoError(t, err)
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), tmpDir, extimportOptions{
+		projectDirs: roots,
+		exclude:     projectCfg.ExcludeMatcher(),
+		format:      diagnostic.FormatText,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	err = doExtimport(loader.New(loader.Options{}), tmpDir, extimportOptions{
+		projectDirs: roots,
+		pkgPaths:    []string{"./foo"},
+		format:      diagnostic.FormatText,
+	}, &buf)
+	assert.EqualError(t, err, "packages cannot be specified when checking multiple project directories")
+}
+
+func TestExtimportStdPrefixes(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import "{{index . "x/text/text.go"}}";`,
+		},
+		{
+			RelPath: "foo/bar/bar.go",
+			Src:     `package bar; import "{{index . "foo/vendor/github.com/org/lib/lib.go"}}";`,
+		},
+		{
+			RelPath: "foo/vendor/github.com/org/lib/lib.go",
+			Src:     `package lib; import "{{index . "x/text/text.go"}}"; import "{{index . "z/ext/ext.go"}}";`,
+		},
+		{
+			RelPath: "x/text/text.go",
+			Src:     `package text`,
+		},
+		{
+			RelPath: "z/ext/ext.go",
+			Src:     `package ext`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+	std := stdPrefixes{
+		prefixes: []string{path.Dir(files["x/text/text.go"].ImportPath) + "/"},
+	}
+
+	// imports of packages that match a standard prefix are ignored, but other external packages are still reported
+	buf := bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		pkgPaths: []string{"./.", "./bar"},
+		std:      std,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		fmt.Sprintf("%s:1:21: imports external package %s transitively via %s", files["foo/bar/bar.go"].Path, files["z/ext/ext.go"].ImportPath, "github.com/org/lib"),
+		fmt.Sprintf("\thint: vendor %s or move %s so that it no longer imports github.com/org/lib (%s is required by vendored package github.com/org/lib)", files["z/ext/ext.go"].ImportPath, files["foo/bar/bar.go"].ImportPath, files["z/ext/ext.go"].ImportPath),
+	}, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"))
+
+	// imports of packages that match a standard prefix are reported as warnings, which do not fail the check
+	std.warn = true
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		pkgPaths: []string{"./."},
+		std:      std,
+		format:   diagnostic.FormatJSON,
+	}, &buf)
+	require.NoError(t, err)
+	var diags []diagnostic.Diagnostic
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &diags))
+	require.Equal(t, 1, len(diags))
+	assert.Equal(t, fmt.Sprintf("imports external package %s (matches a standard prefix)", files["x/text/text.go"].ImportPath), diags[0].Message)
+	assert.Equal(t, diagnostic.SeverityWarning, diags[0].Severity)
+
+	// chains to external packages that do not match a standard prefix are reported in preference to those that do
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		pkgPaths: []string{"./bar"},
+		std:      std,
+		format:   diagnostic.FormatJSON,
+	}, &buf)
+	require.Error(t, err)
+	diags = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &diags))
+	require.Equal(t, 1, len(diags))
+	assert.Equal(t, fmt.Sprintf("imports external package %s transitively via github.com/org/lib", files["z/ext/ext.go"].ImportPath), diags[0].Message)
+	assert.Equal(t, diagnostic.SeverityError, diags[0].Severity)
+}
+
+func TestExtimportInternal(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+import "github.com/org/lib/internal/impl"
+import "{{index . "foo/internal/util/util.go"}}"
+import "github.com/org/lib"
+`,
+		},
+		{
+			RelPath: "foo/internal/util/util.go",
+			Src:     `package util`,
+		},
+		{
+			RelPath: "foo/vendor/github.com/org/lib/lib.go",
+			Src:     `package lib; import "github.com/org/lib/internal/impl"`,
+		},
+		{
+			RelPath: "foo/vendor/github.com/org/lib/internal/impl/impl.go",
+			Src:     `package impl`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+
+	buf := bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		pkgPaths: []string{"./."},
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	// only the import of the internal package of the vendored project is reported
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		checkInternal: true,
+		format:        diagnostic.FormatJSON,
+	}, &buf)
+	require.Error(t, err)
+	var diags []diagnostic.Diagnostic
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &diags))
+	require.Equal(t, 1, len(diags))
+	assert.Equal(t, files["foo/foo.go"].Path, diags[0].File)
+	assert.Equal(t, 2, diags[0].Line)
+	assert.Equal(t, foreignInternalRule, diags[0].RuleID)
+	assert.Equal(t, "imports package github.com/org/lib/internal/impl, which is internal to github.com/org/lib", diags[0].Message)
+}
+
+func TestForeignInternalRoot(t *testing.T) {
+	for i, tc := range []struct {
+		importer, imported string
+		wantRoot           string
+		wantOK             bool
+	}{
+		{"github.com/org/project/foo", "github.com/org/project/internal/bar", "", false},
+		{"github.com/org/project", "github.com/org/project/internal", "", false},
+		{"github.com/org/project/foo", "github.com/org/project/foo/internal/bar/internal/baz", "github.com/org/project/foo/internal/bar", true},
+		{"github.com/org/project/foo", "github.com/org/project/vendor/github.com/org/lib/internal/impl", "github.com/org/project/vendor/github.com/org/lib", true},
+		{"github.com/org/project-other", "github.com/org/project/internal", "github.com/org/project", true},
+		{"github.com/org/project/foo", "github.com/org/project/internalfoo", "", false},
+	} {
+		root, ok := foreignInternalRoot(tc.importer, tc.imported)
+		assert.Equal(t, tc.wantOK, ok, "Case %d", i)
+		assert.Equal(t, tc.wantRoot, root, "Case %d", i)
+	}
+}
+
+func TestExtimportAllPlatforms(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	otherOS := "windows"
+	if runtime.GOOS == otherOS {
+		otherOS = "linux"
+	}
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo`,
+		},
+		{
+			RelPath: "foo/foo_" + otherOS + ".go",
+			Src:     `package foo; import "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "foo/gen.go",
+			Src: `// +build ignore
+
+package main; import "{{index . "ext/other/other.go"}}"`,
+		},
+		{
+			RelPath: "ext/ext.go",
+			Src:     `package ext`,
+		},
+		{
+			RelPath: "ext/other/other.go",
+			Src:     `package other`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+
+	// files for other platforms are not considered by default
+	buf := bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+		list:   true,
+		format: diagnostic.FormatText,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	buf = bytes.Buffer{}
+	err = doExtimport(loader.New(loader.Options{UseAllFiles: true}), projectDir, extimportOptions{
+		list:   true,
+		format: diagnostic.FormatText,
+	}, &buf)
+	require.EqualError(t, err, "")
+	assert.Equal(t, files["ext/ext.go"].ImportPath+"\n"+files["ext/other/other.go"].ImportPath+"\n", buf.String())
+}
+
+func TestExtimportGenerated(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import _ "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "foo/foo.pb.go",
+			Src: `// Code generated by protoc-gen-go. DO NOT EDIT.
+
+package foo; import _ "{{index . "ext/other/other.go"}}"`,
+		},
+		{
+			RelPath: "ext/ext.go",
+			Src:     `package ext`,
+		},
+		{
+			RelPath: "ext/other/other.go",
+			Src:     `package other`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+	extLine := fmt.Sprintf("%s:1:21: imports external package %s", files["foo/foo.go"].Path, files["ext/ext.go"].ImportPath)
+	for i, currCase := range []struct {
+		generated generatedFiles
+		want      string
+		wantErr   string
+	}{
+		{
+			generatedFiles{},
+			fmt.Sprintf("%s\n%s:3:21: imports external package %s\n", extLine, files["foo/foo.pb.go"].Path, files["ext/other/other.go"].ImportPath),
+			"found 2 external imports, which exceeds the maximum of 1",
+		},
+		{
+			generatedFiles{ignore: true},
+			extLine + "\n",
+			"<nil>",
+		},
+		{
+			generatedFiles{warn: true},
+			fmt.Sprintf("%s\n%s:3:21: imports external package %s (in generated file)\n", extLine, files["foo/foo.pb.go"].Path, files["ext/other/other.go"].ImportPath),
+			// warnings are not counted
+			"<nil>",
+		},
+	} {
+		buf := bytes.Buffer{}
+		err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+			generated: currCase.generated,
+			format:    diagnostic.FormatText,
+			policy:    failurePolicy{maxViolations: 1},
+		}, &buf)
+		assert.Equal(t, currCase.wantErr, fmt.Sprint(err), "Case %d", i)
+		assert.Equal(t, currCase.want, buf.String(), "Case %d", i)
+	}
+}
+
+func TestExtimportPatterns(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo`,
+		},
+		{
+			RelPath: "foo/bar/bar.go",
+			Src:     `package bar; import _ "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "foo/bar/baz/baz.go",
+			Src:     `package baz; import _ "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "foo/barbaz/barbaz.go",
+			Src:     `package barbaz; import _ "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "foo/bar/testdata/data.go",
+			Src:     `package data; import _ "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "foo/.hidden/hidden.go",
+			Src:     `package hidden; import _ "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "foo/vendor/github.com/org/lib/lib.go",
+			Src:     `package lib; import _ "{{index . "ext/ext.go"}}"`,
+		},
+		{
+			RelPath: "ext/ext.go",
+			Src:     `package ext`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectDir := path.Join(tmpDir, "foo")
+	line := func(relPath string) string {
+		// the import is reported at the column of the blank identifier, which follows "package <name>; import "
+		col := len("package "+strings.TrimSuffix(path.Base(relPath), ".go")+"; import ") + 1
+		return fmt.Sprintf("%s:1:%d: imports external package %s", files[relPath].Path, col, files["ext/ext.go"].ImportPath)
+	}
+	for i, currCase := range []struct {
+		pkgPaths []string
+		want     []string
+		wantErr  string
+	}{
+		{
+			// vendor, hidden and testdata directories are not matched
+			[]string{"./..."},
+			[]string{line("foo/bar/bar.go"), line("foo/bar/baz/baz.go"), line("foo/barbaz/barbaz.go")},
+			"",
+		},
+		{
+			[]string{"./bar/..."},
+			[]string{line("foo/bar/bar.go"), line("foo/bar/baz/baz.go")},
+			"",
+		},
+		{
+			// packages matched by multiple arguments are only checked once
+			[]string{"./bar/baz", "bar/..."},
+			[]string{line("foo/bar/baz/baz.go"), line("foo/bar/bar.go")},
+			"",
+		},
+		{
+			[]string{"./missing/..."},
+			nil,
+			"pattern ./missing/... did not match any packages",
+		},
+		{
+			[]string{"../..."},
+			nil,
+			fmt.Sprintf("pattern ../... is not within the project directory %s", projectDir),
+		},
+	} {
+		buf := bytes.Buffer{}
+		err = doExtimport(loader.New(loader.Options{}), projectDir, extimportOptions{
+			pkgPaths: currCase.pkgPaths,
+			format:   diagnostic.FormatText,
+			policy:   failurePolicy{warnOnly: true},
+		}, &buf)
+		if currCase.wantErr != "" {
+			assert.EqualError(t, err, currCase.wantErr, "Case %d", i)
+			continue
+		}
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"), "Case %d", i)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+// DoPrintCategory prints the packages in the provided category that are imported by the packages in each of the
+// provided directories. If multiple directories are provided, the packages for each directory are printed under a
+// header that contains the directory.
+func DoPrintCategory(l *loader.Loader, dirs []string, category gocd.ImportCategory, w io.Writer) error {
+	for _, dir := range dirs {
+		rootDir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		pkgsByCategory, err := gocd.ImportsByCategory(l, rootDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to determine imports for %s", dir)
+		}
+
+		prefix := ""
+		if len(dirs) > 1 {
+			fmt.Fprintf(w, "%s:\n", dir)
+			prefix = "\t"
+		}
+		for _, pkg := range pkgsByCategory[category] {
+			fmt.Fprintf(w, "%s%s\n", prefix, pkg)
+		}
+	}
+	return nil
+}
@@ -22,24 +22,72 @@ import (
 	"github.com/palantir/pkg/cli/flag"
 	"github.com/pkg/errors"
 
+	"github.com/palantir/checks/checks/loader"
 	"github.com/palantir/checks/gocd/config"
+	"github.com/palantir/checks/gocd/gocd"
 )
 
 const (
 	inputDirsParamName = "dirs"
 	verifyFlagName     = "verify"
+	categoryFlagName   = "category"
+	cacheDirFlagName   = "cache-dir"
+	formatFlagName     = "format"
 )
 
-var flags = []flag.Flag{
+var flags = append([]flag.Flag{
 	flag.BoolFlag{
 		Name:  verifyFlagName,
 		Usage: "verify that imports file exists and is up-to-date",
 	},
+	flag.StringFlag{
+		Name:  categoryFlagName,
+		Usage: "print the imported packages in the specified category ('stdlib', 'vendored', 'internal' or 'external') instead of writing the imports file",
+	},
+	flag.StringFlag{
+		Name:  formatFlagName,
+		Usage: "print the import report in the specified format ('json' or 'csv') instead of writing the imports file",
+	},
+	flag.StringFlag{
+		Name:  cacheDirFlagName,
+		Usage: "directory in which to cache package information so that only changed packages are re-analyzed on subsequent runs",
+	},
+}, append(loaderFlags(),
 	flag.StringSlice{
 		Name:     inputDirsParamName,
 		Usage:    "directories for which to perform operation",
 		Optional: true,
 	},
+)...)
+
+// loaderFlags returns the flags that configure the build context used to import packages. They are supported by every
+// command.
+func loaderFlags() []flag.Flag {
+	return []flag.Flag{
+		flag.StringFlag{
+			Name:  loader.GOOSFlagName,
+			Usage: "only analyze the files that match the specified target operating system",
+		},
+		flag.StringFlag{
+			Name:  loader.GOARCHFlagName,
+			Usage: "only analyze the files that match the specified target architecture",
+		},
+		flag.StringFlag{
+			Name:  loader.TagsFlagName,
+			Usage: "only analyze the files that match the specified comma-separated build tags",
+		},
+	}
+}
+
+// newLoader returns the loader used to import packages based on the build context flags. All Go files are analyzed
+// unless any of the flags are specified.
+func newLoader(ctx cli.Context) *loader.Loader {
+	return loader.New(loader.Options{
+		GOOS:        ctx.String(loader.GOOSFlagName),
+		GOARCH:      ctx.String(loader.GOARCHFlagName),
+		Tags:        ctx.String(loader.TagsFlagName),
+		UseAllFiles: true,
+	})
 }
 
 func Command() cli.Command {
@@ -48,51 +96,74 @@ func Command() cli.Command {
 		Usage: "Write or verify package import information for Go packages",
 		Flags: flags,
 		Action: func(ctx cli.Context) error {
+			l := newLoader(ctx)
 			params, err := config.Load(cfgcli.ConfigPath, cfgcli.ConfigJSON)
 			if err != nil {
 				return err
 			}
 
-			var dirs []string
-			if len(params.RootDirs) > 0 {
-				cfgDirs := make(map[string]struct{})
-				for _, dir := range params.RootDirs {
-					cfgDirs[dir] = struct{}{}
-				}
+			dirs, err := inputDirs(ctx, params)
+			if err != nil {
+				return err
+			}
 
-				// if dirs argument was provided, use it as a filter
-				if ctx.Has(inputDirsParamName) {
-					inputDirsSlice := ctx.Slice(inputDirsParamName)
-					for _, dir := range inputDirsSlice {
-						if _, ok := cfgDirs[dir]; ok {
-							dirs = append(dirs, dir)
-						}
-					}
-					if len(dirs) == 0 {
-						return errors.Errorf("specified directories %v did not match any directories in configuration: %v", inputDirsSlice, sortedKeys(cfgDirs))
-					}
-				} else {
-					dirs = params.RootDirs
-					if len(dirs) == 0 {
-						return errors.New("no input directories were specified and none were found in the configuration")
-					}
-				}
-			} else if ctx.Has(inputDirsParamName) {
-				dirs = ctx.Slice(inputDirsParamName)
-				if len(dirs) == 0 {
-					return errors.New("no input directories specified")
+			if category := ctx.String(categoryFlagName); category != "" {
+				importCategory, err := gocd.ParseImportCategory(category)
+				if err != nil {
+					return err
 				}
+				return DoPrintCategory(l, dirs, importCategory, ctx.App.Stdout)
+			}
+
+			if format := ctx.String(formatFlagName); format != "" {
+				return DoPrintReport(l, dirs, format, ctx.String(cacheDirFlagName), ctx.App.Stdout)
 			}
 
 			if ctx.Bool(verifyFlagName) {
-				return DoVerify(dirs)
+				return DoVerify(l, dirs, ctx.String(cacheDirFlagName))
 			}
 
-			return DoWriteImportsJSON(dirs)
+			return DoWriteImportsJSON(l, dirs, ctx.String(cacheDirFlagName))
 		},
 	}
 }
 
+// inputDirs returns the directories on which to operate based on the dirs argument and the root directories in the
+// configuration.
+func inputDirs(ctx cli.Context, params gocd.Params) ([]string, error) {
+	var dirs []string
+	if len(params.RootDirs) > 0 {
+		cfgDirs := make(map[string]struct{})
+		for _, dir := range params.RootDirs {
+			cfgDirs[dir] = struct{}{}
+		}
+
+		// if dirs argument was provided, use it as a filter
+		if ctx.Has(inputDirsParamName) {
+			inputDirsSlice := ctx.Slice(inputDirsParamName)
+			for _, dir := range inputDirsSlice {
+				if _, ok := cfgDirs[dir]; ok {
+					dirs = append(dirs, dir)
+				}
+			}
+			if len(dirs) == 0 {
+				return nil, errors.Errorf("specified directories %v did not match any directories in configuration: %v", inputDirsSlice, sortedKeys(cfgDirs))
+			}
+		} else {
+			dirs = params.RootDirs
+			if len(dirs) == 0 {
+				return nil, errors.New("no input directories were specified and none were found in the configuration")
+			}
+		}
+	} else if ctx.Has(inputDirsParamName) {
+		dirs = ctx.Slice(inputDirsParamName)
+		if len(dirs) == 0 {
+			return nil, errors.New("no input directories specified")
+		}
+	}
+	return dirs, nil
+}
+
 func sortedKeys(in map[string]struct{}) []string {
 	out := make([]string, 0, len(in))
 	for k := range in {
@@ -0,0 +1,118 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/cfgcli"
+	"github.com/palantir/pkg/cli/flag"
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/gocd/config"
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+// EnforceCommandName is the name of the command that enforces dependency budgets.
+const EnforceCommandName = "enforce"
+
+func EnforceCommand() cli.Command {
+	return cli.Command{
+		Name:  EnforceCommandName,
+		Usage: "Verify that the packages in the directories do not exceed the dependency budgets in the configuration",
+		Flags: append(loaderFlags(),
+			flag.StringSlice{
+				Name:     inputDirsParamName,
+				Usage:    "directories for which to enforce budgets",
+				Optional: true,
+			},
+		),
+		Action: func(ctx cli.Context) error {
+			l := newLoader(ctx)
+			params, err := config.Load(cfgcli.ConfigPath, cfgcli.ConfigJSON)
+			if err != nil {
+				return err
+			}
+			if len(params.Budgets) == 0 {
+				return errors.New("no budgets were found in the configuration")
+			}
+
+			dirs, err := inputDirs(ctx, params)
+			if err != nil {
+				return err
+			}
+			if len(dirs) == 0 {
+				return errors.New("no input directories specified")
+			}
+			return DoEnforce(l, dirs, params.Budgets, ctx.App.Stdout)
+		},
+	}
+}
+
+// DoEnforce checks the provided budgets against the packages in each of the provided directories. Each violation is
+// printed as a line of the form "<dir>: <package>: <message>". Returns an error if any budget is exceeded.
+func DoEnforce(l *loader.Loader, dirs []string, budgets []gocd.Budget, w io.Writer) error {
+	nViolations := 0
+	for _, dir := range dirs {
+		violations, err := checkDirBudgets(l, dir, budgets)
+		if err != nil {
+			return err
+		}
+		for _, violation := range violations {
+			fmt.Fprintf(w, "%s: %s\n", dir, violation)
+		}
+		nViolations += len(violations)
+	}
+
+	if nViolations > 0 {
+		return errors.Errorf("dependency budgets exceeded: %d %s", nViolations, pluralViolations(nViolations))
+	}
+	return nil
+}
+
+// checkDirBudgets returns the violations of the budgets that apply to the provided directory.
+func checkDirBudgets(l *loader.Loader, dir string, budgets []gocd.Budget) ([]gocd.BudgetViolation, error) {
+	var dirBudgets []gocd.Budget
+	for _, budget := range budgets {
+		if budget.RootDir == "" || path.Clean(budget.RootDir) == path.Clean(dir) {
+			dirBudgets = append(dirBudgets, budget)
+		}
+	}
+	if len(dirBudgets) == 0 {
+		return nil, nil
+	}
+
+	rootDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	violations, err := gocd.CheckBudgets(l, rootDir, dirBudgets)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check budgets for %s", dir)
+	}
+	return violations, nil
+}
+
+func pluralViolations(n int) string {
+	if n == 1 {
+		return "violation"
+	}
+	return "violations"
+}
@@ -0,0 +1,54 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// DoPrintReport prints the import report for the provided directory in the provided format ("json" or "csv"). Only a
+// single directory is supported so that the output can be parsed as a single JSON document or CSV table.
+func DoPrintReport(l *loader.Loader, dirs []string, format, cacheDir string, w io.Writer) error {
+	if format != formatJSON && format != formatCSV {
+		return errors.Errorf("invalid format %q: must be %q or %q", format, formatJSON, formatCSV)
+	}
+	if len(dirs) != 1 {
+		return errors.Errorf("exactly one directory must be specified when printing the report, was %v", dirs)
+	}
+
+	rootDir, err := filepath.Abs(dirs[0])
+	if err != nil {
+		return err
+	}
+	report, err := gocd.CreateCachedImportReport(l, rootDir, cacheDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create import report for %s", dirs[0])
+	}
+	if format == formatCSV {
+		return report.WriteCSV(w)
+	}
+	return report.WriteJSON(w)
+}
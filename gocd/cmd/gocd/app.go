@@ -27,5 +27,25 @@ func App() *cli.App {
 	flags := app.Flags
 	app.Command = cmd.Command()
 	app.Flags = append(flags, app.Flags...)
+	// the root command accepts directories as arguments, so "enforce", "leaks", "watch" and "why" are routed to their
+	// commands before the arguments are parsed as directories
+	app.Backcompat = []cli.Backcompat{
+		{
+			Path:    []string{cmd.EnforceCommandName},
+			Command: cmd.EnforceCommand(),
+		},
+		{
+			Path:    []string{cmd.LeaksCommandName},
+			Command: cmd.LeaksCommand(),
+		},
+		{
+			Path:    []string{cmd.WatchCommandName},
+			Command: cmd.WatchCommand(),
+		},
+		{
+			Path:    []string{cmd.WhyCommandName},
+			Command: cmd.WhyCommand(),
+		},
+	}
 	return app
 }
@@ -0,0 +1,163 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/cfgcli"
+	"github.com/palantir/pkg/cli/flag"
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/gocd/config"
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+const (
+	// LeaksCommandName is the name of the command that detects test-only dependencies that are imported by non-test code.
+	LeaksCommandName = "leaks"
+
+	baseFlagName = "base"
+	headFlagName = "head"
+)
+
+func LeaksCommand() cli.Command {
+	return cli.Command{
+		Name:  LeaksCommandName,
+		Usage: "Verify that the packages that were only imported by tests in a previous import report are not imported by non-test code",
+		Flags: append([]flag.Flag{
+			flag.StringFlag{
+				Name:  baseFlagName,
+				Usage: "path to the previous import report or git ref at which to read the imports file of each directory",
+			},
+			flag.StringFlag{
+				Name:  headFlagName,
+				Usage: "path to the newer import report or git ref at which to read the imports file of each directory (by default, the report is created from the current state of the directory)",
+			},
+			flag.StringFlag{
+				Name:  cacheDirFlagName,
+				Usage: "directory in which to cache package information so that only changed packages are re-analyzed on subsequent runs",
+			},
+		}, append(loaderFlags(),
+			flag.StringSlice{
+				Name:     inputDirsParamName,
+				Usage:    "directories for which to perform operation",
+				Optional: true,
+			},
+		)...),
+		Action: func(ctx cli.Context) error {
+			l := newLoader(ctx)
+			base := ctx.String(baseFlagName)
+			if base == "" {
+				return errors.Errorf("--%s must be specified", baseFlagName)
+			}
+			params, err := config.Load(cfgcli.ConfigPath, cfgcli.ConfigJSON)
+			if err != nil {
+				return err
+			}
+
+			dirs, err := inputDirs(ctx, params)
+			if err != nil {
+				return err
+			}
+			if len(dirs) == 0 {
+				return errors.New("no input directories specified")
+			}
+			return DoLeaks(l, dirs, base, ctx.String(headFlagName), ctx.String(cacheDirFlagName), ctx.App.Stdout)
+		},
+	}
+}
+
+// DoLeaks verifies that none of the packages that the base import report of each of the provided directories records as
+// only being imported by tests are imported by non-test code in the head import report. base and head are either paths
+// to import report files (which is only supported for a single directory) or git refs at which the imports file of each
+// directory is read. If head is empty, the head report is created from the current state of each directory. Each leak
+// is printed as a line of the form "<dir>: <message>". Returns an error if any leaks are found.
+func DoLeaks(l *loader.Loader, dirs []string, base, head, cacheDir string, w io.Writer) error {
+	nLeaks := 0
+	for _, dir := range dirs {
+		baseReport, err := loadReport(dir, base, len(dirs))
+		if err != nil {
+			return errors.Wrapf(err, "failed to load base report for %s", dir)
+		}
+		var headReport gocd.ImportReport
+		if head == "" {
+			rootDir, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			if headReport, err = gocd.CreateCachedImportReport(l, rootDir, cacheDir); err != nil {
+				return errors.Wrapf(err, "failed to create report for %s", dir)
+			}
+		} else if headReport, err = loadReport(dir, head, len(dirs)); err != nil {
+			return errors.Wrapf(err, "failed to load head report for %s", dir)
+		}
+
+		leaks := gocd.TestOnlyLeaks(baseReport, headReport)
+		for _, leak := range leaks {
+			fmt.Fprintf(w, "%s: %s\n", dir, leak)
+		}
+		nLeaks += len(leaks)
+	}
+
+	if nLeaks > 0 {
+		noun := "packages"
+		if nLeaks == 1 {
+			noun = "package"
+		}
+		return errors.Errorf("test-only dependencies imported by non-test code: %d %s", nLeaks, noun)
+	}
+	return nil
+}
+
+// loadReport loads the import report for the provided directory specified by spec. If spec is the path to a file, the
+// report is read from the file (which is only supported if nDirs is 1). Otherwise, spec is interpreted as a git ref and
+// the imports file of the directory is read from the commit that it refers to.
+func loadReport(dir, spec string, nDirs int) (gocd.ImportReport, error) {
+	var reportBytes []byte
+	if fi, err := os.Stat(spec); err == nil && !fi.IsDir() {
+		if nDirs > 1 {
+			return gocd.ImportReport{}, errors.Errorf("report file %s can only be used with a single directory", spec)
+		}
+		if reportBytes, err = ioutil.ReadFile(spec); err != nil {
+			return gocd.ImportReport{}, errors.Wrapf(err, "failed to read %s", spec)
+		}
+	} else {
+		// "./" makes the path relative to the directory rather than to the root of the repository
+		cmd := exec.Command("git", "show", spec+":./"+importsFileName)
+		cmd.Dir = dir
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		if reportBytes, err = cmd.Output(); err != nil {
+			return gocd.ImportReport{}, errors.Wrapf(err, "failed to read %s at git ref %s: %s", importsFileName, spec, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	report := gocd.ImportReport{}
+	if err := json.Unmarshal(reportBytes, &report); err != nil {
+		return gocd.ImportReport{}, errors.Wrapf(err, "failed to unmarshal report")
+	}
+	return report, nil
+}
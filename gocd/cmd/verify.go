@@ -26,15 +26,16 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/palantir/checks/checks/loader"
 	"github.com/palantir/checks/gocd/gocd"
 )
 
-func DoVerify(dirs []string) error {
+func DoVerify(l *loader.Loader, dirs []string, cacheDir string) error {
 	var failedDirs []string
 	errs := make(map[string]error)
 
 	for _, dir := range dirs {
-		if err := verify(dir); err != nil {
+		if err := verify(l, dir, cacheDir); err != nil {
 			failedDirs = append(failedDirs, dir)
 			errs[dir] = err
 		}
@@ -59,7 +60,7 @@ func plural(n int) string {
 	return "directories"
 }
 
-func verify(rootDir string) error {
+func verify(l *loader.Loader, rootDir, cacheDir string) error {
 	rootDir, err := filepath.Abs(rootDir)
 	if err != nil {
 		return err
@@ -80,7 +81,7 @@ func verify(rootDir string) error {
 		return errors.Wrapf(err, "failed to unmarshal report")
 	}
 
-	wantReport, err := gocd.CreateImportReport(rootDir)
+	wantReport, err := gocd.CreateCachedImportReport(l, rootDir, cacheDir)
 	if err != nil {
 		return err
 	}
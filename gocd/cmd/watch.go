@@ -0,0 +1,260 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/cfgcli"
+	"github.com/palantir/pkg/cli/flag"
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/gocd/config"
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+// WatchCommandName is the name of the command that re-runs the import report or budget enforcement when files change.
+const WatchCommandName = "watch"
+
+const enforceFlagName = "enforce"
+
+// watchDebounce is the amount of time to wait after a change for further changes before re-running. Editors and tools
+// often write multiple files (or the same file multiple times) in quick succession.
+const watchDebounce = 250 * time.Millisecond
+
+func WatchCommand() cli.Command {
+	return cli.Command{
+		Name:  WatchCommandName,
+		Usage: "Re-run the import report (or budget enforcement) whenever Go files in the directories change and print the changes since the previous run",
+		Flags: append([]flag.Flag{
+			flag.BoolFlag{
+				Name:  enforceFlagName,
+				Usage: "enforce the dependency budgets in the configuration instead of computing the import report",
+			},
+			flag.StringFlag{
+				Name:  cacheDirFlagName,
+				Usage: "directory in which to cache package information so that only changed packages are re-analyzed on subsequent runs",
+			},
+		}, append(loaderFlags(),
+			flag.StringSlice{
+				Name:     inputDirsParamName,
+				Usage:    "directories to watch",
+				Optional: true,
+			},
+		)...),
+		Action: func(ctx cli.Context) error {
+			l := newLoader(ctx)
+			params, err := config.Load(cfgcli.ConfigPath, cfgcli.ConfigJSON)
+			if err != nil {
+				return err
+			}
+			enforce := ctx.Bool(enforceFlagName)
+			if enforce && len(params.Budgets) == 0 {
+				return errors.New("no budgets were found in the configuration")
+			}
+
+			dirs, err := inputDirs(ctx, params)
+			if err != nil {
+				return err
+			}
+			if len(dirs) == 0 {
+				return errors.New("no input directories specified")
+			}
+			return DoWatch(l, dirs, ctx.String(cacheDirFlagName), enforce, params.Budgets, ctx.App.Stdout, nil)
+		},
+	}
+}
+
+// DoWatch watches the provided directories and re-runs the import report (or, if enforce is true, checks the provided
+// budgets) whenever a Go file or directory in them changes. The result of every run is summarized as a set of lines
+// and only the lines that were added (prefixed with "+") or removed (prefixed with "-") since the previous run are
+// printed. The first run is compared against an empty result, so all of its lines are printed. Errors that occur
+// during a run are printed and the previous result is retained. Runs until the stop channel is closed (or forever if
+// it is nil).
+func DoWatch(l *loader.Loader, dirs []string, cacheDir string, enforce bool, budgets []gocd.Budget, w io.Writer, stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file watcher")
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+	for _, dir := range dirs {
+		rootDir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if err := addWatches(watcher, rootDir); err != nil {
+			return err
+		}
+	}
+
+	var prev []string
+	run := func() {
+		curr, err := watchResult(l, dirs, cacheDir, enforce, budgets)
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+			return
+		}
+		for _, line := range resultDelta(prev, curr) {
+			fmt.Fprintln(w, line)
+		}
+		prev = curr
+	}
+	run()
+
+	var rerun <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					if err := addWatches(watcher, event.Name); err != nil {
+						fmt.Fprintf(w, "error: %v\n", err)
+					}
+				}
+			}
+			// only Go files and directories (which have no extension) affect the result
+			if ext := filepath.Ext(event.Name); ext == ".go" || ext == "" {
+				rerun = time.After(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(w, "error: %v\n", err)
+		case <-rerun:
+			rerun = nil
+			run()
+		}
+	}
+}
+
+// addWatches adds the provided directory and all of its subdirectories (except for hidden directories) to the watcher.
+func addWatches(watcher *fsnotify.Watcher, rootDir string) error {
+	return filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// directory may have been removed since it was listed
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != rootDir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return errors.Wrapf(err, "failed to watch %s", path)
+		}
+		return nil
+	})
+}
+
+// watchResult returns the lines that summarize the import report (or the budget violations if enforce is true) for the
+// provided directories.
+func watchResult(l *loader.Loader, dirs []string, cacheDir string, enforce bool, budgets []gocd.Budget) ([]string, error) {
+	var lines []string
+	for _, dir := range dirs {
+		if enforce {
+			violations, err := checkDirBudgets(l, dir, budgets)
+			if err != nil {
+				return nil, err
+			}
+			for _, violation := range violations {
+				lines = append(lines, fmt.Sprintf("%s: %s", dir, violation))
+			}
+			continue
+		}
+
+		rootDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		report, err := gocd.CreateCachedImportReport(l, rootDir, cacheDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create import report for %s", dir)
+		}
+		for _, section := range []struct {
+			name string
+			pkgs []gocd.ImportReportPkg
+		}{
+			{"imports", report.Imports},
+			{"mainOnlyImports", report.MainOnlyImports},
+			{"testOnlyImports", report.TestOnlyImports},
+		} {
+			for _, pkg := range section.pkgs {
+				lines = append(lines, fmt.Sprintf("%s: %s: %s (%d Go files, %d imported Go files, imported by %d packages)", dir, section.name, pkg.Path, pkg.NGoFiles, pkg.NImportedGoFiles, len(pkg.ImportSrc)))
+			}
+		}
+	}
+	return lines, nil
+}
+
+// resultDelta returns the lines that are in prev but not in curr prefixed with "- " and the lines that are in curr but
+// not in prev prefixed with "+ ". The returned lines are sorted by content (ignoring the prefix) so that the removed
+// and added forms of a line that changed are printed next to each other.
+func resultDelta(prev, curr []string) []string {
+	prevSet := make(map[string]struct{}, len(prev))
+	for _, line := range prev {
+		prevSet[line] = struct{}{}
+	}
+	currSet := make(map[string]struct{}, len(curr))
+	for _, line := range curr {
+		currSet[line] = struct{}{}
+	}
+
+	type deltaLine struct {
+		line  string
+		added bool
+	}
+	var delta []deltaLine
+	for line := range prevSet {
+		if _, ok := currSet[line]; !ok {
+			delta = append(delta, deltaLine{line: line})
+		}
+	}
+	for line := range currSet {
+		if _, ok := prevSet[line]; !ok {
+			delta = append(delta, deltaLine{line: line, added: true})
+		}
+	}
+	sort.Slice(delta, func(i, j int) bool {
+		return delta[i].line < delta[j].line
+	})
+
+	out := make([]string, len(delta))
+	for i, v := range delta {
+		prefix := "- "
+		if v.added {
+			prefix = "+ "
+		}
+		out[i] = prefix + v.line
+	}
+	return out
+}
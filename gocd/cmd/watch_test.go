@@ -0,0 +1,47 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultDelta(t *testing.T) {
+	for i, currCase := range []struct {
+		prev []string
+		curr []string
+		want []string
+	}{
+		{
+			prev: nil,
+			curr: []string{"b", "a"},
+			want: []string{"+ a", "+ b"},
+		},
+		{
+			prev: []string{"a", "b"},
+			curr: []string{"a", "b"},
+			want: []string{},
+		},
+		{
+			prev: []string{"pkg/bar (2 Go files)", "pkg/foo (1 Go files)", "pkg/unchanged (1 Go files)"},
+			curr: []string{"pkg/baz (1 Go files)", "pkg/foo (3 Go files)", "pkg/unchanged (1 Go files)"},
+			want: []string{"- pkg/bar (2 Go files)", "+ pkg/baz (1 Go files)", "- pkg/foo (1 Go files)", "+ pkg/foo (3 Go files)"},
+		},
+	} {
+		assert.Equal(t, currCase.want, resultDelta(currCase.prev, currCase.curr), "Case %d", i)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/palantir/pkg/cli"
+	"github.com/palantir/pkg/cli/cfgcli"
+	"github.com/palantir/pkg/cli/flag"
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/gocd/config"
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+const (
+	// WhyCommandName is the name of the command that explains why packages depend on a package.
+	WhyCommandName = "why"
+
+	whyPkgParamName = "pkg"
+	fromFlagName    = "from"
+)
+
+func WhyCommand() cli.Command {
+	return cli.Command{
+		Name:  WhyCommandName,
+		Usage: "Print the shortest chain of imports through which the packages in the directories depend on a package",
+		Flags: append([]flag.Flag{
+			flag.StringFlag{
+				Name:  fromFlagName,
+				Usage: "import path (or path relative to the directory starting with './') of the package from which to start (by default, the shortest chain from any package in the directory is printed)",
+			},
+		}, append(loaderFlags(),
+			flag.StringParam{
+				Name:  whyPkgParamName,
+				Usage: "import path of the package (vendored packages may omit the vendor directory prefix)",
+			},
+			flag.StringSlice{
+				Name:     inputDirsParamName,
+				Usage:    "directories for which to perform operation",
+				Optional: true,
+			},
+		)...),
+		Action: func(ctx cli.Context) error {
+			l := newLoader(ctx)
+			params, err := config.Load(cfgcli.ConfigPath, cfgcli.ConfigJSON)
+			if err != nil {
+				return err
+			}
+
+			dirs, err := inputDirs(ctx, params)
+			if err != nil {
+				return err
+			}
+			if len(dirs) == 0 {
+				return errors.New("no input directories specified")
+			}
+			return DoWhy(l, dirs, ctx.String(whyPkgParamName), ctx.String(fromFlagName), ctx.App.Stdout)
+		},
+	}
+}
+
+// DoWhy prints the shortest chain of imports through which the packages in each of the provided directories depend on
+// the provided package, one import path per line. If from is non-empty, the chain starts at the package with that
+// import path (a path that starts with "./" is resolved relative to the directory); otherwise, the shortest chain from
+// any package in the directory is printed. If multiple directories are provided, the chain for each directory is
+// printed under a header that contains the directory. A line that explains that the package is not imported is printed
+// for directories that do not depend on the package.
+func DoWhy(l *loader.Loader, dirs []string, pkg, from string, w io.Writer) error {
+	for _, dir := range dirs {
+		rootDir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		project, err := gocd.NewProjectPkgInfoer(l, rootDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to determine imports for %s", dir)
+		}
+		chain, err := whyImports(project, pkg, from)
+		if err != nil {
+			return errors.Wrapf(err, "failed to determine imports for %s", dir)
+		}
+
+		prefix := ""
+		if len(dirs) > 1 {
+			fmt.Fprintf(w, "%s:\n", dir)
+			prefix = "\t"
+		}
+		if chain == nil {
+			fmt.Fprintf(w, "%s(%s does not import %s)\n", prefix, dir, pkg)
+			continue
+		}
+		for _, currPkg := range chain {
+			fmt.Fprintf(w, "%s%s\n", prefix, currPkg)
+		}
+	}
+	return nil
+}
+
+// whyImports returns the shortest chain of imports from the package "from" (or, if it is empty, from any package of the
+// project other than pkg itself) to pkg. If multiple packages have equally short chains, the chain of the package that
+// is first in sorted order is returned.
+func whyImports(project gocd.ProjectPkgInfoer, pkg, from string) ([]string, error) {
+	if from != "" {
+		if from == "." || strings.HasPrefix(from, "./") {
+			from = path.Join(project.RootDirImportPath(), from)
+		}
+		return project.WhyImports(from, pkg)
+	}
+
+	var shortest []string
+	for _, pkgInfo := range project.PkgInfos() {
+		chain, err := project.WhyImports(pkgInfo.Path, pkg)
+		if err != nil {
+			return nil, err
+		}
+		// a package trivially depends on itself
+		if len(chain) > 1 && (shortest == nil || len(chain) < len(shortest)) {
+			shortest = chain
+		}
+	}
+	return shortest, nil
+}
@@ -24,17 +24,18 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/palantir/checks/checks/loader"
 	"github.com/palantir/checks/gocd/gocd"
 )
 
 const importsFileName = "gocd_imports.json"
 
-func DoWriteImportsJSON(dirs []string) error {
+func DoWriteImportsJSON(l *loader.Loader, dirs []string, cacheDir string) error {
 	var failedDirs []string
 	errs := make(map[string]error)
 
 	for _, dir := range dirs {
-		if err := writeImportsJSON(dir); err != nil {
+		if err := writeImportsJSON(l, dir, cacheDir); err != nil {
 			failedDirs = append(failedDirs, dir)
 			errs[dir] = err
 		}
@@ -56,13 +57,13 @@ func DoWriteImportsJSON(dirs []string) error {
 	return nil
 }
 
-func writeImportsJSON(rootDir string) error {
+func writeImportsJSON(l *loader.Loader, rootDir, cacheDir string) error {
 	rootDir, err := filepath.Abs(rootDir)
 	if err != nil {
 		return err
 	}
 
-	report, err := gocd.CreateImportReport(rootDir)
+	report, err := gocd.CreateCachedImportReport(l, rootDir, cacheDir)
 	if err != nil {
 		return err
 	}
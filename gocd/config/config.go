@@ -25,11 +25,32 @@ import (
 
 type GoCD struct {
 	RootDirs []string `yaml:"root-dirs"`
+	Budgets  []Budget `yaml:"budgets"`
+}
+
+// Budget is the configuration for a dependency budget. See gocd.Budget for a description of the fields.
+type Budget struct {
+	RootDir                 string   `yaml:"root-dir"`
+	Package                 string   `yaml:"package"`
+	MaxImportedGoFiles      *int     `yaml:"max-imported-go-files"`
+	MaxExternalDeps         *int     `yaml:"max-external-deps"`
+	ForbiddenImportPrefixes []string `yaml:"forbidden-import-prefixes"`
 }
 
 func (r *GoCD) ToParams() gocd.Params {
+	var budgets []gocd.Budget
+	for _, budget := range r.Budgets {
+		budgets = append(budgets, gocd.Budget{
+			RootDir:                 budget.RootDir,
+			Package:                 budget.Package,
+			MaxImportedGoFiles:      budget.MaxImportedGoFiles,
+			MaxExternalDeps:         budget.MaxExternalDeps,
+			ForbiddenImportPrefixes: budget.ForbiddenImportPrefixes,
+		})
+	}
 	return gocd.Params{
 		RootDirs: r.RootDirs,
+		Budgets:  budgets,
 	}
 }
 
@@ -31,5 +31,5 @@ root-dirs:
 		panic(err)
 	}
 	fmt.Printf("%q", fmt.Sprintf("%+v", cfg))
-	// Output: "{RootDirs:[anotherDir/pkg pkg]}"
+	// Output: "{RootDirs:[anotherDir/pkg pkg] Budgets:[]}"
 }
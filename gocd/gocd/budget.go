@@ -0,0 +1,184 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/vendorutil"
+)
+
+// Budget declares limits on the dependencies of a package or of all of the packages in a project. Budgets only apply to
+// non-test packages.
+type Budget struct {
+	// path of the root directory to which the budget applies. If blank, the budget applies to all root directories.
+	RootDir string
+	// path of the package directory relative to the root directory ("." for the root directory). If blank, the budget
+	// applies to all of the packages in the project.
+	Package string
+	// maximum number of Go files in the packages that are transitively imported by the package (or project). Files in
+	// the package (or project) itself are not counted. Nil if there is no limit.
+	MaxImportedGoFiles *int
+	// maximum number of distinct vendored and external packages that are transitively imported by the package (or
+	// project). Nil if there is no limit.
+	MaxExternalDeps *int
+	// import paths that the package (or the packages in the project) may not import directly. A prefix matches an
+	// import path that is equal to the prefix or that starts with the prefix followed by a "/". Vendored packages are
+	// matched using the import path of the package without the vendor directory prefix.
+	ForbiddenImportPrefixes []string
+}
+
+// BudgetViolation describes a way in which a budget was exceeded.
+type BudgetViolation struct {
+	// the package that exceeded the budget or "project <root import path>" if the total for a project exceeded the budget
+	Scope string
+	// description of the violation
+	Message string
+}
+
+func (v BudgetViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Scope, v.Message)
+}
+
+// CheckBudgets returns the violations of the provided budgets by the packages in the project rooted at rootDir. The
+// RootDir field of the budgets is not considered: the caller is responsible for only providing the budgets that apply
+// to rootDir. Returns an error if a budget specifies a package that does not exist in the project.
+func CheckBudgets(l *loader.Loader, rootDir string, budgets []Budget) ([]BudgetViolation, error) {
+	project, err := NewProjectPkgInfoer(l, rootDir)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := newProjectGoFileCounter(l, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []BudgetViolation
+	for _, budget := range budgets {
+		currViolations, err := checkBudget(budget, counter)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, currViolations...)
+	}
+	return violations, nil
+}
+
+func checkBudget(budget Budget, counter *projectGoFileCounter) ([]BudgetViolation, error) {
+	rootDirImportPath := counter.RootDirImportPath()
+
+	// packages to which the budget applies and a function that returns true if a package is part of the scope
+	var scopePkgs []*PkgInfo
+	var scope string
+	var inScope func(pkgPath string) bool
+	if budget.Package == "" {
+		scope = fmt.Sprintf("project %s", rootDirImportPath)
+		for _, pkg := range counter.PkgInfos() {
+			if !strings.HasSuffix(pkg.Path, "_test") {
+				scopePkgs = append(scopePkgs, pkg)
+			}
+		}
+		inScope = func(pkgPath string) bool {
+			return categorize(pkgPath, rootDirImportPath) == Internal
+		}
+	} else {
+		scope = path.Join(rootDirImportPath, budget.Package)
+		pkg, ok := counter.PkgInfo(scope)
+		if !ok {
+			return nil, errors.Errorf("package %s specified in budget does not exist in %s", budget.Package, rootDirImportPath)
+		}
+		scopePkgs = append(scopePkgs, &pkg)
+		inScope = func(pkgPath string) bool {
+			return pkgPath == scope
+		}
+	}
+
+	// all packages outside of the scope that are transitively imported by the packages in the scope
+	imported := make(map[string]*PkgInfo)
+	for _, pkg := range scopePkgs {
+		pkgImports, ok := counter.importedPkgs(pkg.Path)
+		if !ok {
+			return nil, errors.Errorf("could not determine imports of %s", pkg.Path)
+		}
+		for k, v := range pkgImports {
+			if !inScope(k) {
+				imported[k] = v
+			}
+		}
+	}
+
+	var violations []BudgetViolation
+	addViolation := func(scope, format string, args ...interface{}) {
+		violations = append(violations, BudgetViolation{
+			Scope:   scope,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if budget.MaxImportedGoFiles != nil {
+		nImportedGoFiles := 0
+		for _, v := range imported {
+			nImportedGoFiles += v.NGoFiles
+		}
+		if nImportedGoFiles > *budget.MaxImportedGoFiles {
+			addViolation(scope, "imports %d Go files, which exceeds the budget of %d", nImportedGoFiles, *budget.MaxImportedGoFiles)
+		}
+	}
+
+	if budget.MaxExternalDeps != nil {
+		var externalDeps []string
+		for k := range imported {
+			if category := categorize(k, rootDirImportPath); category == Vendored || category == External {
+				externalDeps = append(externalDeps, k)
+			}
+		}
+		if len(externalDeps) > *budget.MaxExternalDeps {
+			sort.Strings(externalDeps)
+			addViolation(scope, "imports %d external packages, which exceeds the budget of %d: %s", len(externalDeps), *budget.MaxExternalDeps, strings.Join(externalDeps, ", "))
+		}
+	}
+
+	for _, pkg := range scopePkgs {
+		var directImports []string
+		for k := range pkg.Imports {
+			directImports = append(directImports, k)
+		}
+		directImports = append(directImports, pkg.StdLibImports...)
+		sort.Strings(directImports)
+
+		for _, currImport := range directImports {
+			if prefix, ok := forbiddenPrefix(vendorutil.StripVendorPrefix(currImport), budget.ForbiddenImportPrefixes); ok {
+				addViolation(pkg.Path, "imports %s, which matches forbidden import prefix %s", currImport, prefix)
+			}
+		}
+	}
+	return violations, nil
+}
+
+// forbiddenPrefix returns the first of the provided prefixes that matches the provided import path.
+func forbiddenPrefix(importPath string, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if importPath == prefix || strings.HasPrefix(importPath, prefix+"/") {
+			return prefix, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,187 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocd_test
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+func TestCheckBudgets(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	tmpDir, err = filepath.Abs(tmpDir)
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "projectDir/doc.go",
+			Src:     "package project",
+		},
+		{
+			RelPath: "projectDir/foo/foo.go",
+			Src:     `package foo; import _ "fmt"; import _ "{{index . "projectDir/baz/baz.go"}}"; import _ "github.com/org/vendored";`,
+		},
+		{
+			RelPath: "projectDir/foo/foo_test.go",
+			Src:     `package foo; import _ "{{index . "bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "projectDir/baz/baz.go",
+			Src:     `package baz; import _ "{{index . "bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "projectDir/vendor/github.com/org/vendored/vendored.go",
+			Src:     "package vendored",
+		},
+		{
+			RelPath: "projectDir/vendor/github.com/org/vendored/other.go",
+			Src:     "package vendored",
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     "package bar",
+		},
+	})
+	require.NoError(t, err)
+	projectDir := path.Join(tmpDir, "projectDir")
+	projectImportPath := files["projectDir/doc.go"].ImportPath
+	fooImportPath := files["projectDir/foo/foo.go"].ImportPath
+	vendoredImportPath := path.Join(projectImportPath, "vendor", "github.com/org/vendored")
+	barImportPath := files["bar/bar.go"].ImportPath
+
+	for i, currCase := range []struct {
+		name    string
+		budgets []gocd.Budget
+		want    []gocd.BudgetViolation
+	}{
+		{
+			name: "package within budget",
+			budgets: []gocd.Budget{
+				{
+					Package:                 "foo",
+					MaxImportedGoFiles:      intPtr(4),
+					MaxExternalDeps:         intPtr(2),
+					ForbiddenImportPrefixes: []string{"net", "github.com/org/vendoredother"},
+				},
+			},
+		},
+		{
+			name: "package exceeds imported Go files budget",
+			budgets: []gocd.Budget{
+				{
+					Package:            "foo",
+					MaxImportedGoFiles: intPtr(3),
+				},
+			},
+			want: []gocd.BudgetViolation{
+				{
+					Scope:   fooImportPath,
+					Message: "imports 4 Go files, which exceeds the budget of 3",
+				},
+			},
+		},
+		{
+			name: "package exceeds external dependencies budget",
+			budgets: []gocd.Budget{
+				{
+					Package:         "foo",
+					MaxExternalDeps: intPtr(1),
+				},
+			},
+			want: []gocd.BudgetViolation{
+				{
+					Scope:   fooImportPath,
+					Message: fmt.Sprintf("imports 2 external packages, which exceeds the budget of 1: %s, %s", barImportPath, vendoredImportPath),
+				},
+			},
+		},
+		{
+			name: "package imports forbidden packages",
+			budgets: []gocd.Budget{
+				{
+					Package:                 "foo",
+					ForbiddenImportPrefixes: []string{"fmt", "github.com/org"},
+				},
+			},
+			want: []gocd.BudgetViolation{
+				{
+					Scope:   fooImportPath,
+					Message: "imports fmt, which matches forbidden import prefix fmt",
+				},
+				{
+					Scope:   fooImportPath,
+					Message: fmt.Sprintf("imports %s, which matches forbidden import prefix github.com/org", vendoredImportPath),
+				},
+			},
+		},
+		{
+			name: "project budget does not count packages in project",
+			budgets: []gocd.Budget{
+				{
+					MaxImportedGoFiles: intPtr(2),
+					MaxExternalDeps:    intPtr(1),
+				},
+			},
+			want: []gocd.BudgetViolation{
+				{
+					Scope:   "project " + projectImportPath,
+					Message: "imports 3 Go files, which exceeds the budget of 2",
+				},
+				{
+					Scope:   "project " + projectImportPath,
+					Message: fmt.Sprintf("imports 2 external packages, which exceeds the budget of 1: %s, %s", barImportPath, vendoredImportPath),
+				},
+			},
+		},
+		{
+			name: "forbidden import prefixes only apply to direct imports of non-test packages",
+			budgets: []gocd.Budget{
+				{
+					Package:                 "foo",
+					ForbiddenImportPrefixes: []string{barImportPath},
+				},
+			},
+		},
+	} {
+		got, err := gocd.CheckBudgets(loader.New(loader.Options{UseAllFiles: true}), projectDir, currCase.budgets)
+		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
+		assert.Equal(t, currCase.want, got, "Case %d (%s)", i, currCase.name)
+	}
+
+	_, err = gocd.CheckBudgets(loader.New(loader.Options{UseAllFiles: true}), projectDir, []gocd.Budget{
+		{
+			Package:         "nonexistent",
+			MaxExternalDeps: intPtr(0),
+		},
+	})
+	assert.EqualError(t, err, fmt.Sprintf("package nonexistent specified in budget does not exist in %s", projectImportPath))
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+)
+
+// cachedPkgInfo is the content of a package information cache entry.
+type cachedPkgInfo struct {
+	PkgInfo PkgInfo `json:"pkgInfo"`
+	Empty   bool    `json:"empty"`
+}
+
+// cachedDirPkgInfo returns the result of DirPkgInfo for the provided directory and mode. If cacheDir is non-empty, the
+// information that only depends on the files in the directory is read from the cache in cacheDir if an entry exists for
+// the current content of the directory and is written to the cache otherwise. The key for a cache entry is a hash of
+// the directory path, the GOPATH and build context of the provided loader, the mode and the names and content of the Go
+// files in the directory, so an entry is only used if none of the Go files in the directory have changed and the same
+// files are considered. Cache entries store the imports as they are written in the files, which are resolved (for
+// example, to vendored packages) every time the entry is used.
+func cachedDirPkgInfo(l *loader.Loader, srcDir string, mode PkgMode, cacheDir string) (PkgInfo, bool, error) {
+	if cacheDir == "" {
+		return DirPkgInfo(l, srcDir, mode)
+	}
+
+	key, err := dirCacheKey(l, srcDir, mode)
+	if err != nil {
+		return PkgInfo{}, false, err
+	}
+	cacheFile := path.Join(cacheDir, key+".json")
+
+	if bytes, err := ioutil.ReadFile(cacheFile); err == nil {
+		var cached cachedPkgInfo
+		if err := json.Unmarshal(bytes, &cached); err == nil {
+			if cached.PkgInfo.Imports, err = resolveImports(l, cached.PkgInfo.Imports, srcDir); err != nil {
+				return PkgInfo{}, false, err
+			}
+			return cached.PkgInfo, cached.Empty, nil
+		}
+		// if the cache entry cannot be read, fall through and re-compute it
+	}
+
+	pkgInfo, empty, err := unresolvedPkgInfo(l, ".", srcDir, mode)
+	if err != nil {
+		return PkgInfo{}, false, err
+	}
+
+	bytes, err := json.Marshal(cachedPkgInfo{
+		PkgInfo: pkgInfo,
+		Empty:   empty,
+	})
+	if err != nil {
+		return PkgInfo{}, false, errors.Wrapf(err, "failed to marshal cache entry for %s", srcDir)
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return PkgInfo{}, false, errors.Wrapf(err, "failed to create cache directory %s", cacheDir)
+	}
+	if err := ioutil.WriteFile(cacheFile, bytes, 0644); err != nil {
+		return PkgInfo{}, false, errors.Wrapf(err, "failed to write cache entry %s", cacheFile)
+	}
+	if pkgInfo.Imports, err = resolveImports(l, pkgInfo.Imports, srcDir); err != nil {
+		return PkgInfo{}, false, err
+	}
+	return pkgInfo, empty, nil
+}
+
+// dirCacheKey returns the cache key for the package information of the provided directory and mode.
+func dirCacheKey(l *loader.Loader, srcDir string, mode PkgMode) (string, error) {
+	fis, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read directory %s", srcDir)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%v\x00%s\x00", srcDir, l.Context().GOPATH, mode, l)
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") {
+			continue
+		}
+		content, err := ioutil.ReadFile(path.Join(srcDir, fi.Name()))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read %s", path.Join(srcDir, fi.Name()))
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", fi.Name(), len(content))
+		_, _ = h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -18,6 +18,8 @@ import (
 	"os"
 	"path"
 	"strings"
+
+	"github.com/palantir/checks/checks/loader"
 )
 
 type ProjectGoFileCounter interface {
@@ -27,7 +29,11 @@ type ProjectGoFileCounter interface {
 
 type projectGoFileCounter struct {
 	ProjectPkgInfoer
-	counts map[string]goFileCount
+	// loader used to import the packages outside of the project
+	pkgLoader *loader.Loader
+	counts    map[string]goFileCount
+	// pkg -> all packages imported by the package (recursive)
+	imports map[string]map[string]*PkgInfo
 }
 
 type goFileCount struct {
@@ -35,19 +41,23 @@ type goFileCount struct {
 	total int
 }
 
-func NewProjectGoFileCounter(p ProjectPkgInfoer) (ProjectGoFileCounter, error) {
+func NewProjectGoFileCounter(l *loader.Loader, p ProjectPkgInfoer) (ProjectGoFileCounter, error) {
+	return newProjectGoFileCounter(l, p)
+}
+
+func newProjectGoFileCounter(l *loader.Loader, p ProjectPkgInfoer) (*projectGoFileCounter, error) {
 	counter := projectGoFileCounter{
 		ProjectPkgInfoer: p,
+		pkgLoader:        l,
 		counts:           make(map[string]goFileCount),
+		imports:          make(map[string]map[string]*PkgInfo),
 	}
 
-	// cache from pkg -> all packages imported by the package (recursive)
-	importsCache := make(map[string]map[string]*PkgInfo)
 	for _, v := range p.PkgInfos() {
 		// determine file count by determining all of the unique packages imported by a package and then summing
 		// up the package file count of each. This approach is required to avoid double-counting packages that
 		// are imported multiple times.
-		if _, err := counter.allImports(v, importsCache, counter.counts); err != nil {
+		if _, err := counter.allImports(v, counter.imports, counter.counts); err != nil {
 			return nil, err
 		}
 	}
@@ -69,6 +79,13 @@ func (p *projectGoFileCounter) NTotalGoFiles(pkg string) (int, bool) {
 	return 0, false
 }
 
+// importedPkgs returns all of the packages imported by the provided package (recursive). The returned map is keyed by
+// the import path of the imported package. Returns false if the package is not known to the counter.
+func (p *projectGoFileCounter) importedPkgs(pkg string) (map[string]*PkgInfo, bool) {
+	v, ok := p.imports[pkg]
+	return v, ok
+}
+
 func (p *projectGoFileCounter) allImports(pkg *PkgInfo, cache map[string]map[string]*PkgInfo, countsMap map[string]goFileCount) (map[string]*PkgInfo, error) {
 	if v, ok := cache[pkg.Path]; ok {
 		return v, nil
@@ -80,7 +97,7 @@ func (p *projectGoFileCounter) allImports(pkg *PkgInfo, cache map[string]map[str
 		if v, ok := p.PkgInfo(k); ok {
 			importPkg = &v
 		} else {
-			if newImportPkg, empty, err := ImportPkgInfo(k, path.Join(os.Getenv("GOPATH"), "src", strings.TrimSuffix(pkg.Path, "_test")), Default); err != nil {
+			if newImportPkg, empty, err := ImportPkgInfo(p.pkgLoader, k, path.Join(os.Getenv("GOPATH"), "src", strings.TrimSuffix(pkg.Path, "_test")), Default); err != nil {
 				return nil, err
 			} else if !empty {
 				importPkg = &newImportPkg
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/palantir/checks/checks/loader"
 	"github.com/palantir/checks/gocd/gocd"
 )
 
@@ -99,10 +100,10 @@ func TestNGoFiles(t *testing.T) {
 		files, err := gofiles.Write(currCaseTmpDir, currCase.files)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
-		project, err := gocd.NewProjectPkgInfoer(currCaseProjectDir)
+		project, err := gocd.NewProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), currCaseProjectDir)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
-		counter, err := gocd.NewProjectGoFileCounter(project)
+		counter, err := gocd.NewProjectGoFileCounter(loader.New(loader.Options{UseAllFiles: true}), project)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
 		nGoFiles, ok := counter.NGoFiles(currCase.pkg(files))
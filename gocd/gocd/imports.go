@@ -19,9 +19,12 @@ import (
 	"go/build"
 	"go/token"
 	"io/ioutil"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
 )
 
 type PkgInfo struct {
@@ -34,6 +37,9 @@ type PkgInfo struct {
 	// importPath of all of the packages imported by the package. If usage information was retrieved, the value is
 	// a set that contains the files in the package that imported the package; otherwise, it is nil.
 	Imports map[string]map[string]struct{}
+	// sorted import paths of the standard library packages imported by the package. These packages are not included
+	// in Imports. Nil if the package does not import any standard library packages.
+	StdLibImports []string
 }
 
 type PkgMode bool
@@ -81,22 +87,36 @@ func (m PkgMode) importPos(pkg *build.Package) map[string][]token.Position {
 // DirPkgInfo returns a PkgInfo for the package in the specified srcDir using the specified mode. If the mode is
 // Default, the package information is that of the non-test files in the package, while if it is Test, it is the
 // information for the test files (internal and external) in the package. The package information is obtained by running
-// a local import (".") for the package from its own directory. If the mode is Test, the path of the returned package
-// will have "_test" appended to it to differentiate it from the non-test package.
-func DirPkgInfo(srcDir string, mode PkgMode) (PkgInfo, bool, error) {
-	return ImportPkgInfo(".", srcDir, mode)
+// a local import (".") for the package from its own directory using the provided loader. If the mode is Test, the path
+// of the returned package will have "_test" appended to it to differentiate it from the non-test package.
+func DirPkgInfo(l *loader.Loader, srcDir string, mode PkgMode) (PkgInfo, bool, error) {
+	return ImportPkgInfo(l, ".", srcDir, mode)
 }
 
 // ImportPkgInfo returns a PkgInfo for the package specified by importPkgPath imported from srcPkgDir using the
 // specified mode. If the mode is Default, the package information is that of the non-test files in the package, while
 // if it is Test, it is the information for the test files (internal and external) in the package. The package
-// information is obtained by running an import for importPkgPath from the srcPkgDir directory, which is equivalent to
-// an import statement `import "importPkgPath"` in a package located in srcPkgDir. If the package resolved from that
-// location is a vendored package, the path will be the vendored import path. If the mode is Test, the path of the
-// returned package will have "_test" appended to it to differentiate it from the non-test package.
-func ImportPkgInfo(importPkgPath, srcPkgDir string, mode PkgMode) (PkgInfo, bool, error) {
+// information is obtained by using the provided loader to run an import for importPkgPath from the srcPkgDir directory,
+// which is equivalent to an import statement `import "importPkgPath"` in a package located in srcPkgDir. If the package
+// resolved from that location is a vendored package, the path will be the vendored import path. If the mode is Test,
+// the path of the returned package will have "_test" appended to it to differentiate it from the non-test package.
+func ImportPkgInfo(l *loader.Loader, importPkgPath, srcPkgDir string, mode PkgMode) (PkgInfo, bool, error) {
+	pi, empty, err := unresolvedPkgInfo(l, importPkgPath, srcPkgDir, mode)
+	if err != nil {
+		return PkgInfo{}, false, err
+	}
+	if pi.Imports, err = resolveImports(l, pi.Imports, srcPkgDir); err != nil {
+		return PkgInfo{}, false, err
+	}
+	return pi, empty, nil
+}
+
+// unresolvedPkgInfo returns the result of ImportPkgInfo except that the keys of the Imports of the returned PkgInfo are
+// the import paths as they are written in the files of the package. These are not resolved to vendored import paths,
+// so the returned information only depends on the files of the package.
+func unresolvedPkgInfo(l *loader.Loader, importPkgPath, srcPkgDir string, mode PkgMode) (PkgInfo, bool, error) {
 	// get information for package
-	pkg, err := doImport(importPkgPath, srcPkgDir)
+	pkg, err := doImport(l, importPkgPath, srcPkgDir)
 	if err != nil {
 		return PkgInfo{}, false, err
 	}
@@ -114,26 +134,39 @@ func ImportPkgInfo(importPkgPath, srcPkgDir string, mode PkgMode) (PkgInfo, bool
 		return PkgInfo{}, false, err
 	}
 
-	imports := make(map[string]map[string]struct{})
-	for k, v := range importsWithLocs(mode.importPos(pkg)) {
-		// translate import path to actual path used by project (for example, may be in a vendor directory)
-		pkg, err := doImport(k, srcPkgDir)
-		if err != nil {
-			return PkgInfo{}, false, err
+	var stdLibImports []string
+	for k := range mode.importPos(pkg) {
+		if isStdLibImport(k) && k != "C" {
+			stdLibImports = append(stdLibImports, k)
 		}
-		imports[pkg.ImportPath] = v
 	}
+	sort.Strings(stdLibImports)
 
 	pi := PkgInfo{
-		Path:     pkgImportPath,
-		Name:     pkg.Name,
-		NGoFiles: nGoFiles,
-		Imports:  imports,
+		Path:          pkgImportPath,
+		Name:          pkg.Name,
+		NGoFiles:      nGoFiles,
+		Imports:       importsWithLocs(mode.importPos(pkg)),
+		StdLibImports: stdLibImports,
 	}
 
 	return pi, mode.empty(pkg), nil
 }
 
+// resolveImports returns the provided imports with each import path translated to the path of the package that it
+// resolves to when imported from srcPkgDir using the provided loader (for example, a package in a vendor directory).
+func resolveImports(l *loader.Loader, imports map[string]map[string]struct{}, srcPkgDir string) (map[string]map[string]struct{}, error) {
+	resolved := make(map[string]map[string]struct{})
+	for k, v := range imports {
+		pkg, err := doImport(l, k, srcPkgDir)
+		if err != nil {
+			return nil, err
+		}
+		resolved[pkg.ImportPath] = v
+	}
+	return resolved, nil
+}
+
 func importsWithLocs(posMap map[string][]token.Position) map[string]map[string]struct{} {
 	info := make(map[string]map[string]struct{})
 	for k, v := range posMap {
@@ -175,23 +208,15 @@ func nGoFiles(pkg *build.Package) (int, error) {
 	return nGoFiles, nil
 }
 
-// allContext is a build.Context based on build.Default that has "UseAllFiles" set to true. Makes it such that analysis
-// is done on all Go files rather than on just those that match the default build context.
-var allContext = getAllContext()
-
-func getAllContext() build.Context {
-	ctx := build.Default
-	ctx.UseAllFiles = true
-	return ctx
-}
-
-func doImport(path, srcDir string) (*build.Package, error) {
-	pkg, err := allContext.Import(path, srcDir, build.ImportComment)
+func doImport(l *loader.Loader, path, srcDir string) (*build.Package, error) {
+	pkg, err := l.Import(path, srcDir, build.ImportComment)
 	if err != nil {
 		if _, ok := err.(*build.MultiplePackageError); ok {
-			// if error is multiple packages, re-try using default context (build tags may be used to
-			// exclude packages)
-			if pkg, err := build.Import(path, srcDir, build.ImportComment); err == nil {
+			// if error is multiple packages, re-try using only the files that match the build context (build tags
+			// may be used to exclude packages)
+			ctx := l.Context()
+			ctx.UseAllFiles = false
+			if pkg, err := ctx.Import(path, srcDir, build.ImportComment); err == nil {
 				return pkg, nil
 			}
 		}
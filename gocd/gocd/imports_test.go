@@ -27,6 +27,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/palantir/checks/checks/loader"
 	"github.com/palantir/checks/gocd/gocd"
 )
 
@@ -349,7 +350,7 @@ package main`,
 		files, err := gofiles.Write(currCaseTmpDir, currCase.files)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
-		got, empty, err := gocd.DirPkgInfo(currCaseProjectDir, currCase.mode)
+		got, empty, err := gocd.DirPkgInfo(loader.New(loader.Options{UseAllFiles: true}), currCaseProjectDir, currCase.mode)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
 		assert.Equal(t, currCase.want(files), got, "Case %d (%s)", i, currCase.name)
@@ -412,7 +413,7 @@ func TestImportPkgInfo(t *testing.T) {
 		files, err := gofiles.Write(currCaseTmpDir, currCase.files)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
-		got, empty, err := gocd.ImportPkgInfo(currCase.importPath(files), path.Join(currCaseTmpDir, currCase.srcDir), currCase.mode)
+		got, empty, err := gocd.ImportPkgInfo(loader.New(loader.Options{UseAllFiles: true}), currCase.importPath(files), path.Join(currCaseTmpDir, currCase.srcDir), currCase.mode)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
 		assert.Equal(t, currCase.want(files), got, "Case %d (%s)", i, currCase.name)
@@ -0,0 +1,66 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TestOnlyLeak describes a package that a previous import report recorded as only being imported by tests that is
+// imported by non-test code in a newer report.
+type TestOnlyLeak struct {
+	// import path of the package
+	Path string
+	// the non-test packages of the project that import the package in the newer report
+	ImportedFrom []string
+}
+
+func (l TestOnlyLeak) String() string {
+	return fmt.Sprintf("%s was only imported by tests but is now imported by %s", l.Path, strings.Join(l.ImportedFrom, ", "))
+}
+
+// TestOnlyLeaks returns the packages in the test-only imports of the base report that are in the imports or main-only
+// imports of the head report sorted by path. Such packages were previously only used by tests and are now linked into
+// production code.
+func TestOnlyLeaks(base, head ImportReport) []TestOnlyLeak {
+	testOnly := make(map[string]struct{})
+	for _, pkg := range base.TestOnlyImports {
+		testOnly[pkg.Path] = struct{}{}
+	}
+
+	var leaks []TestOnlyLeak
+	for _, pkg := range append(append([]ImportReportPkg{}, head.Imports...), head.MainOnlyImports...) {
+		if _, ok := testOnly[pkg.Path]; !ok {
+			continue
+		}
+		var importedFrom []string
+		for _, src := range pkg.ImportSrc {
+			if !strings.HasSuffix(src, "_test") {
+				importedFrom = append(importedFrom, src)
+			}
+		}
+		sort.Strings(importedFrom)
+		leaks = append(leaks, TestOnlyLeak{
+			Path:         pkg.Path,
+			ImportedFrom: importedFrom,
+		})
+	}
+	sort.Slice(leaks, func(i, j int) bool {
+		return leaks[i].Path < leaks[j].Path
+	})
+	return leaks
+}
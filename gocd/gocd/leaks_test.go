@@ -0,0 +1,58 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/palantir/checks/gocd/gocd"
+)
+
+func TestTestOnlyLeaks(t *testing.T) {
+	base := gocd.ImportReport{
+		Imports: []gocd.ImportReportPkg{
+			{Path: "github.com/org/lib", ImportSrc: []string{"github.com/org/project/foo"}},
+		},
+		TestOnlyImports: []gocd.ImportReportPkg{
+			{Path: "github.com/org/project/vendor/github.com/org/mock", ImportSrc: []string{"github.com/org/project/foo_test"}},
+			{Path: "github.com/org/project/vendor/github.com/org/assert", ImportSrc: []string{"github.com/org/project/foo_test"}},
+			{Path: "github.com/org/project/vendor/github.com/org/fixtures", ImportSrc: []string{"github.com/org/project/foo_test"}},
+		},
+	}
+	head := gocd.ImportReport{
+		Imports: []gocd.ImportReportPkg{
+			{Path: "github.com/org/lib", ImportSrc: []string{"github.com/org/project/foo"}},
+			{Path: "github.com/org/project/vendor/github.com/org/mock", ImportSrc: []string{"github.com/org/project/foo_test", "github.com/org/project/foo", "github.com/org/project/bar"}},
+		},
+		MainOnlyImports: []gocd.ImportReportPkg{
+			{Path: "github.com/org/project/vendor/github.com/org/fixtures", ImportSrc: []string{"github.com/org/project/cmd"}},
+		},
+		TestOnlyImports: []gocd.ImportReportPkg{
+			{Path: "github.com/org/project/vendor/github.com/org/assert", ImportSrc: []string{"github.com/org/project/foo_test"}},
+		},
+	}
+
+	leaks := gocd.TestOnlyLeaks(base, head)
+	assert.Equal(t, []gocd.TestOnlyLeak{
+		{Path: "github.com/org/project/vendor/github.com/org/fixtures", ImportedFrom: []string{"github.com/org/project/cmd"}},
+		{Path: "github.com/org/project/vendor/github.com/org/mock", ImportedFrom: []string{"github.com/org/project/bar", "github.com/org/project/foo"}},
+	}, leaks)
+	assert.Equal(t, "github.com/org/project/vendor/github.com/org/fixtures was only imported by tests but is now imported by github.com/org/project/cmd", leaks[0].String())
+
+	// packages that were not test-only in the base report are not leaks
+	assert.Nil(t, gocd.TestOnlyLeaks(head, base))
+}
@@ -16,4 +16,5 @@ package gocd
 
 type Params struct {
 	RootDirs []string
+	Budgets  []Budget
 }
@@ -23,6 +23,9 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/vendorutil"
 )
 
 type PkgInfos []*PkgInfo
@@ -31,6 +34,7 @@ type ProjectPkgInfoer interface {
 	RootDirImportPath() string
 	PkgInfo(pkg string) (PkgInfo, bool)
 	PkgInfos() PkgInfos
+	WhyImports(from, to string) ([]string, error)
 }
 
 type projectPkgInfo struct {
@@ -59,14 +63,74 @@ func (p *projectPkgInfo) PkgInfos() PkgInfos {
 	return pi
 }
 
+// WhyImports returns the shortest chain of imports through which the project package "from" depends on the package
+// "to". The first element of the chain is "from", the last element is the import path of "to" and every element imports
+// the next one. A vendored package may be specified using its import path without the vendor directory prefix. Only the
+// imports of project packages are known, so every element except for the last one is a project package. If multiple
+// chains are equally short, the imports of every package are considered in sorted order to pick one. Returns nil if
+// "from" does not depend on "to" and an error if "from" is not a package of the project.
+func (p *projectPkgInfo) WhyImports(from, to string) ([]string, error) {
+	if _, ok := p.pkgs[from]; !ok {
+		return nil, errors.Errorf("%s is not a package in %s", from, p.rootDirImportPath)
+	}
+	isTarget := func(pkg string) bool {
+		return pkg == to || vendorutil.StripVendorPrefix(pkg) == to
+	}
+	if isTarget(from) {
+		return []string{from}, nil
+	}
+
+	// package -> package that imports it in the chain from "from"
+	importedBy := map[string]string{
+		from: "",
+	}
+	queue := []string{from}
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+
+		imports := make([]string, 0, len(p.pkgs[curr].Imports))
+		for k := range p.pkgs[curr].Imports {
+			imports = append(imports, k)
+		}
+		sort.Strings(imports)
+		for _, imported := range imports {
+			if _, ok := importedBy[imported]; ok {
+				continue
+			}
+			importedBy[imported] = curr
+			if isTarget(imported) {
+				var chain []string
+				for pkg := imported; pkg != ""; pkg = importedBy[pkg] {
+					chain = append([]string{pkg}, chain...)
+				}
+				return chain, nil
+			}
+			if _, ok := p.pkgs[imported]; ok {
+				queue = append(queue, imported)
+			}
+		}
+	}
+	return nil, nil
+}
+
 type pkgInfoByPath []*PkgInfo
 
 func (p pkgInfoByPath) Len() int           { return len(p) }
 func (p pkgInfoByPath) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 func (p pkgInfoByPath) Less(i, j int) bool { return p[i].Path < p[j].Path }
 
-func NewProjectPkgInfoer(rootDir string) (ProjectPkgInfoer, error) {
-	rootDirImportPath, err := dirImportPath(rootDir)
+func NewProjectPkgInfoer(l *loader.Loader, rootDir string) (ProjectPkgInfoer, error) {
+	return NewCachedProjectPkgInfoer(l, rootDir, "")
+}
+
+// NewCachedProjectPkgInfoer returns a ProjectPkgInfoer for the project rooted at rootDir. If cacheDir is non-empty, the
+// information for each package is cached in cacheDir and the cached information is used for packages whose Go files
+// have not changed since the information was cached. Because the cached information includes the resolved paths of
+// the packages imported by a package, the cache directory should be cleared if the packages available to the project
+// (for example, the vendored packages) change without the importing package changing.
+func NewCachedProjectPkgInfoer(l *loader.Loader, rootDir, cacheDir string) (ProjectPkgInfoer, error) {
+	rootDirImportPath, err := dirImportPath(l, rootDir)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +142,7 @@ func NewProjectPkgInfoer(rootDir string) (ProjectPkgInfoer, error) {
 		}
 
 		// skip any paths in a vendor directory
-		if strings.Contains(path, "/vendor/") {
+		if vendorutil.IsVendoredBy(path, rootDir) {
 			return nil
 		}
 
@@ -99,13 +163,13 @@ func NewProjectPkgInfoer(rootDir string) (ProjectPkgInfoer, error) {
 			return nil
 		}
 
-		if pkg, empty, err := DirPkgInfo(path, Default); err != nil {
+		if pkg, empty, err := cachedDirPkgInfo(l, path, Default, cacheDir); err != nil {
 			return err
 		} else if !empty {
 			pkgs[pkg.Path] = pkg
 		}
 
-		if pkg, empty, err := DirPkgInfo(path, Test); err != nil {
+		if pkg, empty, err := cachedDirPkgInfo(l, path, Test, cacheDir); err != nil {
 			return err
 		} else if !empty {
 			pkgs[pkg.Path] = pkg
@@ -121,9 +185,9 @@ func NewProjectPkgInfoer(rootDir string) (ProjectPkgInfoer, error) {
 	}, nil
 }
 
-func dirImportPath(dir string) (string, error) {
+func dirImportPath(l *loader.Loader, dir string) (string, error) {
 	// attempt to import
-	if pkg, err := doImport(".", dir); err == nil {
+	if pkg, err := doImport(l, ".", dir); err == nil {
 		return pkg.ImportPath, nil
 	}
 
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/nmiyake/pkg/dirs"
@@ -26,6 +27,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/palantir/checks/checks/loader"
 	"github.com/palantir/checks/gocd/gocd"
 )
 
@@ -132,9 +134,192 @@ func TestPkgInfos(t *testing.T) {
 		files, err := gofiles.Write(currCaseTmpDir, currCase.files)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
-		project, err := gocd.NewProjectPkgInfoer(currCaseProjectDir)
+		project, err := gocd.NewProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), currCaseProjectDir)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
 		assert.Equal(t, currCase.want(files), project.PkgInfos(), "Case %d (%s)", i, currCase.name)
 	}
 }
+
+func TestCachedPkgInfos(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	tmpDir, err = filepath.Abs(tmpDir)
+	require.NoError(t, err)
+	projectDir := path.Join(tmpDir, "projectDir")
+	cacheDir := path.Join(tmpDir, "cache")
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "projectDir/main.go",
+			Src:     `package main; import _ "{{index . "bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     "package bar",
+		},
+	})
+	require.NoError(t, err)
+
+	uncached, err := gocd.NewProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir)
+	require.NoError(t, err)
+
+	// first run populates the cache (entries are written for both the non-test and test package)
+	project, err := gocd.NewCachedProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir, cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, uncached.PkgInfos(), project.PkgInfos())
+	entries, err := ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// second run uses the cache
+	project, err = gocd.NewCachedProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir, cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, uncached.PkgInfos(), project.PkgInfos())
+	entries, err = ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// changed package is re-analyzed
+	err = ioutil.WriteFile(files["projectDir/main.go"].Path, []byte("package main\n"), 0644)
+	require.NoError(t, err)
+	project, err = gocd.NewCachedProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir, cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, gocd.PkgInfos{
+		{
+			Path:     files["projectDir/main.go"].ImportPath,
+			Name:     "main",
+			NGoFiles: 1,
+			Imports:  map[string]map[string]struct{}{},
+		},
+	}, project.PkgInfos())
+	entries, err = ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(entries))
+}
+
+func TestWhyImports(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	tmpDir, err = filepath.Abs(tmpDir)
+	require.NoError(t, err)
+	projectDir := path.Join(tmpDir, "projectDir")
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "projectDir/main.go",
+			Src:     `package main; import _ "{{index . "projectDir/a/a.go"}}"; import _ "{{index . "projectDir/b/b.go"}}";`,
+		},
+		{
+			RelPath: "projectDir/a/a.go",
+			Src:     `package a; import _ "{{index . "projectDir/c/c.go"}}"; import _ "github.com/org/lib";`,
+		},
+		{
+			RelPath: "projectDir/b/b.go",
+			Src:     `package b`,
+		},
+		{
+			RelPath: "projectDir/b/b_test.go",
+			Src:     `package b; import _ "{{index . "bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "projectDir/c/c.go",
+			Src:     `package c; import _ "{{index . "bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "projectDir/vendor/github.com/org/lib/lib.go",
+			Src:     "package lib",
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     "package bar",
+		},
+	})
+	require.NoError(t, err)
+
+	project, err := gocd.NewProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir)
+	require.NoError(t, err)
+
+	mainPkg, aPkg, bPkg, cPkg := files["projectDir/main.go"].ImportPath, files["projectDir/a/a.go"].ImportPath, files["projectDir/b/b.go"].ImportPath, files["projectDir/c/c.go"].ImportPath
+	barPkg, libPkg := files["bar/bar.go"].ImportPath, path.Join(mainPkg, "vendor/github.com/org/lib")
+
+	for i, currCase := range []struct {
+		from, to string
+		want     []string
+	}{
+		{mainPkg, barPkg, []string{mainPkg, aPkg, cPkg, barPkg}},
+		{mainPkg, bPkg, []string{mainPkg, bPkg}},
+		{mainPkg, mainPkg, []string{mainPkg}},
+		// vendored packages can be specified without the vendor directory prefix
+		{mainPkg, "github.com/org/lib", []string{mainPkg, aPkg, libPkg}},
+		// test packages include the imports of test files
+		{bPkg + "_test", barPkg, []string{bPkg + "_test", barPkg}},
+		{bPkg, barPkg, nil},
+	} {
+		got, err := project.WhyImports(currCase.from, currCase.to)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, got, "Case %d", i)
+	}
+
+	_, err = project.WhyImports(barPkg, mainPkg)
+	assert.EqualError(t, err, barPkg+" is not a package in "+project.RootDirImportPath())
+}
+
+func TestCachedPkgInfosVendoredAfterCaching(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	tmpDir, err = filepath.Abs(tmpDir)
+	require.NoError(t, err)
+	projectDir := path.Join(tmpDir, "projectDir")
+	cacheDir := path.Join(tmpDir, "cache")
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "projectDir/main.go",
+			Src:     `package main; import _ "{{index . "lib/lib.go"}}";`,
+		},
+		{
+			RelPath: "lib/lib.go",
+			Src:     "package lib",
+		},
+	})
+	require.NoError(t, err)
+
+	project, err := gocd.NewCachedProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir, cacheDir)
+	require.NoError(t, err)
+	pkgInfo, ok := project.PkgInfo(files["projectDir/main.go"].ImportPath)
+	require.True(t, ok)
+	assert.Equal(t, []string{files["lib/lib.go"].ImportPath}, sortedImports(pkgInfo))
+
+	// vendoring the dependency does not change the files of the package, but changes the package that its import
+	// resolves to
+	vendoredLibDir := path.Join(projectDir, "vendor", files["lib/lib.go"].ImportPath)
+	err = os.MkdirAll(vendoredLibDir, 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(vendoredLibDir, "lib.go"), []byte("package lib\n"), 0644)
+	require.NoError(t, err)
+
+	uncached, err := gocd.NewProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir)
+	require.NoError(t, err)
+	project, err = gocd.NewCachedProjectPkgInfoer(loader.New(loader.Options{UseAllFiles: true}), projectDir, cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, uncached.PkgInfos(), project.PkgInfos())
+	pkgInfo, ok = project.PkgInfo(files["projectDir/main.go"].ImportPath)
+	require.True(t, ok)
+	assert.Equal(t, []string{path.Join(files["projectDir/main.go"].ImportPath, "vendor", files["lib/lib.go"].ImportPath)}, sortedImports(pkgInfo))
+}
+
+func sortedImports(pkgInfo gocd.PkgInfo) []string {
+	var imports []string
+	for k := range pkgInfo.Imports {
+		imports = append(imports, k)
+	}
+	sort.Strings(imports)
+	return imports
+}
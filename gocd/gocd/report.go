@@ -19,12 +19,59 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/vendorutil"
 )
 
 type ImportReport struct {
 	Imports         []ImportReportPkg `json:"imports"`
 	MainOnlyImports []ImportReportPkg `json:"mainOnlyImports"`
 	TestOnlyImports []ImportReportPkg `json:"testOnlyImports"`
+	// number of distinct packages imported by the packages in the project for each category
+	CategoryCounts map[ImportCategory]int `json:"categoryCounts"`
+}
+
+// ImportCategory classifies an imported package based on where it is located relative to the project.
+type ImportCategory string
+
+const (
+	// StdLib is the category for packages in the standard library.
+	StdLib ImportCategory = "stdlib"
+	// Vendored is the category for packages in a vendor directory.
+	Vendored ImportCategory = "vendored"
+	// Internal is the category for non-vendored packages in the project.
+	Internal ImportCategory = "internal"
+	// External is the category for packages that are outside of the project and are resolved from the GOPATH.
+	External ImportCategory = "external"
+)
+
+// ImportCategories contains all of the import categories.
+var ImportCategories = []ImportCategory{StdLib, Vendored, Internal, External}
+
+// ParseImportCategory returns the ImportCategory with the provided name. Returns an error if no such category exists.
+func ParseImportCategory(name string) (ImportCategory, error) {
+	for _, category := range ImportCategories {
+		if string(category) == name {
+			return category, nil
+		}
+	}
+	return "", errors.Errorf("invalid import category %q: must be one of %v", name, ImportCategories)
+}
+
+// categorize returns the category of the package with the provided import path for the project with the provided root
+// import path.
+func categorize(pkgPath, rootDirImportPath string) ImportCategory {
+	switch {
+	case isStdLibImport(pkgPath):
+		return StdLib
+	case vendorutil.IsVendoredBy(pkgPath, ""):
+		return Vendored
+	case pkgPath == rootDirImportPath || strings.HasPrefix(pkgPath, rootDirImportPath+"/"):
+		return Internal
+	default:
+		return External
+	}
 }
 
 type importReportPkgByPath []ImportReportPkg
@@ -42,15 +89,31 @@ type ImportReportPkg struct {
 	NImportedGoFiles int `json:"numImportedGoFiles"`
 	// package path of the packages that import this package
 	ImportSrc []string `json:"importedFrom"`
+	// category of the package
+	Category ImportCategory `json:"category"`
+	// upstream origin of the package if it is vendored and its origin is recorded by the metadata of the tool that
+	// vendored it (see VendorOrigin)
+	Origin *VendorOrigin `json:"origin,omitempty"`
 }
 
-func CreateImportReport(rootDir string) (ImportReport, error) {
-	project, err := NewProjectPkgInfoer(rootDir)
+func CreateImportReport(l *loader.Loader, rootDir string) (ImportReport, error) {
+	return CreateCachedImportReport(l, rootDir, "")
+}
+
+// CreateCachedImportReport creates the import report for the project rooted at rootDir. If cacheDir is non-empty, the
+// package information for the project is cached in cacheDir (see NewCachedProjectPkgInfoer).
+func CreateCachedImportReport(l *loader.Loader, rootDir, cacheDir string) (ImportReport, error) {
+	project, err := NewCachedProjectPkgInfoer(l, rootDir, cacheDir)
 	if err != nil {
 		return ImportReport{}, err
 	}
 
-	pkgs, err := importReportPkgs(project)
+	pkgs, err := importReportPkgs(l, project)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	pkgsByCategory, err := importsByCategory(project)
 	if err != nil {
 		return ImportReport{}, err
 	}
@@ -59,6 +122,10 @@ func CreateImportReport(rootDir string) (ImportReport, error) {
 		Imports:         make([]ImportReportPkg, 0),
 		MainOnlyImports: make([]ImportReportPkg, 0),
 		TestOnlyImports: make([]ImportReportPkg, 0),
+		CategoryCounts:  make(map[ImportCategory]int),
+	}
+	for _, category := range ImportCategories {
+		report.CategoryCounts[category] = len(pkgsByCategory[category])
 	}
 
 	for _, v := range pkgs {
@@ -78,6 +145,43 @@ func CreateImportReport(rootDir string) (ImportReport, error) {
 	return report, nil
 }
 
+// ImportsByCategory returns the import paths of the distinct packages imported by the packages in the project rooted at
+// rootDir grouped by category. The import paths for each category are sorted.
+func ImportsByCategory(l *loader.Loader, rootDir string) (map[ImportCategory][]string, error) {
+	project, err := NewProjectPkgInfoer(l, rootDir)
+	if err != nil {
+		return nil, err
+	}
+	return importsByCategory(project)
+}
+
+func importsByCategory(project ProjectPkgInfoer) (map[ImportCategory][]string, error) {
+	seen := make(map[string]struct{})
+	pkgsByCategory := make(map[ImportCategory][]string)
+	addPkg := func(pkgPath string) {
+		if _, ok := seen[pkgPath]; ok {
+			return
+		}
+		seen[pkgPath] = struct{}{}
+		category := categorize(pkgPath, project.RootDirImportPath())
+		pkgsByCategory[category] = append(pkgsByCategory[category], pkgPath)
+	}
+
+	for _, pkg := range project.PkgInfos() {
+		for k := range pkg.Imports {
+			addPkg(k)
+		}
+		for _, k := range pkg.StdLibImports {
+			addPkg(k)
+		}
+	}
+
+	for _, v := range pkgsByCategory {
+		sort.Strings(v)
+	}
+	return pkgsByCategory, nil
+}
+
 func importedByMainOnly(pkg *ImportReportPkg, project ProjectPkgInfoer) bool {
 	for _, p := range pkg.ImportSrc {
 		if pkgInfo, ok := project.PkgInfo(p); ok {
@@ -100,16 +204,17 @@ func importedByTestOnly(pkg *ImportReportPkg) bool {
 	return true
 }
 
-func importReportPkgs(project ProjectPkgInfoer) (map[string]ImportReportPkg, error) {
-	counter, err := NewProjectGoFileCounter(project)
+func importReportPkgs(l *loader.Loader, project ProjectPkgInfoer) (map[string]ImportReportPkg, error) {
+	counter, err := NewProjectGoFileCounter(l, project)
 	if err != nil {
 		return nil, err
 	}
+	origins := newVendorOrigins()
 	impProvs := make(map[string]ImportReportPkg)
 	for _, pkg := range project.PkgInfos() {
 		for k := range pkg.Imports {
 			// skip intra-project imports
-			if !strings.Contains(k, "/vendor/") && strings.HasPrefix(k, project.RootDirImportPath()) {
+			if !vendorutil.IsVendoredBy(k, "") && strings.HasPrefix(k, project.RootDirImportPath()) {
 				continue
 			}
 
@@ -123,10 +228,16 @@ func importReportPkgs(project ProjectPkgInfoer) (map[string]ImportReportPkg, err
 				if !ok {
 					return nil, errors.Errorf("could not determine number of Go files in %s", k)
 				}
+				origin, err := origins.origin(k)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to determine origin of %s", k)
+				}
 				impProvs[k] = ImportReportPkg{
 					Path:             k,
 					NGoFiles:         nGoFiles,
 					NImportedGoFiles: nTotalGoFiles - nGoFiles,
+					Category:         categorize(k, project.RootDirImportPath()),
+					Origin:           origin,
 				}
 			}
 
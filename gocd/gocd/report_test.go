@@ -15,6 +15,8 @@
 package gocd_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path"
@@ -26,6 +28,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/palantir/checks/checks/loader"
 	"github.com/palantir/checks/gocd/gocd"
 )
 
@@ -56,6 +59,7 @@ func TestImportReport(t *testing.T) {
 					Imports:         []gocd.ImportReportPkg{},
 					MainOnlyImports: []gocd.ImportReportPkg{},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 0),
 				}
 			},
 		},
@@ -78,6 +82,7 @@ func TestImportReport(t *testing.T) {
 							Path:             files["bar/bar.go"].ImportPath,
 							NGoFiles:         1,
 							NImportedGoFiles: 0,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/foo.go"].ImportPath,
 							},
@@ -85,6 +90,7 @@ func TestImportReport(t *testing.T) {
 					},
 					MainOnlyImports: []gocd.ImportReportPkg{},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -108,12 +114,14 @@ func TestImportReport(t *testing.T) {
 							Path:             files["bar/bar.go"].ImportPath,
 							NGoFiles:         1,
 							NImportedGoFiles: 0,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/main.go"].ImportPath,
 							},
 						},
 					},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -138,11 +146,13 @@ func TestImportReport(t *testing.T) {
 							Path:             files["bar/bar.go"].ImportPath,
 							NGoFiles:         1,
 							NImportedGoFiles: 0,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/foo_test.go"].ImportPath + "_test",
 							},
 						},
 					},
+					CategoryCounts: categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -169,6 +179,7 @@ func TestImportReport(t *testing.T) {
 							Path:             files["bar/bar.go"].ImportPath,
 							NGoFiles:         1,
 							NImportedGoFiles: 0,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/foo.go"].ImportPath,
 								files["projectDir/main/main.go"].ImportPath,
@@ -177,6 +188,7 @@ func TestImportReport(t *testing.T) {
 					},
 					MainOnlyImports: []gocd.ImportReportPkg{},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -203,6 +215,7 @@ func TestImportReport(t *testing.T) {
 							Path:             files["bar/bar.go"].ImportPath,
 							NGoFiles:         1,
 							NImportedGoFiles: 0,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/baz/baz.go"].ImportPath,
 								files["projectDir/foo_test.go"].ImportPath + "_test",
@@ -211,6 +224,7 @@ func TestImportReport(t *testing.T) {
 					},
 					MainOnlyImports: []gocd.ImportReportPkg{},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -238,6 +252,7 @@ func TestImportReport(t *testing.T) {
 							Path:             files["bar/bar.go"].ImportPath,
 							NGoFiles:         1,
 							NImportedGoFiles: 0,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/main/main.go"].ImportPath,
 								files["projectDir/foo_test.go"].ImportPath + "_test",
@@ -245,6 +260,7 @@ func TestImportReport(t *testing.T) {
 						},
 					},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -283,6 +299,7 @@ func TestImportReport(t *testing.T) {
 							Path:             files["bar/bar.go"].ImportPath,
 							NGoFiles:         2,
 							NImportedGoFiles: 3,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/foo.go"].ImportPath,
 							},
@@ -290,6 +307,7 @@ func TestImportReport(t *testing.T) {
 					},
 					MainOnlyImports: []gocd.ImportReportPkg{},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -329,12 +347,14 @@ func TestImportReport(t *testing.T) {
 							Path:             files["foo/foo.go"].ImportPath,
 							NGoFiles:         1,
 							NImportedGoFiles: 4,
+							Category:         gocd.External,
 							ImportSrc: []string{
 								files["projectDir/main.go"].ImportPath,
 							},
 						},
 					},
 					TestOnlyImports: []gocd.ImportReportPkg{},
+					CategoryCounts:  categoryCounts(0, 0, 0, 1),
 				}
 			},
 		},
@@ -349,8 +369,228 @@ func TestImportReport(t *testing.T) {
 		files, err := gofiles.Write(currCaseTmpDir, currCase.files)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 
-		got, err := gocd.CreateImportReport(currCaseProjectDir)
+		got, err := gocd.CreateImportReport(loader.New(loader.Options{UseAllFiles: true}), currCaseProjectDir)
 		require.NoError(t, err, "Case %d (%s)", i, currCase.name)
 		assert.Equal(t, currCase.want(files), got, "Case %d (%s)", i, currCase.name)
 	}
 }
+
+func TestImportsByCategory(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	tmpDir, err = filepath.Abs(tmpDir)
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "projectDir/foo.go",
+			Src:     `package foo; import _ "fmt"; import _ "{{index . "projectDir/baz/baz.go"}}"; import _ "github.com/org/vendored"; import _ "{{index . "bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "projectDir/foo_test.go",
+			Src:     `package foo; import _ "testing";`,
+		},
+		{
+			RelPath: "projectDir/baz/baz.go",
+			Src:     `package baz; import _ "fmt";`,
+		},
+		{
+			RelPath: "projectDir/vendor/github.com/org/vendored/vendored.go",
+			Src:     "package vendored",
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     "package bar",
+		},
+	})
+	require.NoError(t, err)
+	projectDir := path.Join(tmpDir, "projectDir")
+
+	got, err := gocd.ImportsByCategory(loader.New(loader.Options{UseAllFiles: true}), projectDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[gocd.ImportCategory][]string{
+		gocd.StdLib:   {"fmt", "testing"},
+		gocd.Vendored: {path.Join(files["projectDir/foo.go"].ImportPath, "vendor", "github.com/org/vendored")},
+		gocd.Internal: {files["projectDir/baz/baz.go"].ImportPath},
+		gocd.External: {files["bar/bar.go"].ImportPath},
+	}, got)
+
+	report, err := gocd.CreateImportReport(loader.New(loader.Options{UseAllFiles: true}), projectDir)
+	require.NoError(t, err)
+	assert.Equal(t, categoryCounts(2, 1, 1, 1), report.CategoryCounts)
+	require.Equal(t, 2, len(report.Imports))
+	assert.Equal(t, gocd.External, report.Imports[0].Category)
+	assert.Equal(t, gocd.Vendored, report.Imports[1].Category)
+}
+
+func categoryCounts(stdLib, vendored, internal, external int) map[gocd.ImportCategory]int {
+	return map[gocd.ImportCategory]int{
+		gocd.StdLib:   stdLib,
+		gocd.Vendored: vendored,
+		gocd.Internal: internal,
+		gocd.External: external,
+	}
+}
+
+func TestImportReportWriters(t *testing.T) {
+	report := gocd.ImportReport{
+		Imports: []gocd.ImportReportPkg{
+			{
+				Path:             "github.com/org/project/vendor/github.com/org/lib",
+				NGoFiles:         2,
+				NImportedGoFiles: 3,
+				ImportSrc:        []string{"github.com/org/project", "github.com/org/project/api"},
+				Category:         gocd.Vendored,
+				Origin: &gocd.VendorOrigin{
+					Project:  "github.com/org/lib",
+					Version:  "v1.2.0",
+					Revision: "0123abc",
+					Metadata: gocd.DepMetadata,
+				},
+			},
+		},
+		MainOnlyImports: []gocd.ImportReportPkg{},
+		TestOnlyImports: []gocd.ImportReportPkg{
+			{
+				Path:      "github.com/org/assert",
+				NGoFiles:  1,
+				ImportSrc: []string{"github.com/org/project_test"},
+				Category:  gocd.External,
+			},
+		},
+		CategoryCounts: categoryCounts(0, 1, 0, 1),
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.WriteCSV(buf))
+	assert.Equal(t, `section,path,category,numGoFiles,numImportedGoFiles,importedFrom,project,version,revision
+imports,github.com/org/project/vendor/github.com/org/lib,vendored,2,3,github.com/org/project github.com/org/project/api,github.com/org/lib,v1.2.0,0123abc
+testOnlyImports,github.com/org/assert,external,1,0,github.com/org/project_test,,,
+`, buf.String())
+
+	buf = &bytes.Buffer{}
+	require.NoError(t, report.WriteJSON(buf))
+	var got gocd.ImportReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, report, got)
+}
+
+func TestImportReportVendorOrigins(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	tmpDir, err = filepath.Abs(tmpDir)
+	require.NoError(t, err)
+
+	for i, currCase := range []struct {
+		name     string
+		metadata map[string]string
+		want     map[string]*gocd.VendorOrigin
+	}{
+		{
+			name: "no metadata",
+			want: map[string]*gocd.VendorOrigin{
+				"github.com/org/lib/api": nil,
+				"github.com/org/other":   nil,
+			},
+		},
+		{
+			name: "dep",
+			metadata: map[string]string{
+				"Gopkg.lock": `# This file is autogenerated, do not edit; changes may be undone by the next 'dep ensure'.
+
+[[projects]]
+  name = "github.com/org/lib"
+  packages = [
+    "api",
+  ]
+  revision = "0123abc"
+  version = "v1.2.0"
+
+[[projects]]
+  branch = "master"
+  name = "github.com/org/other"
+  packages = ["."]
+  revision = "4567def"
+
+[solve-meta]
+  analyzer-name = "dep"
+  inputs-digest = "89ab"
+`,
+			},
+			want: map[string]*gocd.VendorOrigin{
+				"github.com/org/lib/api": {Project: "github.com/org/lib", Version: "v1.2.0", Revision: "0123abc", Metadata: gocd.DepMetadata},
+				"github.com/org/other":   {Project: "github.com/org/other", Version: "master", Revision: "4567def", Metadata: gocd.DepMetadata},
+			},
+		},
+		{
+			name: "govendor",
+			metadata: map[string]string{
+				"vendor/vendor.json": `{
+	"package": [
+		{"path": "github.com/org/lib/api", "revision": "0123abc", "version": "v1", "versionExact": "v1.2.0"},
+		{"path": "github.com/org/other", "revision": "4567def"}
+	]
+}`,
+			},
+			want: map[string]*gocd.VendorOrigin{
+				"github.com/org/lib/api": {Project: "github.com/org/lib/api", Version: "v1.2.0", Revision: "0123abc", Metadata: gocd.GovendorMetadata},
+				"github.com/org/other":   {Project: "github.com/org/other", Revision: "4567def", Metadata: gocd.GovendorMetadata},
+			},
+		},
+		{
+			name: "modules",
+			metadata: map[string]string{
+				"vendor/modules.txt": `# github.com/org/lib v1.2.0
+## explicit
+github.com/org/lib/api
+# github.com/org/other v0.1.0 => github.com/fork/other v0.1.1
+github.com/org/other
+`,
+			},
+			want: map[string]*gocd.VendorOrigin{
+				"github.com/org/lib/api": {Project: "github.com/org/lib", Version: "v1.2.0", Metadata: gocd.ModulesMetadata},
+				"github.com/org/other":   {Project: "github.com/org/other", Version: "v0.1.1", Metadata: gocd.ModulesMetadata},
+			},
+		},
+	} {
+		currTmpDir, err := ioutil.TempDir(tmpDir, "")
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+
+		files, err := gofiles.Write(currTmpDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "foo.go",
+				Src:     `package foo; import _ "github.com/org/lib/api"; import _ "github.com/org/other";`,
+			},
+			{
+				RelPath: "vendor/github.com/org/lib/api/api.go",
+				Src:     "package api",
+			},
+			{
+				RelPath: "vendor/github.com/org/other/other.go",
+				Src:     "package other",
+			},
+		})
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		for k, v := range currCase.metadata {
+			err := ioutil.WriteFile(path.Join(currTmpDir, k), []byte(v), 0644)
+			require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		}
+
+		report, err := gocd.CreateImportReport(loader.New(loader.Options{UseAllFiles: true}), currTmpDir)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+
+		got := make(map[string]*gocd.VendorOrigin)
+		for _, pkg := range report.Imports {
+			got[pkg.Path] = pkg.Origin
+		}
+		want := make(map[string]*gocd.VendorOrigin)
+		for k, v := range currCase.want {
+			want[path.Join(files["foo.go"].ImportPath, "vendor", k)] = v
+		}
+		assert.Equal(t, want, got, "Case %d: %s", i, currCase.name)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// csvHeader is the header row written by ImportReport.WriteCSV.
+var csvHeader = []string{"section", "path", "category", "numGoFiles", "numImportedGoFiles", "importedFrom", "project", "version", "revision"}
+
+// WriteJSON writes the report as indented JSON followed by a newline. The JSON has the same form as the content of an
+// imports file.
+func (r ImportReport) WriteJSON(w io.Writer) error {
+	bytes, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal report")
+	}
+	if _, err := w.Write(append(bytes, '\n')); err != nil {
+		return errors.Wrapf(err, "failed to write report")
+	}
+	return nil
+}
+
+// WriteCSV writes the report as CSV with a header row followed by one row for every package in the report. The
+// "section" column is the name of the section of the report that contains the package ("imports", "mainOnlyImports" or
+// "testOnlyImports") and the "importedFrom" column is the space-separated list of the packages that import it. The
+// "project", "version" and "revision" columns are the origin of the package and are blank if it has no origin. The
+// category counts of the report are not written.
+func (r ImportReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return errors.Wrapf(err, "failed to write CSV header")
+	}
+	for _, section := range []struct {
+		name string
+		pkgs []ImportReportPkg
+	}{
+		{"imports", r.Imports},
+		{"mainOnlyImports", r.MainOnlyImports},
+		{"testOnlyImports", r.TestOnlyImports},
+	} {
+		for _, pkg := range section.pkgs {
+			var origin VendorOrigin
+			if pkg.Origin != nil {
+				origin = *pkg.Origin
+			}
+			if err := cw.Write([]string{
+				section.name,
+				pkg.Path,
+				string(pkg.Category),
+				strconv.Itoa(pkg.NGoFiles),
+				strconv.Itoa(pkg.NImportedGoFiles),
+				strings.Join(pkg.ImportSrc, " "),
+				origin.Project,
+				origin.Version,
+				origin.Revision,
+			}); err != nil {
+				return errors.Wrapf(err, "failed to write CSV row for %s", pkg.Path)
+			}
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return errors.Wrapf(err, "failed to write CSV")
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/vendorutil"
+)
+
+const (
+	// DepMetadata is the name of the metadata file written by dep, which is in the directory that contains the vendor
+	// directory.
+	DepMetadata = "Gopkg.lock"
+	// GovendorMetadata is the name of the metadata file written by govendor, which is in the vendor directory.
+	GovendorMetadata = "vendor.json"
+	// ModulesMetadata is the name of the metadata file written by "go mod vendor", which is in the vendor directory.
+	ModulesMetadata = "modules.txt"
+)
+
+// VendorOrigin is the upstream origin of a vendored package as recorded by the metadata of the tool that vendored it.
+type VendorOrigin struct {
+	// import path of the upstream project (or module) that contains the package
+	Project string `json:"project"`
+	// version of the project (a tag, branch or module version) if it is recorded
+	Version string `json:"version,omitempty"`
+	// VCS revision of the project if it is recorded
+	Revision string `json:"revision,omitempty"`
+	// name of the metadata file from which the origin was read
+	Metadata string `json:"metadata"`
+}
+
+// vendorOrigins determines the origins of vendored packages. The metadata of each vendor directory is only read once.
+type vendorOrigins struct {
+	// import path of vendor directory -> origins of the projects vendored in it
+	origins map[string][]VendorOrigin
+}
+
+func newVendorOrigins() *vendorOrigins {
+	return &vendorOrigins{
+		origins: make(map[string][]VendorOrigin),
+	}
+}
+
+// origin returns the origin of the vendored package with the provided import path, which is the origin of the project
+// with the longest import path that contains the package. Returns nil if the metadata of the vendor directory that
+// contains the package does not record such a project or if there is no metadata.
+func (o *vendorOrigins) origin(pkgPath string) (*VendorOrigin, error) {
+	vendorPath, nonVendorPath := vendorutil.SplitVendorPath(pkgPath)
+	if vendorPath == "" {
+		return nil, nil
+	}
+	origins, ok := o.origins[vendorPath]
+	if !ok {
+		var err error
+		if origins, err = readVendorOrigins(path.Join(os.Getenv("GOPATH"), "src", vendorPath)); err != nil {
+			return nil, err
+		}
+		o.origins[vendorPath] = origins
+	}
+
+	var match *VendorOrigin
+	for i, currOrigin := range origins {
+		if nonVendorPath != currOrigin.Project && !strings.HasPrefix(nonVendorPath, currOrigin.Project+"/") {
+			continue
+		}
+		if match == nil || len(currOrigin.Project) > len(match.Project) {
+			match = &origins[i]
+		}
+	}
+	if match == nil {
+		return nil, nil
+	}
+	origin := *match
+	return &origin, nil
+}
+
+// readVendorOrigins returns the origins recorded by the metadata for the provided vendor directory. The metadata of "go
+// mod vendor", dep and govendor are considered in that order and the first one that exists is used. Returns an empty
+// slice if the vendor directory does not have any metadata.
+func readVendorOrigins(vendorDir string) ([]VendorOrigin, error) {
+	for _, currMetadata := range []struct {
+		path  string
+		parse func(content []byte) ([]VendorOrigin, error)
+	}{
+		{path.Join(vendorDir, ModulesMetadata), parseModulesTxt},
+		{path.Join(path.Dir(vendorDir), DepMetadata), parseGopkgLock},
+		{path.Join(vendorDir, GovendorMetadata), parseVendorJSON},
+	} {
+		content, err := ioutil.ReadFile(currMetadata.path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", currMetadata.path)
+		}
+		origins, err := currMetadata.parse(content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", currMetadata.path)
+		}
+		return origins, nil
+	}
+	return []VendorOrigin{}, nil
+}
+
+// parseModulesTxt returns the origins of the modules listed in the content of a vendor/modules.txt file. The version of
+// a module that is replaced is the version of its replacement (which is blank for replacements by local directories).
+func parseModulesTxt(content []byte) ([]VendorOrigin, error) {
+	var origins []VendorOrigin
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		// module line: "# path version", "# path version => replacement [version]" or "# path => replacement [version]"
+		fields := strings.Fields(strings.TrimPrefix(line, "# "))
+		origin := VendorOrigin{
+			Project:  fields[0],
+			Metadata: ModulesMetadata,
+		}
+		versionFields := fields[1:]
+		for i, currField := range fields {
+			if currField == "=>" {
+				// the replacement is followed by its version
+				versionFields = fields[i+2:]
+				break
+			}
+		}
+		if len(versionFields) > 0 {
+			origin.Version = versionFields[0]
+		}
+		origins = append(origins, origin)
+	}
+	return origins, scanner.Err()
+}
+
+// parseGopkgLock returns the origins of the projects listed in the content of a Gopkg.lock file. Only the string
+// values of the "[[projects]]" tables are read. The version of a project that is locked to a branch is the branch.
+func parseGopkgLock(content []byte) ([]VendorOrigin, error) {
+	var origins []VendorOrigin
+	var currOrigin *VendorOrigin
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "[[projects]]" {
+			origins = append(origins, VendorOrigin{
+				Metadata: DepMetadata,
+			})
+			currOrigin = &origins[len(origins)-1]
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			// start of another table such as "[solve-meta]"
+			currOrigin = nil
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if currOrigin == nil || len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.Unquote(strings.TrimSpace(parts[1]))
+		if err != nil {
+			// not a string value
+			continue
+		}
+		switch strings.TrimSpace(parts[0]) {
+		case "name":
+			currOrigin.Project = value
+		case "version":
+			currOrigin.Version = value
+		case "branch":
+			if currOrigin.Version == "" {
+				currOrigin.Version = value
+			}
+		case "revision":
+			currOrigin.Revision = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, currOrigin := range origins {
+		if currOrigin.Project == "" {
+			return nil, errors.Errorf("project does not have a name")
+		}
+	}
+	return origins, nil
+}
+
+// parseVendorJSON returns the origins of the packages listed in the content of a govendor vendor.json file. The version
+// of a package is its exact version if one is recorded.
+func parseVendorJSON(content []byte) ([]VendorOrigin, error) {
+	var vendorJSON struct {
+		Package []struct {
+			Path         string `json:"path"`
+			Revision     string `json:"revision"`
+			Version      string `json:"version"`
+			VersionExact string `json:"versionExact"`
+		} `json:"package"`
+	}
+	if err := json.Unmarshal(content, &vendorJSON); err != nil {
+		return nil, err
+	}
+	var origins []VendorOrigin
+	for _, currPkg := range vendorJSON.Package {
+		origin := VendorOrigin{
+			Project:  strings.TrimSuffix(currPkg.Path, "/..."),
+			Version:  currPkg.Version,
+			Revision: currPkg.Revision,
+			Metadata: GovendorMetadata,
+		}
+		if currPkg.VersionExact != "" {
+			origin.Version = currPkg.VersionExact
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
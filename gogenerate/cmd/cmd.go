@@ -15,17 +15,26 @@
 package cmd
 
 import (
+	"path"
+
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/palantir/pkg/cli"
 	"github.com/palantir/pkg/cli/cfgcli"
 	"github.com/palantir/pkg/cli/flag"
+	"github.com/pkg/errors"
 
 	"github.com/palantir/checks/gogenerate/config"
 	"github.com/palantir/checks/gogenerate/gogenerate"
 )
 
 const (
-	verifyFlagName = "verify"
+	verifyFlagName        = "verify"
+	quietFlagName         = "quiet"
+	verboseFlagName       = "verbose"
+	writeManifestFlagName = "write-manifest"
+	fromManifestFlagName  = "from-manifest"
+	listFlagName          = "list"
+	resolveFlagName       = "resolve"
 )
 
 var flags = []flag.Flag{
@@ -33,6 +42,32 @@ var flags = []flag.Flag{
 		Name:  verifyFlagName,
 		Usage: "verify that running generators does not change the current output",
 	},
+	flag.BoolFlag{
+		Name:  quietFlagName,
+		Usage: "do not print the output of generators (the end of the output of a failed generator is still reported)",
+		Alias: "q",
+	},
+	flag.BoolFlag{
+		Name:  verboseFlagName,
+		Usage: "print the directory and environment of each generator before running it",
+		Alias: "v",
+	},
+	flag.BoolFlag{
+		Name:  writeManifestFlagName,
+		Usage: "write the checksums of the output of the generators to " + gogenerate.ManifestFileName + " after the generators run successfully",
+	},
+	flag.BoolFlag{
+		Name:  fromManifestFlagName,
+		Usage: "used with --" + verifyFlagName + ": verify the output of the generators against " + gogenerate.ManifestFileName + " without running the generators",
+	},
+	flag.BoolFlag{
+		Name:  listFlagName,
+		Usage: "print the directory, command, gen-paths and environment of each generator without running the generators",
+	},
+	flag.BoolFlag{
+		Name:  resolveFlagName,
+		Usage: "used with --" + listFlagName + ": also print the paths that are currently matched by the gen-paths of each generator",
+	},
 }
 
 func Command() cli.Command {
@@ -51,7 +86,43 @@ func Command() cli.Command {
 				return err
 			}
 
-			return gogenerate.Run(wd, cfg, ctx.Bool(verifyFlagName), ctx.App.Stdout)
+			if ctx.Bool(resolveFlagName) && !ctx.Bool(listFlagName) {
+				return errors.Errorf("--%s can only be specified with --%s", resolveFlagName, listFlagName)
+			}
+			if ctx.Bool(listFlagName) {
+				for _, other := range []string{verifyFlagName, writeManifestFlagName} {
+					if ctx.Bool(other) {
+						return errors.Errorf("--%s and --%s cannot both be specified", listFlagName, other)
+					}
+				}
+				return gogenerate.List(wd, cfg, ctx.Bool(resolveFlagName), ctx.App.Stdout)
+			}
+
+			manifestPath := path.Join(wd, gogenerate.ManifestFileName)
+			if ctx.Bool(fromManifestFlagName) {
+				if !ctx.Bool(verifyFlagName) {
+					return errors.Errorf("--%s can only be specified with --%s", fromManifestFlagName, verifyFlagName)
+				}
+				return gogenerate.VerifyManifest(wd, cfg, manifestPath)
+			}
+
+			verbosity := gogenerate.Normal
+			switch {
+			case ctx.Bool(quietFlagName) && ctx.Bool(verboseFlagName):
+				return errors.Errorf("--%s and --%s cannot both be specified", quietFlagName, verboseFlagName)
+			case ctx.Bool(quietFlagName):
+				verbosity = gogenerate.Quiet
+			case ctx.Bool(verboseFlagName):
+				verbosity = gogenerate.Verbose
+			}
+
+			if err := gogenerate.RunWithVerbosity(wd, cfg, ctx.Bool(verifyFlagName), verbosity, ctx.App.Stdout); err != nil {
+				return err
+			}
+			if ctx.Bool(writeManifestFlagName) {
+				return gogenerate.WriteManifest(wd, cfg, manifestPath)
+			}
+			return nil
 		},
 	}
 }
@@ -40,8 +40,12 @@ func (g Generators) SortedKeys() []string {
 }
 
 type GeneratorConfig struct {
-	// GoGenDir is the relative path to the directory in which "go generate" should be run.
+	// GoGenDir is the relative path to the directory in which "go generate" (or Command) should be run.
 	GoGenDir string `yaml:"go-generate-dir" json:"go-generate-dir"`
+	// Command is the command (the executable followed by its arguments) that should be run in GoGenDir instead of
+	// "go generate". Allows generators that are not invoked using go:generate directives (such as make targets or
+	// protoc invocations) to be run and verified. If empty, "go generate" is run.
+	Command []string `yaml:"command" json:"command"`
 	// GenPaths is the configuration that specifies the criteria for matching the output files and directories
 	// generated by the "go generate" command. Any file or directory that is matched by the matchers are used to
 	// determine whether or not the "go generate" command caused any changes.
@@ -53,6 +57,16 @@ type GeneratorConfig struct {
 	//     GOOS: darwin
 	//     GOARCH: amd64
 	Environment map[string]string `yaml:"environment" json:"environment"`
+	// Environments specifies sets of environment variables for which the generator should be run. If non-empty, the
+	// generator is run once for each set (in order) with the variables in the set added to (and overriding) the
+	// variables in Environment, and its output paths are verified after all of the runs have completed. Allows
+	// generators whose output is platform-dependent to generate the output for every platform. For example, the
+	// following would run the generator once with GOOS set to "linux" and once with GOOS set to "darwin":
+	//
+	//   environments:
+	//     - GOOS: linux
+	//     - GOOS: darwin
+	Environments []map[string]string `yaml:"environments" json:"environments"`
 }
 
 func Load(configPath, jsonContent string) (GoGenerate, error) {
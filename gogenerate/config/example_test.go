@@ -38,5 +38,5 @@ generators:
 		panic(err)
 	}
 	fmt.Printf("%q", fmt.Sprintf("%+v", cfg))
-	// Output: "{Generators:map[foo:{GoGenDir:testbar GenPaths:{Names:[bar] Paths:[testbar/output.txt]} Environment:map[GOOS:darwin]}]}"
+	// Output: "{Generators:map[foo:{GoGenDir:testbar Command:[] GenPaths:{Names:[bar] Paths:[testbar/output.txt]} Environment:map[GOOS:darwin] Environments:[]}]}"
 }
@@ -32,7 +32,14 @@ import (
 )
 
 func Run(rootDir string, cfg config.GoGenerate, verify bool, stdout io.Writer) error {
-	diff, err := runGenerate(rootDir, cfg, stdout)
+	return RunWithVerbosity(rootDir, cfg, verify, Normal, stdout)
+}
+
+// RunWithVerbosity runs the generators in the provided configuration and writes their output to stdout based on the
+// provided verbosity. If verify is true, returns an error if running the generators changed any of their output paths.
+// If a generator fails, the returned error includes the end of the output of the generator.
+func RunWithVerbosity(rootDir string, cfg config.GoGenerate, verify bool, verbosity Verbosity, stdout io.Writer) error {
+	diff, err := runGenerate(rootDir, cfg, verbosity, stdout)
 	if err != nil {
 		return err
 	}
@@ -40,7 +47,12 @@ func Run(rootDir string, cfg config.GoGenerate, verify bool, stdout io.Writer) e
 	if !verify || len(diff) == 0 {
 		return nil
 	}
+	return diffError("Generators produced output that differed from what already exists", diff)
+}
 
+// diffError returns an error that consists of the provided message followed by the sorted names of the generators in
+// the provided diff and the differences for each generator.
+func diffError(msg string, diff map[string]ChecksumsDiff) error {
 	var sortedKeys []string
 	for k := range diff {
 		sortedKeys = append(sortedKeys, k)
@@ -48,7 +60,7 @@ func Run(rootDir string, cfg config.GoGenerate, verify bool, stdout io.Writer) e
 	sort.Strings(sortedKeys)
 
 	var outputParts []string
-	outputParts = append(outputParts, fmt.Sprintf("Generators produced output that differed from what already exists: %v", sortedKeys))
+	outputParts = append(outputParts, fmt.Sprintf("%s: %v", msg, sortedKeys))
 	for _, k := range sortedKeys {
 		outputParts = append(outputParts, fmt.Sprintf("  %s:", k))
 		for _, currGenLine := range strings.Split(diff[k].String(), "\n") {
@@ -58,7 +70,7 @@ func Run(rootDir string, cfg config.GoGenerate, verify bool, stdout io.Writer) e
 	return fmt.Errorf(strings.Join(outputParts, "\n"))
 }
 
-func runGenerate(rootDir string, cfg config.GoGenerate, stdout io.Writer) (map[string]ChecksumsDiff, error) {
+func runGenerate(rootDir string, cfg config.GoGenerate, verbosity Verbosity, stdout io.Writer) (map[string]ChecksumsDiff, error) {
 	diffs := make(map[string]ChecksumsDiff)
 	for _, k := range cfg.Generators.SortedKeys() {
 		v := cfg.Generators[k]
@@ -68,20 +80,14 @@ func runGenerate(rootDir string, cfg config.GoGenerate, stdout io.Writer) (map[s
 			return nil, errors.Wrapf(err, "failed to compute checksums")
 		}
 
-		genDir := path.Join(rootDir, v.GoGenDir)
-		cmd := exec.Command("go", "generate")
-		cmd.Dir = genDir
-		cmd.Stdout = stdout
-		cmd.Stderr = stdout
-
-		var envVars []string
-		for k, v := range cfg.Generators[k].Environment {
-			envVars = append(envVars, fmt.Sprintf("%s=%v", k, v))
+		envSets := v.Environments
+		if len(envSets) == 0 {
+			envSets = []map[string]string{nil}
 		}
-		cmd.Env = append(envVars, os.Environ()...)
-
-		if err := cmd.Run(); err != nil {
-			return nil, errors.Wrapf(err, "failed to run go generate in %q", genDir)
+		for _, envSet := range envSets {
+			if err := runGenerator(rootDir, k, v, envSet, verbosity, stdout); err != nil {
+				return nil, err
+			}
 		}
 
 		newChecksums, err := checksumsForMatchingPaths(rootDir, m)
@@ -97,6 +103,64 @@ func runGenerate(rootDir string, cfg config.GoGenerate, stdout io.Writer) (map[s
 	return diffs, nil
 }
 
+// runGenerator runs the command for the provided generator once. The variables in envSet are added to (and override)
+// the variables in the Environment of the generator.
+func runGenerator(rootDir, name string, v config.GeneratorConfig, envSet map[string]string, verbosity Verbosity, stdout io.Writer) error {
+	var outputWriter io.Writer
+	if verbosity != Quiet {
+		outputWriter = stdout
+	}
+	output := newGeneratorOutput(name, outputWriter)
+
+	genDir := path.Join(rootDir, v.GoGenDir)
+	args := v.Command
+	if len(args) == 0 {
+		args = []string{"go", "generate"}
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = genDir
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	env := make(map[string]string)
+	for key, val := range v.Environment {
+		env[key] = val
+	}
+	for key, val := range envSet {
+		env[key] = val
+	}
+	envVars := sortedEnvVars(env)
+	// the variables for the generator are appended last so that they take precedence over those of this process
+	cmd.Env = append(os.Environ(), envVars...)
+
+	if verbosity == Verbose {
+		fmt.Fprintf(stdout, "[%s] running %s in %s", name, strings.Join(args, " "), v.GoGenDir)
+		if len(envVars) > 0 {
+			fmt.Fprintf(stdout, " with environment %v", envVars)
+		}
+		fmt.Fprintln(stdout)
+	}
+
+	runErr := cmd.Run()
+	if err := output.Flush(); err != nil {
+		return errors.Wrapf(err, "failed to write output of generator %s", name)
+	}
+	if runErr != nil {
+		msg := fmt.Sprintf("generator %s failed to run %s in %q", name, strings.Join(args, " "), genDir)
+		if len(envSet) > 0 {
+			// identify the environment set that failed when the generator is run for multiple sets
+			msg += fmt.Sprintf(" with environment %v", envVars)
+		}
+		err := errors.Wrap(runErr, msg)
+		if tail := output.Tail(); len(tail) > 0 {
+			// include the end of the output so that the failure can be diagnosed without re-running the generator
+			return errors.Errorf("%v\nlast %d lines of output of generator %s:\n    %s", err, len(tail), name, strings.Join(tail, "\n    "))
+		}
+		return err
+	}
+	return nil
+}
+
 type checksumSet map[string]*fileChecksumInfo
 
 func (c checksumSet) sortedKeys() []string {
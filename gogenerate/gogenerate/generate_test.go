@@ -15,6 +15,7 @@
 package gogenerate_test
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -141,6 +142,153 @@ generators:
 	assert.Equal(t, "test-val", string(outputTxt))
 }
 
+func TestGenerateCommand(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	if err := ioutil.WriteFile("output.txt", []byte(os.Args[1]+"-"+os.Getenv("GOGEN_VAR")), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+	_, err = gofiles.Write(testDir, specs)
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    command: ["go", "run", "generator_main.go", "foo-output"]
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+    environment:
+      GOGEN_VAR: test-val
+`
+	cfg, err := config.LoadFromStrings(configYML, "")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	err = gogenerate.RunWithVerbosity(testDir, cfg, false, gogenerate.Verbose, buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "[foo] running go run generator_main.go foo-output in gen")
+
+	outputTxt, err := ioutil.ReadFile(path.Join(testDir, "gen", "output.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo-output-test-val", string(outputTxt))
+
+	// verify detects changes to the output of the command
+	err = ioutil.WriteFile(path.Join(testDir, "gen", "output.txt"), []byte("modified"), 0644)
+	require.NoError(t, err)
+	err = gogenerate.Run(testDir, cfg, true, ioutil.Discard)
+	require.Error(t, err)
+}
+
+func TestGenerateEnvironments(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	// the environment of the generator takes precedence over the environment in which it is run
+	prevOS, prevOSSet := os.LookupEnv("GOGEN_OS")
+	err = os.Setenv("GOGEN_OS", "parent")
+	require.NoError(t, err)
+	defer func() {
+		if prevOSSet {
+			_ = os.Setenv("GOGEN_OS", prevOS)
+			return
+		}
+		_ = os.Unsetenv("GOGEN_OS")
+	}()
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	if err := ioutil.WriteFile("output_"+os.Getenv("GOGEN_OS")+".txt", []byte(os.Getenv("GOGEN_VAR")), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+	_, err = gofiles.Write(testDir, specs)
+	require.NoError(t, err)
+
+	const configYML = `
+generators:
+  foo:
+    go-generate-dir: gen
+    command: ["go", "run", "generator_main.go"]
+    gen-paths:
+      paths:
+        - "gen/output_*.txt"
+    environment:
+      GOGEN_OS: default
+      GOGEN_VAR: test-val
+    environments:
+      - GOGEN_OS: linux
+      - GOGEN_OS: darwin
+        GOGEN_VAR: darwin-val
+`
+	cfg, err := config.LoadFromStrings(configYML, "")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	err = gogenerate.RunWithVerbosity(testDir, cfg, false, gogenerate.Verbose, buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "[foo] running go run generator_main.go in gen with environment [GOGEN_OS=linux GOGEN_VAR=test-val]")
+	assert.Contains(t, buf.String(), "[foo] running go run generator_main.go in gen with environment [GOGEN_OS=darwin GOGEN_VAR=darwin-val]")
+
+	for name, want := range map[string]string{
+		"output_linux.txt":  "test-val",
+		"output_darwin.txt": "darwin-val",
+	} {
+		outputTxt, err := ioutil.ReadFile(path.Join(testDir, "gen", name))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(outputTxt))
+	}
+	_, err = os.Stat(path.Join(testDir, "gen", "output_default.txt"))
+	assert.True(t, os.IsNotExist(err), "generator should not be run with only the base environment")
+	_, err = os.Stat(path.Join(testDir, "gen", "output_parent.txt"))
+	assert.True(t, os.IsNotExist(err), "generator should not be run with the environment of the parent process")
+
+	// output is verified after the generator has run for all of the environment sets
+	err = gogenerate.Run(testDir, cfg, true, ioutil.Discard)
+	require.NoError(t, err)
+
+	err = os.Remove(path.Join(testDir, "gen", "output_darwin.txt"))
+	require.NoError(t, err)
+	err = gogenerate.Run(testDir, cfg, true, ioutil.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gen/output_darwin.txt: did not exist before, now exists")
+}
+
 func TestGenerateVerifyErrors(t *testing.T) {
 	testDir, cleanup, err := dirs.TempDir(".", "")
 	defer cleanup()
@@ -428,3 +576,229 @@ func main() {
 		assert.EqualError(t, err, currCase.wantError, "Case %d: %s\n%s", currCaseNum, currCase.name, err.Error())
 	}
 }
+
+func TestGenerateOutput(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println("first line")
+	fmt.Print("second line")
+	if os.Getenv("GOGEN_FAIL") != "" {
+		os.Exit(1)
+	}
+}
+`,
+		},
+	}
+	_, err = gofiles.Write(testDir, specs)
+	require.NoError(t, err)
+
+	cfg, err := config.LoadFromStrings(`
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`, "")
+	require.NoError(t, err)
+
+	for i, currCase := range []struct {
+		verbosity gogenerate.Verbosity
+		want      string
+	}{
+		{
+			verbosity: gogenerate.Quiet,
+			want:      "",
+		},
+		{
+			verbosity: gogenerate.Normal,
+			want:      "[foo] first line\n[foo] second line\n",
+		},
+		{
+			verbosity: gogenerate.Verbose,
+			want:      "[foo] running go generate in gen\n[foo] first line\n[foo] second line\n",
+		},
+	} {
+		buf := &bytes.Buffer{}
+		err = gogenerate.RunWithVerbosity(testDir, cfg, false, currCase.verbosity, buf)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, buf.String(), "Case %d", i)
+	}
+
+	cfg.Generators["foo"] = config.GeneratorConfig{
+		GoGenDir:    cfg.Generators["foo"].GoGenDir,
+		GenPaths:    cfg.Generators["foo"].GenPaths,
+		Environment: map[string]string{"GOGEN_FAIL": "true"},
+	}
+	buf := &bytes.Buffer{}
+	err = gogenerate.RunWithVerbosity(testDir, cfg, false, gogenerate.Quiet, buf)
+	require.Error(t, err)
+	assert.Equal(t, "", buf.String())
+	assert.Contains(t, err.Error(), "generator foo failed to run go generate in")
+	assert.Contains(t, err.Error(), "lines of output of generator foo:\n    first line\n    second line")
+}
+
+func TestManifest(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	specs := []gofiles.GoFileSpec{
+		{
+			RelPath: "gen/testbar.go",
+			Src: `package testbar
+
+//go:generate go run generator_main.go
+`,
+		},
+		{
+			RelPath: "gen/generator_main.go",
+			Src: `// +build ignore
+
+package main
+
+import (
+	"io/ioutil"
+)
+
+func main() {
+	if err := ioutil.WriteFile("output.txt", []byte("foo-output"), 0644); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+	}
+	_, err = gofiles.Write(testDir, specs)
+	require.NoError(t, err)
+
+	cfg, err := config.LoadFromStrings(`
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+`, "")
+	require.NoError(t, err)
+	manifestPath := path.Join(testDir, gogenerate.ManifestFileName)
+
+	err = gogenerate.VerifyManifest(testDir, cfg, manifestPath)
+	assert.EqualError(t, err, fmt.Sprintf("manifest %s does not exist", manifestPath))
+
+	err = gogenerate.Run(testDir, cfg, false, ioutil.Discard)
+	require.NoError(t, err)
+	err = gogenerate.WriteManifest(testDir, cfg, manifestPath)
+	require.NoError(t, err)
+
+	err = gogenerate.VerifyManifest(testDir, cfg, manifestPath)
+	assert.NoError(t, err)
+
+	err = os.Remove(path.Join(testDir, "gen", "output.txt"))
+	require.NoError(t, err)
+	err = gogenerate.VerifyManifest(testDir, cfg, manifestPath)
+	assert.EqualError(t, err, "Output of generators differs from what is recorded in the manifest: [foo]\n  foo:\n    gen/output.txt: existed before, no longer exists")
+
+	cfg.Generators["bar"] = cfg.Generators["foo"]
+	err = gogenerate.VerifyManifest(testDir, cfg, manifestPath)
+	assert.EqualError(t, err, fmt.Sprintf("manifest %s does not contain entries for generators [bar]", manifestPath))
+}
+
+func TestList(t *testing.T) {
+	testDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	for _, p := range []string{"gen/output.txt", "gen/other.txt", "proto/foo.pb.go", "proto/foo.proto"} {
+		require.NoError(t, os.MkdirAll(path.Join(testDir, path.Dir(p)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(testDir, p), []byte("content"), 0644))
+	}
+
+	cfg, err := config.LoadFromStrings(`
+generators:
+  foo:
+    go-generate-dir: gen
+    gen-paths:
+      paths:
+        - "gen/output.txt"
+        - "gen/missing.txt"
+    environment:
+      CGO_ENABLED: "0"
+    environments:
+      - GOOS: linux
+      - GOOS: darwin
+  protos:
+    go-generate-dir: proto
+    command: ["make", "protos"]
+    gen-paths:
+      names:
+        - ".+\\.pb\\.go"
+`, "")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	err = gogenerate.List(testDir, cfg, false, buf)
+	require.NoError(t, err)
+	assert.Equal(t, `foo:
+  go-generate-dir: gen
+  command: go generate
+  gen-paths:
+    path: gen/output.txt
+    path: gen/missing.txt
+  environment: [CGO_ENABLED=0]
+  environments:
+    [GOOS=linux]
+    [GOOS=darwin]
+protos:
+  go-generate-dir: proto
+  command: make protos
+  gen-paths:
+    name: .+\.pb\.go
+`, buf.String())
+
+	buf.Reset()
+	err = gogenerate.List(testDir, cfg, true, buf)
+	require.NoError(t, err)
+	assert.Equal(t, `foo:
+  go-generate-dir: gen
+  command: go generate
+  gen-paths:
+    path: gen/output.txt
+    path: gen/missing.txt
+  environment: [CGO_ENABLED=0]
+  environments:
+    [GOOS=linux]
+    [GOOS=darwin]
+  matched paths (1):
+    gen/output.txt
+protos:
+  go-generate-dir: proto
+  command: make protos
+  gen-paths:
+    name: .+\.pb\.go
+  matched paths (1):
+    proto/foo.pb.go
+`, buf.String())
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/palantir/pkg/matcher"
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/gogenerate/config"
+)
+
+// List writes a description of the generators in the provided configuration to w: the directory, command, gen-paths
+// and environment of each generator. If resolve is true, the paths in rootDir that are currently matched by the
+// gen-paths of each generator (and are therefore verified) are written as well. Generators are not run.
+func List(rootDir string, cfg config.GoGenerate, resolve bool, w io.Writer) error {
+	for _, k := range cfg.Generators.SortedKeys() {
+		v := cfg.Generators[k]
+		args := v.Command
+		if len(args) == 0 {
+			args = []string{"go", "generate"}
+		}
+
+		fmt.Fprintf(w, "%s:\n", k)
+		fmt.Fprintf(w, "  go-generate-dir: %s\n", v.GoGenDir)
+		fmt.Fprintf(w, "  command: %s\n", strings.Join(args, " "))
+		fmt.Fprintln(w, "  gen-paths:")
+		if v.GenPaths.Empty() {
+			fmt.Fprintln(w, "    (none)")
+		}
+		for _, name := range v.GenPaths.Names {
+			fmt.Fprintf(w, "    name: %s\n", name)
+		}
+		for _, p := range v.GenPaths.Paths {
+			fmt.Fprintf(w, "    path: %s\n", p)
+		}
+		if len(v.Environment) > 0 {
+			fmt.Fprintf(w, "  environment: %v\n", sortedEnvVars(v.Environment))
+		}
+		if len(v.Environments) > 0 {
+			fmt.Fprintln(w, "  environments:")
+			for _, envSet := range v.Environments {
+				fmt.Fprintf(w, "    %v\n", sortedEnvVars(envSet))
+			}
+		}
+
+		if !resolve {
+			continue
+		}
+		paths, err := matchingPaths(rootDir, v.GenPaths.Matcher())
+		if err != nil {
+			return errors.Wrapf(err, "failed to determine paths matched by generator %s", k)
+		}
+		fmt.Fprintf(w, "  matched paths (%d):\n", len(paths))
+		for _, p := range paths {
+			fmt.Fprintf(w, "    %s\n", p)
+		}
+	}
+	return nil
+}
+
+// sortedEnvVars returns the provided environment variables in "key=value" form sorted by key.
+func sortedEnvVars(env map[string]string) []string {
+	var envVars []string
+	for key, val := range env {
+		envVars = append(envVars, fmt.Sprintf("%s=%v", key, val))
+	}
+	sort.Strings(envVars)
+	return envVars
+}
+
+// matchingPaths returns the sorted paths (relative to rootDir) of the files and directories in rootDir that are matched
+// by the provided matcher.
+func matchingPaths(rootDir string, m matcher.Matcher) ([]string, error) {
+	var paths []string
+	if err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return err
+		}
+		if m.Match(relPath) {
+			paths = append(paths, relPath)
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to walk directory %q", rootDir)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/gogenerate/config"
+)
+
+// ManifestFileName is the name of the manifest file that records the checksums of the output paths of generators.
+const ManifestFileName = "gogenerate.lock"
+
+// manifestDirEntry is the value recorded in a manifest for an output path that is a directory.
+const manifestDirEntry = "directory"
+
+// Manifest records the checksums of the output paths of generators.
+type Manifest struct {
+	// Generators is a map from the name of a generator to a map from each of the output paths of the generator
+	// (relative to the root directory) to its SHA-256 checksum (or "directory" if the path is a directory).
+	Generators map[string]map[string]string `json:"generators"`
+}
+
+// WriteManifest writes a manifest that records the current checksums of the output paths of the generators in the
+// provided configuration to manifestPath.
+func WriteManifest(rootDir string, cfg config.GoGenerate, manifestPath string) error {
+	manifest := Manifest{
+		Generators: make(map[string]map[string]string),
+	}
+	for _, k := range cfg.Generators.SortedKeys() {
+		v := cfg.Generators[k]
+		checksums, err := checksumsForMatchingPaths(rootDir, v.GenPaths.Matcher())
+		if err != nil {
+			return errors.Wrapf(err, "failed to compute checksums")
+		}
+		entries := make(map[string]string)
+		for path, checksum := range checksums {
+			if checksum.isDir {
+				entries[path] = manifestDirEntry
+			} else {
+				entries[path] = checksum.sha256checksum
+			}
+		}
+		manifest.Generators[k] = entries
+	}
+
+	bytes, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal manifest")
+	}
+	if err := ioutil.WriteFile(manifestPath, append(bytes, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write manifest %s", manifestPath)
+	}
+	return nil
+}
+
+// VerifyManifest verifies that the current checksums of the output paths of the generators in the provided
+// configuration match the checksums recorded in the manifest at manifestPath. Generators are not run. Returns an error
+// that describes the differences if the manifest does not match the current output or if the manifest does not record
+// the output of every generator in the configuration.
+func VerifyManifest(rootDir string, cfg config.GoGenerate, manifestPath string) error {
+	bytes, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("manifest %s does not exist", manifestPath)
+		}
+		return errors.Wrapf(err, "failed to read manifest %s", manifestPath)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(bytes, &manifest); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal manifest %s", manifestPath)
+	}
+
+	var missing []string
+	diffs := make(map[string]ChecksumsDiff)
+	for _, k := range cfg.Generators.SortedKeys() {
+		entries, ok := manifest.Generators[k]
+		if !ok {
+			missing = append(missing, k)
+			continue
+		}
+
+		recordedChecksums := make(checksumSet)
+		for path, entry := range entries {
+			if entry == manifestDirEntry {
+				recordedChecksums[path] = &fileChecksumInfo{
+					path:  path,
+					isDir: true,
+				}
+			} else {
+				recordedChecksums[path] = &fileChecksumInfo{
+					path:           path,
+					sha256checksum: entry,
+				}
+			}
+		}
+
+		v := cfg.Generators[k]
+		currChecksums, err := checksumsForMatchingPaths(rootDir, v.GenPaths.Matcher())
+		if err != nil {
+			return errors.Wrapf(err, "failed to compute checksums")
+		}
+		if diff := recordedChecksums.compare(currChecksums); len(diff) > 0 {
+			diffs[k] = diff
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("manifest %s does not contain entries for generators %v", manifestPath, missing)
+	}
+	if len(diffs) > 0 {
+		return diffError("Output of generators differs from what is recorded in the manifest", diffs)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gogenerate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Verbosity specifies the amount of output that is written while generators are run.
+type Verbosity int
+
+const (
+	// Quiet does not write the output of generators. The end of the output of a generator that fails is still included
+	// in the returned error.
+	Quiet Verbosity = iota
+	// Normal writes the output of generators with every line prefixed by the name of the generator.
+	Normal
+	// Verbose writes the output of generators along with a line that describes each generator before it is run.
+	Verbose
+)
+
+// numTailLines is the number of lines at the end of the output of a failed generator that are included in the error.
+const numTailLines = 20
+
+// generatorOutput is an io.Writer for the output of a generator. Every complete line that is written is written to the
+// underlying writer (if it is non-nil) prefixed with "[<generator>] ". The last numTailLines lines are retained so
+// that they can be reported if the generator fails.
+type generatorOutput struct {
+	prefix string
+	w      io.Writer
+	// content of the current line that has not yet been terminated by a newline
+	partial []byte
+	tail    []string
+}
+
+func newGeneratorOutput(generator string, w io.Writer) *generatorOutput {
+	return &generatorOutput{
+		prefix: fmt.Sprintf("[%s] ", generator),
+		w:      w,
+	}
+}
+
+func (o *generatorOutput) Write(p []byte) (int, error) {
+	o.partial = append(o.partial, p...)
+	for {
+		idx := bytes.IndexByte(o.partial, '\n')
+		if idx == -1 {
+			break
+		}
+		if err := o.writeLine(string(o.partial[:idx])); err != nil {
+			return 0, err
+		}
+		o.partial = o.partial[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes the current line if it has not been terminated by a newline.
+func (o *generatorOutput) Flush() error {
+	if len(o.partial) == 0 {
+		return nil
+	}
+	line := string(o.partial)
+	o.partial = nil
+	return o.writeLine(line)
+}
+
+func (o *generatorOutput) writeLine(line string) error {
+	o.tail = append(o.tail, line)
+	if len(o.tail) > numTailLines {
+		o.tail = o.tail[len(o.tail)-numTailLines:]
+	}
+	if o.w == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(o.w, "%s%s\n", o.prefix, line)
+	return err
+}
+
+// Tail returns the last lines of output that were written.
+func (o *generatorOutput) Tail() []string {
+	return o.tail
+}
@@ -15,8 +15,12 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/palantir/pkg/cli"
@@ -25,6 +29,7 @@ import (
 	"github.com/palantir/pkg/matcher"
 	"github.com/pkg/errors"
 
+	"github.com/palantir/checks/checks/projectconfig"
 	"github.com/palantir/checks/golicense/config"
 	"github.com/palantir/checks/golicense/golicense"
 )
@@ -33,6 +38,14 @@ const (
 	filesFlagName  = "files"
 	verifyFlagName = "verify"
 	removeFlagName = "remove"
+	diffFlagName   = "diff"
+	formatFlagName = "format"
+	fixFlagName    = "fix"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
 )
 
 var flags = []flag.Flag{
@@ -44,6 +57,19 @@ var flags = []flag.Flag{
 		Name:  removeFlagName,
 		Usage: "remove the license header from files (no-op if verify is true)",
 	},
+	flag.BoolFlag{
+		Name:  diffFlagName,
+		Usage: "print a unified diff of the changes that would be made to each file instead of modifying the files",
+	},
+	flag.StringFlag{
+		Name:  formatFlagName,
+		Usage: fmt.Sprintf("format of the summary that is printed when verifying. Must be %q or %q", formatTable, formatJSON),
+		Value: formatTable,
+	},
+	flag.StringFlag{
+		Name:  projectconfig.FlagName,
+		Usage: "path to a project configuration file whose exclude section specifies additional files and directories to exclude",
+	},
 	flag.StringSlice{
 		Name:     filesFlagName,
 		Usage:    "files on which to perform operation (if they are not excluded by configuration)",
@@ -57,12 +83,17 @@ func Command() cli.Command {
 		Usage: "Write or verify license headers for Go files",
 		Flags: flags,
 		Action: func(ctx cli.Context) error {
+			format := ctx.String(formatFlagName)
+			if format != formatTable && format != formatJSON {
+				return errors.Errorf("invalid format %q: must be %q or %q", format, formatTable, formatJSON)
+			}
+
 			wd, err := dirs.GetwdEvalSymLinks()
 			if err != nil {
 				return err
 			}
 
-			params, err := config.Load(cfgcli.ConfigPath, cfgcli.ConfigJSON)
+			params, err := loadParams(ctx)
 			if err != nil {
 				return err
 			}
@@ -72,14 +103,9 @@ func Command() cli.Command {
 				return nil
 			}
 
-			var files []string
-			if ctx.Has(filesFlagName) {
-				files = ctx.Slice(filesFlagName)
-			} else {
-				files, err = matcher.ListFiles(wd, matcher.Name(`.+`), nil)
-				if err != nil {
-					return err
-				}
+			files, err := filesToProcess(ctx, wd)
+			if err != nil {
+				return err
 			}
 
 			verify := false
@@ -87,14 +113,36 @@ func Command() cli.Command {
 				verify = ctx.Bool(verifyFlagName)
 			}
 
+			diff := ctx.Has(diffFlagName) && ctx.Bool(diffFlagName)
+
 			switch {
 			case verify:
 				// run verify
-				modified, err := golicense.LicenseFiles(files, params, !verify)
+				summary, err := golicense.SummarizeVerify(files, params)
 				if err != nil {
 					return err
 				}
-				if len(modified) > 0 {
+				if format == formatJSON {
+					if err := writeVerifySummaryJSON(ctx.App.Stdout, summary); err != nil {
+						return err
+					}
+					if !summary.Passed() {
+						// output has already been printed, so return empty error
+						return fmt.Errorf("")
+					}
+					return nil
+				}
+				if err := writeVerifySummary(ctx.App.Stdout, summary); err != nil {
+					return err
+				}
+				if modified := summary.Files; len(modified) > 0 {
+					if diff {
+						diffOutput, err := golicense.LicenseFilesDiff(files, params)
+						if err != nil {
+							return err
+						}
+						ctx.Print(diffOutput)
+					}
 					var plural string
 					if len(modified) == 1 {
 						plural = "file does"
@@ -105,6 +153,20 @@ func Command() cli.Command {
 					parts := append([]string{fmt.Sprintf("%d %s not have the correct license header:", len(modified), plural)}, modified...)
 					return errors.New(strings.Join(parts, "\n\t"))
 				}
+			case diff && ctx.Has(removeFlagName) && ctx.Bool(removeFlagName):
+				// print diff for unlicense
+				diffOutput, err := golicense.UnlicenseFilesDiff(files, params)
+				if err != nil {
+					return err
+				}
+				ctx.Print(diffOutput)
+			case diff:
+				// print diff for license
+				diffOutput, err := golicense.LicenseFilesDiff(files, params)
+				if err != nil {
+					return err
+				}
+				ctx.Print(diffOutput)
 			case ctx.Has(removeFlagName) && ctx.Bool(removeFlagName):
 				// run unlicense
 				if _, err := golicense.UnlicenseFiles(files, params, true); err != nil {
@@ -120,3 +182,211 @@ func Command() cli.Command {
 		},
 	}
 }
+
+// ReportCommand returns the command that reports the license header ownership of files.
+func ReportCommand() cli.Command {
+	return cli.Command{
+		Name:  "report",
+		Usage: "Report the configured license header that each Go file belongs to and the header that it starts with",
+		Flags: []flag.Flag{
+			flag.StringFlag{
+				Name:  formatFlagName,
+				Usage: fmt.Sprintf("format of the report. Must be %q or %q", formatTable, formatJSON),
+				Value: formatTable,
+			},
+			flag.StringFlag{
+				Name:  projectconfig.FlagName,
+				Usage: "path to a project configuration file whose exclude section specifies additional files and directories to exclude",
+			},
+			flag.StringSlice{
+				Name:     filesFlagName,
+				Usage:    "files to report (if they are not excluded by configuration)",
+				Optional: true,
+			},
+		},
+		Action: func(ctx cli.Context) error {
+			format := ctx.String(formatFlagName)
+			if format != formatTable && format != formatJSON {
+				return errors.Errorf("invalid format %q: must be %q or %q", format, formatTable, formatJSON)
+			}
+
+			wd, err := dirs.GetwdEvalSymLinks()
+			if err != nil {
+				return err
+			}
+			params, err := loadParams(ctx)
+			if err != nil {
+				return err
+			}
+			files, err := filesToProcess(ctx, wd)
+			if err != nil {
+				return err
+			}
+
+			reports, err := golicense.ReportFiles(files, params)
+			if err != nil {
+				return err
+			}
+			return writeReport(ctx.App.Stdout, reports, format)
+		},
+	}
+}
+
+// YearCommand returns the command that verifies that the copyright year of files is not older than the year in which
+// they were last modified according to the git history of the working directory.
+func YearCommand() cli.Command {
+	return cli.Command{
+		Name:  "year",
+		Usage: "Verify that the copyright year of Go files is not older than the year in which they were last modified in git",
+		Flags: []flag.Flag{
+			flag.BoolFlag{
+				Name:  fixFlagName,
+				Usage: "update the copyright year of files whose year is older than the year in which they were last modified",
+			},
+			flag.StringFlag{
+				Name:  projectconfig.FlagName,
+				Usage: "path to a project configuration file whose exclude section specifies additional files and directories to exclude",
+			},
+			flag.StringSlice{
+				Name:     filesFlagName,
+				Usage:    "files to check (if they are not excluded by configuration)",
+				Optional: true,
+			},
+		},
+		Action: func(ctx cli.Context) error {
+			wd, err := dirs.GetwdEvalSymLinks()
+			if err != nil {
+				return err
+			}
+			params, err := loadParams(ctx)
+			if err != nil {
+				return err
+			}
+			files, err := filesToProcess(ctx, wd)
+			if err != nil {
+				return err
+			}
+			// the last modified years are relative to the working directory
+			for i, f := range files {
+				if rel, err := filepath.Rel(wd, f); filepath.IsAbs(f) && err == nil {
+					files[i] = rel
+				}
+			}
+			years, err := golicense.LastModifiedYears(wd)
+			if err != nil {
+				return err
+			}
+
+			fix := ctx.Bool(fixFlagName)
+			stale, err := golicense.StaleYearFiles(files, params, years, fix)
+			if err != nil {
+				return err
+			}
+			if len(stale) == 0 || fix {
+				return nil
+			}
+			var plural string
+			if len(stale) == 1 {
+				plural = "file does"
+			} else {
+				plural = "files do"
+			}
+			parts := []string{fmt.Sprintf("%d %s not have a current copyright year:", len(stale), plural)}
+			for _, s := range stale {
+				parts = append(parts, s.String())
+			}
+			return errors.New(strings.Join(parts, "\n\t"))
+		},
+	}
+}
+
+func writeReport(w io.Writer, reports []golicense.FileReport, format string) error {
+	summary := golicense.SummarizeReport(reports)
+	if format == formatJSON {
+		if reports == nil {
+			reports = []golicense.FileReport{}
+		}
+		if summary == nil {
+			summary = []golicense.ReportSummary{}
+		}
+		bytes, err := json.MarshalIndent(struct {
+			Files   []golicense.FileReport    `json:"files"`
+			Summary []golicense.ReportSummary `json:"summary"`
+		}{
+			Files:   reports,
+			Summary: summary,
+		}, "", "    ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal report")
+		}
+		_, err = fmt.Fprintln(w, string(bytes))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OWNER\tHEADER\tFILES")
+	total := 0
+	for _, v := range summary {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", v.Owner, v.Header, v.Files)
+		total += v.Files
+	}
+	fmt.Fprintf(tw, "total\t\t%d\n", total)
+	return tw.Flush()
+}
+
+// writeVerifySummary writes the provided summary as a table with a row for each header followed by a line with the
+// number of excluded files.
+func writeVerifySummary(w io.Writer, summary golicense.VerifySummary) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "MATCHER\tCOMPLIANT\tMISSING HEADER\tWRONG HEADER")
+	var total golicense.MatcherSummary
+	for _, v := range summary.Matchers {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", v.Matcher, v.Compliant, v.MissingHeader, v.WrongHeader)
+		total.Compliant += v.Compliant
+		total.MissingHeader += v.MissingHeader
+		total.WrongHeader += v.WrongHeader
+	}
+	fmt.Fprintf(tw, "total\t%d\t%d\t%d\n", total.Compliant, total.MissingHeader, total.WrongHeader)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "excluded files: %d\n", summary.Excluded)
+	return err
+}
+
+func writeVerifySummaryJSON(w io.Writer, summary golicense.VerifySummary) error {
+	if summary.Files == nil {
+		summary.Files = []string{}
+	}
+	bytes, err := json.MarshalIndent(summary, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal summary")
+	}
+	_, err = fmt.Fprintln(w, string(bytes))
+	return err
+}
+
+// loadParams returns the license parameters specified by the configuration along with the exclusions of the project
+// configuration.
+func loadParams(ctx cli.Context) (golicense.LicenseParams, error) {
+	params, err := config.Load(cfgcli.ConfigPath, cfgcli.ConfigJSON)
+	if err != nil {
+		return golicense.LicenseParams{}, err
+	}
+	projectCfg, err := projectconfig.Load(ctx.String(projectconfig.FlagName))
+	if err != nil {
+		return golicense.LicenseParams{}, err
+	}
+	if projectExclude := projectCfg.ExcludeMatcher(); projectExclude != nil {
+		params.Exclude = matcher.Any(params.Exclude, projectExclude)
+	}
+	return params, nil
+}
+
+// filesToProcess returns the files provided as arguments, or all of the files in wd if no files were provided.
+func filesToProcess(ctx cli.Context, wd string) ([]string, error) {
+	if ctx.Has(filesFlagName) {
+		return ctx.Slice(filesFlagName), nil
+	}
+	return matcher.ListFiles(wd, matcher.Name(`.+`), nil)
+}
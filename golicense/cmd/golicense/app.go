@@ -18,6 +18,7 @@ import (
 	"github.com/nmiyake/pkg/errorstringer"
 	"github.com/palantir/pkg/cli"
 	"github.com/palantir/pkg/cli/cfgcli"
+	"github.com/palantir/pkg/cli/flag"
 
 	"github.com/palantir/checks/golicense/cmd"
 )
@@ -27,5 +28,16 @@ func App() *cli.App {
 	flags := app.Flags
 	app.Command = cmd.Command()
 	app.Flags = append(flags, app.Flags...)
+
+	// the files of the main command are positional arguments, so "report" and "year" are routed to their commands
+	// before the arguments are parsed. The global flags are added to the commands so that they can be specified after
+	// the command name.
+	for _, subCmd := range []cli.Command{cmd.ReportCommand(), cmd.YearCommand()} {
+		subCmd.Flags = append(append([]flag.Flag{}, flags...), subCmd.Flags...)
+		app.Backcompat = append(app.Backcompat, cli.Backcompat{
+			Path:    []string{subCmd.Name},
+			Command: subCmd,
+		})
+	}
 	return app
 }
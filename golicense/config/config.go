@@ -30,6 +30,11 @@ type GoLicense struct {
 	// by a newline.
 	Header string `yaml:"header" json:"header"`
 
+	// AcceptedHeaders specifies alternate forms of the license header that are also accepted when verifying licenses
+	// (for example, the previous wording of a header that is being migrated). "Header" is always used when applying
+	// licenses.
+	AcceptedHeaders []string `yaml:"accepted-headers" json:"accepted-headers"`
+
 	// CustomHeaders specifies the custom header parameters. Custom header parameters can be used to specify that
 	// certain directories or files in the project should use a header that is different from "Header".
 	CustomHeaders []License `yaml:"custom-headers" json:"custom-headers"`
@@ -37,6 +42,24 @@ type GoLicense struct {
 	// Exclude matches the files and directories that should be excluded from consideration for verifying or
 	// applying licenses.
 	Exclude matcher.NamesPathsCfg `yaml:"exclude" json:"exclude"`
+
+	// DirectivePlacement specifies whether the header is placed before or after the directives that can appear at the
+	// top of a file so that applying the header does not break them.
+	DirectivePlacement DirectivePlacement `yaml:"directive-placement" json:"directive-placement"`
+}
+
+// DirectivePlacement specifies the placement of the header relative to each type of directive. Each value must be
+// "before", "after" or blank, in which case the default placement is used (after a shebang line and before build
+// constraints and go:generate directives).
+type DirectivePlacement struct {
+	// Shebang is the placement of the header relative to a first line that starts with "#!".
+	Shebang string `yaml:"shebang" json:"shebang"`
+
+	// Build is the placement of the header relative to "//go:build" and "// +build" constraints.
+	Build string `yaml:"build" json:"build"`
+
+	// Generate is the placement of the header relative to "//go:generate" directives.
+	Generate string `yaml:"generate" json:"generate"`
 }
 
 type License struct {
@@ -47,10 +70,18 @@ type License struct {
 	// by a newline.
 	Header string `yaml:"header" json:"header"`
 
-	// Paths specifies the paths for which this custom license is applicable. If multiple custom parameters match a
-	// file or directory, the parameter with the longest path match is used. If multiple custom parameters match a
-	// file or directory exactly (match length is equal), it is treated as an error.
+	// AcceptedHeaders specifies alternate forms of the license header that are also accepted when verifying licenses.
+	// "Header" is always used when applying licenses.
+	AcceptedHeaders []string `yaml:"accepted-headers" json:"accepted-headers"`
+
+	// Paths specifies the paths for which this custom license is applicable. Paths may be glob patterns. If multiple
+	// custom parameters match a file, the parameter that matches the longest subpath of the file is used. Paths of
+	// different custom parameters that are equal or can match the same path are treated as an error.
 	Paths []string `yaml:"paths" json:"paths"`
+
+	// Names specifies regular expressions for the names of the files and directories for which this custom license
+	// is applicable. Names of different custom parameters that are equal are treated as an error.
+	Names []string `yaml:"names" json:"names"`
 }
 
 func (l *GoLicense) ToParams() (golicense.LicenseParams, error) {
@@ -62,18 +93,30 @@ func (l *GoLicense) ToParams() (golicense.LicenseParams, error) {
 	if err != nil {
 		return golicense.LicenseParams{}, err
 	}
+	placement := golicense.DirectivePlacement{
+		Shebang:  golicense.Placement(l.DirectivePlacement.Shebang),
+		Build:    golicense.Placement(l.DirectivePlacement.Build),
+		Generate: golicense.Placement(l.DirectivePlacement.Generate),
+	}
+	if err := placement.Validate(); err != nil {
+		return golicense.LicenseParams{}, err
+	}
 	return golicense.LicenseParams{
-		Header:        l.Header,
-		CustomHeaders: customParams,
-		Exclude:       l.Exclude.Matcher(),
+		Header:          l.Header,
+		AcceptedHeaders: l.AcceptedHeaders,
+		CustomHeaders:   customParams,
+		Exclude:         l.Exclude.Matcher(),
+		Placement:       placement,
 	}, nil
 }
 
 func (l *License) ToParam() golicense.CustomLicenseParam {
 	return golicense.CustomLicenseParam{
-		Name:         l.Name,
-		Header:       l.Header,
-		IncludePaths: l.Paths,
+		Name:            l.Name,
+		Header:          l.Header,
+		AcceptedHeaders: l.AcceptedHeaders,
+		IncludePaths:    l.Paths,
+		IncludeNames:    l.Names,
 	}
 }
 
@@ -41,5 +41,5 @@ custom-headers:
 		panic(err)
 	}
 	fmt.Printf("%q", fmt.Sprintf("%+v", cfg))
-	// Output: "{Header:// Copyright 2016 Palantir Technologies, Inc.\n//\n// License content.\n CustomHeaders:[{Name:subproject Header:// Copyright 2016 Palantir Technologies, Inc. All rights reserved.\n// Subproject license.\n Paths:[subprojectDir]}] Exclude:{Names:[] Paths:[]}}"
+	// Output: "{Header:// Copyright 2016 Palantir Technologies, Inc.\n//\n// License content.\n AcceptedHeaders:[] CustomHeaders:[{Name:subproject Header:// Copyright 2016 Palantir Technologies, Inc. All rights reserved.\n// Subproject license.\n AcceptedHeaders:[] Paths:[subprojectDir] Names:[]}] Exclude:{Names:[] Paths:[]} DirectivePlacement:{Shebang: Build: Generate:}}"
 }
@@ -0,0 +1,158 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golicense
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// fallbackClassChars are characters that are tested (in addition to the characters that appear in the classes) when
+// determining whether two character classes have a character in common. Required for negated classes, which match
+// characters that do not appear in them.
+const fallbackClassChars = "aZ0_-."
+
+// globToken is a single element of a glob pattern segment: '*', '?', a character class or a literal character.
+type globToken struct {
+	// kind is '*', '?' or '[' for the corresponding elements and 0 for a literal character
+	kind byte
+	// value is the literal character or the character class including its brackets
+	value string
+}
+
+// globsOverlap returns true if there is a path that is matched by both of the provided glob patterns (which must be
+// valid filepath.Match patterns). Since '*' does not match the path separator, patterns can only match the same path
+// if they have the same number of path elements and the patterns of every element overlap.
+func globsOverlap(a, b string) bool {
+	aSegments, bSegments := strings.Split(a, "/"), strings.Split(b, "/")
+	if len(aSegments) != len(bSegments) {
+		return false
+	}
+	for i := range aSegments {
+		if !segmentsOverlap(globTokens(aSegments[i]), globTokens(bSegments[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// segmentsOverlap returns true if there is a string that is matched by both of the provided tokenized patterns.
+func segmentsOverlap(a, b []globToken) bool {
+	// reachable[i][j] is true if a[:i] and b[:j] can match the same string
+	reachable := make([][]bool, len(a)+1)
+	for i := range reachable {
+		reachable[i] = make([]bool, len(b)+1)
+	}
+	reachable[0][0] = true
+	for i := 0; i <= len(a); i++ {
+		for j := 0; j <= len(b); j++ {
+			if !reachable[i][j] {
+				continue
+			}
+			// '*' may match the empty string
+			if i < len(a) && a[i].kind == '*' {
+				reachable[i+1][j] = true
+			}
+			if j < len(b) && b[j].kind == '*' {
+				reachable[i][j+1] = true
+			}
+			if i == len(a) || j == len(b) {
+				continue
+			}
+			switch {
+			case a[i].kind == '*' && b[j].kind == '*':
+			case a[i].kind == '*':
+				// '*' matches the character matched by b[j] and may match more
+				reachable[i][j+1] = true
+			case b[j].kind == '*':
+				reachable[i+1][j] = true
+			case tokensOverlap(a[i], b[j]):
+				reachable[i+1][j+1] = true
+			}
+		}
+	}
+	return reachable[len(a)][len(b)]
+}
+
+// tokensOverlap returns true if there is a character that is matched by both of the provided single character tokens.
+func tokensOverlap(a, b globToken) bool {
+	if a.kind == 0 {
+		return tokenMatches(b, a.value)
+	}
+	if b.kind == 0 {
+		return tokenMatches(a, b.value)
+	}
+	var candidates []rune
+	for _, r := range a.value + b.value + fallbackClassChars {
+		candidates = append(candidates, r-1, r, r+1)
+	}
+	for _, r := range candidates {
+		if c := string(r); utf8.ValidString(c) && tokenMatches(a, c) && tokenMatches(b, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenMatches returns true if the provided single character token matches the provided character.
+func tokenMatches(token globToken, c string) bool {
+	switch token.kind {
+	case 0:
+		return token.value == c
+	case '?':
+		return c != "/"
+	default:
+		match, _ := filepath.Match(token.value, c)
+		return match
+	}
+}
+
+// globTokens splits the provided glob pattern segment (which must be valid) into tokens. Escaped characters are
+// returned as literal characters.
+func globTokens(segment string) []globToken {
+	var tokens []globToken
+	for len(segment) > 0 {
+		switch segment[0] {
+		case '*':
+			// consecutive stars are equivalent to a single star
+			if len(tokens) == 0 || tokens[len(tokens)-1].kind != '*' {
+				tokens = append(tokens, globToken{kind: '*'})
+			}
+			segment = segment[1:]
+		case '?':
+			tokens = append(tokens, globToken{kind: '?'})
+			segment = segment[1:]
+		case '[':
+			end := 1
+			for end < len(segment) && segment[end] != ']' {
+				if segment[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			tokens = append(tokens, globToken{kind: '[', value: segment[:end+1]})
+			segment = segment[end+1:]
+		default:
+			if segment[0] == '\\' && len(segment) > 1 {
+				segment = segment[1:]
+			}
+			_, size := utf8.DecodeRuneInString(segment)
+			tokens = append(tokens, globToken{value: segment[:size]})
+			segment = segment[size:]
+		}
+	}
+	return tokens
+}
@@ -15,24 +15,84 @@
 package golicense
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/palantir/pkg/matcher"
 	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 func LicenseFiles(files []string, params LicenseParams, modify bool) ([]string, error) {
-	return processFiles(files, params, modify, applyLicenseToFiles)
+	changes, err := processFiles(files, params, modify, params.Placement.applyLicense)
+	if err != nil {
+		return nil, err
+	}
+	return changedPaths(changes), nil
 }
 
 func UnlicenseFiles(files []string, params LicenseParams, modify bool) ([]string, error) {
-	return processFiles(files, params, modify, removeLicenseFromFiles)
+	changes, err := processFiles(files, params, modify, params.Placement.removeLicense)
+	if err != nil {
+		return nil, err
+	}
+	return changedPaths(changes), nil
+}
+
+// LicenseFilesDiff returns a unified diff of the changes that LicenseFiles would make to the provided files. Files are
+// not modified. Returns an empty string if no files would be changed.
+func LicenseFilesDiff(files []string, params LicenseParams) (string, error) {
+	changes, err := processFiles(files, params, false, params.Placement.applyLicense)
+	if err != nil {
+		return "", err
+	}
+	return diffChanges(changes)
+}
+
+// UnlicenseFilesDiff returns a unified diff of the changes that UnlicenseFiles would make to the provided files. Files
+// are not modified. Returns an empty string if no files would be changed.
+func UnlicenseFilesDiff(files []string, params LicenseParams) (string, error) {
+	changes, err := processFiles(files, params, false, params.Placement.removeLicense)
+	if err != nil {
+		return "", err
+	}
+	return diffChanges(changes)
+}
+
+// fileChange records the change that was made (or would be made) to the content of a file.
+type fileChange struct {
+	path       string
+	oldContent string
+	newContent string
+}
+
+// fileAction returns the new content for a file with the provided content given the license headers that apply to the
+// file and whether the new content differs from the provided content. The first header is the canonical header and the
+// remaining headers are the alternate forms of the header that are also accepted.
+type fileAction func(content string, headers []string) (string, bool)
+
+func processFiles(files []string, params LicenseParams, modify bool, action fileAction) ([]fileChange, error) {
+	// all files that were modified (or would have been modified)
+	modified, err := visitFiles(filesToVisit(files, params), modify, action)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(modified, func(i, j int) bool {
+		return modified[i].path < modified[j].path
+	})
+	return modified, nil
 }
 
-func processFiles(files []string, params LicenseParams, modify bool, f func(files []string, header string, modify bool) ([]string, error)) ([]string, error) {
+// filesToVisit returns the Go files in the provided files that are not excluded by the provided parameters along with
+// the headers that apply to each of them.
+func filesToVisit(files []string, params LicenseParams) []fileToVisit {
 	goFileMatcher := matcher.Name(`.*\.go`)
 	var goFiles []string
 	for _, f := range files {
@@ -44,109 +104,174 @@ func processFiles(files []string, params LicenseParams, modify bool, f func(file
 	// name of custom matcher -> files to process for the matcher
 	m := make(map[string][]string)
 	for _, f := range goFiles {
-		var longestMatcher string
-		longestMatchLen := 0
-		for _, v := range params.CustomHeaders.headers() {
-			for _, p := range v.IncludePaths {
-				if matcher.PathLiteral(p).Match(f) && len(p) >= longestMatchLen {
-					longestMatcher = v.Name
-					longestMatchLen = len(p)
-				}
-			}
-		}
 		// file may match multiple custom header params -- if that is the case, use the longest match. Allows
 		// for hierarchical matching.
-		if longestMatcher != "" {
-			m[longestMatcher] = append(m[longestMatcher], f)
+		if owner := params.CustomHeaders.owner(f); owner != "" {
+			m[owner] = append(m[owner], f)
 		}
 	}
 
+	// files to visit along with the headers that apply to them
+	var toVisit []fileToVisit
 	// all files that were processed (considered by a matcher)
 	processedFiles := make(map[string]struct{})
-	// all files that were modified (or would have been modified)
-	var modified []string
 
 	// process custom matchers
 	for _, v := range params.CustomHeaders.headers() {
-		currModified, err := f(m[v.Name], v.Header, modify)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to process headers for matcher %s", v.Name)
-		}
-		modified = append(modified, currModified...)
+		headers := append([]string{v.Header}, v.AcceptedHeaders...)
 		for _, f := range m[v.Name] {
+			toVisit = append(toVisit, fileToVisit{
+				path:    f,
+				headers: headers,
+				matcher: v.Name,
+			})
 			processedFiles[f] = struct{}{}
 		}
 	}
 
 	// process all "*.go" files not matched by custom matchers
-	var unprocessedGoFiles []string
+	headers := append([]string{params.Header}, params.AcceptedHeaders...)
 	for _, f := range goFiles {
 		if _, ok := processedFiles[f]; !ok {
-			unprocessedGoFiles = append(unprocessedGoFiles, f)
+			toVisit = append(toVisit, fileToVisit{
+				path:    f,
+				headers: headers,
+			})
 		}
 	}
-	currModified, err := f(unprocessedGoFiles, params.Header, modify)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to process headers for default *.go matcher")
-	}
-	modified = append(modified, currModified...)
-	for _, f := range currModified {
-		processedFiles[f] = struct{}{}
+
+	return toVisit
+}
+
+// matchingHeader returns the first of the provided headers that the content starts with (followed by a newline).
+// Returns false if the content does not start with any of the headers.
+func matchingHeader(content string, headers []string) (string, bool) {
+	for _, header := range headers {
+		if strings.HasPrefix(content, header+"\n") {
+			return header, true
+		}
 	}
+	return "", false
+}
 
-	sort.Strings(modified)
-	return modified, nil
+// fileToVisit is a file along with the license headers that apply to it.
+type fileToVisit struct {
+	path    string
+	headers []string
+	// matcher is the name of the custom header matcher that matched the file. Blank for the default *.go matcher.
+	matcher string
 }
 
-func applyLicenseToFiles(files []string, header string, modify bool) ([]string, error) {
-	return visitFiles(files, func(path string, fi os.FileInfo, content string) (bool, error) {
-		if !strings.HasPrefix(content, header+"\n") {
-			if modify {
-				content = header + "\n" + content
-				if err := ioutil.WriteFile(path, []byte(content), fi.Mode()); err != nil {
-					return false, errors.Wrapf(err, "failed to write file %s with new license", path)
+// visitFiles applies the provided action to the content of each of the provided files using the headers for the file
+// and returns the changes for the files whose content changed (in the order in which the files were provided). If
+// modify is true, the new content is written to the files. Files are processed concurrently using at most
+// runtime.NumCPU() goroutines. If processing any file fails, the error for the first such file is returned.
+func visitFiles(files []fileToVisit, modify bool, action fileAction) ([]fileChange, error) {
+	type result struct {
+		change *fileChange
+		err    error
+	}
+	results := make([]result, len(files))
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				change, err := visitFile(files[idx], modify, action)
+				results[idx] = result{
+					change: change,
+					err:    err,
 				}
 			}
-			return true, nil
+		}()
+	}
+	for i := range files {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	var modified []fileChange
+	for i, currResult := range results {
+		if currResult.err != nil {
+			if files[i].matcher != "" {
+				return nil, errors.Wrapf(currResult.err, "failed to process headers for matcher %s", files[i].matcher)
+			}
+			return nil, errors.Wrapf(currResult.err, "failed to process headers for default *.go matcher")
 		}
-		return false, nil
-	})
+		if currResult.change != nil {
+			modified = append(modified, *currResult.change)
+		}
+	}
+	return modified, nil
 }
 
-func removeLicenseFromFiles(files []string, header string, modify bool) ([]string, error) {
-	return visitFiles(files, func(path string, fi os.FileInfo, content string) (bool, error) {
-		if strings.HasPrefix(content, header+"\n") {
-			if modify {
-				content = strings.TrimPrefix(content, header+"\n")
-				if err := ioutil.WriteFile(path, []byte(content), fi.Mode()); err != nil {
-					return false, errors.Wrapf(err, "failed to write file %s with license removed", path)
-				}
-			}
-			return true, nil
+// visitFile applies the provided action to the content of the provided file and returns the change if the content
+// changed or nil otherwise. If modify is true, the new content is written to the file.
+func visitFile(f fileToVisit, modify bool, action fileAction) (*fileChange, error) {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat %s", f.path)
+	}
+	bytes, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", f.path)
+	}
+	content := string(bytes)
+	newContent, changed := action(content, f.headers)
+	if !changed {
+		return nil, nil
+	}
+	if modify {
+		if err := ioutil.WriteFile(f.path, []byte(newContent), fi.Mode()); err != nil {
+			return nil, errors.Wrapf(err, "failed to write file %s", f.path)
 		}
-		return false, nil
-	})
+	}
+	return &fileChange{
+		path:       f.path,
+		oldContent: content,
+		newContent: newContent,
+	}, nil
 }
 
-func visitFiles(files []string, visitor func(path string, fi os.FileInfo, content string) (bool, error)) ([]string, error) {
-	var modified []string
+func changedPaths(changes []fileChange) []string {
+	var paths []string
+	for _, change := range changes {
+		paths = append(paths, change.path)
+	}
+	return paths
+}
 
-	for _, f := range files {
-		fi, err := os.Stat(f)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to stat %s", f)
-		}
-		bytes, err := ioutil.ReadFile(f)
+// diffChanges returns the unified diffs of the provided changes. The diff for each file is preceded by a line of the
+// form "diff -u path.orig path".
+func diffChanges(changes []fileChange) (string, error) {
+	buf := &bytes.Buffer{}
+	for _, change := range changes {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(change.oldContent),
+			B:        splitLines(change.newContent),
+			FromFile: change.path + ".orig",
+			ToFile:   change.path,
+			Context:  3,
+		})
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to read %s", f)
-		}
-		content := string(bytes)
-		if changed, err := visitor(f, fi, content); err != nil {
-			return nil, errors.WithStack(err)
-		} else if changed {
-			modified = append(modified, f)
+			return "", errors.Wrapf(err, "failed to compute diff for %s", change.path)
 		}
+		fmt.Fprintf(buf, "diff -u %s.orig %s\n", change.path, change.path)
+		buf.WriteString(diff)
 	}
+	return buf.String(), nil
+}
 
-	return modified, nil
+// splitLines splits the provided content into lines that each end with a newline.
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
 }
@@ -15,8 +15,10 @@
 package golicense_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"testing"
 
@@ -246,6 +248,87 @@ package bar`,
 package main`,
 			},
 		},
+		{
+			name: "custom matchers match glob paths and names",
+			params: golicense.LicenseParams{
+				Header: `// Copyright 2016 Palantir Technologies, Inc.`,
+			},
+			customLicenses: []golicense.CustomLicenseParam{
+				{
+					Name:         "Custom Co.",
+					Header:       "// Copyright 2016 Custom Co.",
+					IncludePaths: []string{"*/custom"},
+				},
+				{
+					Name:         "Generated",
+					Header:       "// Copyright 2006 Generator Inc.",
+					IncludeNames: []string{`.+_gen\.go`},
+				},
+			},
+			goFiles: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo.go",
+					Src:     `package foo`,
+				},
+				{
+					RelPath: "foo/custom/custom.go",
+					Src:     `package custom`,
+				},
+				{
+					RelPath: "bar/custom/types_gen.go",
+					Src:     `package custom`,
+				},
+				{
+					RelPath: "bar/types_gen.go",
+					Src:     `package bar`,
+				},
+			},
+			wantModified: []string{
+				"bar/custom/types_gen.go",
+				"bar/types_gen.go",
+				"foo.go",
+				"foo/custom/custom.go",
+			},
+			wantContent: map[string]string{
+				"foo.go": `// Copyright 2016 Palantir Technologies, Inc.
+package foo`,
+				"foo/custom/custom.go": `// Copyright 2016 Custom Co.
+package custom`,
+				"bar/custom/types_gen.go": `// Copyright 2006 Generator Inc.
+package custom`,
+				"bar/types_gen.go": `// Copyright 2006 Generator Inc.
+package bar`,
+			},
+		},
+		{
+			name: "license not applied to files that have an accepted header",
+			params: golicense.LicenseParams{
+				Header: `// Copyright 2017 Palantir Technologies, Inc.`,
+				AcceptedHeaders: []string{
+					`// Copyright 2016 Palantir Technologies, Inc.`,
+				},
+			},
+			goFiles: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo.go",
+					Src:     `package foo`,
+				},
+				{
+					RelPath: "bar/bar.go",
+					Src: `// Copyright 2016 Palantir Technologies, Inc.
+package bar`,
+				},
+			},
+			wantModified: []string{
+				"foo.go",
+			},
+			wantContent: map[string]string{
+				"foo.go": `// Copyright 2017 Palantir Technologies, Inc.
+package foo`,
+				"bar/bar.go": `// Copyright 2016 Palantir Technologies, Inc.
+package bar`,
+			},
+		},
 	} {
 		currTmpDir, err := ioutil.TempDir(tmpDir, "")
 		require.NoError(t, err, "Case %d: %s", i, currCase.name)
@@ -396,6 +479,35 @@ package bar`,
 package bar`,
 			},
 		},
+		{
+			name: "unlicense removes accepted headers",
+			params: golicense.LicenseParams{
+				Header: `// Copyright 2017 Palantir Technologies, Inc.`,
+				AcceptedHeaders: []string{
+					`// Copyright 2016 Palantir Technologies, Inc.`,
+				},
+			},
+			goFiles: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo.go",
+					Src: `// Copyright 2017 Palantir Technologies, Inc.
+package foo`,
+				},
+				{
+					RelPath: "bar/bar.go",
+					Src: `// Copyright 2016 Palantir Technologies, Inc.
+package bar`,
+				},
+			},
+			wantModified: []string{
+				"bar/bar.go",
+				"foo.go",
+			},
+			wantContent: map[string]string{
+				"foo.go":     `package foo`,
+				"bar/bar.go": `package bar`,
+			},
+		},
 		{
 			name: "custom license removed from files that match custom matchers",
 			params: golicense.LicenseParams{
@@ -472,6 +584,517 @@ package baz`,
 	}
 }
 
+func TestLicenseFilesDirectivePlacement(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	originalWd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		if err := os.Chdir(originalWd); err != nil {
+			require.NoError(t, err)
+		}
+	}()
+
+	const header = "// Copyright 2016 Palantir Technologies, Inc.\n"
+	for i, currCase := range []struct {
+		name        string
+		placement   golicense.DirectivePlacement
+		content     string
+		wantContent string
+	}{
+		{
+			name:        "header placed after shebang by default",
+			content:     "#!/usr/bin/env gorun\npackage main\n",
+			wantContent: "#!/usr/bin/env gorun\n" + header + "\npackage main\n",
+		},
+		{
+			name:        "header placed before shebang",
+			placement:   golicense.DirectivePlacement{Shebang: golicense.PlacementBefore},
+			content:     "#!/usr/bin/env gorun\npackage main\n",
+			wantContent: header + "\n#!/usr/bin/env gorun\npackage main\n",
+		},
+		{
+			name:        "shebang only recognized on first line",
+			content:     "package main\n#!/usr/bin/env gorun\n",
+			wantContent: header + "\npackage main\n#!/usr/bin/env gorun\n",
+		},
+		{
+			name:        "header placed before build constraints by default",
+			content:     "//go:build linux\n// +build linux\n\npackage foo\n",
+			wantContent: header + "\n//go:build linux\n// +build linux\n\npackage foo\n",
+		},
+		{
+			name:        "header placed after build constraints and the blank lines that follow them",
+			placement:   golicense.DirectivePlacement{Build: golicense.PlacementAfter},
+			content:     "//go:build linux\n// +build linux\n\npackage foo\n",
+			wantContent: "//go:build linux\n// +build linux\n\n" + header + "\npackage foo\n",
+		},
+		{
+			name:        "header placed before go:generate directives by default",
+			content:     "//go:generate go run gen.go\npackage foo\n",
+			wantContent: header + "\n//go:generate go run gen.go\npackage foo\n",
+		},
+		{
+			name:        "header placed after go:generate directives",
+			placement:   golicense.DirectivePlacement{Generate: golicense.PlacementAfter},
+			content:     "//go:generate go run gen.go\n//go:generate stringer -type=Kind\npackage foo\n",
+			wantContent: "//go:generate go run gen.go\n//go:generate stringer -type=Kind\n" + header + "\npackage foo\n",
+		},
+		{
+			name: "header placed after all leading directives that are placed after it",
+			placement: golicense.DirectivePlacement{
+				Build:    golicense.PlacementAfter,
+				Generate: golicense.PlacementAfter,
+			},
+			content:     "#!/usr/bin/env gorun\n//go:build ignore\n\n//go:generate go run gen.go\npackage main\n",
+			wantContent: "#!/usr/bin/env gorun\n//go:build ignore\n\n//go:generate go run gen.go\n" + header + "\npackage main\n",
+		},
+		{
+			name:        "header placed before directives that are placed before it",
+			placement:   golicense.DirectivePlacement{Generate: golicense.PlacementAfter},
+			content:     "//go:build ignore\n\n//go:generate go run gen.go\npackage main\n",
+			wantContent: header + "\n//go:build ignore\n\n//go:generate go run gen.go\npackage main\n",
+		},
+	} {
+		currTmpDir, err := ioutil.TempDir(tmpDir, "")
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		err = os.Chdir(currTmpDir)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		file := "foo.go"
+		err = ioutil.WriteFile(file, []byte(currCase.content), 0644)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+
+		customHeaders, err := golicense.NewCustomLicenseParams(nil)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		params := golicense.LicenseParams{
+			Header:        header,
+			CustomHeaders: customHeaders,
+			Placement:     currCase.placement,
+		}
+
+		modified, err := golicense.LicenseFiles([]string{file}, params, true)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		assert.Equal(t, []string{file}, modified, "Case %d: %s", i, currCase.name)
+		bytes, err := ioutil.ReadFile(file)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		assert.Equal(t, currCase.wantContent, string(bytes), "Case %d: %s", i, currCase.name)
+
+		// the header is found in its placement when verifying
+		modified, err = golicense.LicenseFiles([]string{file}, params, false)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		assert.Empty(t, modified, "Case %d: %s", i, currCase.name)
+
+		reports, err := golicense.ReportFiles([]string{file}, params)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		require.Equal(t, 1, len(reports), "Case %d: %s", i, currCase.name)
+		assert.Equal(t, golicense.DefaultHeaderName, reports[0].Header, "Case %d: %s", i, currCase.name)
+
+		// removing the header restores the original content
+		_, err = golicense.UnlicenseFiles([]string{file}, params, true)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		bytes, err = ioutil.ReadFile(file)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		assert.Equal(t, currCase.content, string(bytes), "Case %d: %s", i, currCase.name)
+	}
+}
+
+func TestValidateDirectivePlacement(t *testing.T) {
+	assert.NoError(t, golicense.DirectivePlacement{}.Validate())
+	assert.NoError(t, golicense.DirectivePlacement{
+		Shebang:  golicense.PlacementBefore,
+		Build:    golicense.PlacementAfter,
+		Generate: golicense.PlacementAfter,
+	}.Validate())
+	assert.EqualError(t, golicense.DirectivePlacement{Generate: "below"}.Validate(), `invalid placement "below" for generate directives: must be "before" or "after"`)
+}
+
+func TestLicenseFilesDiff(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	originalWd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		if err := os.Chdir(originalWd); err != nil {
+			require.NoError(t, err)
+		}
+	}()
+	err = os.Chdir(tmpDir)
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     "package foo\n",
+		},
+		{
+			RelPath: "bar.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage bar\n",
+		},
+	})
+	require.NoError(t, err)
+	fooPath := "foo.go"
+	barPath := "bar.go"
+
+	customHeaders, err := golicense.NewCustomLicenseParams(nil)
+	require.NoError(t, err)
+	params := golicense.LicenseParams{
+		Header:        "// Copyright 2016 Palantir Technologies, Inc.",
+		CustomHeaders: customHeaders,
+	}
+
+	diff, err := golicense.LicenseFilesDiff([]string{fooPath, barPath}, params)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`diff -u %s.orig %s
+--- %s.orig
++++ %s
+@@ -1 +1,2 @@
++// Copyright 2016 Palantir Technologies, Inc.
+ package foo
+`, fooPath, fooPath, fooPath, fooPath), diff)
+
+	diff, err = golicense.UnlicenseFilesDiff([]string{fooPath, barPath}, params)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`diff -u %s.orig %s
+--- %s.orig
++++ %s
+@@ -1,2 +1 @@
+-// Copyright 2016 Palantir Technologies, Inc.
+ package bar
+`, barPath, barPath, barPath, barPath), diff)
+
+	// files are not modified
+	bytes, err := ioutil.ReadFile(fooPath)
+	require.NoError(t, err)
+	assert.Equal(t, "package foo\n", string(bytes))
+}
+
+func TestReportFiles(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	originalWd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		if err := os.Chdir(originalWd); err != nil {
+			require.NoError(t, err)
+		}
+	}()
+	err = os.Chdir(tmpDir)
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage foo\n",
+		},
+		{
+			RelPath: "bar.go",
+			Src:     "// Copyright (c) 2016 Palantir Technologies, Inc.\npackage bar\n",
+		},
+		{
+			RelPath: "baz.go",
+			Src:     "// Copyright 2016 Unknown, Inc.\npackage baz\n",
+		},
+		{
+			RelPath: "acme/acme.go",
+			Src:     "// Copyright 2016 Acme, Inc.\npackage acme\n",
+		},
+		{
+			RelPath: "acme/palantir.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage acme\n",
+		},
+		{
+			RelPath: "excluded/excluded.go",
+			Src:     "package excluded\n",
+		},
+	})
+	require.NoError(t, err)
+
+	customHeaders, err := golicense.NewCustomLicenseParams([]golicense.CustomLicenseParam{
+		{
+			Name:         "acme",
+			Header:       "// Copyright 2016 Acme, Inc.",
+			IncludePaths: []string{"acme"},
+		},
+	})
+	require.NoError(t, err)
+	params := golicense.LicenseParams{
+		Header:          "// Copyright 2016 Palantir Technologies, Inc.",
+		AcceptedHeaders: []string{"// Copyright (c) 2016 Palantir Technologies, Inc."},
+		CustomHeaders:   customHeaders,
+		Exclude:         matcher.Name("excluded"),
+	}
+
+	reports, err := golicense.ReportFiles([]string{"foo.go", "bar.go", "baz.go", "acme/acme.go", "acme/palantir.go", "excluded/excluded.go"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, []golicense.FileReport{
+		{Path: "acme/acme.go", Owner: "acme", Header: "acme"},
+		{Path: "acme/palantir.go", Owner: "acme", Header: golicense.DefaultHeaderName},
+		{Path: "bar.go", Owner: golicense.DefaultHeaderName, Header: golicense.DefaultHeaderName},
+		{Path: "baz.go", Owner: golicense.DefaultHeaderName, Header: golicense.UnknownHeaderName},
+		{Path: "foo.go", Owner: golicense.DefaultHeaderName, Header: golicense.DefaultHeaderName},
+	}, reports)
+
+	assert.Equal(t, []golicense.ReportSummary{
+		{Owner: "acme", Header: "acme", Files: 1},
+		{Owner: "acme", Header: golicense.DefaultHeaderName, Files: 1},
+		{Owner: golicense.DefaultHeaderName, Header: golicense.DefaultHeaderName, Files: 2},
+		{Owner: golicense.DefaultHeaderName, Header: golicense.UnknownHeaderName, Files: 1},
+	}, golicense.SummarizeReport(reports))
+}
+
+func TestSummarizeVerify(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	originalWd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		if err := os.Chdir(originalWd); err != nil {
+			require.NoError(t, err)
+		}
+	}()
+	err = os.Chdir(tmpDir)
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage foo\n",
+		},
+		{
+			RelPath: "bar.go",
+			Src:     "// Copyright (c) 2016 Palantir Technologies, Inc.\npackage bar\n",
+		},
+		{
+			RelPath: "baz.go",
+			Src:     "package baz\n",
+		},
+		{
+			RelPath: "acme/acme.go",
+			Src:     "// Copyright 2016 Acme, Inc.\npackage acme\n",
+		},
+		{
+			RelPath: "acme/palantir.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage acme\n",
+		},
+		{
+			RelPath: "excluded/excluded.go",
+			Src:     "package excluded\n",
+		},
+	})
+	require.NoError(t, err)
+
+	customHeaders, err := golicense.NewCustomLicenseParams([]golicense.CustomLicenseParam{
+		{
+			Name:         "acme",
+			Header:       "// Copyright 2016 Acme, Inc.",
+			IncludePaths: []string{"acme"},
+		},
+		{
+			Name:         "unused",
+			Header:       "// Copyright 2016 Unused, Inc.",
+			IncludePaths: []string{"unused"},
+		},
+	})
+	require.NoError(t, err)
+	params := golicense.LicenseParams{
+		Header:          "// Copyright 2016 Palantir Technologies, Inc.",
+		AcceptedHeaders: []string{"// Copyright (c) 2016 Palantir Technologies, Inc."},
+		CustomHeaders:   customHeaders,
+		Exclude:         matcher.Name("excluded"),
+	}
+
+	files := []string{"foo.go", "bar.go", "baz.go", "acme/acme.go", "acme/palantir.go", "excluded/excluded.go", "README.md"}
+	summary, err := golicense.SummarizeVerify(files, params)
+	require.NoError(t, err)
+	assert.Equal(t, golicense.VerifySummary{
+		Matchers: []golicense.MatcherSummary{
+			{Matcher: golicense.DefaultHeaderName, Compliant: 2, MissingHeader: 1},
+			{Matcher: "acme", Compliant: 1, WrongHeader: 1},
+			{Matcher: "unused"},
+		},
+		Excluded: 1,
+		Files:    []string{"acme/palantir.go", "baz.go"},
+	}, summary)
+	assert.False(t, summary.Passed())
+
+	// files are not modified
+	content, err := ioutil.ReadFile(path.Join(tmpDir, "baz.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package baz\n", string(content))
+}
+
+func TestStaleYearFiles(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "old.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage old\n",
+		},
+		{
+			RelPath: "current.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage current\n",
+		},
+		{
+			RelPath: "foo/range.go",
+			Src:     "/*\nCopyright (c) 2014-2016 Palantir Technologies, Inc.\n*/\npackage foo\n",
+		},
+		{
+			RelPath: "foo/noheader.go",
+			Src:     "package foo\n\n// Copyright 2016 is not a header\n",
+		},
+		{
+			RelPath: "excluded/excluded.go",
+			Src:     "// Copyright 2016 Palantir Technologies, Inc.\npackage excluded\n",
+		},
+	})
+	require.NoError(t, err)
+
+	// appends a newline to each of the provided files and commits them (or all files if none are provided) with the
+	// provided date
+	gitCommit := func(date string, files ...string) {
+		for _, f := range files {
+			content, err := ioutil.ReadFile(path.Join(tmpDir, f))
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(path.Join(tmpDir, f), append(content, '\n'), 0644))
+		}
+		if len(files) == 0 {
+			files = []string{"."}
+		}
+		for _, args := range [][]string{
+			append([]string{"add"}, files...),
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "commit"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = tmpDir
+			cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(output))
+		}
+	}
+	cmd := exec.Command("git", "init")
+	cmd.Dir = tmpDir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	gitCommit("2016-06-01T00:00:00Z")
+	gitCommit("2018-06-01T00:00:00Z", "old.go", "foo/range.go", "foo/noheader.go", "excluded/excluded.go")
+	gitCommit("2016-12-01T00:00:00Z", "current.go")
+
+	years, err := golicense.LastModifiedYears(path.Join(tmpDir, "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"range.go": 2018, "noheader.go": 2018}, years)
+
+	years, err = golicense.LastModifiedYears(tmpDir)
+	require.NoError(t, err)
+
+	originalWd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		if err := os.Chdir(originalWd); err != nil {
+			require.NoError(t, err)
+		}
+	}()
+	err = os.Chdir(tmpDir)
+	require.NoError(t, err)
+
+	files := []string{"old.go", "current.go", "foo/range.go", "foo/noheader.go", "excluded/excluded.go"}
+	customHeaders, err := golicense.NewCustomLicenseParams(nil)
+	require.NoError(t, err)
+	params := golicense.LicenseParams{
+		CustomHeaders: customHeaders,
+		Exclude:       matcher.Name("excluded"),
+	}
+	want := []golicense.StaleYear{
+		{Path: "foo/range.go", HeaderYear: 2016, LastModifiedYear: 2018},
+		{Path: "old.go", HeaderYear: 2016, LastModifiedYear: 2018},
+	}
+
+	stale, err := golicense.StaleYearFiles(files, params, years, false)
+	require.NoError(t, err)
+	assert.Equal(t, want, stale)
+	assert.Equal(t, "old.go: copyright year 2016 is older than last modified year 2018", stale[1].String())
+
+	stale, err = golicense.StaleYearFiles(files, params, years, true)
+	require.NoError(t, err)
+	assert.Equal(t, want, stale)
+	for f, wantContent := range map[string]string{
+		"old.go":               "// Copyright 2016-2018 Palantir Technologies, Inc.\npackage old\n\n",
+		"current.go":           "// Copyright 2016 Palantir Technologies, Inc.\npackage current\n\n",
+		"foo/range.go":         "/*\nCopyright (c) 2014-2018 Palantir Technologies, Inc.\n*/\npackage foo\n\n",
+		"foo/noheader.go":      "package foo\n\n// Copyright 2016 is not a header\n\n",
+		"excluded/excluded.go": "// Copyright 2016 Palantir Technologies, Inc.\npackage excluded\n\n",
+	} {
+		content, err := ioutil.ReadFile(f)
+		require.NoError(t, err)
+		assert.Equal(t, wantContent, string(content), f)
+	}
+
+	stale, err = golicense.StaleYearFiles(files, params, years, false)
+	require.NoError(t, err)
+	assert.Empty(t, stale)
+}
+
+func TestLicenseFilesManyFiles(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	originalWd, err := os.Getwd()
+	require.NoError(t, err)
+	defer func() {
+		if err := os.Chdir(originalWd); err != nil {
+			require.NoError(t, err)
+		}
+	}()
+	err = os.Chdir(tmpDir)
+	require.NoError(t, err)
+
+	var specs []gofiles.GoFileSpec
+	var want []string
+	for i := 0; i < 250; i++ {
+		relPath := fmt.Sprintf("pkg%03d/file.go", i)
+		src := "package foo"
+		if i%2 == 0 {
+			src = "// Copyright 2016 Palantir Technologies, Inc.\npackage foo"
+		} else {
+			want = append(want, relPath)
+		}
+		specs = append(specs, gofiles.GoFileSpec{
+			RelPath: relPath,
+			Src:     src,
+		})
+	}
+	_, err = gofiles.Write(tmpDir, specs)
+	require.NoError(t, err)
+
+	files, err := matcher.ListFiles(tmpDir, matcher.Name(`.+`), nil)
+	require.NoError(t, err)
+
+	customHeaders, err := golicense.NewCustomLicenseParams(nil)
+	require.NoError(t, err)
+	params := golicense.LicenseParams{
+		Header:        "// Copyright 2016 Palantir Technologies, Inc.",
+		CustomHeaders: customHeaders,
+	}
+
+	// results are aggregated deterministically even though files are processed concurrently
+	for i := 0; i < 3; i++ {
+		modified, err := golicense.LicenseFiles(files, params, false)
+		require.NoError(t, err)
+		assert.Equal(t, want, modified)
+	}
+}
+
 func TestValidateCustomLicenseParams(t *testing.T) {
 	for i, currCase := range []struct {
 		name           string
@@ -490,7 +1113,7 @@ func TestValidateCustomLicenseParams(t *testing.T) {
 					IncludePaths: []string{""},
 				},
 			},
-			wantErr: "custom header entries have blank names: [{Name: Header:// Header IncludePaths:[]}]",
+			wantErr: "custom header entries have blank names: [{Name: Header:// Header AcceptedHeaders:[] IncludePaths:[] IncludeNames:[]}]",
 		},
 		{
 			name: "non-unique custom configuration names invalid",
@@ -506,7 +1129,7 @@ func TestValidateCustomLicenseParams(t *testing.T) {
 					IncludePaths: []string{""},
 				},
 			},
-			wantErr: "multiple custom header entries have the same name:\n\tfoo: [{Name:foo Header:// Header IncludePaths:[]} {Name:foo Header:// Header IncludePaths:[]}]",
+			wantErr: "multiple custom header entries have the same name:\n\tfoo: [{Name:foo Header:// Header AcceptedHeaders:[] IncludePaths:[] IncludeNames:[]} {Name:foo Header:// Header AcceptedHeaders:[] IncludePaths:[] IncludeNames:[]}]",
 		},
 		{
 			name: "custom configurations with same paths invalid",
@@ -544,6 +1167,75 @@ func TestValidateCustomLicenseParams(t *testing.T) {
 			},
 			wantErr: "the same path is defined by multiple custom header entries:\n\tbar: foo, bar, collides",
 		},
+		{
+			name: "custom configurations with globs that do not overlap valid",
+			customLicenses: []golicense.CustomLicenseParam{
+				{
+					Name:         "foo",
+					Header:       "// Header",
+					IncludePaths: []string{"foo/*", "[a-c]*"},
+				},
+				{
+					Name:         "bar",
+					Header:       "// Header",
+					IncludePaths: []string{"bar/*", "foo", "foo/*/baz", "[d-f]*"},
+				},
+			},
+		},
+		{
+			name: "custom configurations with overlapping globs invalid",
+			customLicenses: []golicense.CustomLicenseParam{
+				{
+					Name:         "foo",
+					Header:       "// Header",
+					IncludePaths: []string{"foo/*", "[a-m]?"},
+				},
+				{
+					Name:         "bar",
+					Header:       "// Header",
+					IncludePaths: []string{"*/bar", "[h-z]*"},
+				},
+			},
+			wantErr: "paths defined by multiple custom header entries can match the same path:\n\tfoo/* (foo) and */bar (bar)\n\t[a-m]? (foo) and [h-z]* (bar)",
+		},
+		{
+			name: "custom configurations with same names invalid",
+			customLicenses: []golicense.CustomLicenseParam{
+				{
+					Name:         "foo",
+					Header:       "// Header",
+					IncludeNames: []string{`.+_gen\.go`},
+				},
+				{
+					Name:         "bar",
+					Header:       "// Header",
+					IncludeNames: []string{`.+_gen\.go`},
+				},
+			},
+			wantErr: "the same name is defined by multiple custom header entries:\n\t.+_gen\\.go: foo, bar",
+		},
+		{
+			name: "invalid path pattern",
+			customLicenses: []golicense.CustomLicenseParam{
+				{
+					Name:         "foo",
+					Header:       "// Header",
+					IncludePaths: []string{"foo/[a"},
+				},
+			},
+			wantErr: `custom header entry foo has an invalid path pattern "foo/[a"`,
+		},
+		{
+			name: "invalid name pattern",
+			customLicenses: []golicense.CustomLicenseParam{
+				{
+					Name:         "foo",
+					Header:       "// Header",
+					IncludeNames: []string{"foo("},
+				},
+			},
+			wantErr: "custom header entry foo has an invalid name pattern \"foo(\": error parsing regexp: missing closing ): `foo(`",
+		},
 	} {
 		_, err := golicense.NewCustomLicenseParams(currCase.customLicenses)
 		if currCase.wantErr == "" {
@@ -16,6 +16,9 @@ package golicense
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -28,6 +31,11 @@ type LicenseParams struct {
 	// by a newline.
 	Header string
 
+	// AcceptedHeaders specifies alternate forms of the license header that are also accepted when verifying licenses
+	// (for example, the previous wording of a header that is being migrated). Files that start with one of these
+	// headers are considered to be licensed, but "Header" is always used when applying licenses.
+	AcceptedHeaders []string
+
 	// CustomHeaders specifies the custom header parameters. Custom header parameters can be used to specify that
 	// certain directories or files in the project should use a header that is different from "Header".
 	CustomHeaders CustomLicenseParams
@@ -35,28 +43,72 @@ type LicenseParams struct {
 	// Exclude matches the files and directories that should be excluded from consideration for verifying or
 	// applying licenses.
 	Exclude matcher.Matcher
+
+	// Placement specifies where the header is placed relative to the directives at the top of a file.
+	Placement DirectivePlacement
 }
 
 type CustomLicenseParams interface {
 	Len() int
 	headers() []CustomLicenseParam
+	// owner returns the name of the custom header entry that applies to the provided file, or "" if no entry applies.
+	owner(file string) string
 }
 
-type customLicenseParams []CustomLicenseParam
+type customLicenseParams struct {
+	params []CustomLicenseParam
+	// names contains the compiled IncludeNames of the entry at the same index in params
+	names [][]*regexp.Regexp
+}
 
-func (p customLicenseParams) Len() int {
-	return len(p)
+func (p *customLicenseParams) Len() int {
+	return len(p.params)
 }
 
-func (p customLicenseParams) headers() []CustomLicenseParam {
-	return p
+func (p *customLicenseParams) headers() []CustomLicenseParam {
+	return p.params
 }
 
-func (p customLicenseParams) validate() error {
+// owner returns the name of the custom header entry whose include paths or names match the longest subpath of the
+// provided file. If multiple entries match the same subpath, the entry that is defined first is used. Returns "" if no
+// entry matches the file.
+func (p *customLicenseParams) owner(file string) string {
+	file = path.Clean(filepath.ToSlash(file))
+	if path.IsAbs(file) {
+		return ""
+	}
+	for currPath := file; currPath != "."; currPath = path.Dir(currPath) {
+		for i, v := range p.params {
+			if matchesSubpath(currPath, v.IncludePaths, p.names[i]) {
+				return v.Name
+			}
+		}
+	}
+	return ""
+}
+
+// matchesSubpath returns true if the provided subpath matches one of the provided glob patterns or if its last element
+// fully matches one of the provided regular expressions.
+func matchesSubpath(subpath string, globs []string, names []*regexp.Regexp) bool {
+	for _, glob := range globs {
+		if match, _ := filepath.Match(glob, subpath); match {
+			return true
+		}
+	}
+	name := path.Base(subpath)
+	for _, currRegexp := range names {
+		if loc := currRegexp.FindStringIndex(name); loc != nil && loc[0] == 0 && loc[1] == len(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *customLicenseParams) validate() error {
 	var emptyNameParams []CustomLicenseParam
 	nameToParams := make(map[string][]CustomLicenseParam)
 
-	for _, v := range p {
+	for _, v := range p.params {
 		if v.Name == "" {
 			emptyNameParams = append(emptyNameParams, v)
 		}
@@ -77,34 +129,86 @@ func (p customLicenseParams) validate() error {
 		return errors.Errorf(strings.Join(append([]string{"multiple custom header entries have the same name:"}, nameCollisionMsgs...), "\n\t"))
 	}
 
-	// map from path to custom header entries that have the path
-	pathsToCustomEntries := make(map[string][]string)
-	for _, ch := range p {
-		for _, path := range ch.IncludePaths {
-			pathsToCustomEntries[path] = append(pathsToCustomEntries[path], ch.Name)
+	for _, ch := range p.params {
+		for _, glob := range ch.IncludePaths {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return errors.Errorf("custom header entry %s has an invalid path pattern %q", ch.Name, glob)
+			}
 		}
 	}
-	var customPathCollisionMsgs []string
-	sortedKeys := make([]string, 0, len(pathsToCustomEntries))
-	for k := range pathsToCustomEntries {
+
+	if err := validateNoDuplicates("path", p.params, func(ch CustomLicenseParam) []string {
+		return ch.IncludePaths
+	}); err != nil {
+		return err
+	}
+	if err := validateNoDuplicates("name", p.params, func(ch CustomLicenseParam) []string {
+		return ch.IncludeNames
+	}); err != nil {
+		return err
+	}
+
+	// distinct path patterns of different entries that can match the same path
+	var overlapMsgs []string
+	for i, ch := range p.params {
+		for _, otherCh := range p.params[i+1:] {
+			for _, glob := range ch.IncludePaths {
+				for _, otherGlob := range otherCh.IncludePaths {
+					if glob != otherGlob && globsOverlap(glob, otherGlob) {
+						overlapMsgs = append(overlapMsgs, fmt.Sprintf("%s (%s) and %s (%s)", glob, ch.Name, otherGlob, otherCh.Name))
+					}
+				}
+			}
+		}
+	}
+	if len(overlapMsgs) > 0 {
+		return errors.Errorf(strings.Join(append([]string{"paths defined by multiple custom header entries can match the same path:"}, overlapMsgs...), "\n\t"))
+	}
+	return nil
+}
+
+// validateNoDuplicates returns an error if the same value (as returned by the provided function) is defined by multiple
+// custom header entries. The kind of value is used in the error message.
+func validateNoDuplicates(kind string, params []CustomLicenseParam, values func(CustomLicenseParam) []string) error {
+	// map from value to custom header entries that have the value
+	valuesToCustomEntries := make(map[string][]string)
+	for _, ch := range params {
+		for _, v := range values(ch) {
+			valuesToCustomEntries[v] = append(valuesToCustomEntries[v], ch.Name)
+		}
+	}
+	var collisionMsgs []string
+	sortedKeys := make([]string, 0, len(valuesToCustomEntries))
+	for k := range valuesToCustomEntries {
 		sortedKeys = append(sortedKeys, k)
 	}
 	sort.Strings(sortedKeys)
 	for _, k := range sortedKeys {
-		v := pathsToCustomEntries[k]
+		v := valuesToCustomEntries[k]
 		if len(v) > 1 {
-			customPathCollisionMsgs = append(customPathCollisionMsgs, fmt.Sprintf("%s: %s", k, strings.Join(v, ", ")))
+			collisionMsgs = append(collisionMsgs, fmt.Sprintf("%s: %s", k, strings.Join(v, ", ")))
 		}
 	}
-	if len(customPathCollisionMsgs) > 0 {
-		return errors.Errorf(strings.Join(append([]string{"the same path is defined by multiple custom header entries:"}, customPathCollisionMsgs...), "\n\t"))
+	if len(collisionMsgs) > 0 {
+		return errors.New(strings.Join(append([]string{fmt.Sprintf("the same %s is defined by multiple custom header entries:", kind)}, collisionMsgs...), "\n\t"))
 	}
-
 	return nil
 }
 
 func NewCustomLicenseParams(customHeaders []CustomLicenseParam) (CustomLicenseParams, error) {
-	params := customLicenseParams(customHeaders)
+	params := &customLicenseParams{
+		params: customHeaders,
+		names:  make([][]*regexp.Regexp, len(customHeaders)),
+	}
+	for i, ch := range customHeaders {
+		for _, name := range ch.IncludeNames {
+			currRegexp, err := regexp.Compile(name)
+			if err != nil {
+				return nil, errors.Wrapf(err, "custom header entry %s has an invalid name pattern %q", ch.Name, name)
+			}
+			params.names[i] = append(params.names[i], currRegexp)
+		}
+	}
 	if err := params.validate(); err != nil {
 		return nil, err
 	}
@@ -119,8 +223,20 @@ type CustomLicenseParam struct {
 	// by a newline.
 	Header string
 
-	// IncludePaths specifies the paths for which this custom license is applicable. If multiple custom parameters
-	// match a file or directory, the parameter with the longest path match is used. If multiple custom parameters
-	// match a file or directory exactly (match length is equal), it is treated as an error.
+	// AcceptedHeaders specifies alternate forms of the license header that are also accepted when verifying licenses.
+	// Files that start with one of these headers are considered to be licensed, but "Header" is always used when
+	// applying licenses.
+	AcceptedHeaders []string
+
+	// IncludePaths specifies the paths for which this custom license is applicable. Paths are glob patterns (same as
+	// filepath.Match) that match a file or directory and all of its subpaths. If multiple custom parameters match a
+	// file, the parameter that matches the longest subpath of the file is used. It is an error for multiple custom
+	// parameters to define the same path or paths that can match the same path.
 	IncludePaths []string
+
+	// IncludeNames specifies regular expressions that match the names of the files and directories (and all of their
+	// subpaths) for which this custom license is applicable. A name must fully match the expression. Name matches take
+	// part in longest match selection like path matches. It is an error for multiple custom parameters to define the
+	// same name expression.
+	IncludeNames []string
 }
@@ -0,0 +1,126 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golicense
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Placement specifies whether the license header is placed before or after a directive at the top of a file.
+type Placement string
+
+const (
+	PlacementBefore Placement = "before"
+	PlacementAfter  Placement = "after"
+)
+
+// DirectivePlacement specifies where the license header is placed relative to the directives that can appear at the
+// top of a file. The directives that are placed after the header are kept at the top of the file when the header is
+// applied, and the header is expected to follow them when licenses are verified or removed. A blank value uses the
+// default placement for the directive: the header is placed after a shebang line (since the shebang must be the first
+// line of the file for it to take effect) and before build constraints and go:generate directives (since these are
+// valid anywhere before the package clause).
+type DirectivePlacement struct {
+	// Shebang is the placement of the header relative to a first line that starts with "#!".
+	Shebang Placement
+
+	// Build is the placement of the header relative to build constraints ("//go:build" and "// +build" lines).
+	Build Placement
+
+	// Generate is the placement of the header relative to "//go:generate" directives.
+	Generate Placement
+}
+
+// Validate returns an error if any of the placements is not blank, PlacementBefore or PlacementAfter.
+func (p DirectivePlacement) Validate() error {
+	for _, currPlacement := range []struct {
+		directive string
+		placement Placement
+	}{
+		{"shebang", p.Shebang},
+		{"build", p.Build},
+		{"generate", p.Generate},
+	} {
+		switch currPlacement.placement {
+		case "", PlacementBefore, PlacementAfter:
+		default:
+			return errors.Errorf("invalid placement %q for %s directives: must be %q or %q", currPlacement.placement, currPlacement.directive, PlacementBefore, PlacementAfter)
+		}
+	}
+	return nil
+}
+
+// after returns true if the header is placed after the directive on the provided line.
+func (p DirectivePlacement) after(line string, firstLine bool) bool {
+	switch {
+	case firstLine && strings.HasPrefix(line, "#!"):
+		return p.Shebang != PlacementBefore
+	case strings.HasPrefix(line, "//go:build ") || strings.HasPrefix(line, "// +build "):
+		return p.Build == PlacementAfter
+	case strings.HasPrefix(line, "//go:generate "):
+		return p.Generate == PlacementAfter
+	}
+	return false
+}
+
+// split splits the provided content into the directives at the top of the content that are placed before the header
+// (along with the blank lines that follow them) and the remaining content. The first return value is empty if the
+// content does not start with such a directive.
+func (p DirectivePlacement) split(content string) (string, string) {
+	end := 0
+	directiveEnd := 0
+	for end < len(content) {
+		lineEnd := len(content)
+		if idx := strings.IndexByte(content[end:], '\n'); idx != -1 {
+			lineEnd = end + idx + 1
+		}
+		line := strings.TrimRight(content[end:lineEnd], "\r\n")
+		if directiveEnd > 0 && strings.TrimSpace(line) == "" {
+			end = lineEnd
+			continue
+		}
+		if !p.after(line, end == 0) {
+			break
+		}
+		end = lineEnd
+		directiveEnd = end
+	}
+	if directiveEnd == 0 {
+		return "", content
+	}
+	return content[:end], content[end:]
+}
+
+// applyLicense returns the provided content with the canonical header placed after the directives that precede it.
+// The content is returned unchanged if one of the headers already follows the directives.
+func (p DirectivePlacement) applyLicense(content string, headers []string) (string, bool) {
+	directives, rest := p.split(content)
+	if _, ok := matchingHeader(rest, headers); ok {
+		return content, false
+	}
+	return directives + headers[0] + "\n" + rest, true
+}
+
+// removeLicense returns the provided content with the header that follows the directives that precede it removed.
+func (p DirectivePlacement) removeLicense(content string, headers []string) (string, bool) {
+	directives, rest := p.split(content)
+	header, ok := matchingHeader(rest, headers)
+	if !ok {
+		return content, false
+	}
+	return directives + strings.TrimPrefix(rest, header+"\n"), true
+}
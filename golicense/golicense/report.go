@@ -0,0 +1,132 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golicense
+
+import (
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultHeaderName is the name used in reports for the default license header ("Header" in LicenseParams).
+	DefaultHeaderName = "default"
+	// UnknownHeaderName is the name used in reports for files that do not start with any of the configured headers.
+	UnknownHeaderName = "unknown header"
+)
+
+// FileReport describes the license header ownership of a single file.
+type FileReport struct {
+	// Path is the path to the file.
+	Path string `json:"path"`
+	// Owner is the name of the custom header entry whose include paths match the file, or DefaultHeaderName if the
+	// file is not matched by any custom header entry.
+	Owner string `json:"owner"`
+	// Header is the name of the configured header (DefaultHeaderName or the name of a custom header entry) that the
+	// file starts with, or UnknownHeaderName if the file does not start with any of the configured headers. Accepted
+	// forms of a header are reported as that header.
+	Header string `json:"header"`
+}
+
+// ReportSummary is the number of files that have a particular owner and header.
+type ReportSummary struct {
+	Owner  string `json:"owner"`
+	Header string `json:"header"`
+	Files  int    `json:"files"`
+}
+
+// ReportFiles returns a report of the license header ownership of the provided files sorted by path. Only Go files that
+// are not excluded by the provided parameters are reported. Files are not modified.
+func ReportFiles(files []string, params LicenseParams) ([]FileReport, error) {
+	// name of header -> headers that are accepted for it. The default header is considered first, followed by the
+	// custom headers in the order in which they are defined.
+	headerNames := []string{DefaultHeaderName}
+	headers := map[string][]string{
+		DefaultHeaderName: append([]string{params.Header}, params.AcceptedHeaders...),
+	}
+	for _, v := range params.CustomHeaders.headers() {
+		headerNames = append(headerNames, v.Name)
+		headers[v.Name] = append([]string{v.Header}, v.AcceptedHeaders...)
+	}
+
+	var reports []FileReport
+	for _, f := range filesToVisit(files, params) {
+		bytes, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", f.path)
+		}
+		owner := f.matcher
+		if owner == "" {
+			owner = DefaultHeaderName
+		}
+		reports = append(reports, FileReport{
+			Path:   f.path,
+			Owner:  owner,
+			Header: detectHeader(string(bytes), owner, headerNames, headers, params.Placement),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Path < reports[j].Path
+	})
+	return reports, nil
+}
+
+// detectHeader returns the name of the header that the provided content starts with (after the directives that are
+// placed before the header). The headers of the owner of the file are considered first so that the owner is reported if
+// multiple headers match. Returns UnknownHeaderName if the content does not start with any of the headers.
+func detectHeader(content, owner string, headerNames []string, headers map[string][]string, placement DirectivePlacement) string {
+	_, content = placement.split(content)
+	for _, name := range append([]string{owner}, headerNames...) {
+		if _, ok := matchingHeader(content, nonEmpty(headers[name])); ok {
+			return name
+		}
+	}
+	return UnknownHeaderName
+}
+
+func nonEmpty(in []string) []string {
+	var out []string
+	for _, s := range in {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// SummarizeReport returns the number of files in the provided report for every combination of owner and header that
+// occurs in the report, sorted by owner and then by header.
+func SummarizeReport(reports []FileReport) []ReportSummary {
+	counts := make(map[ReportSummary]int)
+	for _, r := range reports {
+		counts[ReportSummary{
+			Owner:  r.Owner,
+			Header: r.Header,
+		}]++
+	}
+	var summary []ReportSummary
+	for k, v := range counts {
+		k.Files = v
+		summary = append(summary, k)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Owner != summary[j].Owner {
+			return summary[i].Owner < summary[j].Owner
+		}
+		return summary[i].Header < summary[j].Header
+	})
+	return summary
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golicense
+
+import "github.com/palantir/pkg/matcher"
+
+// VerifySummary summarizes the result of verifying the license headers of a set of files.
+type VerifySummary struct {
+	// Matchers contains the summary for the default header followed by the summaries for the custom headers in the
+	// order in which they are defined.
+	Matchers []MatcherSummary `json:"matchers"`
+	// Excluded is the number of Go files that were excluded from consideration.
+	Excluded int `json:"excluded"`
+	// Files are the paths of the files that do not have the correct license header sorted by path.
+	Files []string `json:"files"`
+}
+
+// MatcherSummary is the number of files matched by a single header (DefaultHeaderName or the name of a custom header
+// entry) that are compliant and that are not.
+type MatcherSummary struct {
+	Matcher string `json:"matcher"`
+	// Compliant is the number of files that have the correct license header.
+	Compliant int `json:"compliant"`
+	// MissingHeader is the number of files that do not start with any of the configured headers.
+	MissingHeader int `json:"missingHeader"`
+	// WrongHeader is the number of files that start with a header that is configured for other files.
+	WrongHeader int `json:"wrongHeader"`
+}
+
+// Passed returns true if all of the summarized files have the correct license header.
+func (s VerifySummary) Passed() bool {
+	return len(s.Files) == 0
+}
+
+// SummarizeVerify verifies the license headers of the provided files in the same manner as LicenseFiles without
+// modifying them and returns a summary of the result.
+func SummarizeVerify(files []string, params LicenseParams) (VerifySummary, error) {
+	modified, err := LicenseFiles(files, params, false)
+	if err != nil {
+		return VerifySummary{}, err
+	}
+	reports, err := ReportFiles(files, params)
+	if err != nil {
+		return VerifySummary{}, err
+	}
+
+	summary := VerifySummary{
+		Files: modified,
+	}
+	matcherIdx := map[string]int{
+		DefaultHeaderName: 0,
+	}
+	summary.Matchers = append(summary.Matchers, MatcherSummary{Matcher: DefaultHeaderName})
+	for _, v := range params.CustomHeaders.headers() {
+		matcherIdx[v.Name] = len(summary.Matchers)
+		summary.Matchers = append(summary.Matchers, MatcherSummary{Matcher: v.Name})
+	}
+
+	modifiedSet := make(map[string]bool)
+	for _, f := range modified {
+		modifiedSet[f] = true
+	}
+	for _, r := range reports {
+		curr := &summary.Matchers[matcherIdx[r.Owner]]
+		switch {
+		case !modifiedSet[r.Path]:
+			curr.Compliant++
+		case r.Header == UnknownHeaderName:
+			curr.MissingHeader++
+		default:
+			curr.WrongHeader++
+		}
+	}
+
+	goFileMatcher := matcher.Name(`.*\.go`)
+	for _, f := range files {
+		if goFileMatcher.Match(f) && params.Exclude != nil && params.Exclude.Match(f) {
+			summary.Excluded++
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golicense
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// copyrightYearRegexp matches a copyright notice along with its year or range of years (for example, "Copyright 2016",
+// "Copyright (c) 2016" or "Copyright 2016-2018"). The first group is the first year and the second group (if present)
+// is the end of the range.
+var copyrightYearRegexp = regexp.MustCompile(`(?i)\bcopyright(?:\s+\(c\)|\s+©)?\s+(\d{4})(?:\s*-\s*(\d{4}))?`)
+
+// StaleYear describes a file whose copyright year is older than the year in which it was last modified.
+type StaleYear struct {
+	// Path is the path to the file.
+	Path string `json:"path"`
+	// HeaderYear is the copyright year in the header of the file (the end of the range if the header specifies a
+	// range of years).
+	HeaderYear int `json:"headerYear"`
+	// LastModifiedYear is the year of the most recent commit that modified the file.
+	LastModifiedYear int `json:"lastModifiedYear"`
+}
+
+func (s StaleYear) String() string {
+	return fmt.Sprintf("%s: copyright year %d is older than last modified year %d", s.Path, s.HeaderYear, s.LastModifiedYear)
+}
+
+// LastModifiedYears returns a map from the path of every file in the provided directory that has git history to the
+// year of the most recent commit that modified it. Paths are relative to the directory. Uncommitted changes are not
+// considered.
+func LastModifiedYears(dir string) (map[string]int, error) {
+	cmd := exec.Command("git", "-c", "core.quotePath=false", "log", "--relative", "--name-only", "--format=%x00%cd", "--date=format:%Y", "--", ".")
+	cmd.Dir = dir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine git history of %s: %s", dir, strings.TrimSpace(stderr.String()))
+	}
+
+	// commits are printed from newest to oldest, so the first commit that lists a file is the one that last modified it
+	years := make(map[string]int)
+	year := 0
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\x00"):
+			if year, err = strconv.Atoi(line[1:]); err != nil {
+				return nil, errors.Wrapf(err, "invalid commit year in git log output: %q", line[1:])
+			}
+		case line != "":
+			if _, ok := years[line]; !ok {
+				years[line] = year
+			}
+		}
+	}
+	return years, nil
+}
+
+// StaleYearFiles returns the Go files in the provided files whose copyright year is older than the year in which they
+// were last modified, sorted by path. Only files that are not excluded by the provided parameters are considered. The
+// copyright year is read from the first copyright notice before the package clause of the file, and the last modified
+// year is looked up in lastModified using the cleaned path of the file (see LastModifiedYears). Files without a
+// copyright notice or history are ignored. If modify is true, the copyright year of each stale file is updated in
+// place: the end of a range of years is replaced with the last modified year, and a single year is turned into a range
+// that ends with the last modified year.
+func StaleYearFiles(files []string, params LicenseParams, lastModified map[string]int, modify bool) ([]StaleYear, error) {
+	var stale []StaleYear
+	for _, f := range filesToVisit(files, params) {
+		modifiedYear, ok := lastModified[filepath.Clean(f.path)]
+		if !ok {
+			continue
+		}
+		var headerYear int
+		if _, err := visitFile(f, modify, func(content string, _ []string) (string, bool) {
+			var newContent string
+			headerYear, newContent = updateCopyrightYear(content, modifiedYear)
+			return newContent, headerYear != 0
+		}); err != nil {
+			return nil, err
+		}
+		if headerYear != 0 {
+			stale = append(stale, StaleYear{
+				Path:             f.path,
+				HeaderYear:       headerYear,
+				LastModifiedYear: modifiedYear,
+			})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Path < stale[j].Path
+	})
+	return stale, nil
+}
+
+// updateCopyrightYear returns the copyright year in the header of the provided content and the content with the year
+// updated to the provided year if the copyright year is older than it. Returns 0 and the content as-is if the header
+// does not have a copyright notice or if its year is current.
+func updateCopyrightYear(content string, year int) (int, string) {
+	header := content
+	if idx := strings.Index(content, "\npackage "); idx != -1 {
+		header = content[:idx]
+	} else if strings.HasPrefix(content, "package ") {
+		header = ""
+	}
+	loc := copyrightYearRegexp.FindStringSubmatchIndex(header)
+	if loc == nil {
+		return 0, content
+	}
+
+	// start and end of the last year of the notice
+	start, end := loc[2], loc[3]
+	if loc[4] != -1 {
+		start, end = loc[4], loc[5]
+	}
+	headerYear, _ := strconv.Atoi(content[start:end])
+	if headerYear >= year {
+		return 0, content
+	}
+	newYear := strconv.Itoa(year)
+	if loc[4] == -1 {
+		newYear = content[start:end] + "-" + newYear
+	}
+	return headerYear, content[:start] + newYear + content[end:]
+}
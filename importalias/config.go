@@ -0,0 +1,56 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type config struct {
+	// NoAlias specifies the import paths of the packages that must never be imported using an alias (for example,
+	// "fmt" or "context"). Aliased imports of these packages are reported regardless of the consensus alias and the
+	// packages are not considered when computing consensus.
+	NoAlias []string `yaml:"no-alias" json:"no-alias"`
+}
+
+// loadConfig returns the configuration in the YAML file at the provided path. Returns an empty configuration if the
+// path is empty.
+func loadConfig(configPath string) (config, error) {
+	var cfg config
+	if configPath == "" {
+		return cfg, nil
+	}
+	yml, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return config{}, errors.Wrapf(err, "failed to read file %s", configPath)
+	}
+	if err := yaml.Unmarshal(yml, &cfg); err != nil {
+		return config{}, errors.Wrapf(err, "failed to unmarshal YML %s", string(yml))
+	}
+	return cfg, nil
+}
+
+// noAliasSet returns the set of quoted import paths of the packages that must not be imported using an alias.
+func (c config) noAliasSet() map[string]struct{} {
+	set := make(map[string]struct{}, len(c.NoAlias))
+	for _, importPath := range c.NoAlias {
+		set[strconv.Quote(importPath)] = struct{}{}
+	}
+	return set
+}
@@ -28,13 +28,30 @@ import (
 	"github.com/nmiyake/pkg/errorstringer"
 	"github.com/palantir/pkg/cli"
 	"github.com/palantir/pkg/cli/flag"
+	"github.com/palantir/pkg/matcher"
 	"github.com/palantir/pkg/pkgpath"
 	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+	"github.com/palantir/checks/checks/projectconfig"
+)
+
+const (
+	pkgsFlagName      = "pkgs"
+	verboseFlagName   = "verbose"
+	formatFlagName    = "format"
+	scopeFlagName     = "scope"
+	normalizeFlagName = "normalize-paths"
+	weightFlagName    = "weight"
+	tieBreakFlagName  = "tie-break"
+	configFlagName    = "config"
+	fixFlagName       = "fix"
 )
 
 const (
-	pkgsFlagName    = "pkgs"
-	verboseFlagName = "verbose"
+	checkName         = "importalias"
+	inconsistentAlias = "inconsistent-alias"
 )
 
 var (
@@ -48,6 +65,51 @@ var (
 		Usage: "print verbose analysis of all imports that have multiple aliases",
 		Alias: "v",
 	}
+	formatFlag = flag.StringFlag{
+		Name:  formatFlagName,
+		Value: diagnostic.FormatText,
+		Usage: "format of the output. Must be 'text', 'json', 'checkstyle' or 'github'. Must be 'text' when printing verbose analysis",
+	}
+	scopeFlag = flag.StringFlag{
+		Name:  scopeFlagName,
+		Value: scopeProject,
+		Usage: "scope over which the consensus alias for an import is computed. Must be 'project', 'dir' (each top-level directory) or 'module' (each Go module)",
+	}
+	normalizeFlag = flag.BoolFlag{
+		Name: normalizeFlagName,
+		Usage: "treat import paths that only differ by a major version element or a vendor directory prefix as the " +
+			"same package when computing the consensus alias",
+	}
+	weightFlag = flag.StringFlag{
+		Name:  weightFlagName,
+		Value: weightFiles,
+		Usage: "how the uses of an alias are weighted when computing the consensus alias for an import. Must be 'files' (number of files that use the alias) or 'usages' (number of references to the alias in those files)",
+	}
+	tieBreakFlag = flag.StringFlag{
+		Name:  tieBreakFlagName,
+		Value: tieBreakNone,
+		Usage: "how ties between the most common aliases for an import are broken. Must be 'recent' (alias whose most recent import was committed last), 'oldest' (alias whose earliest import was committed first) or 'none' (report that no consensus alias exists). 'recent' and 'oldest' use the git history of the imports",
+	}
+	configFlag = flag.StringFlag{
+		Name:  configFlagName,
+		Usage: "path to a YAML configuration file whose no-alias section specifies packages that must never be imported using an alias",
+	}
+	fixFlag = flag.BoolFlag{
+		Name:  fixFlagName,
+		Usage: "remove the aliases of the imports of packages that must never be imported using an alias and only report the imports that cannot be fixed",
+	}
+	baselineFlag = flag.StringFlag{
+		Name:  baseline.FlagName,
+		Usage: "path to a baseline file: inconsistent aliases recorded in the baseline are not reported",
+	}
+	writeBaselineFlag = flag.StringFlag{
+		Name:  baseline.WriteFlagName,
+		Usage: "path to which a baseline file that records the current inconsistent aliases is written",
+	}
+	projectConfigFlag = flag.StringFlag{
+		Name:  projectconfig.FlagName,
+		Usage: "path to a project configuration file whose exclude section specifies packages to exclude",
+	}
 )
 
 func main() {
@@ -55,55 +117,261 @@ func main() {
 	app.Flags = append(app.Flags,
 		pkgsFlag,
 		verboseFlag,
+		formatFlag,
+		scopeFlag,
+		normalizeFlag,
+		weightFlag,
+		tieBreakFlag,
+		configFlag,
+		fixFlag,
+		baselineFlag,
+		writeBaselineFlag,
+		projectConfigFlag,
 	)
 	app.Action = func(ctx cli.Context) error {
 		wd, err := dirs.GetwdEvalSymLinks()
 		if err != nil {
 			return errors.Wrapf(err, "Failed to get working directory")
 		}
-		return doImportAlias(wd, ctx.Slice(pkgsFlagName), ctx.Bool(verboseFlagName), ctx.App.Stdout)
+		cfg, err := loadConfig(ctx.String(configFlagName))
+		if err != nil {
+			return err
+		}
+		projectCfg, err := projectconfig.Load(ctx.String(projectconfig.FlagName))
+		if err != nil {
+			return err
+		}
+		_, err = doImportAlias(wd, importAliasOptions{
+			pkgPaths:       ctx.Slice(pkgsFlagName),
+			exclude:        projectCfg.ExcludeMatcher(),
+			verbose:        ctx.Bool(verboseFlagName),
+			scope:          ctx.String(scopeFlagName),
+			normalizePaths: ctx.Bool(normalizeFlagName),
+			weight:         ctx.String(weightFlagName),
+			tieBreak:       ctx.String(tieBreakFlagName),
+			cfg:            cfg,
+			fix:            ctx.Bool(fixFlagName),
+			format:         ctx.String(formatFlagName),
+			baseline: baseline.Options{
+				Path:      ctx.String(baseline.FlagName),
+				WritePath: ctx.String(baseline.WriteFlagName),
+			},
+		}, ctx.App.Stdout)
+		return err
 	}
 	os.Exit(app.Run(os.Args))
 }
 
-func doImportAlias(projectDir string, pkgPaths []string, verbose bool, w io.Writer) error {
+// inconsistentImport is a package that is imported using multiple different aliases within a scope.
+type inconsistentImport struct {
+	// Scope is the name of the scope in which the package is imported, e.g. "the project".
+	Scope string
+	// ImportPath is the quoted import path of the package.
+	ImportPath string
+	// Aliases are the aliases used to import the package in the scope in descending order of weight.
+	Aliases []ImportAliasInfo
+	// TieBreak is the alias chosen by the tie-breaking strategy if the aliases with the greatest weight are tied. Empty
+	// if the aliases are not tied or if ties are not broken.
+	TieBreak string
+	// Diagnostics are the diagnostics for the imports of the package that use an inconsistent alias and that are not
+	// suppressed by the baseline. Not populated when performing verbose analysis.
+	Diagnostics []diagnostic.Diagnostic
+}
+
+// importAliasOptions configures the packages that are checked by doImportAlias and how the results are reported.
+type importAliasOptions struct {
+	// pkgPaths are the paths of the packages to check, relative to the project directory. If empty, all of the
+	// packages in the project directory are checked.
+	pkgPaths []string
+	// exclude matches the packages that are not checked when the packages are listed from the project directory. May
+	// be nil.
+	exclude matcher.Matcher
+	// verbose specifies whether the analysis of every import that has multiple aliases is written instead of
+	// diagnostics.
+	verbose bool
+	// scope is the type of scope ("project", "dir" or "module") for which the consensus alias of an import is computed.
+	scope string
+	// normalizePaths specifies whether imports of the same logical package are considered together (see
+	// logicalImportPath) and are reported using the logical import path.
+	normalizePaths bool
+	// weight determines how the aliases are weighted when computing consensus (weightFiles or weightUsages).
+	weight string
+	// tieBreak is the strategy used to break ties between the aliases with the greatest weight. If it is
+	// tieBreakRecent or tieBreakOldest, ties are broken using the git history of the imports (see breakTie) rather than
+	// being reported as having no consensus alias.
+	tieBreak string
+	// cfg is the configuration of the check.
+	cfg config
+	// fix specifies whether the aliases of the imports of packages in the NoAlias section of the configuration are
+	// removed.
+	fix bool
+	// format is the format in which the diagnostics are written.
+	format string
+	// baseline suppresses the diagnostics recorded in a baseline file or specifies the file to which they are
+	// written.
+	baseline baseline.Options
+}
+
+// doImportAlias checks that the packages of the project in projectDir specified by the provided options import every
+// package using a consistent alias. The consensus alias for an import is computed separately for the packages in each
+// scope. Returns the imports that are imported using multiple different aliases in their scope.
+//
+// Imports that use an alias to import one of the packages in the NoAlias section of the configuration are reported
+// regardless of consensus (see checkNoAlias) and the aliases of these packages are not considered when computing
+// consensus. If the imports are fixed, the aliases of these imports are removed and only the imports that cannot be
+// fixed are reported.
+//
+// Findings are written to w as each scope is checked. In verbose mode, the analysis of every import that has multiple
+// aliases is written. Otherwise, a diagnostic is written for every import that uses an inconsistent alias and that is
+// not suppressed by the baseline: diagnostics in the text format are written as they are found, while diagnostics in
+// other formats are written once all of the scopes have been checked. If a baseline is being written, no diagnostics
+// are written and no imports are returned. A blank error is returned if any findings were written.
+func doImportAlias(projectDir string, opts importAliasOptions, w io.Writer) ([]inconsistentImport, error) {
+	if err := diagnostic.ValidateFormat(opts.format); err != nil {
+		return nil, err
+	}
+	if err := validateScope(opts.scope); err != nil {
+		return nil, err
+	}
+	if err := validateWeight(opts.weight); err != nil {
+		return nil, err
+	}
+	if err := validateTieBreak(opts.tieBreak); err != nil {
+		return nil, err
+	}
+	if opts.verbose && opts.format != diagnostic.FormatText {
+		return nil, errors.Errorf("format %q is not supported when printing verbose analysis", opts.format)
+	}
+	if opts.verbose && opts.baseline != (baseline.Options{}) {
+		return nil, errors.Errorf("baselines are not supported when printing verbose analysis")
+	}
+
 	if !path.IsAbs(projectDir) {
-		return errors.Errorf("projectDir %s must be an absolute path", projectDir)
+		return nil, errors.Errorf("projectDir %s must be an absolute path", projectDir)
 	}
 
 	gopath := os.Getenv("GOPATH")
 	if gopath == "" {
-		return errors.Errorf("GOPATH environment variable must be set")
+		return nil, errors.Errorf("GOPATH environment variable must be set")
 	}
 
 	if relPath, err := filepath.Rel(path.Join(gopath, "src"), projectDir); err != nil || strings.HasPrefix(relPath, "../") {
-		return errors.Wrapf(err, "Project directory %s must be a subdirectory of $GOPATH/src (%s)", projectDir, path.Join(gopath, "src"))
+		return nil, errors.Wrapf(err, "Project directory %s must be a subdirectory of $GOPATH/src (%s)", projectDir, path.Join(gopath, "src"))
 	}
 
+	pkgPaths := opts.pkgPaths
 	if len(pkgPaths) == 0 {
-		pkgs, err := pkgpath.PackagesInDir(projectDir, pkgpath.DefaultGoPkgExcludeMatcher())
+		pkgs, err := pkgpath.PackagesInDir(projectDir, matcher.Any(pkgpath.DefaultGoPkgExcludeMatcher(), opts.exclude))
 		if err != nil {
-			return errors.Wrapf(err, "Failed to list packages")
+			return nil, errors.Wrapf(err, "Failed to list packages")
 		}
 
 		pkgPaths, err = pkgs.Paths(pkgpath.Relative)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to convert package paths")
+			return nil, errors.Wrapf(err, "Failed to convert package paths")
+		}
+	}
+
+	scopes, err := aliasScopes(projectDir, pkgPaths, opts.scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var suppressor *baseline.Baseline
+	if opts.baseline.WritePath == "" && opts.baseline.Path != "" {
+		if suppressor, err = baseline.Load(opts.baseline.Path, projectDir); err != nil {
+			return nil, err
+		}
+	}
+	// diagnostics can only be written as they are found if they do not need to be considered as a whole
+	streamDiags := opts.format == diagnostic.FormatText && opts.baseline.WritePath == ""
+
+	noAlias := opts.cfg.noAliasSet()
+	allDiags, err := checkNoAlias(projectDir, pkgPaths, noAlias, opts.fix)
+	if err != nil {
+		return nil, err
+	}
+	if suppressor != nil {
+		allDiags = suppressor.Filter(allDiags)
+	}
+	diagnostic.Sort(allDiags)
+	if streamDiags {
+		for _, diag := range allDiags {
+			fmt.Fprintln(w, diag.String())
+		}
+	}
+	nFindings := len(allDiags)
+
+	var results []inconsistentImport
+	for _, currScope := range scopes {
+		scopeResults, err := checkScope(projectDir, currScope, opts.normalizePaths, opts.weight, opts.tieBreak, noAlias, !opts.verbose)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.verbose {
+			if err := printVerboseAnalysis(w, projectDir, opts.weight, opts.tieBreak, scopeResults); err != nil {
+				return nil, err
+			}
+			results = append(results, scopeResults...)
+			nFindings += len(scopeResults)
+			continue
+		}
+
+		var scopeDiags []diagnostic.Diagnostic
+		for i := range scopeResults {
+			if suppressor != nil {
+				scopeResults[i].Diagnostics = suppressor.Filter(scopeResults[i].Diagnostics)
+			}
+			scopeDiags = append(scopeDiags, scopeResults[i].Diagnostics...)
+		}
+		diagnostic.Sort(scopeDiags)
+		if streamDiags {
+			for _, diag := range scopeDiags {
+				fmt.Fprintln(w, diag.String())
+			}
+		}
+		results = append(results, scopeResults...)
+		allDiags = append(allDiags, scopeDiags...)
+		nFindings += len(scopeDiags)
+	}
+
+	if opts.baseline.WritePath != "" {
+		if _, err := opts.baseline.Apply(projectDir, allDiags); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if !opts.verbose && !streamDiags {
+		diagnostic.Sort(allDiags)
+		if err := diagnostic.Print(w, opts.format, allDiags); err != nil {
+			return nil, err
 		}
 	}
+	if nFindings > 0 {
+		return results, fmt.Errorf("")
+	}
+	return results, nil
+}
 
-	projectImportInfo := NewProjectImportInfo()
-	for _, pkgPath := range pkgPaths {
+// checkScope returns the imports that are imported using multiple different aliases in the packages in the provided
+// scope sorted by import path. If normalizePaths is true, imports are keyed by their logical import path. Aliases are
+// weighted as specified by weight and ties between the aliases with the greatest weight are broken as specified by
+// tieBreak. The imports of the packages in noAlias are not considered. If populateDiags is true, the diagnostics for
+// the imports of each package that use an inconsistent alias are populated.
+func checkScope(projectDir string, scope aliasScope, normalizePaths bool, weight, tieBreak string, noAlias map[string]struct{}, populateDiags bool) ([]inconsistentImport, error) {
+	projectImportInfo := newScopeImportInfo(scope.name, normalizePaths, weight, tieBreak)
+	for _, pkgPath := range scope.pkgPaths {
 		currPath := path.Join(projectDir, pkgPath)
 		fis, err := ioutil.ReadDir(currPath)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to list contents of directory %s", currPath)
+			return nil, errors.Wrapf(err, "Failed to list contents of directory %s", currPath)
 		}
 		for _, fi := range fis {
 			if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".go") {
 				currFile := path.Join(currPath, fi.Name())
 				if err := projectImportInfo.AddImportAliasesFromFile(currFile); err != nil {
-					return errors.Wrapf(err, "failed to determine imports in file %s", currFile)
+					return nil, errors.Wrapf(err, "failed to determine imports in file %s", currFile)
 				}
 			}
 		}
@@ -111,78 +379,138 @@ func doImportAlias(projectDir string, pkgPaths []string, verbose bool, w io.Writ
 
 	importsToAliases := projectImportInfo.ImportsToAliases()
 	var pkgsWithMultipleAliases []string
-	pkgsWithMultipleAliasesMap := make(map[string]struct{})
 	for k, v := range importsToAliases {
+		if _, ok := noAlias[k]; ok {
+			continue
+		}
 		if len(v) > 1 {
 			// package is imported using more than 1 alias
 			pkgsWithMultipleAliases = append(pkgsWithMultipleAliases, k)
-			pkgsWithMultipleAliasesMap[k] = struct{}{}
 		}
 	}
 	sort.Strings(pkgsWithMultipleAliases)
-	if len(pkgsWithMultipleAliases) > 0 {
-		var output []string
-		if verbose {
-			for _, k := range pkgsWithMultipleAliases {
-				output = append(output, fmt.Sprintf("%s is imported using multiple different aliases:", k))
-				for _, currAliasInfo := range importsToAliases[k] {
-					var files []string
-					for k, v := range currAliasInfo.Occurrences {
-						relPkgPath, err := pkgpath.NewAbsPkgPath(k).Rel(projectDir)
-						if err != nil {
-							return errors.Wrapf(err, "failed to get package path")
-						}
-						relPkgPath = strings.TrimLeft(relPkgPath, "./")
-						files = append(files, fmt.Sprintf("%s:%d:%d", relPkgPath, v.Line, v.Column))
-					}
-					sort.Strings(files)
-
-					var numFilesMsg string
-					if len(currAliasInfo.Occurrences) == 1 {
-						numFilesMsg = "(1 file)"
-					} else {
-						numFilesMsg = fmt.Sprintf("(%d files)", len(currAliasInfo.Occurrences))
-					}
-					output = append(output, fmt.Sprintf("\t%s %s:\n\t\t%s", currAliasInfo.Alias, numFilesMsg, strings.Join(files, "\n\t\t")))
+
+	var results []inconsistentImport
+	resultIdx := make(map[string]int)
+	times := make(blameTimes)
+	for i, k := range pkgsWithMultipleAliases {
+		aliases := importsToAliases[k]
+		var chosen string
+		// aliases are sorted by weight, so the aliases with the greatest weight are tied if the first 2 have the same weight
+		if tieBreak != tieBreakNone && aliases[0].Weight == aliases[1].Weight {
+			var tied []ImportAliasInfo
+			for _, currAlias := range aliases {
+				if currAlias.Weight != aliases[0].Weight {
+					break
 				}
+				tied = append(tied, currAlias)
+			}
+			var err error
+			if chosen, err = times.breakTie(tied, tieBreak); err != nil {
+				return nil, err
+			}
+			projectImportInfo.SetTieBreak(k, chosen)
+		}
+		results = append(results, inconsistentImport{
+			Scope:      scope.name,
+			ImportPath: k,
+			Aliases:    aliases,
+			TieBreak:   chosen,
+		})
+		resultIdx[k] = i
+	}
+	if !populateDiags {
+		return results, nil
+	}
+
+	filesToAliases := projectImportInfo.FilesToImportAliases()
+
+	var relPkgPaths []string
+	relPkgPathToFile := make(map[string]string)
+	for file := range filesToAliases {
+		relPkgPath, err := pkgpath.NewAbsPkgPath(file).GoPathSrcRel()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get package path")
+		}
+		relPkgPaths = append(relPkgPaths, relPkgPath)
+		relPkgPathToFile[relPkgPath] = file
+	}
+	sort.Strings(relPkgPaths)
+
+	for _, relPkgPath := range relPkgPaths {
+		file := relPkgPathToFile[relPkgPath]
+		for _, alias := range filesToAliases[file] {
+			idx, ok := resultIdx[alias.ImportPath]
+			if !ok {
+				continue
+			}
+			status := projectImportInfo.GetAliasStatus(alias.Alias, alias.ImportPath)
+			if status.OK {
+				continue
 			}
-		} else {
-			filesToAliases := projectImportInfo.FilesToImportAliases()
 
-			var relPkgPaths []string
-			relPkgPathToFile := make(map[string]string)
-			for file := range filesToAliases {
-				relPkgPath, err := pkgpath.NewAbsPkgPath(file).GoPathSrcRel()
+			relPkgPath, err := pkgpath.NewAbsPkgPath(file).Rel(projectDir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get package path")
+			}
+			relPkgPath = strings.TrimLeft(relPkgPath, "./")
+			pos := alias.Pos
+			pos.Filename = relPkgPath
+			diag := diagnostic.New(pos, checkName, inconsistentAlias, fmt.Sprintf("uses alias %q to import package %s. %s.", alias.Alias, alias.ImportPath, status.Recommendation))
+			results[idx].Diagnostics = append(results[idx].Diagnostics, diag)
+		}
+	}
+	return results, nil
+}
+
+// printVerboseAnalysis writes the aliases used for each of the provided imports and the locations at which each alias
+// is used to w. If weight is weightUsages, the number of references to each alias is written as well. If the aliases
+// with the greatest weight are tied, the alias chosen by tieBreak (or the alias suggested by the import path if ties
+// are not broken) is written.
+func printVerboseAnalysis(w io.Writer, projectDir, weight, tieBreak string, imports []inconsistentImport) error {
+	for _, currImport := range imports {
+		// only name the scope if consensus is not computed over the whole project
+		var scopeMsg string
+		if currImport.Scope != projectScopeName {
+			scopeMsg = " in " + currImport.Scope
+		}
+		fmt.Fprintf(w, "%s is imported using multiple different aliases%s:\n", currImport.ImportPath, scopeMsg)
+
+		for _, currAliasInfo := range currImport.Aliases {
+			var files []string
+			for k, v := range currAliasInfo.Occurrences {
+				relPkgPath, err := pkgpath.NewAbsPkgPath(k).Rel(projectDir)
 				if err != nil {
 					return errors.Wrapf(err, "failed to get package path")
 				}
-				relPkgPaths = append(relPkgPaths, relPkgPath)
-				relPkgPathToFile[relPkgPath] = file
+				relPkgPath = strings.TrimLeft(relPkgPath, "./")
+				files = append(files, fmt.Sprintf("%s:%d:%d", relPkgPath, v.Line, v.Column))
 			}
-			sort.Strings(relPkgPaths)
-
-			for _, relPkgPath := range relPkgPaths {
-				file := relPkgPathToFile[relPkgPath]
-				for _, alias := range filesToAliases[file] {
-					if _, ok := pkgsWithMultipleAliasesMap[alias.ImportPath]; !ok {
-						continue
-					}
-					status := projectImportInfo.GetAliasStatus(alias.Alias, alias.ImportPath)
-					if status.OK {
-						continue
-					}
-
-					relPkgPath, err := pkgpath.NewAbsPkgPath(file).Rel(projectDir)
-					if err != nil {
-						return errors.Wrapf(err, "failed to get package path")
-					}
-					relPkgPath = strings.TrimLeft(relPkgPath, "./")
-					msg := fmt.Sprintf("%s:%d:%d: uses alias %q to import package %s. %s.", relPkgPath, alias.Pos.Line, alias.Pos.Column, alias.Alias, alias.ImportPath, status.Recommendation)
-					output = append(output, msg)
-				}
+			sort.Strings(files)
+
+			var numFilesMsg string
+			if len(currAliasInfo.Occurrences) == 1 {
+				numFilesMsg = "1 file"
+			} else {
+				numFilesMsg = fmt.Sprintf("%d files", len(currAliasInfo.Occurrences))
+			}
+			if weight == weightUsages {
+				numFilesMsg += fmt.Sprintf(", %d usages", currAliasInfo.Weight)
+			}
+			numFilesMsg = "(" + numFilesMsg + ")"
+			fmt.Fprintf(w, "\t%s %s:\n\t\t%s\n", currAliasInfo.Alias, numFilesMsg, strings.Join(files, "\n\t\t"))
+		}
+
+		if currImport.TieBreak != "" {
+			fmt.Fprintf(w, "\tno consensus alias exists, %s of the most common aliases: %s\n", tieBreakDescription(tieBreak), currImport.TieBreak)
+			continue
+		}
+		// aliases are sorted by weight, so there is no consensus if the first 2 have the same weight
+		if len(currImport.Aliases) > 1 && currImport.Aliases[0].Weight == currImport.Aliases[1].Weight {
+			if suggested := suggestedAlias(currImport.ImportPath); suggested != "" {
+				fmt.Fprintf(w, "\tno consensus alias exists, suggested alias based on the import path: %s\n", suggested)
 			}
 		}
-		return errors.New(strings.Join(output, "\n"))
 	}
 	return nil
 }
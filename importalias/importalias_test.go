@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path"
 	"strings"
 	"testing"
 
@@ -26,6 +28,10 @@ import (
 	"github.com/nmiyake/pkg/gofiles"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+	"github.com/palantir/checks/checks/projectconfig"
 )
 
 func TestImportAliasNoError(t *testing.T) {
@@ -145,8 +151,16 @@ func TestImportAliasNoError(t *testing.T) {
 		dir, args := currCase.getArgs(currTmpDir)
 
 		buf := bytes.Buffer{}
-		doMainErr := doImportAlias(dir, args, true, &buf)
+		_, doMainErr := doImportAlias(dir, importAliasOptions{
+			pkgPaths: args,
+			verbose:  true,
+			scope:    scopeProject,
+			weight:   weightFiles,
+			tieBreak: tieBreakNone,
+			format:   diagnostic.FormatText,
+		}, &buf)
 		assert.NoError(t, doMainErr, "Case %d (%s)", i, currCase.name)
+		assert.Equal(t, "", buf.String(), "Case %d (%s)", i, currCase.name)
 	}
 }
 
@@ -182,8 +196,8 @@ func TestImportAliasError(t *testing.T) {
 			},
 			regularOutput: func(files map[string]gofiles.GoFile) []string {
 				return []string{
-					`bar/bar.go:1:21: uses alias "bar" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each).`,
-					`foo.go:1:22: uses alias "foo" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each).`,
+					`bar/bar.go:1:21: uses alias "bar" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each). Suggested alias based on the import path: "fmt".`,
+					`foo.go:1:22: uses alias "foo" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each). Suggested alias based on the import path: "fmt".`,
 				}
 			},
 			verboseOutput: func(files map[string]gofiles.GoFile) []string {
@@ -193,6 +207,7 @@ func TestImportAliasError(t *testing.T) {
 					"\t\tbar/bar.go:1:21",
 					"\tfoo (1 file):",
 					"\t\tfoo.go:1:22",
+					"\tno consensus alias exists, suggested alias based on the import path: fmt",
 				}
 			},
 		},
@@ -221,10 +236,10 @@ func TestImportAliasError(t *testing.T) {
 			},
 			regularOutput: func(files map[string]gofiles.GoFile) []string {
 				return []string{
-					`bar/bar.go:1:21: uses alias "bar" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each).`,
-					`baz/baz.go:1:21: uses alias "baz" to import package "io". No consensus alias exists for this import in the project ("baz" and "other" are both used once each).`,
-					`foo.go:1:22: uses alias "foo" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each).`,
-					`other/other.go:1:23: uses alias "other" to import package "io". No consensus alias exists for this import in the project ("baz" and "other" are both used once each).`,
+					`bar/bar.go:1:21: uses alias "bar" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each). Suggested alias based on the import path: "fmt".`,
+					`baz/baz.go:1:21: uses alias "baz" to import package "io". No consensus alias exists for this import in the project ("baz" and "other" are both used once each). Suggested alias based on the import path: "io".`,
+					`foo.go:1:22: uses alias "foo" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each). Suggested alias based on the import path: "fmt".`,
+					`other/other.go:1:23: uses alias "other" to import package "io". No consensus alias exists for this import in the project ("baz" and "other" are both used once each). Suggested alias based on the import path: "io".`,
 				}
 			},
 			verboseOutput: func(files map[string]gofiles.GoFile) []string {
@@ -234,11 +249,13 @@ func TestImportAliasError(t *testing.T) {
 					"\t\tbar/bar.go:1:21",
 					"\tfoo (1 file):",
 					"\t\tfoo.go:1:22",
+					"\tno consensus alias exists, suggested alias based on the import path: fmt",
 					"\"io\" is imported using multiple different aliases:",
 					"\tbaz (1 file):",
 					"\t\tbaz/baz.go:1:21",
 					"\tother (1 file):",
 					"\t\tother/other.go:1:23",
+					"\tno consensus alias exists, suggested alias based on the import path: io",
 				}
 			},
 		},
@@ -298,9 +315,9 @@ func TestImportAliasError(t *testing.T) {
 			},
 			regularOutput: func(files map[string]gofiles.GoFile) []string {
 				return []string{
-					`bar/bar.go:1:21: uses alias "bar" to import package "fmt". No consensus alias exists for this import in the project ("bar", "baz" and "foo" are all used once each).`,
-					`baz/baz.go:1:21: uses alias "baz" to import package "fmt". No consensus alias exists for this import in the project ("bar", "baz" and "foo" are all used once each).`,
-					`foo.go:1:22: uses alias "foo" to import package "fmt". No consensus alias exists for this import in the project ("bar", "baz" and "foo" are all used once each).`,
+					`bar/bar.go:1:21: uses alias "bar" to import package "fmt". No consensus alias exists for this import in the project ("bar", "baz" and "foo" are all used once each). Suggested alias based on the import path: "fmt".`,
+					`baz/baz.go:1:21: uses alias "baz" to import package "fmt". No consensus alias exists for this import in the project ("bar", "baz" and "foo" are all used once each). Suggested alias based on the import path: "fmt".`,
+					`foo.go:1:22: uses alias "foo" to import package "fmt". No consensus alias exists for this import in the project ("bar", "baz" and "foo" are all used once each). Suggested alias based on the import path: "fmt".`,
 				}
 			},
 			verboseOutput: func(files map[string]gofiles.GoFile) []string {
@@ -312,6 +329,7 @@ func TestImportAliasError(t *testing.T) {
 					"\t\tbaz/baz.go:1:21",
 					"\tfoo (1 file):",
 					"\t\tfoo.go:1:22",
+					"\tno consensus alias exists, suggested alias based on the import path: fmt",
 				}
 			},
 		},
@@ -327,12 +345,729 @@ func TestImportAliasError(t *testing.T) {
 		dir, args := currCase.getArgs(currTmpDir)
 
 		buf := bytes.Buffer{}
-		doMainErr := doImportAlias(dir, args, false, &buf)
+		_, doMainErr := doImportAlias(dir, importAliasOptions{
+			pkgPaths: args,
+			scope:    scopeProject,
+			weight:   weightFiles,
+			tieBreak: tieBreakNone,
+			format:   diagnostic.FormatText,
+		}, &buf)
 		require.Error(t, doMainErr, fmt.Sprintf("Case %d (%s)", i, currCase.name))
-		assert.Equal(t, currCase.regularOutput(files), strings.Split(doMainErr.Error(), "\n"), "Case %d (%s)", i, currCase.name)
+		assert.Equal(t, currCase.regularOutput(files), outputLines(buf.String()), "Case %d (%s)", i, currCase.name)
 
-		doMainErr = doImportAlias(dir, args, true, &buf)
+		buf.Reset()
+		_, doMainErr = doImportAlias(dir, importAliasOptions{
+			pkgPaths: args,
+			verbose:  true,
+			scope:    scopeProject,
+			weight:   weightFiles,
+			tieBreak: tieBreakNone,
+			format:   diagnostic.FormatText,
+		}, &buf)
 		require.Error(t, doMainErr, fmt.Sprintf("Case %d (%s)", i, currCase.name))
-		assert.Equal(t, currCase.verboseOutput(files), strings.Split(doMainErr.Error(), "\n"), "Case %d (%s)", i, currCase.name)
+		assert.Equal(t, currCase.verboseOutput(files), outputLines(buf.String()), "Case %d (%s)", i, currCase.name)
+	}
+}
+
+func TestImportAliasCheckstyle(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     `package main; import foo "fmt"; func main(){ foo.Println() }`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import bar "fmt"; func Bar(){ bar.Println() }`,
+		},
+		{
+			RelPath: "baz/baz.go",
+			Src:     `package baz; import foo "fmt"; func Baz(){ foo.Println() }`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatCheckstyle,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0">
+    <file name="bar/bar.go">
+        <error line="1" column="21" severity="error" message="uses alias &#34;bar&#34; to import package &#34;fmt&#34;. Use alias &#34;foo&#34; instead." source="importalias.inconsistent-alias"></error>
+    </file>
+</checkstyle>
+`, buf.String())
+
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		verbose:  true,
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatCheckstyle,
+	}, &buf)
+	assert.EqualError(t, err, `format "checkstyle" is not supported when printing verbose analysis`)
+}
+
+func TestImportAliasBaseline(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectDir, err := ioutil.TempDir(tmpDir, "")
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     `package main; import foo "fmt"; func main(){ foo.Println() }`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import bar "fmt"; func Bar(){ bar.Println() }`,
+		},
+		{
+			RelPath: "baz/baz.go",
+			Src:     `package baz; import foo "fmt"; func Baz(){ foo.Println() }`,
+		},
+	})
+	require.NoError(t, err)
+
+	baselineFile := path.Join(tmpDir, "baseline.json")
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(projectDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+		baseline: baseline.Options{WritePath: baselineFile},
+	}, &buf)
+	require.NoError(t, err)
+
+	_, err = doImportAlias(projectDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+		baseline: baseline.Options{Path: baselineFile},
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "other/other.go",
+			Src:     `package other; import other "fmt"; func Other(){ other.Println() }`,
+		},
+	})
+	require.NoError(t, err)
+	_, err = doImportAlias(projectDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+		baseline: baseline.Options{Path: baselineFile},
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "other/other.go:1:23: uses alias \"other\" to import package \"fmt\". Use alias \"foo\" instead.\n", buf.String())
+}
+
+func TestImportAliasScope(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import x "fmt"; func Foo(){ x.Println() }`,
+		},
+		{
+			RelPath: "foo/sub/sub.go",
+			Src:     `package sub; import x "fmt"; func Sub(){ x.Println() }`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import y "fmt"; func Bar(){ y.Println() }`,
+		},
+		{
+			RelPath: "bar/sub/sub.go",
+			Src:     `package sub; import y "fmt"; func Sub(){ y.Println() }`,
+		},
+		{
+			RelPath: "bar/other/other.go",
+			Src:     `package other; import z "fmt"; func Other(){ z.Println() }`,
+		},
+	})
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path.Join(tmpDir, "bar", "go.mod"), []byte("module example.com/bar\n"), 0644)
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		`bar/bar.go:1:21: uses alias "y" to import package "fmt". No consensus alias exists for this import in the project ("x" and "y" are both used 2 times each). Suggested alias based on the import path: "fmt".`,
+		`bar/other/other.go:1:23: uses alias "z" to import package "fmt". No consensus alias exists for this import in the project ("x" and "y" are both used 2 times each). Suggested alias based on the import path: "fmt".`,
+		`bar/sub/sub.go:1:21: uses alias "y" to import package "fmt". No consensus alias exists for this import in the project ("x" and "y" are both used 2 times each). Suggested alias based on the import path: "fmt".`,
+		`foo/foo.go:1:21: uses alias "x" to import package "fmt". No consensus alias exists for this import in the project ("x" and "y" are both used 2 times each). Suggested alias based on the import path: "fmt".`,
+		`foo/sub/sub.go:1:21: uses alias "x" to import package "fmt". No consensus alias exists for this import in the project ("x" and "y" are both used 2 times each). Suggested alias based on the import path: "fmt".`,
+	}, outputLines(buf.String()))
+
+	for _, scope := range []string{scopeDir, scopeModule} {
+		buf.Reset()
+		_, err = doImportAlias(tmpDir, importAliasOptions{
+			scope:    scope,
+			weight:   weightFiles,
+			tieBreak: tieBreakNone,
+			format:   diagnostic.FormatText,
+		}, &buf)
+		require.Error(t, err, "Scope %s", scope)
+		assert.Equal(t, "bar/other/other.go:1:23: uses alias \"z\" to import package \"fmt\". Use alias \"y\" instead.\n", buf.String(), "Scope %s", scope)
+	}
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		verbose:  true,
+		scope:    scopeDir,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "\"fmt\" is imported using multiple different aliases in directory \"bar\":\n\ty (2 files):\n\t\tbar/bar.go:1:21\n\t\tbar/sub/sub.go:1:21\n\tz (1 file):\n\t\tbar/other/other.go:1:23\n", buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		verbose:  true,
+		scope:    scopeModule,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "\"fmt\" is imported using multiple different aliases in module example.com/bar:\n\ty (2 files):\n\t\tbar/bar.go:1:21\n\t\tbar/sub/sub.go:1:21\n\tz (1 file):\n\t\tbar/other/other.go:1:23\n", buf.String())
+
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    "unknown",
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	assert.EqualError(t, err, `invalid scope "unknown": must be one of [project dir module]`)
+}
+
+func TestImportAliasNormalizePaths(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import lib "github.com/org/project/lib"; var _ = lib.X`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import lib "github.com/org/project/v2/lib"; var _ = lib.X`,
+		},
+		{
+			RelPath: "baz/baz.go",
+			Src:     `package baz; import projectlib "vendor/github.com/org/project/lib"; var _ = projectlib.X`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:          scopeProject,
+		normalizePaths: true,
+		weight:         weightFiles,
+		tieBreak:       tieBreakNone,
+		format:         diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "baz/baz.go:1:21: uses alias \"projectlib\" to import package \"github.com/org/project/lib\". Use alias \"lib\" instead.\n", buf.String())
+}
+
+func TestImportAliasWeightUsages(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "core/core.go",
+			Src:     `package core; import lib "github.com/org/lib"; var _, _, _, _ = lib.A, lib.B, lib.C, lib.D; func f(lib struct{ X int }) int { return lib.X }`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import olib "github.com/org/lib"; var _ = olib.A`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import olib "github.com/org/lib"; var _ = olib.A`,
+		},
+	})
+	require.NoError(t, err)
+
+	// by default, the alias used in the most files is the consensus
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "core/core.go:1:22: uses alias \"lib\" to import package \"github.com/org/lib\". Use alias \"olib\" instead.\n", buf.String())
+
+	// when weighting by usages, the alias with the most references is the consensus (references to the local variable
+	// that shadows the alias are not counted)
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightUsages,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `bar/bar.go:1:21: uses alias "olib" to import package "github.com/org/lib". Use alias "lib" instead.
+foo/foo.go:1:21: uses alias "olib" to import package "github.com/org/lib". Use alias "lib" instead.
+`, buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		verbose:  true,
+		scope:    scopeProject,
+		weight:   weightUsages,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `"github.com/org/lib" is imported using multiple different aliases:
+	lib (1 file, 4 usages):
+		core/core.go:1:22
+	olib (2 files, 2 usages):
+		bar/bar.go:1:21
+		foo/foo.go:1:21
+`, buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   "unknown",
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	assert.EqualError(t, err, `invalid weight "unknown": must be one of [files usages]`)
+}
+
+func TestImportAliasTieBreak(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import flib "github.com/org/lib"; var _ = flib.A`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import blib "github.com/org/lib"; var _ = blib.A`,
+		},
+	})
+	require.NoError(t, err)
+
+	// commits the provided files with the provided date
+	gitCommit := func(date string, files ...string) {
+		for _, args := range [][]string{
+			append([]string{"add"}, files...),
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "commit"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = tmpDir
+			cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(output))
+		}
+	}
+	cmd := exec.Command("git", "init")
+	cmd.Dir = tmpDir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	gitCommit("2016-06-01T00:00:00Z", "foo/foo.go")
+	gitCommit("2018-06-01T00:00:00Z", "bar/bar.go")
+
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakRecent,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `foo/foo.go:1:21: uses alias "flib" to import package "github.com/org/lib". Use alias "blib" instead (no consensus alias exists for this import in the project, so the most recently used of the most common aliases is used).
+`, buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakOldest,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `bar/bar.go:1:21: uses alias "blib" to import package "github.com/org/lib". Use alias "flib" instead (no consensus alias exists for this import in the project, so the earliest used of the most common aliases is used).
+`, buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		verbose:  true,
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakOldest,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `"github.com/org/lib" is imported using multiple different aliases:
+	blib (1 file):
+		bar/bar.go:1:21
+	flib (1 file):
+		foo/foo.go:1:21
+	no consensus alias exists, earliest used of the most common aliases: flib
+`, buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: "unknown",
+		format:   diagnostic.FormatText,
+	}, &buf)
+	assert.EqualError(t, err, `invalid tie-break "unknown": must be one of [recent oldest none]`)
+}
+
+func TestImportAliasTieBreakUntracked(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import flib "github.com/org/lib"; var _ = flib.A`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import blib "github.com/org/lib"; var _ = blib.A`,
+		},
+	})
+	require.NoError(t, err)
+
+	// only foo/foo.go is committed: bar/bar.go is not tracked by git, so its lines are treated as being written now
+	for _, args := range [][]string{
+		{"init"},
+		{"add", "foo/foo.go"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2016-06-01T00:00:00Z", "GIT_COMMITTER_DATE=2016-06-01T00:00:00Z")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakRecent,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `foo/foo.go:1:21: uses alias "flib" to import package "github.com/org/lib". Use alias "blib" instead (no consensus alias exists for this import in the project, so the most recently used of the most common aliases is used).
+`, buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakOldest,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `bar/bar.go:1:21: uses alias "blib" to import package "github.com/org/lib". Use alias "flib" instead (no consensus alias exists for this import in the project, so the earliest used of the most common aliases is used).
+`, buf.String())
+}
+
+func TestImportAliasNoAlias(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import f "fmt"; func Foo(){ f.Println() }`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import (c "context"; fmt "fmt"); func Bar(ctx c.Context){ fmt.Println(ctx) }`,
+		},
+		{
+			RelPath: "baz/baz.go",
+			Src:     `package baz; import f "fmt"; var fmt = 1; func Baz(){ f.Println(fmt) }`,
+		},
+		{
+			RelPath: "qux/qux.go",
+			Src:     `package qux; import _ "context"`,
+		},
+	})
+	require.NoError(t, err)
+
+	// without configuration, only inconsistent aliases are reported
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "bar/bar.go:1:35: uses alias \"fmt\" to import package \"fmt\". Use alias \"f\" instead.\n", buf.String())
+
+	// aliases of configured packages are reported regardless of consensus
+	cfg := config{
+		NoAlias: []string{"fmt", "context"},
+	}
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		cfg:      cfg,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		`bar/bar.go:1:22: uses alias "c" to import package "context", which must not be imported using an alias. Remove the alias.`,
+		`bar/bar.go:1:35: uses alias "fmt" to import package "fmt", which must not be imported using an alias. Remove the alias.`,
+		`baz/baz.go:1:21: uses alias "f" to import package "fmt", which must not be imported using an alias. Remove the alias.`,
+		`foo/foo.go:1:21: uses alias "f" to import package "fmt", which must not be imported using an alias. Remove the alias.`,
+	}, outputLines(buf.String()))
+
+	// fix removes the aliases that can be removed and reports the rest
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		cfg:      cfg,
+		fix:      true,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "baz/baz.go:1:21: uses alias \"f\" to import package \"fmt\", which must not be imported using an alias. Remove the alias.\n", buf.String())
+
+	for relPath, want := range map[string]string{
+		"foo/foo.go": `package foo; import "fmt"; func Foo(){ fmt.Println() }`,
+		"bar/bar.go": `package bar; import ("context"; "fmt"); func Bar(ctx context.Context){ fmt.Println(ctx) }`,
+		"baz/baz.go": `package baz; import f "fmt"; var fmt = 1; func Baz(){ f.Println(fmt) }`,
+	} {
+		got, err := ioutil.ReadFile(files[relPath].Path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got), relPath)
+	}
+}
+
+func TestLogicalImportPath(t *testing.T) {
+	for i, currCase := range []struct {
+		importPath string
+		want       string
+	}{
+		{`"github.com/org/project/foo"`, `"github.com/org/project/foo"`},
+		{`"github.com/org/project/v2/foo"`, `"github.com/org/project/foo"`},
+		{`"github.com/org/project/v10"`, `"github.com/org/project"`},
+		{`"k8s.io/api/core/v1"`, `"k8s.io/api/core/v1"`},
+		{`"vendor/github.com/org/project/foo"`, `"github.com/org/project/foo"`},
+		{`"github.com/org/repo/vendor/github.com/org/project/v3/foo"`, `"github.com/org/project/foo"`},
+		{`"v2/foo"`, `"v2/foo"`},
+	} {
+		assert.Equal(t, currCase.want, logicalImportPath(currCase.importPath), "Case %d", i)
+	}
+}
+
+func TestImportAliasResults(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     `package main; import foo "fmt"; func main(){ foo.Println() }`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import bar "fmt"; func Bar(){ bar.Println() }`,
+		},
+		{
+			RelPath: "baz/baz.go",
+			Src:     `package baz; import foo "fmt"; func Baz(){ foo.Println() }`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	got, err := doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	require.Equal(t, 1, len(got))
+	assert.Equal(t, projectScopeName, got[0].Scope)
+	assert.Equal(t, `"fmt"`, got[0].ImportPath)
+	require.Equal(t, 2, len(got[0].Aliases))
+	assert.Equal(t, "foo", got[0].Aliases[0].Alias)
+	assert.Equal(t, "bar", got[0].Aliases[1].Alias)
+	require.Equal(t, 1, len(got[0].Diagnostics))
+	assert.Equal(t, "bar/bar.go", got[0].Diagnostics[0].File)
+	assert.Equal(t, inconsistentAlias, got[0].Diagnostics[0].RuleID)
+}
+
+func TestImportAliasProjectConfigExclude(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import foo "fmt"; func Foo(){ foo.Println() }`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar; import bar "fmt"; func Bar(){ bar.Println() }`,
+		},
+		{
+			RelPath: "third_party/baz/baz.go",
+			Src:     `package baz; import bar "fmt"; func Baz(){ bar.Println() }`,
+		},
+	})
+	require.NoError(t, err)
+
+	projectCfg, err := projectconfig.LoadFromString(`exclude:
+  paths:
+    - "third_party"
+`)
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "foo/foo.go:1:21: uses alias \"foo\" to import package \"fmt\". Use alias \"bar\" instead.\n", buf.String())
+
+	buf.Reset()
+	_, err = doImportAlias(tmpDir, importAliasOptions{
+		exclude:  projectCfg.ExcludeMatcher(),
+		scope:    scopeProject,
+		weight:   weightFiles,
+		tieBreak: tieBreakNone,
+		format:   diagnostic.FormatText,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		`bar/bar.go:1:21: uses alias "bar" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each). Suggested alias based on the import path: "fmt".`,
+		`foo/foo.go:1:21: uses alias "foo" to import package "fmt". No consensus alias exists for this import in the project ("bar" and "foo" are both used once each). Suggested alias based on the import path: "fmt".`,
+	}, outputLines(buf.String()))
+}
+
+// outputLines returns the lines of the provided output without the trailing newline.
+func outputLines(output string) []string {
+	return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+}
+
+func TestSuggestedAlias(t *testing.T) {
+	for i, currCase := range []struct {
+		importPath string
+		want       string
+	}{
+		{`"fmt"`, "fmt"},
+		{`"github.com/org/project/pkg"`, "pkg"},
+		{`"github.com/org/project/v2"`, "project"},
+		{`"github.com/org/project-name/v10"`, "projectname"},
+		{`"github.com/dustin/go-humanize"`, "humanize"},
+		{`"github.com/satori/go.uuid"`, "uuid"},
+		{`"github.com/org/client-go"`, "client"},
+		{`"github.com/org/Foo_Bar"`, "foobar"},
+		{`"gopkg.in/yaml.v2"`, "yaml"},
+		{`"k8s.io/api/core/v1"`, "corev1"},
+		{`"k8s.io/api/apps/v1beta2"`, "appsv1beta2"},
+		{`"v1"`, "v1"},
+		{`"github.com/org/2fa"`, ""},
+		{`"github.com/org/go-func"`, ""},
+	} {
+		assert.Equal(t, currCase.want, suggestedAlias(currCase.importPath), "Case %d: %s", i, currCase.importPath)
 	}
 }
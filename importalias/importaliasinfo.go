@@ -21,7 +21,12 @@ import (
 	"go/token"
 	"io/ioutil"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/vendorutil"
 )
 
 type ImportAliasInfo struct {
@@ -29,6 +34,28 @@ type ImportAliasInfo struct {
 	Alias      string
 	// file -> line information for import in the file
 	Occurrences map[string]token.Position
+	// file -> number of references to the alias (selector expressions of the form "alias.Name") in the file
+	Usages map[string]int
+	// weight of the alias when computing consensus: the number of files that use the alias or the total number of
+	// references to the alias in those files, depending on the weighting of the ProjectImportInfo
+	Weight int
+}
+
+const (
+	// every file that uses an alias counts once towards consensus
+	weightFiles = "files"
+	// every reference to an alias counts once towards consensus
+	weightUsages = "usages"
+)
+
+// validateWeight returns an error if the provided weighting is not a valid weighting.
+func validateWeight(weight string) error {
+	switch weight {
+	case weightFiles, weightUsages:
+		return nil
+	default:
+		return errors.Errorf("invalid weight %q: must be one of %v", weight, []string{weightFiles, weightUsages})
+	}
 }
 
 type ImportAlias struct {
@@ -38,6 +65,17 @@ type ImportAlias struct {
 }
 
 type projectImportAliasInfo struct {
+	// description of the set of packages for which information is recorded, e.g. "the project"
+	scopeName string
+	// if true, imports are recorded using their logical import path
+	normalizePaths bool
+	// weighting used to compute consensus (weightFiles or weightUsages)
+	weight string
+	// strategy used to break ties between the aliases with the greatest weight (tieBreakNone, tieBreakRecent or
+	// tieBreakOldest)
+	tieBreak string
+	// import path -> alias chosen by the tie-breaking strategy for imports whose aliases with the greatest weight are tied
+	tieBreaks map[string]string
 	// import path -> alias -> all aliases for the import
 	importInfos map[string]map[string]ImportAliasInfo
 }
@@ -47,7 +85,7 @@ type ProjectImportInfo interface {
 	AddImportAliasesFromFile(filename string) error
 
 	// ImportsWithMultipleAliases returns a map from an imported package path to all of the aliases to import the package.
-	// The aliases are sorted by the number of uses of that alias.
+	// The aliases are sorted by their weight.
 	ImportsToAliases() map[string][]ImportAliasInfo
 
 	// FilesToImportAliases returns a map from each file in the project to all of the alias imports in the file.
@@ -55,18 +93,38 @@ type ProjectImportInfo interface {
 
 	// GetAliasStatus returns the AliasStatus for the given alias used to import the package with the provided path.
 	GetAliasStatus(alias, importPath string) AliasStatus
+
+	// SetTieBreak records the alias chosen by the tie-breaking strategy for the package with the provided path, whose
+	// aliases with the greatest weight are tied.
+	SetTieBreak(importPath, alias string)
 }
 
 type AliasStatus struct {
-	// true if this alias is the only alias used for a package or is the most common alias used for a package.
+	// true if this alias is the only alias used for a package or is the alias with the greatest weight for a package.
 	OK bool
 	// recommendation for how to fix the issue if OK is false.
 	Recommendation string
 }
 
 func NewProjectImportInfo() ProjectImportInfo {
+	return newScopeImportInfo(projectScopeName, false, weightFiles, tieBreakNone)
+}
+
+// newScopeImportInfo returns a ProjectImportInfo that records the import information for the packages in the scope with
+// the provided name. The name is used in recommendations. If normalizePaths is true, imports are recorded using their
+// logical import path so that the aliases used for the same logical package are compared together. The weight of an
+// alias is the number of files that use it if weight is weightFiles or the number of references to it in those files if
+// weight is weightUsages, which prevents an alias that is used heavily in a few files from being outvoted by an alias
+// that is used once in many files. The alias chosen for an import whose aliases are tied is recommended if one was
+// recorded using SetTieBreak; tieBreak is the strategy that was used to choose it.
+func newScopeImportInfo(scopeName string, normalizePaths bool, weight, tieBreak string) ProjectImportInfo {
 	return &projectImportAliasInfo{
-		importInfos: make(map[string]map[string]ImportAliasInfo),
+		scopeName:      scopeName,
+		normalizePaths: normalizePaths,
+		weight:         weight,
+		tieBreak:       tieBreak,
+		tieBreaks:      make(map[string]string),
+		importInfos:    make(map[string]map[string]ImportAliasInfo),
 	}
 }
 
@@ -82,6 +140,10 @@ func (p *projectImportAliasInfo) AddImportAliasesFromFile(filename string) error
 		return fmt.Errorf("failed to parse file %s: %v", filename, err)
 	}
 
+	// references to the names in the file scope (package names are not resolved by the parser, so references to a
+	// local variable that shadows an alias are not counted)
+	references := make(map[string]int)
+	var aliasImports []*ast.ImportSpec
 	var visitor visitFn
 	visitor = visitFn(func(node ast.Node) ast.Visitor {
 		if node == nil {
@@ -91,23 +153,55 @@ func (p *projectImportAliasInfo) AddImportAliasesFromFile(filename string) error
 		case *ast.ImportSpec:
 			if v.Name != nil && v.Name.Name != "." && v.Name.Name != "_" {
 				// import has alias: record
-				p.addImportAlias(filename, v.Name.Name, v.Path.Value, fset.Position(v.Pos()))
+				aliasImports = append(aliasImports, v)
 				break
 			}
+		case *ast.SelectorExpr:
+			if ident, ok := v.X.(*ast.Ident); ok && ident.Obj == nil {
+				references[ident.Name]++
+			}
 		}
 		return visitor
 	})
 	ast.Walk(visitor, file)
+
+	for _, v := range aliasImports {
+		p.addImportAlias(filename, v.Name.Name, v.Path.Value, fset.Position(v.Pos()), references[v.Name.Name])
+	}
 	return nil
 }
 
+// logicalImportPath returns the provided quoted import path with any vendor directory prefix and major version elements
+// removed, which identifies the logical package regardless of the major version or vendoring that is used to import it.
+// For example, "github.com/org/project/v2/foo", "vendor/github.com/org/project/foo" and "github.com/org/project/foo"
+// all have the logical import path "github.com/org/project/foo". The first element of the path is never removed.
+func logicalImportPath(importPath string) string {
+	unquoted, err := strconv.Unquote(importPath)
+	if err != nil {
+		return importPath
+	}
+	unquoted = vendorutil.StripVendorPrefix(unquoted)
+
+	elems := strings.Split(unquoted, "/")
+	logicalElems := elems[:1]
+	for _, elem := range elems[1:] {
+		if !majorVersionRegexp.MatchString(elem) {
+			logicalElems = append(logicalElems, elem)
+		}
+	}
+	return strconv.Quote(strings.Join(logicalElems, "/"))
+}
+
 type visitFn func(node ast.Node) ast.Visitor
 
 func (fn visitFn) Visit(node ast.Node) ast.Visitor {
 	return fn(node)
 }
 
-func (p *projectImportAliasInfo) addImportAlias(file, alias, importPath string, pos token.Position) {
+func (p *projectImportAliasInfo) addImportAlias(file, alias, importPath string, pos token.Position, usages int) {
+	if p.normalizePaths {
+		importPath = logicalImportPath(importPath)
+	}
 	if _, ok := p.importInfos[importPath]; !ok {
 		p.importInfos[importPath] = make(map[string]ImportAliasInfo)
 	}
@@ -116,20 +210,39 @@ func (p *projectImportAliasInfo) addImportAlias(file, alias, importPath string,
 			ImportPath:  importPath,
 			Alias:       alias,
 			Occurrences: make(map[string]token.Position),
+			Usages:      make(map[string]int),
 		}
 	}
 	p.importInfos[importPath][alias].Occurrences[file] = pos
+	p.importInfos[importPath][alias].Usages[file] = usages
+}
+
+// aliasWeight returns the weight of the provided alias. When weighting by usages, every file that uses the alias
+// contributes at least 1 so that an alias that is imported but not referenced through a selector still counts.
+func (p *projectImportAliasInfo) aliasWeight(info ImportAliasInfo) int {
+	if p.weight != weightUsages {
+		return len(info.Occurrences)
+	}
+	weight := 0
+	for _, usages := range info.Usages {
+		if usages < 1 {
+			usages = 1
+		}
+		weight += usages
+	}
+	return weight
 }
 
 func (p *projectImportAliasInfo) ImportsToAliases() map[string][]ImportAliasInfo {
 	m := make(map[string][]ImportAliasInfo)
 	for importPath, aliases := range p.importInfos {
 		for _, aliasInfo := range aliases {
+			aliasInfo.Weight = p.aliasWeight(aliasInfo)
 			m[importPath] = append(m[importPath], aliasInfo)
 		}
 	}
 	for _, v := range m {
-		sort.Sort(byNumOccurrencesDesc(v))
+		sort.Sort(byWeightDesc(v))
 	}
 	return m
 }
@@ -153,17 +266,30 @@ func (p *projectImportAliasInfo) FilesToImportAliases() map[string][]ImportAlias
 	return m
 }
 
+func (p *projectImportAliasInfo) SetTieBreak(importPath, alias string) {
+	p.tieBreaks[importPath] = alias
+}
+
 func (p *projectImportAliasInfo) GetAliasStatus(alias, importPath string) AliasStatus {
 	importsToAliases := p.ImportsToAliases()
 	if aliases, ok := importsToAliases[importPath]; ok && len(aliases) > 1 {
 		var mostCommonAliases []string
 		for _, currAlias := range aliases {
-			if len(currAlias.Occurrences) != len(aliases[0].Occurrences) {
+			if currAlias.Weight != aliases[0].Weight {
 				break
 			}
 			mostCommonAliases = append(mostCommonAliases, currAlias.Alias)
 		}
+		chosen, tieBroken := p.tieBreaks[importPath]
 		switch {
+		case len(mostCommonAliases) > 1 && tieBroken:
+			if alias != chosen {
+				// there is not a single most common alias, so the alias chosen by the tie-breaking strategy is used
+				return AliasStatus{
+					OK:             false,
+					Recommendation: fmt.Sprintf("Use alias %q instead (no consensus alias exists for this import in %s, so the %s of the most common aliases is used)", chosen, p.scopeName, tieBreakDescription(p.tieBreak)),
+				}
+			}
 		case len(mostCommonAliases) > 1:
 			var aliasesUsed string
 			if len(mostCommonAliases) == 2 {
@@ -178,16 +304,20 @@ func (p *projectImportAliasInfo) GetAliasStatus(alias, importPath string) AliasS
 			}
 
 			var timesUsed string
-			if len(aliases[0].Occurrences) == 1 {
+			if aliases[0].Weight == 1 {
 				timesUsed = "once"
 			} else {
-				timesUsed = fmt.Sprintf("%d times", len(aliases[0].Occurrences))
+				timesUsed = fmt.Sprintf("%d times", aliases[0].Weight)
 			}
 
 			// there is not a single most common alias
+			recommendation := fmt.Sprintf("No consensus alias exists for this import in %s (%s used %s each)", p.scopeName, aliasesUsed, timesUsed)
+			if suggested := suggestedAlias(importPath); suggested != "" {
+				recommendation += fmt.Sprintf(". Suggested alias based on the import path: %q", suggested)
+			}
 			return AliasStatus{
 				OK:             false,
-				Recommendation: fmt.Sprintf("No consensus alias exists for this import in the project (%s used %s each)", aliasesUsed, timesUsed),
+				Recommendation: recommendation,
 			}
 		case alias != mostCommonAliases[0]:
 			// this is not the most common alias
@@ -202,17 +332,17 @@ func (p *projectImportAliasInfo) GetAliasStatus(alias, importPath string) AliasS
 	}
 }
 
-type byNumOccurrencesDesc []ImportAliasInfo
+type byWeightDesc []ImportAliasInfo
 
-func (a byNumOccurrencesDesc) Len() int      { return len(a) }
-func (a byNumOccurrencesDesc) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
-func (a byNumOccurrencesDesc) Less(i, j int) bool {
-	if len(a[i].Occurrences) == len(a[j].Occurrences) {
-		// if number of occurrences are the same, do secondary sort based on name of alias
+func (a byWeightDesc) Len() int      { return len(a) }
+func (a byWeightDesc) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byWeightDesc) Less(i, j int) bool {
+	if a[i].Weight == a[j].Weight {
+		// if weights are the same, do secondary sort based on name of alias
 		return strings.Compare(a[i].Alias, a[j].Alias) < 0
 	}
-	// sort occurrences by descending order
-	return len(a[i].Occurrences) > len(a[j].Occurrences)
+	// sort weights by descending order
+	return a[i].Weight > a[j].Weight
 }
 
 type byPos []ImportAlias
@@ -0,0 +1,185 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/diagnostic"
+)
+
+// forbiddenAlias is the rule ID of the diagnostics for imports that use an alias to import a package that must not be
+// imported using an alias.
+const forbiddenAlias = "forbidden-alias"
+
+// checkNoAlias returns the diagnostics for the imports in the Go files of the packages with the provided paths
+// (relative to projectDir) that use an alias to import one of the packages in noAlias (which contains quoted import
+// paths). Blank imports are not considered. If fix is true, the alias of every such import is removed and the
+// references to the alias in the file are renamed to the name of the imported package. Only the imports that cannot be
+// fixed are reported: dot imports and imports of packages whose name cannot be determined or is already used in the
+// file.
+func checkNoAlias(projectDir string, pkgPaths []string, noAlias map[string]struct{}, fix bool) ([]diagnostic.Diagnostic, error) {
+	if len(noAlias) == 0 {
+		return nil, nil
+	}
+	var diags []diagnostic.Diagnostic
+	for _, pkgPath := range pkgPaths {
+		currPath := path.Join(projectDir, pkgPath)
+		fis, err := ioutil.ReadDir(currPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to list contents of directory %s", currPath)
+		}
+		for _, fi := range fis {
+			if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") {
+				continue
+			}
+			currFile := path.Join(currPath, fi.Name())
+			fileDiags, err := checkFileNoAlias(currFile, noAlias, fix)
+			if err != nil {
+				return nil, err
+			}
+			for _, diag := range fileDiags {
+				if relPath, err := filepath.Rel(projectDir, diag.File); err == nil {
+					diag.File = relPath
+				}
+				diags = append(diags, diag)
+			}
+		}
+	}
+	return diags, nil
+}
+
+// noAliasEdit replaces the content of a file between two offsets.
+type noAliasEdit struct {
+	start, end int
+	text       string
+}
+
+// checkFileNoAlias returns the diagnostics for the imports in the provided file that use an alias to import one of the
+// packages in noAlias. If fix is true, the imports that can be fixed are fixed in place (see checkNoAlias) and are not
+// returned.
+func checkFileNoAlias(filename string, noAlias map[string]struct{}, fix bool) ([]diagnostic.Diagnostic, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", filename)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse file %s", filename)
+	}
+
+	var diags []diagnostic.Diagnostic
+	var edits []noAliasEdit
+	for _, spec := range file.Imports {
+		if _, ok := noAlias[spec.Path.Value]; !ok || spec.Name == nil || spec.Name.Name == "_" {
+			continue
+		}
+		if fix {
+			if specEdits, ok := removeAliasEdits(fset, file, spec, path.Dir(filename)); ok {
+				edits = append(edits, specEdits...)
+				continue
+			}
+		}
+		diags = append(diags, diagnostic.New(fset.Position(spec.Pos()), checkName, forbiddenAlias, fmt.Sprintf("uses alias %q to import package %s, which must not be imported using an alias. Remove the alias.", spec.Name.Name, spec.Path.Value)))
+	}
+	if len(edits) == 0 {
+		return diags, nil
+	}
+
+	// apply edits from the end of the file so that earlier offsets remain valid
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	for _, edit := range edits {
+		src = append(src[:edit.start], append([]byte(edit.text), src[edit.end:]...)...)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat %s", filename)
+	}
+	if err := ioutil.WriteFile(filename, src, info.Mode()); err != nil {
+		return nil, errors.Wrapf(err, "failed to write %s", filename)
+	}
+	return diags, nil
+}
+
+// removeAliasEdits returns the edits that remove the alias of the provided import from the provided file and rename the
+// references to the alias to the name of the imported package. Returns false if the alias cannot be removed: the
+// import is a dot import, the name of the package cannot be determined or the name is already used in the file.
+func removeAliasEdits(fset *token.FileSet, file *ast.File, spec *ast.ImportSpec, srcDir string) ([]noAliasEdit, bool) {
+	alias := spec.Name.Name
+	if alias == "." {
+		return nil, false
+	}
+	importPath, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return nil, false
+	}
+	pkg, err := build.Import(importPath, srcDir, build.ImportComment)
+	if err != nil || pkg.Name == "" {
+		return nil, false
+	}
+
+	edits := []noAliasEdit{{
+		start: fset.Position(spec.Name.Pos()).Offset,
+		end:   fset.Position(spec.Path.Pos()).Offset,
+	}}
+	if alias == pkg.Name {
+		return edits, true
+	}
+
+	for _, otherSpec := range file.Imports {
+		if otherSpec != spec && otherSpec.Name == nil && path.Base(strings.Trim(otherSpec.Path.Value, `"`)) == pkg.Name {
+			return nil, false
+		}
+	}
+	nameUsed := false
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch v := node.(type) {
+		case *ast.SelectorExpr:
+			// references to imported packages are not resolved by the parser
+			if ident, ok := v.X.(*ast.Ident); ok && ident.Name == alias && ident.Obj == nil {
+				edits = append(edits, noAliasEdit{
+					start: fset.Position(ident.Pos()).Offset,
+					end:   fset.Position(ident.End()).Offset,
+					text:  pkg.Name,
+				})
+			}
+		case *ast.Ident:
+			if v.Name == pkg.Name && v != spec.Name {
+				nameUsed = true
+			}
+		}
+		return true
+	})
+	if nameUsed {
+		return nil, false
+	}
+	return edits, true
+}
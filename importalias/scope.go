@@ -0,0 +1,142 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	// consensus is computed over all of the packages in the project
+	scopeProject = "project"
+	// consensus is computed separately for the packages in each top-level directory of the project
+	scopeDir = "dir"
+	// consensus is computed separately for the packages in each Go module in the project
+	scopeModule = "module"
+)
+
+// projectScopeName is the name of the scope that contains all of the packages in the project.
+const projectScopeName = "the project"
+
+// aliasScope is a set of packages over which the consensus alias for imports is computed.
+type aliasScope struct {
+	// description of the scope used in messages, e.g. "the project" or `directory "foo"`
+	name     string
+	pkgPaths []string
+}
+
+// validateScope returns an error if the provided scope is not a valid scope.
+func validateScope(scope string) error {
+	switch scope {
+	case scopeProject, scopeDir, scopeModule:
+		return nil
+	default:
+		return errors.Errorf("invalid scope %q: must be one of %v", scope, []string{scopeProject, scopeDir, scopeModule})
+	}
+}
+
+// aliasScopes groups the provided package paths (which are relative to projectDir) into the scopes over which consensus
+// is computed for the provided scope type. The returned scopes are sorted by name.
+func aliasScopes(projectDir string, pkgPaths []string, scope string) ([]aliasScope, error) {
+	if scope == scopeProject {
+		return []aliasScope{{
+			name:     projectScopeName,
+			pkgPaths: pkgPaths,
+		}}, nil
+	}
+
+	scopes := make(map[string]*aliasScope)
+	moduleNames := make(map[string]string)
+	for _, pkgPath := range pkgPaths {
+		var name string
+		switch scope {
+		case scopeDir:
+			name = topLevelDirScopeName(pkgPath)
+		case scopeModule:
+			var err error
+			name, err = moduleScopeName(projectDir, path.Join(projectDir, pkgPath), moduleNames)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, validateScope(scope)
+		}
+		if _, ok := scopes[name]; !ok {
+			scopes[name] = &aliasScope{
+				name: name,
+			}
+		}
+		scopes[name].pkgPaths = append(scopes[name].pkgPaths, pkgPath)
+	}
+
+	var sortedScopes []aliasScope
+	for _, v := range scopes {
+		sortedScopes = append(sortedScopes, *v)
+	}
+	sort.Slice(sortedScopes, func(i, j int) bool {
+		return sortedScopes[i].name < sortedScopes[j].name
+	})
+	return sortedScopes, nil
+}
+
+// topLevelDirScopeName returns the name of the scope for the top-level directory that contains the package with the
+// provided path relative to the project directory. Packages in the project directory itself are in the scope for
+// directory ".".
+func topLevelDirScopeName(pkgPath string) string {
+	pkgPath = path.Clean(pkgPath)
+	if idx := strings.Index(pkgPath, "/"); idx != -1 {
+		pkgPath = pkgPath[:idx]
+	}
+	return fmt.Sprintf("directory %q", pkgPath)
+}
+
+// moduleScopeName returns the name of the scope for the Go module that contains the provided package directory. The
+// module is determined by the closest go.mod file in the package directory or its parent directories up to and
+// including projectDir. Packages that are not in a module are in the scope for the project. The moduleNames map caches
+// the scope name for directories that have already been examined.
+func moduleScopeName(projectDir, pkgDir string, moduleNames map[string]string) (string, error) {
+	if name, ok := moduleNames[pkgDir]; ok {
+		return name, nil
+	}
+
+	var name string
+	goModPath := path.Join(pkgDir, "go.mod")
+	if goModBytes, err := ioutil.ReadFile(goModPath); err == nil {
+		modulePath := modfile.ModulePath(goModBytes)
+		if modulePath == "" {
+			return "", errors.Errorf("failed to determine module path from %s", goModPath)
+		}
+		name = fmt.Sprintf("module %s", modulePath)
+	} else if !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "failed to read %s", goModPath)
+	} else if path.Clean(pkgDir) == path.Clean(projectDir) || path.Dir(pkgDir) == pkgDir {
+		name = projectScopeName
+	} else {
+		name, err = moduleScopeName(projectDir, path.Dir(pkgDir), moduleNames)
+		if err != nil {
+			return "", err
+		}
+	}
+	moduleNames[pkgDir] = name
+	return name, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// matches a major version suffix of a module path such as "v2"
+	majorVersionRegexp = regexp.MustCompile(`^v([2-9]|[1-9][0-9]+)$`)
+	// matches an API version element such as "v1" or "v1beta1"
+	apiVersionRegexp = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]*)?$`)
+	// matches the version suffix of a gopkg.in path element such as "yaml.v2"
+	gopkgVersionRegexp = regexp.MustCompile(`\.v[0-9]+$`)
+)
+
+// suggestedAlias returns an alias for the package with the provided (possibly quoted) import path that is derived from
+// the import path using common naming conventions. The last element of the import path is used, except that a major
+// version suffix such as "v2" is collapsed into the element before it and an API version such as "v1" or "v1beta1" is
+// appended to the element before it ("k8s.io/api/core/v1" is "corev1"). A gopkg.in version suffix such as ".v2" and a
+// "go-" or "go." prefix or "-go" or ".go" suffix are removed, the remaining dashes, dots and underscores are removed
+// and the result is converted to lower case. Returns an empty string if the result is not a valid identifier.
+func suggestedAlias(importPath string) string {
+	if unquoted, err := strconv.Unquote(importPath); err == nil {
+		importPath = unquoted
+	}
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 {
+		prev := cleanAliasElem(elems[len(elems)-2])
+		switch {
+		case majorVersionRegexp.MatchString(name):
+			name = prev
+		case apiVersionRegexp.MatchString(name):
+			name = prev + name
+		}
+	}
+	name = cleanAliasElem(name)
+	if !token.IsIdentifier(name) || token.IsKeyword(name) {
+		return ""
+	}
+	return name
+}
+
+// cleanAliasElem converts the provided import path element into a form that can be used as an alias.
+func cleanAliasElem(elem string) string {
+	elem = gopkgVersionRegexp.ReplaceAllString(elem, "")
+	for _, prefix := range []string{"go-", "go."} {
+		elem = strings.TrimPrefix(elem, prefix)
+	}
+	for _, suffix := range []string{"-go", ".go"} {
+		elem = strings.TrimSuffix(elem, suffix)
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '_' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, elem)
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ties between the aliases with the greatest weight are reported as having no consensus alias
+	tieBreakNone = "none"
+	// ties are broken in favor of the alias whose most recent import was committed last
+	tieBreakRecent = "recent"
+	// ties are broken in favor of the alias whose earliest import was committed first
+	tieBreakOldest = "oldest"
+)
+
+// validateTieBreak returns an error if the provided tie-breaking strategy is not a valid strategy.
+func validateTieBreak(tieBreak string) error {
+	switch tieBreak {
+	case tieBreakNone, tieBreakRecent, tieBreakOldest:
+		return nil
+	default:
+		return errors.Errorf("invalid tie-break %q: must be one of %v", tieBreak, []string{tieBreakRecent, tieBreakOldest, tieBreakNone})
+	}
+}
+
+// tieBreakDescription returns a description of the alias that is chosen by the provided tie-breaking strategy.
+func tieBreakDescription(tieBreak string) string {
+	if tieBreak == tieBreakOldest {
+		return "earliest used"
+	}
+	return "most recently used"
+}
+
+// blameLineRegexp matches the header line of a line of "git blame --porcelain" output. The first group is the commit
+// and the second group is the line number of the line in the final file.
+var blameLineRegexp = regexp.MustCompile(`^([0-9a-f]{40,64}) [0-9]+ ([0-9]+)`)
+
+// blameTimes caches the author times (in seconds since the epoch) of the lines of files as reported by "git blame".
+// Lines that have not been committed (including all of the lines of files that are not tracked by git) have the current
+// time.
+type blameTimes map[string]map[int]int64
+
+// lineTime returns the author time of the provided line (starting at 1) of the provided file.
+func (b blameTimes) lineTime(file string, line int) (int64, error) {
+	if _, ok := b[file]; !ok {
+		times, err := blameFile(file)
+		if err != nil {
+			return 0, err
+		}
+		b[file] = times
+	}
+	lineTime, ok := b[file][line]
+	if !ok {
+		return 0, errors.Errorf("git blame did not report line %d of %s", line, file)
+	}
+	return lineTime, nil
+}
+
+// breakTie returns the alias among the provided aliases (which all have the greatest weight) that is preferred by the
+// provided tie-breaking strategy: the alias with the most recently committed import for tieBreakRecent or the alias
+// with the earliest committed import for tieBreakOldest. If multiple aliases have the same time, the first of them is
+// returned.
+func (b blameTimes) breakTie(aliases []ImportAliasInfo, tieBreak string) (string, error) {
+	prefer := func(t, other int64) bool {
+		if tieBreak == tieBreakOldest {
+			return t < other
+		}
+		return t > other
+	}
+
+	var chosen string
+	var chosenTime int64
+	for _, currAlias := range aliases {
+		var aliasTime int64
+		first := true
+		for file, pos := range currAlias.Occurrences {
+			t, err := b.lineTime(file, pos.Line)
+			if err != nil {
+				return "", err
+			}
+			if first || prefer(t, aliasTime) {
+				aliasTime = t
+				first = false
+			}
+		}
+		if chosen == "" || prefer(aliasTime, chosenTime) {
+			chosen = currAlias.Alias
+			chosenTime = aliasTime
+		}
+	}
+	return chosen, nil
+}
+
+// blameFile returns a map from the number of every line of the provided file to its author time as reported by "git
+// blame". "git blame" fails for files that are not tracked by git, so every line of such a file has the current time.
+func blameFile(file string) (map[int]int64, error) {
+	tracked, err := isTracked(file)
+	if err != nil {
+		return nil, err
+	}
+	if !tracked {
+		return untrackedFileTimes(file)
+	}
+
+	output, err := runGit(file, "blame", "--porcelain", "--", filepath.Base(file))
+	if err != nil {
+		return nil, err
+	}
+
+	// the author time of a commit is only printed for the first line that is attributed to it
+	commitTimes := make(map[string]int64)
+	lineCommits := make(map[int]string)
+	var commit string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			// content of the line
+			continue
+		}
+		if match := blameLineRegexp.FindStringSubmatch(line); match != nil {
+			commit = match[1]
+			lineNum, _ := strconv.Atoi(match[2])
+			lineCommits[lineNum] = commit
+		} else if strings.HasPrefix(line, "author-time ") {
+			authorTime, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid author time in git blame output for %s: %q", file, line)
+			}
+			commitTimes[commit] = authorTime
+		}
+	}
+
+	times := make(map[int]int64, len(lineCommits))
+	for lineNum, lineCommit := range lineCommits {
+		times[lineNum] = commitTimes[lineCommit]
+	}
+	return times, nil
+}
+
+// isTracked returns true if the provided file is tracked by git (that is, if it has been committed or staged).
+func isTracked(file string) (bool, error) {
+	output, err := runGit(file, "ls-files", "--", filepath.Base(file))
+	if err != nil {
+		return false, err
+	}
+	return len(bytes.TrimSpace(output)) > 0, nil
+}
+
+// untrackedFileTimes returns a map from the number of every line of the provided file to the current time.
+func untrackedFileTimes(file string) (map[int]int64, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", file)
+	}
+	now := time.Now().Unix()
+	nLines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		nLines++
+	}
+	times := make(map[int]int64, nLines)
+	for lineNum := 1; lineNum <= nLines; lineNum++ {
+		times[lineNum] = now
+	}
+	return times, nil
+}
+
+// runGit runs git with the provided arguments in the directory of the provided file and returns its output.
+func runGit(file string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = filepath.Dir(file)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine git history of %s: %s", file, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
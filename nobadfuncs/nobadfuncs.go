@@ -17,22 +17,43 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/nmiyake/pkg/errorstringer"
 	"github.com/palantir/pkg/cli"
 	"github.com/palantir/pkg/cli/flag"
-	"github.com/palantir/pkg/pkgpath"
 	"github.com/pkg/errors"
 
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
 	"github.com/palantir/checks/nobadfuncs/nobadfuncs"
 )
 
 const (
-	printAllFlagName   = "all"
-	jsonConfigFlagName = "config"
-	pkgsFlagName       = "pkgs"
+	printAllFlagName        = "all"
+	listWhitelistedFlagName = "list-whitelisted"
+	listExpiringFlagName    = "list-expiring"
+	statsFlagName           = "stats"
+	previousStatsFlagName   = "previous-stats"
+	jsonConfigFlagName      = "config"
+	bundleFlagName          = "bundle"
+	formatFlagName          = "format"
+	changedOnlyFlagName     = "changed-only"
+	changedFilesFlagName    = "changed-files"
+	changedSinceFlagName    = "changed-since"
+	pkgsFlagName            = "pkgs"
+)
+
+const (
+	textFormat       = diagnostic.FormatText
+	sarifFormat      = "sarif"
+	jsonFormat       = diagnostic.FormatJSON
+	checkstyleFormat = diagnostic.FormatCheckstyle
+	githubFormat     = diagnostic.FormatGitHub
 )
 
 var (
@@ -40,11 +61,65 @@ var (
 		Name:  printAllFlagName,
 		Usage: "print all function references",
 	}
+	listWhitelistedFlag = flag.BoolFlag{
+		Name:  listWhitelistedFlagName,
+		Usage: "print all references to blacklisted functions that are whitelisted along with the recorded reason",
+	}
+	listExpiringFlag = flag.IntFlag{
+		Name:  listExpiringFlagName,
+		Usage: "print all references to blacklisted functions that are whitelisted by a comment of the form '// OK until [YYYY-MM-DD]: [reason]' that expires within the provided number of days",
+	}
+	statsFlag = flag.BoolFlag{
+		Name:  statsFlagName,
+		Usage: "print the number of references and whitelisted references to each blacklisted function",
+	}
+	previousStatsFlag = flag.StringFlag{
+		Name:  previousStatsFlagName,
+		Usage: "path to a stats file written using --" + statsFlagName + " --format json: the change since the previous stats is printed for each count",
+	}
 	jsonFlag = flag.StringFlag{
 		Name: jsonConfigFlagName,
 		Usage: "JSON configuration specifying blacklisted functions. Must be a JSON map from string to string, " +
-			"where the key is a function signature and the value is the failure message printed when a function" +
-			"with that signature is found.",
+			"where the key is a function signature (or a reference to a package-level variable, constant or type " +
+			"such as 'var net/http.DefaultClient') and the value is the failure message printed when a reference " +
+			"to it is found. The value may also be an object with \"message\", \"doc-url\" and \"applies-to\" " +
+			"fields, in which case the documentation URL is appended to the message and references are only " +
+			"reported in source files if \"applies-to\" is 'src' or only in test files if it is 'test' (the " +
+			"default is 'all').",
+	}
+	bundleFlag = flag.StringFlag{
+		Name: bundleFlagName,
+		Usage: "name of a builtin bundle of blacklisted functions to check in addition to the configuration. Can be " +
+			"specified multiple times. Must be one of " + strings.Join(nobadfuncs.BundleNames(), ", ") + ". " +
+			"Bundles can also be selected in the configuration using keys of the form 'bundle <name>'",
+	}
+	formatFlag = flag.StringFlag{
+		Name:  formatFlagName,
+		Value: textFormat,
+		Usage: "format of the output for blacklisted function references. Must be 'text', 'json', 'checkstyle', " +
+			"'github' (GitHub Actions annotations) or 'sarif' (SARIF 2.1.0). Must be 'text' or 'json' when listing whitelisted or expiring references or printing stats.",
+	}
+	baselineFlag = flag.StringFlag{
+		Name:  baseline.FlagName,
+		Usage: "path to a baseline file: references to blacklisted functions recorded in the baseline are not reported",
+	}
+	writeBaselineFlag = flag.StringFlag{
+		Name:  baseline.WriteFlagName,
+		Usage: "path to which a baseline file that records the current references to blacklisted functions is written",
+	}
+	changedOnlyFlag = flag.BoolFlag{
+		Name: changedOnlyFlagName,
+		Usage: "only check the packages that contain changed files and the packages that import them. The changed " +
+			"files are read from the file specified by --" + changedFilesFlagName + " or determined using git",
+	}
+	changedFilesFlag = flag.StringFlag{
+		Name:  changedFilesFlagName,
+		Usage: "path to a file that lists the changed files for --" + changedOnlyFlagName + ", one per line ('-' for stdin)",
+	}
+	changedSinceFlag = flag.StringFlag{
+		Name:  changedSinceFlagName,
+		Usage: "git ref against which changed files are determined for --" + changedOnlyFlagName + " using 'git diff --name-only'",
+		Value: "HEAD",
 	}
 	pkgsFlag = flag.StringSlice{
 		Name:  pkgsFlagName,
@@ -57,33 +132,150 @@ func main() {
 	app.Flags = append(
 		app.Flags,
 		printAllFlag,
+		listWhitelistedFlag,
+		listExpiringFlag,
+		statsFlag,
+		previousStatsFlag,
 		jsonFlag,
+		bundleFlag,
+		formatFlag,
+		baselineFlag,
+		writeBaselineFlag,
+		changedOnlyFlag,
+		changedFilesFlag,
+		changedSinceFlag,
 		pkgsFlag,
 	)
 	app.Action = func(ctx cli.Context) error {
-		pkgPaths, err := getPkgPaths(ctx.Slice(pkgsFlagName))
-		if err != nil {
-			return errors.Wrapf(err, "failed to determine package paths")
+		pkgPatterns := getPkgPatterns(ctx.Slice(pkgsFlagName))
+		if ctx.Bool(changedOnlyFlagName) {
+			var err error
+			if pkgPatterns, err = changedPkgPatterns(ctx, pkgPatterns); err != nil {
+				return err
+			}
+			if len(pkgPatterns) == 0 {
+				// no packages are affected by the changes, so there is nothing to check
+				return nil
+			}
+		}
+
+		format := ctx.String(formatFlagName)
+		if format != sarifFormat {
+			if err := diagnostic.ValidateFormat(format); err != nil {
+				return errors.Errorf("invalid format %q: must be %q, %q, %q, %q or %q", format, textFormat, jsonFormat, checkstyleFormat, githubFormat, sarifFormat)
+			}
 		}
 
 		if ctx.Bool(printAllFlagName) {
-			if err := nobadfuncs.PrintAllFuncRefs(pkgPaths, ctx.App.Stdout); err != nil {
+			if format != textFormat {
+				return errors.Errorf("format %q is not supported when printing all function references", format)
+			}
+			if err := nobadfuncs.PrintAllFuncRefs(pkgPatterns, ctx.App.Stdout); err != nil {
 				return errors.Wrapf(err, "Failed to determine all function references")
 			}
 			return nil
 		}
 
-		var jsonConfig map[string]string
+		var jsonConfig map[string]nobadfuncs.Rule
 		if ctx.Has(jsonConfigFlagName) {
 			if err := json.Unmarshal([]byte(ctx.String(jsonConfigFlagName)), &jsonConfig); err != nil {
 				return errors.Wrapf(err, "failed to read configuration")
 			}
 		}
-		ok, err := nobadfuncs.PrintBadFuncRefs(pkgPaths, jsonConfig, ctx.App.Stdout)
+		var bundleNames []string
+		for _, currBundle := range ctx.StringSlice(bundleFlagName) {
+			if currBundle != "" {
+				bundleNames = append(bundleNames, currBundle)
+			}
+		}
+		jsonConfig, err := nobadfuncs.ExpandBundles(jsonConfig, bundleNames)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Bool(listWhitelistedFlagName) {
+			var err error
+			switch format {
+			case jsonFormat:
+				err = nobadfuncs.PrintWhitelistedFuncRefsJSON(pkgPatterns, nobadfuncs.Messages(jsonConfig), ctx.App.Stdout)
+			case textFormat:
+				err = nobadfuncs.PrintWhitelistedFuncRefs(pkgPatterns, nobadfuncs.Messages(jsonConfig), ctx.App.Stdout)
+			default:
+				return errors.Errorf("format %q is not supported when listing whitelisted function references", format)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to determine whitelisted function references")
+			}
+			return nil
+		}
+
+		if ctx.Has(listExpiringFlagName) {
+			days := ctx.Int(listExpiringFlagName)
+			if days < 0 {
+				return errors.Errorf("invalid number of days %d: must not be negative", days)
+			}
+			var err error
+			switch format {
+			case jsonFormat:
+				err = nobadfuncs.PrintExpiringFuncRefsJSON(pkgPatterns, nobadfuncs.Messages(jsonConfig), days, ctx.App.Stdout)
+			case textFormat:
+				err = nobadfuncs.PrintExpiringFuncRefs(pkgPatterns, nobadfuncs.Messages(jsonConfig), days, ctx.App.Stdout)
+			default:
+				return errors.Errorf("format %q is not supported when listing expiring function references", format)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to determine expiring function references")
+			}
+			return nil
+		}
+
+		if ctx.Bool(statsFlagName) {
+			if format != textFormat && format != jsonFormat {
+				return errors.Errorf("format %q is not supported when printing stats", format)
+			}
+			stats, err := nobadfuncs.FindStats(pkgPatterns, jsonConfig)
+			if err != nil {
+				return errors.Wrapf(err, "failed to determine stats")
+			}
+			if ctx.Has(previousStatsFlagName) {
+				previous, err := nobadfuncs.LoadStats(ctx.String(previousStatsFlagName))
+				if err != nil {
+					return err
+				}
+				nobadfuncs.ApplyPreviousStats(stats, previous)
+			}
+			if format == jsonFormat {
+				return nobadfuncs.WriteStatsJSON(ctx.App.Stdout, stats)
+			}
+			return nobadfuncs.WriteStats(ctx.App.Stdout, stats)
+		}
+
+		wd, err := dirs.GetwdEvalSymLinks()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get working directory")
+		}
+
+		badRefs, err := nobadfuncs.FindBadFuncRefsForRules(pkgPatterns, jsonConfig)
 		if err != nil {
 			return errors.Wrapf(err, "nobadfuncs failed")
 		}
-		if !ok {
+		badRefs, err = nobadfuncs.FilterBadFuncRefs(badRefs, baseline.Options{
+			Path:      ctx.String(baseline.FlagName),
+			WritePath: ctx.String(baseline.WriteFlagName),
+		}, wd)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case sarifFormat:
+			err = nobadfuncs.WriteSARIFForRules(ctx.App.Stdout, jsonConfig, badRefs, wd)
+		default:
+			err = diagnostic.Print(ctx.App.Stdout, format, nobadfuncs.Diagnostics(badRefs))
+		}
+		if err != nil {
+			return err
+		}
+		if len(badRefs) > 0 {
 			// if there was no error but bad references were found, return empty error
 			return fmt.Errorf("")
 		}
@@ -92,19 +284,51 @@ func main() {
 	os.Exit(app.Run(os.Args))
 }
 
-func getPkgPaths(relPaths []string) ([]string, error) {
+// changedPkgPatterns returns the import paths of the packages matched by the provided patterns that are affected by the
+// changed files specified by the flags.
+func changedPkgPatterns(ctx cli.Context, pkgPatterns []string) ([]string, error) {
 	wd, err := dirs.GetwdEvalSymLinks()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get working directory")
 	}
 
-	var goSrcPaths []string
+	var changedFiles []string
+	if changedFilesPath := ctx.String(changedFilesFlagName); changedFilesPath != "" {
+		var r io.Reader = os.Stdin
+		if changedFilesPath != "-" {
+			f, err := os.Open(changedFilesPath)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to open %s", changedFilesPath)
+			}
+			defer func() {
+				_ = f.Close()
+			}()
+			r = f
+		}
+		changedFiles, err = nobadfuncs.ReadChangedFiles(r, wd)
+	} else {
+		changedFiles, err = nobadfuncs.GitChangedFiles(wd, ctx.String(changedSinceFlagName))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine changed files")
+	}
+
+	affected, err := nobadfuncs.AffectedPackages(pkgPatterns, changedFiles)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine packages affected by changed files")
+	}
+	return affected, nil
+}
+
+// getPkgPatterns returns the package patterns for the provided paths. Paths that are not absolute and do not begin
+// with "." are treated as paths relative to the working directory rather than as import paths.
+func getPkgPatterns(relPaths []string) []string {
+	var patterns []string
 	for _, currPkg := range relPaths {
-		newPath, err := pkgpath.NewRelPkgPath(currPkg, wd).GoPathSrcRel()
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to determine package path")
+		if !filepath.IsAbs(currPkg) && !strings.HasPrefix(currPkg, ".") {
+			currPkg = "./" + currPkg
 		}
-		goSrcPaths = append(goSrcPaths, newPath)
+		patterns = append(patterns, currPkg)
 	}
-	return goSrcPaths, nil
+	return patterns
 }
@@ -0,0 +1,155 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// bundleKeyPrefix is the prefix of keys in the configuration that select a builtin rule bundle rather than specifying
+// a signature. For example, the key "bundle time-determinism" selects the "time-determinism" bundle.
+const bundleKeyPrefix = "bundle "
+
+const (
+	// BundleTimeDeterminism is the name of the bundle that blacklists functions that read the wall clock or wait for
+	// real time to pass, which make code non-deterministic and difficult to test.
+	BundleTimeDeterminism = "time-determinism"
+	// BundleUnsafeHTTP is the name of the bundle that blacklists the package-level HTTP client functions and servers
+	// in net/http, which do not have timeouts.
+	BundleUnsafeHTTP = "unsafe-http"
+	// BundleFmtPrintDebug is the name of the bundle that blacklists printing to stdout using the fmt package in
+	// non-test files, which is typically left over from debugging.
+	BundleFmtPrintDebug = "fmt-print-debug"
+)
+
+var bundles = map[string]map[string]Rule{
+	BundleTimeDeterminism: {
+		"func time.Now() time.Time": {
+			Message: "do not read the wall clock directly: inject a clock so that the behavior is deterministic",
+		},
+		"func time.Since(time.Time) time.Duration": {
+			Message: "do not read the wall clock directly: inject a clock so that the behavior is deterministic",
+		},
+		"func time.Until(time.Time) time.Duration": {
+			Message: "do not read the wall clock directly: inject a clock so that the behavior is deterministic",
+		},
+		"func time.Sleep(time.Duration)": {
+			Message: "do not wait for real time to pass: inject a clock or wait for an event instead",
+		},
+		"func time.After(time.Duration) <-chan time.Time": {
+			Message: "do not wait for real time to pass: inject a clock or wait for an event instead",
+		},
+		"func time.Tick(time.Duration) <-chan time.Time": {
+			Message: "do not wait for real time to pass: inject a clock or wait for an event instead",
+		},
+	},
+	BundleUnsafeHTTP: {
+		"func net/http.Get(string) (*net/http.Response, error)": {
+			Message: "net/http.Get uses the default client, which does not have a timeout: use a client with timeouts",
+		},
+		"func net/http.Head(string) (*net/http.Response, error)": {
+			Message: "net/http.Head uses the default client, which does not have a timeout: use a client with timeouts",
+		},
+		"func net/http.Post(string, string, io.Reader) (*net/http.Response, error)": {
+			Message: "net/http.Post uses the default client, which does not have a timeout: use a client with timeouts",
+		},
+		"func net/http.PostForm(string, net/url.Values) (*net/http.Response, error)": {
+			Message: "net/http.PostForm uses the default client, which does not have a timeout: use a client with timeouts",
+		},
+		"var net/http.DefaultClient": {
+			Message: "the default client does not have a timeout: use a client with timeouts",
+		},
+		"func net/http.ListenAndServe(string, net/http.Handler) error": {
+			Message: "net/http.ListenAndServe uses a server without timeouts: use a net/http.Server with timeouts",
+		},
+		"func net/http.ListenAndServeTLS(string, string, string, net/http.Handler) error": {
+			Message: "net/http.ListenAndServeTLS uses a server without timeouts: use a net/http.Server with timeouts",
+		},
+	},
+	BundleFmtPrintDebug: {
+		"func fmt.Print(...interface{}) (int, error)": {
+			Message:   "do not print to stdout: use a logger or write to a provided io.Writer",
+			AppliesTo: AppliesToSrc,
+		},
+		"func fmt.Printf(string, ...interface{}) (int, error)": {
+			Message:   "do not print to stdout: use a logger or write to a provided io.Writer",
+			AppliesTo: AppliesToSrc,
+		},
+		"func fmt.Println(...interface{}) (int, error)": {
+			Message:   "do not print to stdout: use a logger or write to a provided io.Writer",
+			AppliesTo: AppliesToSrc,
+		},
+	},
+}
+
+// BundleNames returns the names of the builtin rule bundles in sorted order.
+func BundleNames() []string {
+	var names []string
+	for name := range bundles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BundleRules returns a copy of the rules in the builtin rule bundle with the provided name. Returns an error if there
+// is no bundle with the provided name.
+func BundleRules(name string) (map[string]Rule, error) {
+	bundle, ok := bundles[name]
+	if !ok {
+		return nil, errors.Errorf("unknown bundle %q: must be one of %v", name, BundleNames())
+	}
+	rules := make(map[string]Rule, len(bundle))
+	for sig, rule := range bundle {
+		rules[sig] = rule
+	}
+	return rules, nil
+}
+
+// ExpandBundles returns the rules that result from expanding the builtin rule bundles with the provided names and the
+// bundles selected in the provided rules using keys of the form "bundle <name>". The rules for the signatures that are
+// specified explicitly in the provided rules take precedence over the rules in the bundles. Returns an error if any of
+// the bundles do not exist.
+func ExpandBundles(rules map[string]Rule, bundleNames []string) (map[string]Rule, error) {
+	var explicit []string
+	for k := range rules {
+		if strings.HasPrefix(k, bundleKeyPrefix) {
+			bundleNames = append(bundleNames, strings.TrimSpace(strings.TrimPrefix(k, bundleKeyPrefix)))
+		} else {
+			explicit = append(explicit, k)
+		}
+	}
+	if len(bundleNames) == 0 {
+		return rules, nil
+	}
+
+	expanded := make(map[string]Rule)
+	for _, name := range bundleNames {
+		bundleRules, err := BundleRules(name)
+		if err != nil {
+			return nil, err
+		}
+		for sig, rule := range bundleRules {
+			expanded[sig] = rule
+		}
+	}
+	for _, sig := range explicit {
+		expanded[sig] = rules[sig]
+	}
+	return expanded, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// ReadChangedFiles reads a list of changed files from the provided reader. Every non-empty line is a path to a file.
+// Paths that are not absolute are resolved relative to baseDir.
+func ReadChangedFiles(r io.Reader, baseDir string) ([]string, error) {
+	var files []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(baseDir, line)
+		}
+		files = append(files, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read changed files")
+	}
+	return files, nil
+}
+
+// GitChangedFiles returns the absolute paths of the files in the git repository that contains dir that differ from the
+// provided ref as reported by "git diff --name-only <ref>". Untracked files are not included.
+func GitChangedFiles(dir, ref string) ([]string, error) {
+	topLevel, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := runGit(dir, "diff", "--name-only", ref)
+	if err != nil {
+		return nil, err
+	}
+	return ReadChangedFiles(strings.NewReader(diff), strings.TrimSpace(topLevel))
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", errors.Wrapf(err, "git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", errors.Wrapf(err, "git %s failed", strings.Join(args, " "))
+	}
+	return string(output), nil
+}
+
+// AffectedPackages returns the import paths of the packages matched by the provided patterns that are affected by the
+// provided changed files, sorted by import path. A package is affected if one of the changed Go files is in its
+// directory (including its in-package tests) or if it imports an affected package, either directly or through other
+// packages matched by the patterns. Only the imports of the packages are loaded, so no packages are type-checked.
+func AffectedPackages(patterns []string, changedFiles []string) ([]string, error) {
+	changedDirs := make(map[string]struct{})
+	for _, file := range changedFiles {
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+		changedDirs[realPath(filepath.Dir(file))] = struct{}{}
+	}
+	if len(changedDirs) == 0 {
+		return nil, nil
+	}
+
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports,
+		Tests: true,
+	}
+	roots, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load packages")
+	}
+
+	affected := make(map[string]struct{})
+	importers := make(map[string][]string)
+	for _, pkg := range roots {
+		if strings.HasSuffix(pkg.ID, ".test") || (strings.HasSuffix(pkg.Name, "_test") && strings.HasSuffix(pkg.PkgPath, "_test")) {
+			// skip generated test mains and external test packages, which are not checked
+			continue
+		}
+		dir := pkg.Dir
+		if dir == "" && len(pkg.GoFiles) > 0 {
+			dir = filepath.Dir(pkg.GoFiles[0])
+		}
+		if _, ok := changedDirs[realPath(dir)]; ok {
+			affected[pkg.PkgPath] = struct{}{}
+		}
+		for _, imported := range pkg.Imports {
+			importers[imported.PkgPath] = append(importers[imported.PkgPath], pkg.PkgPath)
+		}
+	}
+
+	// mark the packages that import affected packages as affected
+	queue := make([]string, 0, len(affected))
+	for pkgPath := range affected {
+		queue = append(queue, pkgPath)
+	}
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+		for _, importer := range importers[curr] {
+			if _, ok := affected[importer]; ok {
+				continue
+			}
+			affected[importer] = struct{}{}
+			queue = append(queue, importer)
+		}
+	}
+
+	var sortedPaths []string
+	for pkgPath := range affected {
+		sortedPaths = append(sortedPaths, pkgPath)
+	}
+	sort.Strings(sortedPaths)
+	return sortedPaths, nil
+}
+
+// realPath returns the provided path with symbolic links evaluated. If the path does not exist (for example, because it
+// was removed), the path of its closest existing parent directory is evaluated instead.
+func realPath(path string) string {
+	path = filepath.Clean(path)
+	if evaluated, err := filepath.EvalSymlinks(path); err == nil {
+		return evaluated
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(realPath(parent), filepath.Base(path))
+}
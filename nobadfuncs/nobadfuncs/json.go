@@ -0,0 +1,74 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type jsonWhitelistedFuncRef struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	Signature string `json:"signature"`
+	Reason    string `json:"reason"`
+	Expires   string `json:"expires,omitempty"`
+}
+
+// PrintWhitelistedFuncRefsJSON prints all of the references to the functions in "sigs" in the provided packages that
+// are whitelisted as a JSON array.
+func PrintWhitelistedFuncRefsJSON(pkgs []string, sigs map[string]string, stdout io.Writer) error {
+	whitelistedRefs, err := FindWhitelistedFuncRefs(pkgs, sigs)
+	if err != nil {
+		return err
+	}
+	return writeWhitelistedJSON(stdout, whitelistedRefs)
+}
+
+// PrintExpiringFuncRefsJSON prints the references to the functions in "sigs" in the provided packages that are
+// whitelisted by a whitelist comment that expires within the provided number of days from today as a JSON array.
+func PrintExpiringFuncRefsJSON(pkgs []string, sigs map[string]string, days int, stdout io.Writer) error {
+	expiringRefs, err := FindExpiringFuncRefs(pkgs, sigs, days)
+	if err != nil {
+		return err
+	}
+	return writeWhitelistedJSON(stdout, expiringRefs)
+}
+
+func writeWhitelistedJSON(w io.Writer, whitelistedRefs []WhitelistedFuncRef) error {
+	out := make([]jsonWhitelistedFuncRef, len(whitelistedRefs))
+	for i, ref := range whitelistedRefs {
+		out[i] = jsonWhitelistedFuncRef{
+			File:      ref.Pos.Filename,
+			Line:      ref.Pos.Line,
+			Column:    ref.Pos.Column,
+			Signature: string(ref.Sig),
+			Reason:    ref.Reason,
+			Expires:   ref.Expires,
+		}
+	}
+	bytes, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal whitelisted function references")
+	}
+	if _, err := fmt.Fprintln(w, string(bytes)); err != nil {
+		return errors.Wrapf(err, "failed to write whitelisted function references")
+	}
+	return nil
+}
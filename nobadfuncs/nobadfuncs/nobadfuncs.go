@@ -17,92 +17,387 @@ package nobadfuncs
 import (
 	"fmt"
 	"go/ast"
-	"go/build"
-	"go/parser"
 	"go/token"
 	"go/types"
 	"io"
 	"regexp"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
-	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
 )
 
-// FuncRef is a reference to a specific function. Matches the string representation of *types.Func, which is of the
-// form "func (*net/http.Client).Do(req *net/http.Request) (*net/http.Response, error)".
+// FuncRef is a reference to a specific function or to a package-level variable, constant or type. For functions, it
+// matches the string representation of *types.Func with the names of parameters and results removed, which is of the
+// form "func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)". For package-level variables,
+// constants and types, it is of the form "var net/http.DefaultClient", "const math.MaxInt64" or "type net/http.Client".
 type FuncRef string
 
+// BadFuncRef is a reference to a blacklisted function.
+type BadFuncRef struct {
+	// Pos is the position of the reference.
+	Pos token.Position
+	// Sig is the signature of the referenced function.
+	Sig FuncRef
+	// Msg is the failure message for the reference.
+	Msg string
+	// DocURL is the URL of the documentation for the blacklisted function. May be empty.
+	DocURL string
+}
+
+// WhitelistedFuncRef is a reference to a blacklisted function that is whitelisted by a comment of the form
+// "// OK: [reason]" or "// OK until [YYYY-MM-DD]: [reason]" on the line before it.
+type WhitelistedFuncRef struct {
+	// Pos is the position of the reference.
+	Pos token.Position
+	// Sig is the signature of the referenced function.
+	Sig FuncRef
+	// Reason is the reason recorded in the whitelist comment.
+	Reason string
+	// Expires is the last date (of the form "YYYY-MM-DD") on which the whitelist comment applies. Empty if the
+	// whitelist comment does not expire.
+	Expires string
+}
+
+// PrintAllFuncRefs prints all of the function references in the provided packages. References to package-level
+// variables, constants and types are not printed.
 func PrintAllFuncRefs(pkgs []string, stdout io.Writer) error {
-	_, err := printFuncRefUsages(pkgs, nil, stdout)
-	return err
+	return visitFuncRefUsages(pkgs, nil, func(pos token.Position, ref FuncRef, expired string) {
+		if !strings.HasPrefix(string(ref), "func ") {
+			return
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", pos.String(), ref)
+	}, nil)
 }
 
 func PrintBadFuncRefs(pkgs []string, sigs map[string]string, stdout io.Writer) (bool, error) {
-	if len(sigs) == 0 {
-		// if there are no signatures, there will be no output
-		return true, nil
+	return PrintBadFuncRefsDiagnostics(pkgs, sigs, diagnostic.FormatText, stdout)
+}
+
+// PrintBadFuncRefsDiagnostics writes the references to blacklisted functions in the provided packages as diagnostics in
+// the provided format (see the diagnostic package for the supported formats).
+func PrintBadFuncRefsDiagnostics(pkgs []string, sigs map[string]string, format string, stdout io.Writer) (bool, error) {
+	badRefs, err := FindBadFuncRefs(pkgs, sigs)
+	if err != nil {
+		return false, err
+	}
+	if err := diagnostic.Print(stdout, format, Diagnostics(badRefs)); err != nil {
+		return false, err
 	}
-	return printFuncRefUsages(pkgs, sigs, stdout)
+	return len(badRefs) == 0, nil
 }
 
-func printFuncRefUsages(pkgs []string, sigs map[string]string, stdout io.Writer) (bool, error) {
-	loadcfg := loader.Config{
-		Build:      &build.Default,
-		ParserMode: parser.ParseComments,
+// Diagnostics returns the diagnostics for the provided references to blacklisted functions. The rule ID of each
+// diagnostic is the ID of the SARIF rule for the referenced signature.
+func Diagnostics(badRefs []BadFuncRef) []diagnostic.Diagnostic {
+	var diags []diagnostic.Diagnostic
+	for _, ref := range badRefs {
+		diag := diagnostic.New(ref.Pos, "nobadfuncs", RuleID(string(ref.Sig)), ref.Msg)
+		diag.DocURL = ref.DocURL
+		diags = append(diags, diag)
 	}
-	// add all packages to load
-	for _, currPkg := range pkgs {
-		loadcfg.ImportWithTests(currPkg)
+	return diags
+}
+
+// PrintBadFuncRefsSARIF writes the references to blacklisted functions in the provided packages as a SARIF log. Every
+// signature in "sigs" is reported as a rule regardless of whether or not it is referenced. The locations of the
+// references are written relative to baseDir if they are within it.
+func PrintBadFuncRefsSARIF(pkgs []string, sigs map[string]string, baseDir string, stdout io.Writer) (bool, error) {
+	badRefs, err := FindBadFuncRefs(pkgs, sigs)
+	if err != nil {
+		return false, err
 	}
+	if err := WriteSARIF(stdout, sigs, badRefs, baseDir); err != nil {
+		return false, err
+	}
+	return len(badRefs) == 0, nil
+}
 
-	// load program
-	prog, err := loadcfg.Load()
+// FilterBadFuncRefs applies the provided baseline options to the provided references to blacklisted functions. If a
+// baseline is written, the baseline records all of the references and no references are returned. Otherwise, the
+// references that are not suppressed by the baseline are returned. The files of the references are recorded in the
+// baseline relative to baseDir.
+func FilterBadFuncRefs(badRefs []BadFuncRef, bl baseline.Options, baseDir string) ([]BadFuncRef, error) {
+	if bl.WritePath != "" {
+		if err := baseline.New(baseDir, Diagnostics(badRefs)).Write(bl.WritePath); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if bl.Path == "" {
+		return badRefs, nil
+	}
+	b, err := baseline.Load(bl.Path, baseDir)
 	if err != nil {
-		return false, errors.Wrapf(err, "failed to load program")
+		return nil, err
+	}
+	var filtered []BadFuncRef
+	for i, diag := range Diagnostics(badRefs) {
+		if !b.Suppress(diag) {
+			filtered = append(filtered, badRefs[i])
+		}
+	}
+	return filtered, nil
+}
+
+// FindBadFuncRefs returns all of the references to the functions in "sigs" in the provided packages. References that
+// are whitelisted are not returned. The returned references are sorted by package, file and position.
+func FindBadFuncRefs(pkgs []string, sigs map[string]string) ([]BadFuncRef, error) {
+	return FindBadFuncRefsForRules(pkgs, rulesForMessages(sigs))
+}
+
+// FindBadFuncRefsForRules returns all of the references to the signatures in "rules" in the provided packages. The
+// message of each reference is determined by the rule for its signature. References that are whitelisted and
+// references in files to which the rule for their signature does not apply are not returned. References whose whitelist
+// comment has expired are returned with a message that states that the waiver expired. The returned references are
+// sorted by package, file and position.
+func FindBadFuncRefsForRules(pkgs []string, rules map[string]Rule) ([]BadFuncRef, error) {
+	if len(rules) == 0 {
+		// if there are no signatures, there will be no output
+		return nil, nil
+	}
+	var badRefs []BadFuncRef
+	if err := visitFuncRefUsages(pkgs, Messages(rules), func(pos token.Position, ref FuncRef, expired string) {
+		rule, ok := rules[string(ref)]
+		if !ok || !rule.appliesToFile(pos.Filename) {
+			return
+		}
+		msg := failureMsg(ref, rule)
+		if expired != "" {
+			msg = fmt.Sprintf("expired waiver: the whitelist comment for this reference only applied until %s. %s", expired, msg)
+		}
+		badRefs = append(badRefs, BadFuncRef{
+			Pos:    pos,
+			Sig:    ref,
+			Msg:    msg,
+			DocURL: rule.DocURL,
+		})
+	}, nil); err != nil {
+		return nil, err
 	}
-	sort.Strings(pkgs)
+	return badRefs, nil
+}
 
-	noBadRefs := true
-	for _, currPkg := range pkgs {
-		info := prog.Package(currPkg)
-		if info == nil {
-			panic(fmt.Sprintf("failed to find %s in %v; imported %v", currPkg, prog.AllPackages, prog.Imported))
+// PrintWhitelistedFuncRefs prints all of the references to the functions in "sigs" in the provided packages that are
+// whitelisted along with the reason recorded for each.
+func PrintWhitelistedFuncRefs(pkgs []string, sigs map[string]string, stdout io.Writer) error {
+	whitelistedRefs, err := FindWhitelistedFuncRefs(pkgs, sigs)
+	if err != nil {
+		return err
+	}
+	for _, ref := range whitelistedRefs {
+		if ref.Expires != "" {
+			fmt.Fprintf(stdout, "%s: %s is whitelisted until %s: %s\n", ref.Pos.String(), ref.Sig, ref.Expires, ref.Reason)
+			continue
 		}
+		fmt.Fprintf(stdout, "%s: %s is whitelisted: %s\n", ref.Pos.String(), ref.Sig, ref.Reason)
+	}
+	return nil
+}
+
+// FindWhitelistedFuncRefs returns all of the references to the functions in "sigs" in the provided packages that are
+// whitelisted. References whose whitelist comment has expired are not returned. The returned references are sorted by
+// package, file and position.
+func FindWhitelistedFuncRefs(pkgs []string, sigs map[string]string) ([]WhitelistedFuncRef, error) {
+	if len(sigs) == 0 {
+		return nil, nil
+	}
+	var whitelistedRefs []WhitelistedFuncRef
+	if err := visitFuncRefUsages(pkgs, sigs, func(token.Position, FuncRef, string) {}, func(pos token.Position, ref FuncRef, w waiver) {
+		whitelistedRefs = append(whitelistedRefs, WhitelistedFuncRef{
+			Pos:     pos,
+			Sig:     ref,
+			Reason:  w.reason,
+			Expires: w.expires,
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return whitelistedRefs, nil
+}
 
-		funcRefMap := filePosFuncRefMap(info.Uses, prog.Fset, sigs)
+// FindExpiringFuncRefs returns the references to the functions in "sigs" in the provided packages that are whitelisted
+// by a whitelist comment that expires within the provided number of days from today. References whose whitelist
+// comment has already expired are reported as references to blacklisted functions and are not returned. The returned
+// references are sorted by package, file and position.
+func FindExpiringFuncRefs(pkgs []string, sigs map[string]string, days int) ([]WhitelistedFuncRef, error) {
+	whitelistedRefs, err := FindWhitelistedFuncRefs(pkgs, sigs)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().AddDate(0, 0, days).Format(waiverDateLayout)
+	var expiringRefs []WhitelistedFuncRef
+	for _, ref := range whitelistedRefs {
+		if ref.Expires != "" && ref.Expires <= cutoff {
+			expiringRefs = append(expiringRefs, ref)
+		}
+	}
+	return expiringRefs, nil
+}
+
+// PrintExpiringFuncRefs prints the references to the functions in "sigs" in the provided packages that are whitelisted
+// by a whitelist comment that expires within the provided number of days from today along with the expiration date and
+// reason recorded for each.
+func PrintExpiringFuncRefs(pkgs []string, sigs map[string]string, days int, stdout io.Writer) error {
+	expiringRefs, err := FindExpiringFuncRefs(pkgs, sigs, days)
+	if err != nil {
+		return err
+	}
+	for _, ref := range expiringRefs {
+		fmt.Fprintf(stdout, "%s: %s is whitelisted until %s: %s\n", ref.Pos.String(), ref.Sig, ref.Expires, ref.Reason)
+	}
+	return nil
+}
+
+func defaultMsg(ref FuncRef) string {
+	return fmt.Sprintf("references to %q are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", ref)
+}
+
+// visitFuncRefUsages loads the provided packages and calls the visitor on the function references in them. If "sigs" is
+// empty, the visitor is called for all function references and whitelist comments are ignored. Otherwise, the visitor
+// is only called for references that match a signature in "sigs" and that are not whitelisted. If the whitelist
+// comment for a reference has expired, the reference is not whitelisted and the visitor is called with the date on
+// which the whitelist comment expired (otherwise, the date is empty). If whitelistVisitor is non-nil, it is called for
+// the references that match a signature in "sigs" and are whitelisted along with the whitelist comment.
+func visitFuncRefUsages(patterns []string, sigs map[string]string, visitor func(token.Position, FuncRef, string), whitelistVisitor func(token.Position, FuncRef, waiver)) error {
+	loaded, err := loadPackages(patterns)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Format(waiverDateLayout)
+	for _, pkg := range loaded {
+		funcRefMap := filePosFuncRefMap(pkg.TypesInfo.Uses, pkg.Fset, sigs)
 		if len(sigs) == 0 {
-			// "all" mode: print all references
+			// "all" mode: visit all references
 			visitInOrder(funcRefMap, func(pos token.Position, ref FuncRef) {
-				fmt.Fprintf(stdout, "%s: %s\n", pos.String(), ref)
+				visitor(pos, ref, "")
 			})
 			continue
 		}
 
-		commentMap := fileLineCommentMap(prog.Fset, info.Files)
+		commentMap := fileLineCommentMap(pkg.Fset, pkg.Syntax)
 
-		// filter out any matches that have a whitelist comment
-		filterFuncRefs(funcRefMap, commentMap, okCommentRegxp.MatchString)
+		// filter out any matches that have a whitelist comment that has not expired
+		whitelisted := filterFuncRefs(funcRefMap, commentMap, func(comment string) bool {
+			w, ok := parseOKComment(comment)
+			return ok && !w.expired(today)
+		})
 
 		visitInOrder(funcRefMap, func(pos token.Position, ref FuncRef) {
-			reason, ok := sigs[string(ref)]
-			if !ok {
-				return
-			}
-			noBadRefs = false
-			if reason == "" {
-				reason = fmt.Sprintf("references to %q are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", ref)
+			var expired string
+			if w, ok := parseOKComment(commentMap[pos.Filename][pos.Line-1]); ok {
+				expired = w.expires
 			}
-			fmt.Fprintf(stdout, "%s: %s\n", pos.String(), reason)
+			visitor(pos, ref, expired)
 		})
+		if whitelistVisitor != nil {
+			visitInOrder(whitelisted, func(pos token.Position, ref FuncRef) {
+				w, _ := parseOKComment(commentMap[pos.Filename][pos.Line-1])
+				whitelistVisitor(pos, ref, w)
+			})
+		}
 	}
-	return noBadRefs, nil
+	return nil
 }
 
-// matches a single-line comment beginning with "// OK: " followed by at least one non-whitespace character.
-var okCommentRegxp = regexp.MustCompile(regexp.QuoteMeta(`// OK: `) + `\S.*`)
+// loadPackages loads the packages matched by the provided patterns along with their in-package tests. Patterns are
+// interpreted by the "go" tool relative to the working directory, so both GOPATH and module projects are supported and
+// build flags such as tags can be specified using the GOFLAGS environment variable. Returns one package per matched
+// import path sorted by import path: if a package has in-package tests, the test variant of the package (which contains
+// the non-test files as well) is returned.
+func loadPackages(patterns []string) ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Tests: true,
+	}
+	roots, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load packages")
+	}
+
+	var loadErrs []string
+	packages.Visit(roots, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			loadErrs = append(loadErrs, err.Error())
+		}
+	})
+	if len(loadErrs) > 0 {
+		return nil, errors.Errorf("failed to load packages:\n%s", strings.Join(loadErrs, "\n"))
+	}
 
-func filterFuncRefs(funcRefs map[string]map[token.Position]FuncRef, comments map[string]map[int]string, filter func(string) bool) {
+	pkgsByPath := make(map[string]*packages.Package)
+	for _, pkg := range roots {
+		if strings.HasSuffix(pkg.ID, ".test") || (strings.HasSuffix(pkg.Name, "_test") && strings.HasSuffix(pkg.PkgPath, "_test")) {
+			// skip generated test mains and external test packages
+			continue
+		}
+		if existing, ok := pkgsByPath[pkg.PkgPath]; ok && existing.ID != existing.PkgPath {
+			// test variant has already been recorded
+			continue
+		}
+		pkgsByPath[pkg.PkgPath] = pkg
+	}
+
+	var sortedPaths []string
+	for pkgPath := range pkgsByPath {
+		sortedPaths = append(sortedPaths, pkgPath)
+	}
+	sort.Strings(sortedPaths)
+
+	loaded := make([]*packages.Package, len(sortedPaths))
+	for i, pkgPath := range sortedPaths {
+		loaded[i] = pkgsByPath[pkgPath]
+	}
+	return loaded, nil
+}
+
+// matches a single-line comment beginning with "// OK: " or "// OK until YYYY-MM-DD: " followed by at least one
+// non-whitespace character.
+var okCommentRegxp = regexp.MustCompile(regexp.QuoteMeta(`// OK`) + `(?: until (\d{4}-\d{2}-\d{2}))?: (\S.*)`)
+
+// waiverDateLayout is the layout of the expiration dates of whitelist comments.
+const waiverDateLayout = "2006-01-02"
+
+// waiver is a whitelist comment.
+type waiver struct {
+	reason string
+	// last date on which the whitelist comment applies. Empty if the comment does not expire.
+	expires string
+}
+
+// expired returns true if the waiver expired before the provided date (which must be of the form "YYYY-MM-DD").
+func (w waiver) expired(today string) bool {
+	return w.expires != "" && today > w.expires
+}
+
+// parseOKComment returns the waiver for the provided comment. Returns false if the comment is not a whitelist comment
+// or if its expiration date is not a valid date.
+func parseOKComment(comment string) (waiver, bool) {
+	match := okCommentRegxp.FindStringSubmatch(comment)
+	if match == nil {
+		return waiver{}, false
+	}
+	if match[1] != "" {
+		if _, err := time.Parse(waiverDateLayout, match[1]); err != nil {
+			return waiver{}, false
+		}
+	}
+	return waiver{
+		reason:  strings.TrimSpace(match[2]),
+		expires: match[1],
+	}, true
+}
+
+// filterFuncRefs removes the entries in funcRefs that have a comment on the line before them for which filter returns
+// true. Returns the removed entries.
+func filterFuncRefs(funcRefs map[string]map[token.Position]FuncRef, comments map[string]map[int]string, filter func(string) bool) map[string]map[token.Position]FuncRef {
+	removed := make(map[string]map[token.Position]FuncRef)
 	for file, posToFuncRef := range funcRefs {
 		lineToComment, ok := comments[file]
 		if !ok {
@@ -120,10 +415,15 @@ func filterFuncRefs(funcRefs map[string]map[token.Position]FuncRef, comments map
 
 			// if filter matches, remove entry from map
 			if filter(commentForLine) {
+				if removed[file] == nil {
+					removed[file] = make(map[token.Position]FuncRef)
+				}
+				removed[file][pos] = posToFuncRef[pos]
 				delete(posToFuncRef, pos)
 			}
 		}
 	}
+	return removed
 }
 
 func visitInOrder(funcRefs map[string]map[token.Position]FuncRef, visitor func(token.Position, FuncRef)) {
@@ -180,9 +480,9 @@ func fileLineCommentMap(fset *token.FileSet, files []*ast.File) map[string]map[i
 	return fileToLineToComment
 }
 
-// filePosFuncRefMap returns a map from filename to position to FuncRef for all of the function references in the
-// specified package. If "sigs" is non-empty, then only function signature that match a key in the "sigs" map are
-// included; otherwise, all function references are returned.
+// filePosFuncRefMap returns a map from filename to position to FuncRef for all of the references to functions and
+// package-level variables, constants and types in the specified package. If "sigs" is non-empty, then only references
+// that match a key in the "sigs" map are included; otherwise, all references are returned.
 func filePosFuncRefMap(uses map[*ast.Ident]types.Object, fset *token.FileSet, sigs map[string]string) map[string]map[token.Position]FuncRef {
 	fileToPosToFuncRef := make(map[string]map[token.Position]FuncRef)
 
@@ -193,17 +493,11 @@ func filePosFuncRefMap(uses map[*ast.Ident]types.Object, fset *token.FileSet, si
 	sort.Sort(identSlice(keys))
 
 	for _, id := range keys {
-		obj := uses[id]
-		funcPtr, ok := obj.(*types.Func)
+		currSig, ok := toFuncRef(uses[id])
 		if !ok {
 			continue
 		}
 
-		// transform function to a form where names are removed from receivers, params and return values
-		// and package references have path to the vendor directory removed.
-		funcPtr = toFuncWithNoIdentifiersRemoveVendor(funcPtr)
-		currSig := FuncRef(funcPtr.String())
-
 		if len(sigs) > 0 {
 			if _, ok := sigs[string(currSig)]; !ok {
 				// if sigs is non-empty, skip any entries that don't match the signature
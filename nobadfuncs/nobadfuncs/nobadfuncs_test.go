@@ -16,20 +16,24 @@ package nobadfuncs_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nmiyake/pkg/dirs"
 	"github.com/nmiyake/pkg/gofiles"
-	"github.com/palantir/pkg/pkgpath"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
 	"github.com/palantir/checks/nobadfuncs/nobadfuncs"
 )
 
@@ -217,6 +221,57 @@ func TypeAlias() {
 				}, "\n") + "\n"
 			},
 		},
+		{
+			name: "references to package-level variables, constants and types",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/bar"
+)
+
+func Globals() {
+	http.DefaultClient.Do(nil)
+	_ = math.MaxInt64
+	var c http.Client
+	_ = c
+	// OK: the default transport is used deliberately
+	_ = http.DefaultTransport
+	bar.Val = 1
+}
+`,
+				},
+				{
+					RelPath: "vendor/github.com/bar/bar.go",
+					Src: `
+package bar
+
+var Val int
+`,
+				},
+			},
+			sigs: map[string]string{
+				"var net/http.DefaultClient":    "",
+				"var net/http.DefaultTransport": "No transport",
+				"const math.MaxInt64":           "No max",
+				"type net/http.Client":          "No client",
+				"var github.com/bar.Val":        "No val",
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:12:7: references to \"var net/http.DefaultClient\" are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(wd, testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:13:11: No max", path.Join(wd, testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:14:13: No client", path.Join(wd, testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:18:6: No val", path.Join(wd, testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
 		{
 			name: "package with multiple files handled properly",
 			specs: []gofiles.GoFileSpec{
@@ -268,7 +323,7 @@ func Foo2() {
 
 		pkgs := make(map[string]struct{})
 		for _, val := range files {
-			currPkg, err := pkgpath.NewAbsPkgPath(path.Dir(val.Path)).GoPathSrcRel()
+			currPkg, err := filepath.Abs(path.Dir(val.Path))
 			require.NoError(t, err)
 			pkgs[currPkg] = struct{}{}
 		}
@@ -287,6 +342,749 @@ func Foo2() {
 
 }
 
+func TestPrintBadFuncRefsSARIF(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `
+package foo
+
+import (
+	"net/http"
+)
+
+func MyFunction() {
+	http.DefaultClient.Do(nil)
+	http.DefaultClient.Get("")
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+
+	const (
+		doSig  = "func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)"
+		getSig = "func (*net/http.Client).Get(string) (*net/http.Response, error)"
+	)
+	var got bytes.Buffer
+	ok, err := nobadfuncs.PrintBadFuncRefsSARIF([]string{pkg}, map[string]string{
+		doSig:  "",
+		getSig: "TEST: don't use this please",
+	}, path.Join(wd, tmpDir), &got)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	want := fmt.Sprintf(`{
+  "version": "2.1.0",
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "nobadfuncs",
+          "informationUri": "https://github.com/palantir/checks/tree/master/nobadfuncs",
+          "rules": [
+            {
+              "id": %q,
+              "name": %q,
+              "shortDescription": {"text": %q}
+            },
+            {
+              "id": %q,
+              "name": %q,
+              "shortDescription": {"text": %q},
+              "fullDescription": {"text": "TEST: don't use this please"}
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": %q,
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {"text": %q},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "foo/foo.go", "uriBaseId": "%%SRCROOT%%"},
+                "region": {"startLine": 9, "startColumn": 21}
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": %q,
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {"text": "TEST: don't use this please"},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "foo/foo.go", "uriBaseId": "%%SRCROOT%%"},
+                "region": {"startLine": 10, "startColumn": 21}
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`,
+		nobadfuncs.RuleID(doSig), doSig, doSig,
+		nobadfuncs.RuleID(getSig), getSig, getSig,
+		nobadfuncs.RuleID(doSig), fmt.Sprintf("references to %q are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", doSig),
+		nobadfuncs.RuleID(getSig),
+	)
+	assert.JSONEq(t, want, got.String())
+}
+
+func TestPrintBadFuncRefsDiagnostics(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `
+package foo
+
+import (
+	"net/http"
+)
+
+func MyFunction() {
+	http.DefaultClient.Get("")
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+	filename, err := filepath.Abs(files["foo/foo.go"].Path)
+	require.NoError(t, err)
+
+	const getSig = "func (*net/http.Client).Get(string) (*net/http.Response, error)"
+	var got bytes.Buffer
+	ok, err := nobadfuncs.PrintBadFuncRefsDiagnostics([]string{pkg}, map[string]string{
+		getSig: "TEST: don't use this please",
+	}, diagnostic.FormatJSON, &got)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	want := fmt.Sprintf(`[
+  {
+    "file": %q,
+    "line": 9,
+    "col": 21,
+    "check": "nobadfuncs",
+    "ruleId": %q,
+    "message": "TEST: don't use this please",
+    "severity": "error"
+  }
+]`, filename, nobadfuncs.RuleID(getSig))
+	assert.JSONEq(t, want, got.String())
+}
+
+func TestRuleDocURL(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `
+package foo
+
+import (
+	"net/http"
+)
+
+func MyFunction() {
+	http.DefaultClient.Do(nil)
+	http.DefaultClient.Get("")
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+
+	const (
+		doSig  = "func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)"
+		getSig = "func (*net/http.Client).Get(string) (*net/http.Response, error)"
+	)
+	var rules map[string]nobadfuncs.Rule
+	err = json.Unmarshal([]byte(fmt.Sprintf(`{
+  %q: {"doc-url": "https://example.com/do"},
+  %q: {"message": "use the shared client", "doc-url": "https://example.com/get"}
+}`, doSig, getSig)), &rules)
+	require.NoError(t, err)
+
+	badRefs, err := nobadfuncs.FindBadFuncRefsForRules([]string{pkg}, rules)
+	require.NoError(t, err)
+	require.Len(t, badRefs, 2)
+	assert.Equal(t, fmt.Sprintf("references to %q are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it. (see https://example.com/do)", doSig), badRefs[0].Msg)
+	assert.Equal(t, "use the shared client (see https://example.com/get)", badRefs[1].Msg)
+
+	diags := nobadfuncs.Diagnostics(badRefs)
+	require.Len(t, diags, 2)
+	assert.Equal(t, "https://example.com/do", diags[0].DocURL)
+	assert.Equal(t, "https://example.com/get", diags[1].DocURL)
+
+	var sarif bytes.Buffer
+	err = nobadfuncs.WriteSARIFForRules(&sarif, rules, badRefs, "")
+	require.NoError(t, err)
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						Name    string `json:"name"`
+						HelpURI string `json:"helpUri"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+		} `json:"runs"`
+	}
+	err = json.Unmarshal(sarif.Bytes(), &log)
+	require.NoError(t, err)
+	require.Len(t, log.Runs, 1)
+	rulesOut := log.Runs[0].Tool.Driver.Rules
+	require.Len(t, rulesOut, 2)
+	assert.Equal(t, doSig, rulesOut[0].Name)
+	assert.Equal(t, "https://example.com/do", rulesOut[0].HelpURI)
+	assert.Equal(t, getSig, rulesOut[1].Name)
+	assert.Equal(t, "https://example.com/get", rulesOut[1].HelpURI)
+}
+
+func TestRuleUnmarshalJSON(t *testing.T) {
+	var rules map[string]nobadfuncs.Rule
+	err := json.Unmarshal([]byte(`{"a": "message", "b": {"message": "other", "doc-url": "https://example.com"}}`), &rules)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]nobadfuncs.Rule{
+		"a": {Message: "message"},
+		"b": {Message: "other", DocURL: "https://example.com"},
+	}, rules)
+
+	err = json.Unmarshal([]byte(`{"a": 1}`), &rules)
+	assert.EqualError(t, err, `rule must be a string or an object with "message", "doc-url" and "applies-to" fields: 1`)
+
+	err = json.Unmarshal([]byte(`{"a": {"applies-to": "src"}, "b": {"applies-to": "test"}}`), &rules)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]nobadfuncs.Rule{
+		"a": {AppliesTo: nobadfuncs.AppliesToSrc},
+		"b": {AppliesTo: nobadfuncs.AppliesToTest},
+	}, rules)
+
+	err = json.Unmarshal([]byte(`{"a": {"applies-to": "tests"}}`), &rules)
+	assert.EqualError(t, err, `invalid applies-to value "tests": must be "src", "test" or "all"`)
+}
+
+func TestRuleAppliesTo(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(1)
+}
+`,
+		},
+		{
+			RelPath: "foo/foo_test.go",
+			Src: `package foo
+
+import "os"
+
+func fooTestHelper() {
+	os.Exit(1)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+	fooPath, err := filepath.Abs(files["foo/foo.go"].Path)
+	require.NoError(t, err)
+	fooTestPath, err := filepath.Abs(files["foo/foo_test.go"].Path)
+	require.NoError(t, err)
+
+	const exitSig = "func os.Exit(int)"
+	for i, currCase := range []struct {
+		appliesTo string
+		want      []string
+	}{
+		{"", []string{fooPath, fooTestPath}},
+		{nobadfuncs.AppliesToAll, []string{fooPath, fooTestPath}},
+		{nobadfuncs.AppliesToSrc, []string{fooPath}},
+		{nobadfuncs.AppliesToTest, []string{fooTestPath}},
+	} {
+		badRefs, err := nobadfuncs.FindBadFuncRefsForRules([]string{pkg}, map[string]nobadfuncs.Rule{
+			exitSig: {AppliesTo: currCase.appliesTo},
+		})
+		require.NoError(t, err, "Case %d", i)
+		var got []string
+		for _, ref := range badRefs {
+			got = append(got, ref.Pos.Filename)
+		}
+		assert.Equal(t, currCase.want, got, "Case %d", i)
+	}
+}
+
+func TestExpandBundles(t *testing.T) {
+	timeRules, err := nobadfuncs.BundleRules(nobadfuncs.BundleTimeDeterminism)
+	require.NoError(t, err)
+	httpRules, err := nobadfuncs.BundleRules(nobadfuncs.BundleUnsafeHTTP)
+	require.NoError(t, err)
+
+	const nowSig = "func time.Now() time.Time"
+	const exitSig = "func os.Exit(int)"
+
+	// bundles selected using flags and using the configuration are both expanded
+	rules, err := nobadfuncs.ExpandBundles(map[string]nobadfuncs.Rule{
+		"bundle " + nobadfuncs.BundleUnsafeHTTP: {},
+		exitSig:                                 {Message: "do not exit"},
+		nowSig:                                  {Message: "use the clock"},
+	}, []string{nobadfuncs.BundleTimeDeterminism})
+	require.NoError(t, err)
+	assert.Equal(t, len(timeRules)+len(httpRules)+1, len(rules))
+	for sig := range httpRules {
+		assert.Contains(t, rules, sig)
+	}
+	_, ok := rules["bundle "+nobadfuncs.BundleUnsafeHTTP]
+	assert.False(t, ok)
+
+	// explicitly configured signatures take precedence over the rules in bundles
+	assert.Equal(t, nobadfuncs.Rule{Message: "use the clock"}, rules[nowSig])
+	assert.Equal(t, nobadfuncs.Rule{Message: "do not exit"}, rules[exitSig])
+
+	// configuration without bundles is returned unmodified
+	rules, err = nobadfuncs.ExpandBundles(map[string]nobadfuncs.Rule{exitSig: {}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]nobadfuncs.Rule{exitSig: {}}, rules)
+
+	_, err = nobadfuncs.ExpandBundles(map[string]nobadfuncs.Rule{"bundle unknown": {}}, nil)
+	assert.EqualError(t, err, `unknown bundle "unknown": must be one of [fmt-print-debug time-determinism unsafe-http]`)
+}
+
+func TestBundleSignatures(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+func Foo() {
+	start := time.Now()
+	_ = time.Since(start)
+	_ = time.Until(start)
+	time.Sleep(time.Second)
+	<-time.After(time.Second)
+	_ = time.Tick(time.Second)
+
+	_, _ = http.Get("")
+	_, _ = http.Head("")
+	_, _ = http.Post("", "", strings.NewReader(""))
+	_, _ = http.PostForm("", nil)
+	_ = http.DefaultClient
+	_ = http.ListenAndServe("", nil)
+	_ = http.ListenAndServeTLS("", "", "", nil)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+
+	// every signature in the bundles is referenced exactly once, which verifies that the signatures are well-formed
+	for _, name := range []string{nobadfuncs.BundleTimeDeterminism, nobadfuncs.BundleUnsafeHTTP} {
+		rules, err := nobadfuncs.BundleRules(name)
+		require.NoError(t, err)
+		badRefs, err := nobadfuncs.FindBadFuncRefsForRules([]string{pkg}, rules)
+		require.NoError(t, err)
+		found := make(map[string]bool)
+		for _, ref := range badRefs {
+			found[string(ref.Sig)] = true
+		}
+		for sig := range rules {
+			assert.True(t, found[sig], "bundle %s: no reference found for %s", name, sig)
+		}
+	}
+}
+
+func TestFindStats(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(1)
+	os.Exit(2)
+	// OK: exit code must be propagated
+	os.Exit(3)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+
+	const (
+		exitSig   = "func os.Exit(int)"
+		unusedSig = "func os.Getenv(string) string"
+	)
+	stats, err := nobadfuncs.FindStats([]string{pkg}, map[string]nobadfuncs.Rule{
+		exitSig:   {},
+		unusedSig: {},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []nobadfuncs.SigStats{
+		{Sig: exitSig, References: 2, Whitelisted: 1},
+		{Sig: unusedSig},
+	}, stats)
+
+	nobadfuncs.ApplyPreviousStats(stats, []nobadfuncs.SigStats{
+		{Sig: exitSig, References: 5, Whitelisted: 1},
+		{Sig: "func os.Getwd() (string, error)", References: 1},
+	})
+	buf := &bytes.Buffer{}
+	require.NoError(t, nobadfuncs.WriteStats(buf, stats))
+	assert.Equal(t, `SIGNATURE                      REFERENCES  WHITELISTED
+func os.Exit(int)              2 (-3)      1 (+0)
+func os.Getenv(string) string  0           0
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, nobadfuncs.WriteStatsJSON(buf, stats))
+	statsFile := path.Join(tmpDir, "stats.json")
+	require.NoError(t, ioutil.WriteFile(statsFile, buf.Bytes(), 0644))
+	loaded, err := nobadfuncs.LoadStats(statsFile)
+	require.NoError(t, err)
+	assert.Equal(t, stats, loaded)
+}
+
+func TestAffectedPackages(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	tmpDir, err = filepath.Abs(tmpDir)
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "bar/bar.go",
+			Src:     "package bar",
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src:     `package foo; import _ "{{index . "bar/bar.go"}}";`,
+		},
+		{
+			RelPath: "qux/qux.go",
+			Src:     `package qux; import _ "{{index . "foo/foo.go"}}";`,
+		},
+		{
+			RelPath: "baz/baz.go",
+			Src:     "package baz",
+		},
+		{
+			RelPath: "baz/baz_test.go",
+			Src:     "package baz",
+		},
+	})
+	require.NoError(t, err)
+
+	barImportPath := files["bar/bar.go"].ImportPath
+	fooImportPath := files["foo/foo.go"].ImportPath
+	quxImportPath := files["qux/qux.go"].ImportPath
+	bazImportPath := files["baz/baz.go"].ImportPath
+	patterns := []string{path.Join(tmpDir, "...")}
+
+	for i, currCase := range []struct {
+		name         string
+		changedFiles string
+		want         []string
+	}{
+		{
+			name:         "importers of changed package are affected",
+			changedFiles: "bar/bar.go\n",
+			want:         []string{barImportPath, fooImportPath, quxImportPath},
+		},
+		{
+			name:         "changed test file affects its package",
+			changedFiles: "baz/baz_test.go\n",
+			want:         []string{bazImportPath},
+		},
+		{
+			name:         "removed file affects the package in its directory",
+			changedFiles: "qux/removed.go\n",
+			want:         []string{quxImportPath},
+		},
+		{
+			name:         "non-Go files do not affect packages",
+			changedFiles: "README.md\nbar/testdata/data.txt\n",
+		},
+	} {
+		changedFiles, err := nobadfuncs.ReadChangedFiles(strings.NewReader(currCase.changedFiles), tmpDir)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+
+		got, err := nobadfuncs.AffectedPackages(patterns, changedFiles)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		assert.Equal(t, currCase.want, got, "Case %d: %s", i, currCase.name)
+	}
+}
+
+func TestFilterBadFuncRefs(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	src := `package foo
+
+import (
+	"net/http"
+)
+
+func MyFunction() {
+	http.DefaultClient.Get("")
+}
+`
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src:     src,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+
+	sigs := map[string]string{
+		"func (*net/http.Client).Get(string) (*net/http.Response, error)": "",
+	}
+	badRefs, err := nobadfuncs.FindBadFuncRefs([]string{pkg}, sigs)
+	require.NoError(t, err)
+	require.Len(t, badRefs, 1)
+
+	baselineFile := path.Join(tmpDir, "baseline.json")
+	filtered, err := nobadfuncs.FilterBadFuncRefs(badRefs, baseline.Options{WritePath: baselineFile}, wd)
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+
+	// add a new reference before the recorded one
+	src = strings.Replace(src, "func MyFunction() {", "func MyFunction() {\n\thttp.DefaultClient.Get(\"other\")", 1)
+	err = ioutil.WriteFile(files["foo/foo.go"].Path, []byte(src), 0644)
+	require.NoError(t, err)
+	badRefs, err = nobadfuncs.FindBadFuncRefs([]string{pkg}, sigs)
+	require.NoError(t, err)
+	require.Len(t, badRefs, 2)
+
+	filtered, err = nobadfuncs.FilterBadFuncRefs(badRefs, baseline.Options{Path: baselineFile}, wd)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, 8, filtered[0].Pos.Line)
+}
+
+func TestPrintWhitelistedFuncRefs(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: `
+package foo
+
+import (
+	"net/http"
+)
+
+func MyFunction() {
+	// OK: my reason for this being good to call
+	http.DefaultClient.Do(nil)
+	http.DefaultClient.Do(nil)
+	// not a whitelist comment
+	http.DefaultClient.Get("")
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+
+	const (
+		doSig  = "func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)"
+		getSig = "func (*net/http.Client).Get(string) (*net/http.Response, error)"
+	)
+	sigs := map[string]string{
+		doSig:  "",
+		getSig: "",
+	}
+	fooPath := path.Join(wd, tmpDir, "foo/foo.go")
+
+	var got bytes.Buffer
+	err = nobadfuncs.PrintWhitelistedFuncRefs([]string{pkg}, sigs, &got)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s:10:21: %s is whitelisted: my reason for this being good to call\n", fooPath, doSig), got.String())
+
+	got.Reset()
+	err = nobadfuncs.PrintWhitelistedFuncRefsJSON([]string{pkg}, sigs, &got)
+	require.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`[
+  {
+    "file": %q,
+    "line": 10,
+    "column": 21,
+    "signature": %q,
+    "reason": "my reason for this being good to call"
+  }
+]`, fooPath, doSig), got.String())
+}
+
+func TestWhitelistExpiry(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	const dateLayout = "2006-01-02"
+	today := time.Now()
+	expired := today.AddDate(0, 0, -1).Format(dateLayout)
+	expiresSoon := today.AddDate(0, 0, 10).Format(dateLayout)
+	expiresLater := today.AddDate(0, 0, 100).Format(dateLayout)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo/foo.go",
+			Src: fmt.Sprintf(`package foo
+
+import (
+	"net/http"
+)
+
+func MyFunction() {
+	// OK until %s: expired reason
+	http.DefaultClient.Do(nil)
+	// OK until %s: soon reason
+	http.DefaultClient.Do(nil)
+	// OK until %s: later reason
+	http.DefaultClient.Do(nil)
+	// OK until %s: today reason
+	http.DefaultClient.Do(nil)
+	// OK until 2020-13-45: invalid date
+	http.DefaultClient.Do(nil)
+}
+`, expired, expiresSoon, expiresLater, today.Format(dateLayout)),
+		},
+	})
+	require.NoError(t, err)
+
+	pkg, err := filepath.Abs(path.Dir(files["foo/foo.go"].Path))
+	require.NoError(t, err)
+
+	const doSig = "func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)"
+	sigs := map[string]string{
+		doSig: "",
+	}
+	fooPath := path.Join(wd, tmpDir, "foo/foo.go")
+
+	badRefs, err := nobadfuncs.FindBadFuncRefs([]string{pkg}, sigs)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(badRefs))
+	assert.Equal(t, 9, badRefs[0].Pos.Line)
+	assert.Equal(t, fmt.Sprintf(`expired waiver: the whitelist comment for this reference only applied until %s. references to %q are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.`, expired, doSig), badRefs[0].Msg)
+	assert.Equal(t, 17, badRefs[1].Pos.Line)
+	assert.False(t, strings.HasPrefix(badRefs[1].Msg, "expired waiver"))
+
+	var got bytes.Buffer
+	err = nobadfuncs.PrintWhitelistedFuncRefs([]string{pkg}, sigs, &got)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`%s:11:21: %s is whitelisted until %s: soon reason
+%s:13:21: %s is whitelisted until %s: later reason
+%s:15:21: %s is whitelisted until %s: today reason
+`, fooPath, doSig, expiresSoon, fooPath, doSig, expiresLater, fooPath, doSig, today.Format(dateLayout)), got.String())
+
+	got.Reset()
+	err = nobadfuncs.PrintExpiringFuncRefs([]string{pkg}, sigs, 30, &got)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`%s:11:21: %s is whitelisted until %s: soon reason
+%s:15:21: %s is whitelisted until %s: today reason
+`, fooPath, doSig, expiresSoon, fooPath, doSig, today.Format(dateLayout)), got.String())
+
+	got.Reset()
+	err = nobadfuncs.PrintExpiringFuncRefsJSON([]string{pkg}, sigs, 0, &got)
+	require.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`[
+  {
+    "file": %q,
+    "line": 15,
+    "column": 21,
+    "signature": %q,
+    "reason": "today reason",
+    "expires": %q
+  }
+]`, fooPath, doSig, today.Format(dateLayout)), got.String())
+}
+
 func TestPrintAllFuncRefs(t *testing.T) {
 	wd, err := os.Getwd()
 	require.NoError(t, err)
@@ -458,7 +1256,7 @@ func LexEnter(l *Lexer) StateFn {
 
 		pkgs := make(map[string]struct{})
 		for _, val := range files {
-			currPkg, err := pkgpath.NewAbsPkgPath(path.Dir(val.Path)).GoPathSrcRel()
+			currPkg, err := filepath.Abs(path.Dir(val.Path))
 			require.NoError(t, err)
 			pkgs[currPkg] = struct{}{}
 		}
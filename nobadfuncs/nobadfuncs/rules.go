@@ -0,0 +1,121 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// AppliesToAll specifies that a rule applies to references in all Go files.
+	AppliesToAll = "all"
+	// AppliesToSrc specifies that a rule only applies to references in non-test Go files.
+	AppliesToSrc = "src"
+	// AppliesToTest specifies that a rule only applies to references in test Go files ("_test.go" files).
+	AppliesToTest = "test"
+)
+
+// Rule is the configuration for a blacklisted signature.
+type Rule struct {
+	// Message is the failure message for references to the signature. If empty, a default message is used.
+	Message string `json:"message"`
+	// DocURL is the URL of documentation that describes why the signature is blacklisted and what should be used
+	// instead. If non-empty, it is appended to the failure message.
+	DocURL string `json:"doc-url"`
+	// AppliesTo specifies the files in which references to the signature are reported: AppliesToAll, AppliesToSrc or
+	// AppliesToTest. If empty, the rule applies to all files.
+	AppliesTo string `json:"applies-to"`
+}
+
+// UnmarshalJSON unmarshals a rule from either a JSON string, which is used as the message of the rule, or a JSON
+// object with "message", "doc-url" and "applies-to" fields.
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	var msg string
+	if err := json.Unmarshal(data, &msg); err == nil {
+		*r = Rule{
+			Message: msg,
+		}
+		return nil
+	}
+
+	// use a type without the UnmarshalJSON method to unmarshal the object form
+	type rule Rule
+	var out rule
+	if err := json.Unmarshal(data, &out); err != nil {
+		return errors.Errorf("rule must be a string or an object with \"message\", \"doc-url\" and \"applies-to\" fields: %s", string(data))
+	}
+	switch out.AppliesTo {
+	case "", AppliesToAll, AppliesToSrc, AppliesToTest:
+	default:
+		return errors.Errorf("invalid applies-to value %q: must be %q, %q or %q", out.AppliesTo, AppliesToSrc, AppliesToTest, AppliesToAll)
+	}
+	*r = Rule(out)
+	return nil
+}
+
+// appliesToFile returns true if references in the file with the provided name are reported for the rule.
+func (r Rule) appliesToFile(filename string) bool {
+	isTest := strings.HasSuffix(filename, "_test.go")
+	switch r.AppliesTo {
+	case AppliesToSrc:
+		return !isTest
+	case AppliesToTest:
+		return isTest
+	default:
+		return true
+	}
+}
+
+// Messages returns a map from each signature in the provided rules to the message of its rule.
+func Messages(rules map[string]Rule) map[string]string {
+	if rules == nil {
+		return nil
+	}
+	sigs := make(map[string]string, len(rules))
+	for sig, rule := range rules {
+		sigs[sig] = rule.Message
+	}
+	return sigs
+}
+
+// rulesForMessages returns the rules for the provided map from signature to message.
+func rulesForMessages(sigs map[string]string) map[string]Rule {
+	if sigs == nil {
+		return nil
+	}
+	rules := make(map[string]Rule, len(sigs))
+	for sig, msg := range sigs {
+		rules[sig] = Rule{
+			Message: msg,
+		}
+	}
+	return rules
+}
+
+// failureMsg returns the failure message for references to the provided signature with the provided rule.
+func failureMsg(ref FuncRef, rule Rule) string {
+	msg := rule.Message
+	if msg == "" {
+		msg = defaultMsg(ref)
+	}
+	if rule.DocURL != "" {
+		msg = fmt.Sprintf("%s (see %s)", msg, rule.DocURL)
+	}
+	return msg
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// uriBaseID is the SARIF base identifier for the root of the source tree. Consumers such as GitHub code scanning
+	// resolve relative artifact locations against it.
+	uriBaseID = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name"`
+	ShortDescription sarifMessage  `json:"shortDescription"`
+	FullDescription  *sarifMessage `json:"fullDescription,omitempty"`
+	HelpURI          string        `json:"helpUri,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// RuleID returns the identifier of the SARIF rule for the provided signature. The identifier is derived from the
+// signature itself so that it remains stable as signatures are added to or removed from the configuration.
+func RuleID(sig string) string {
+	sum := sha256.Sum256([]byte(sig))
+	return fmt.Sprintf("NBF%X", sum[:4])
+}
+
+// WriteSARIF writes the provided references to blacklisted functions as a SARIF log. Every signature in "sigs" is
+// reported as a rule regardless of whether or not it is referenced. The locations of the references are written
+// relative to baseDir if they are within it.
+func WriteSARIF(w io.Writer, sigs map[string]string, badRefs []BadFuncRef, baseDir string) error {
+	return WriteSARIFForRules(w, rulesForMessages(sigs), badRefs, baseDir)
+}
+
+// WriteSARIFForRules writes the provided references to blacklisted functions as a SARIF log. Every signature in "rules"
+// is reported as a rule regardless of whether or not it is referenced, and the documentation URL of a rule (if any) is
+// reported as its help URI. The locations of the references are written relative to baseDir if they are within it.
+func WriteSARIFForRules(w io.Writer, rules map[string]Rule, badRefs []BadFuncRef, baseDir string) error {
+	var sortedSigs []string
+	for sig := range rules {
+		sortedSigs = append(sortedSigs, sig)
+	}
+	sort.Strings(sortedSigs)
+
+	sarifRules := make([]sarifRule, len(sortedSigs))
+	ruleIndices := make(map[string]int, len(sortedSigs))
+	for i, sig := range sortedSigs {
+		sarifRules[i] = sarifRule{
+			ID:   RuleID(sig),
+			Name: sig,
+			ShortDescription: sarifMessage{
+				Text: sig,
+			},
+		}
+		if msg := rules[sig].Message; msg != "" {
+			sarifRules[i].FullDescription = &sarifMessage{
+				Text: msg,
+			}
+		}
+		sarifRules[i].HelpURI = rules[sig].DocURL
+		ruleIndices[sig] = i
+	}
+
+	results := make([]sarifResult, len(badRefs))
+	for i, ref := range badRefs {
+		results[i] = sarifResult{
+			RuleID:    RuleID(string(ref.Sig)),
+			RuleIndex: ruleIndices[string(ref.Sig)],
+			Level:     "error",
+			Message: sarifMessage{
+				Text: ref.Msg,
+			},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: artifactLocation(ref.Pos.Filename, baseDir),
+						Region: sarifRegion{
+							StartLine:   ref.Pos.Line,
+							StartColumn: ref.Pos.Column,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "nobadfuncs",
+						InformationURI: "https://github.com/palantir/checks/tree/master/nobadfuncs",
+						Rules:          sarifRules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+	bytes, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal SARIF log")
+	}
+	if _, err := fmt.Fprintln(w, string(bytes)); err != nil {
+		return errors.Wrapf(err, "failed to write SARIF log")
+	}
+	return nil
+}
+
+// artifactLocation returns the location of the provided file relative to baseDir. If the file is not within baseDir,
+// the location is an absolute "file" URI.
+func artifactLocation(filename, baseDir string) sarifArtifactLocation {
+	if baseDir != "" {
+		if rel, err := filepath.Rel(baseDir, filename); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return sarifArtifactLocation{
+				URI:       filepath.ToSlash(rel),
+				URIBaseID: uriBaseID,
+			}
+		}
+	}
+	return sarifArtifactLocation{
+		URI: "file://" + filepath.ToSlash(filename),
+	}
+}
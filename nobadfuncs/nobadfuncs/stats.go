@@ -0,0 +1,142 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+// SigStats are the usage statistics for a blacklisted signature.
+type SigStats struct {
+	// Sig is the blacklisted signature.
+	Sig string `json:"signature"`
+	// References is the number of references to the signature that are not whitelisted.
+	References int `json:"references"`
+	// Whitelisted is the number of references to the signature that are whitelisted.
+	Whitelisted int `json:"whitelisted"`
+	// PreviousReferences and PreviousWhitelisted are the number of references and whitelisted references recorded for
+	// the signature in a previous stats file. Nil if there are no previous statistics for the signature.
+	PreviousReferences  *int `json:"previousReferences,omitempty"`
+	PreviousWhitelisted *int `json:"previousWhitelisted,omitempty"`
+}
+
+// FindStats returns the usage statistics for every signature in "rules" in the provided packages sorted by signature.
+// Every signature is included regardless of whether or not it is referenced. References in files to which the rule for
+// their signature does not apply are not counted.
+func FindStats(pkgs []string, rules map[string]Rule) ([]SigStats, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	stats := make(map[string]*SigStats, len(rules))
+	for sig := range rules {
+		stats[sig] = &SigStats{
+			Sig: sig,
+		}
+	}
+	if err := visitFuncRefUsages(pkgs, Messages(rules), func(pos token.Position, ref FuncRef, expired string) {
+		if rule, ok := rules[string(ref)]; ok && rule.appliesToFile(pos.Filename) {
+			stats[string(ref)].References++
+		}
+	}, func(pos token.Position, ref FuncRef, w waiver) {
+		if rule, ok := rules[string(ref)]; ok && rule.appliesToFile(pos.Filename) {
+			stats[string(ref)].Whitelisted++
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	out := make([]SigStats, 0, len(stats))
+	for _, v := range stats {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Sig < out[j].Sig
+	})
+	return out, nil
+}
+
+// LoadStats reads the statistics in the JSON stats file at the provided path (the output of WriteStatsJSON).
+func LoadStats(path string) ([]SigStats, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read stats file %s", path)
+	}
+	var stats []SigStats
+	if err := json.Unmarshal(bytes, &stats); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal stats file %s", path)
+	}
+	return stats, nil
+}
+
+// ApplyPreviousStats sets the previous number of references and whitelisted references of each of the provided
+// statistics to the values recorded for its signature in the provided previous statistics. Signatures that are only
+// present in the previous statistics are ignored.
+func ApplyPreviousStats(stats, previous []SigStats) {
+	previousBySig := make(map[string]SigStats, len(previous))
+	for _, v := range previous {
+		previousBySig[v.Sig] = v
+	}
+	for i := range stats {
+		prev, ok := previousBySig[stats[i].Sig]
+		if !ok {
+			continue
+		}
+		references, whitelisted := prev.References, prev.Whitelisted
+		stats[i].PreviousReferences = &references
+		stats[i].PreviousWhitelisted = &whitelisted
+	}
+}
+
+// WriteStats writes the provided statistics as a table with a row for every signature. If previous statistics exist
+// for a signature, the change relative to them is printed after each count.
+func WriteStats(w io.Writer, stats []SigStats) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIGNATURE\tREFERENCES\tWHITELISTED")
+	for _, v := range stats {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Sig, countWithTrend(v.References, v.PreviousReferences), countWithTrend(v.Whitelisted, v.PreviousWhitelisted))
+	}
+	return tw.Flush()
+}
+
+func countWithTrend(count int, previous *int) string {
+	if previous == nil {
+		return fmt.Sprint(count)
+	}
+	return fmt.Sprintf("%d (%+d)", count, count-*previous)
+}
+
+// WriteStatsJSON writes the provided statistics as a JSON array. The output can be provided as the previous statistics
+// of a later run to track the trend of the references to each signature.
+func WriteStatsJSON(w io.Writer, stats []SigStats) error {
+	if stats == nil {
+		stats = []SigStats{}
+	}
+	bytes, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal stats")
+	}
+	if _, err := fmt.Fprintln(w, string(bytes)); err != nil {
+		return errors.Wrapf(err, "failed to write stats")
+	}
+	return nil
+}
@@ -17,9 +17,38 @@ package nobadfuncs
 import (
 	"fmt"
 	"go/types"
-	"strings"
+
+	"github.com/palantir/checks/checks/vendorutil"
 )
 
+// toFuncRef returns the FuncRef for the provided object. Returns false if the object is not a function or a
+// package-level variable, constant or type (for example, if it is a local variable, a struct field or a builtin type).
+func toFuncRef(obj types.Object) (FuncRef, bool) {
+	switch obj := obj.(type) {
+	case *types.Func:
+		// transform function to a form where names are removed from receivers, params and return values
+		// and package references have path to the vendor directory removed.
+		return FuncRef(toFuncWithNoIdentifiersRemoveVendor(obj).String()), true
+	case *types.Var:
+		return pkgLevelRef("var", obj)
+	case *types.Const:
+		return pkgLevelRef("const", obj)
+	case *types.TypeName:
+		return pkgLevelRef("type", obj)
+	default:
+		return "", false
+	}
+}
+
+// pkgLevelRef returns a FuncRef of the form "<kind> <package>.<name>" for the provided object with the path to the
+// vendor directory removed from the package. Returns false if the object is not declared at package level.
+func pkgLevelRef(kind string, obj types.Object) (FuncRef, bool) {
+	if obj.Pkg() == nil || obj.Pkg().Scope().Lookup(obj.Name()) != obj {
+		return "", false
+	}
+	return FuncRef(fmt.Sprintf("%s %s.%s", kind, removeVendor(obj.Pkg().Path()), obj.Name())), true
+}
+
 // returns a new version of the provided *types.Func where all of the identifier names have been removed and all
 // package references have their vendor references removed.
 //
@@ -64,11 +93,7 @@ func pkgNoVendor(in *types.Package) *types.Package {
 }
 
 func removeVendor(in string) string {
-	out := in
-	if vendorIdx := strings.LastIndex(out, "vendor/"); vendorIdx != -1 {
-		out = out[vendorIdx+len("vendor/"):]
-	}
-	return out
+	return vendorutil.StripVendorPrefix(in)
 }
 
 func toTypeRemoveVendor(in types.Type) types.Type {
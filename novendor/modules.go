@@ -0,0 +1,180 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// vendoredModule is a module that is listed in a vendor/modules.txt file.
+type vendoredModule struct {
+	path     string
+	explicit bool
+	pkgs     []string
+}
+
+// doVerifyModules verifies the vendor directory of the Go module project in projectDir against its vendor/modules.txt
+// file. Every directory in the vendor directory that contains Go files must be a package that is listed in modules.txt,
+// every package listed in modules.txt must exist in the vendor directory and every module that modules.txt records as
+// explicitly required must be required by go.mod (and vice versa). Problems are printed one per line.
+func doVerifyModules(projectDir string, w io.Writer) error {
+	goModPath := path.Join(projectDir, "go.mod")
+	goModBytes, err := ioutil.ReadFile(goModPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", goModPath)
+	}
+	goMod, err := modfile.Parse(goModPath, goModBytes, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", goModPath)
+	}
+
+	vendorDir := path.Join(projectDir, "vendor")
+	modules, err := readModulesTxt(path.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	listedPkgs := make(map[string]bool)
+	listedModules := make(map[string]bool)
+	for _, currModule := range modules {
+		listedModules[currModule.path] = true
+		for _, currPkg := range currModule.pkgs {
+			listedPkgs[currPkg] = true
+			if _, err := os.Stat(path.Join(vendorDir, currPkg)); err != nil {
+				problems = append(problems, fmt.Sprintf("vendor/%s: listed in vendor/modules.txt but does not exist", currPkg))
+			}
+		}
+	}
+
+	requiredModules := make(map[string]bool)
+	for _, currRequire := range goMod.Require {
+		requiredModules[currRequire.Mod.Path] = true
+		if !listedModules[currRequire.Mod.Path] {
+			problems = append(problems, fmt.Sprintf("%s: required by go.mod but not listed in vendor/modules.txt", currRequire.Mod.Path))
+		}
+	}
+	for _, currModule := range modules {
+		if currModule.explicit && !requiredModules[currModule.path] {
+			problems = append(problems, fmt.Sprintf("%s: listed in vendor/modules.txt as explicitly required but not required by go.mod", currModule.path))
+		}
+	}
+
+	vendoredPkgDirs, err := getVendoredPkgDirs(vendorDir)
+	if err != nil {
+		return err
+	}
+	for _, currPkg := range vendoredPkgDirs {
+		if !listedPkgs[currPkg] {
+			problems = append(problems, fmt.Sprintf("vendor/%s: not listed in vendor/modules.txt", currPkg))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		fmt.Fprintln(w, strings.Join(problems, "\n"))
+		return fmt.Errorf("")
+	}
+	return nil
+}
+
+// readModulesTxt parses the vendor/modules.txt file at the provided path and returns the modules that it lists in the
+// order in which they appear.
+func readModulesTxt(modulesTxtPath string) ([]*vendoredModule, error) {
+	modulesTxtBytes, err := ioutil.ReadFile(modulesTxtPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", modulesTxtPath)
+	}
+
+	var modules []*vendoredModule
+	var currModule *vendoredModule
+	scanner := bufio.NewScanner(bytes.NewReader(modulesTxtBytes))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "## "):
+			// annotation for the current module: "## explicit" or "## explicit; go 1.x"
+			if currModule != nil {
+				for _, currAnnotation := range strings.Split(strings.TrimPrefix(line, "## "), ";") {
+					if strings.TrimSpace(currAnnotation) == "explicit" {
+						currModule.explicit = true
+					}
+				}
+			}
+		case strings.HasPrefix(line, "# "):
+			// module line: "# path version", "# path version => replacement [version]" or "# path => replacement [version]"
+			fields := strings.Fields(strings.TrimPrefix(line, "# "))
+			currModule = &vendoredModule{
+				path: fields[0],
+			}
+			modules = append(modules, currModule)
+		default:
+			if currModule == nil {
+				return nil, errors.Errorf("package %s in %s is not preceded by a module", line, modulesTxtPath)
+			}
+			currModule.pkgs = append(currModule.pkgs, line)
+		}
+	}
+	return modules, nil
+}
+
+// getVendoredPkgDirs returns the paths (relative to vendorDir) of all of the directories in vendorDir that contain Go
+// files in sorted order. Directories named "testdata" and directories whose names begin with "." or "_" are skipped.
+func getVendoredPkgDirs(vendorDir string) ([]string, error) {
+	pkgDirs := make(map[string]bool)
+	err := filepath.Walk(vendorDir, func(currPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if currPath != vendorDir && (info.Name() == "testdata" || strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(info.Name(), ".go") {
+			return nil
+		}
+		rel, err := filepath.Rel(vendorDir, filepath.Dir(currPath))
+		if err != nil {
+			return err
+		}
+		pkgDirs[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine vendored package directories")
+	}
+	var sortedPkgDirs []string
+	for k := range pkgDirs {
+		sortedPkgDirs = append(sortedPkgDirs, k)
+	}
+	sort.Strings(sortedPkgDirs)
+	return sortedPkgDirs, nil
+}
@@ -33,14 +33,27 @@ import (
 	"github.com/palantir/pkg/matcher"
 	"github.com/palantir/pkg/pkgpath"
 	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/vendorutil"
 )
 
 const (
-	pkgsFlagName         = "pkgs"
-	projectPkgFlagName   = "project-package"
-	fullPathFlagName     = "full"
-	printPkgInfoFlagName = "print-pkg-info"
-	ignoreFlagName       = "ignore"
+	pkgsFlagName          = "pkgs"
+	projectPkgFlagName    = "project-package"
+	fullPathFlagName      = "full"
+	printPkgInfoFlagName  = "print-pkg-info"
+	ignoreFlagName        = "ignore"
+	staleFlagName         = "stale"
+	modulesFlagName       = "modules"
+	checkTestsFlagName    = "check-tests"
+	allowTestOnlyFlagName = "allow-test-only"
+	deadPkgsFlagName      = "dead-packages"
+	deadPkgsIgnoreName    = "dead-packages-ignore"
+	printPathsFlagName    = "print-paths"
+	shadowingFlagName     = "shadowing"
+
+	whyCommandName = "why"
 )
 
 var (
@@ -66,6 +79,53 @@ var (
 		Name:  ignoreFlagName,
 		Usage: "packages to ignore (specified package and all its dependencies will be excluded from novendor)",
 	}
+	staleFlag = flag.BoolFlag{
+		Name:  staleFlagName,
+		Usage: "also report vendored packages that are stale (cannot be built for any GOOS/GOARCH or are only imported by test files of vendored packages)",
+	}
+	modulesFlag = flag.BoolFlag{
+		Name:  modulesFlagName,
+		Usage: "verify the vendor directory of a Go module project against vendor/modules.txt instead of checking for unused vendored packages",
+	}
+	checkTestsFlag = flag.BoolFlag{
+		Name:  checkTestsFlagName,
+		Usage: "report vendored packages that are only used by test code separately from those used by production code",
+	}
+	allowTestOnlyFlag = flag.BoolFlag{
+		Name:  allowTestOnlyFlagName,
+		Usage: "do not fail if vendored packages are only used by test code (only applies if --" + checkTestsFlagName + " is specified)",
+	}
+	deadPkgsFlag = flag.BoolFlag{
+		Name:  deadPkgsFlagName,
+		Usage: "also report project packages that are not reachable from any main package or package with tests of the project",
+	}
+	deadPkgsIgnoreFlag = flag.StringFlag{
+		Name: deadPkgsIgnoreName,
+		Usage: "path (relative to the working directory) of project packages that are intentionally standalone and are " +
+			"not reported as dead. Matches subdirectories and supports glob patterns. Can be specified multiple times",
+	}
+	printPathsFlag = flag.BoolFlag{
+		Name: printPathsFlagName,
+		Usage: "print the paths (relative to the working directory) of the directories that can be deleted to remove the " +
+			"unused vendored packages instead of the packages. Only unused packages are reported in this mode",
+	}
+	shadowingFlag = flag.BoolFlag{
+		Name: shadowingFlagName,
+		Usage: "also report vendored packages that shadow a package of the project or a package that is vendored in a " +
+			"vendor directory higher in the tree",
+	}
+	goosFlag = flag.StringFlag{
+		Name:  loader.GOOSFlagName,
+		Usage: "only consider the files that match the specified target operating system (by default, all files are considered)",
+	}
+	goarchFlag = flag.StringFlag{
+		Name:  loader.GOARCHFlagName,
+		Usage: "only consider the files that match the specified target architecture (by default, all files are considered)",
+	}
+	tagsFlag = flag.StringFlag{
+		Name:  loader.TagsFlagName,
+		Usage: "only consider the files that match the specified comma-separated build tags (by default, all files are considered)",
+	}
 )
 
 func main() {
@@ -77,17 +137,68 @@ func main() {
 		pkgsFlag,
 		printPkgInfoFlag,
 		ignoreFlag,
+		staleFlag,
+		modulesFlag,
+		checkTestsFlag,
+		allowTestOnlyFlag,
+		deadPkgsFlag,
+		deadPkgsIgnoreFlag,
+		printPathsFlag,
+		shadowingFlag,
+		goosFlag,
+		goarchFlag,
+		tagsFlag,
 	)
 	app.Action = func(ctx cli.Context) error {
+		// analysis is done on all Go files rather than on just those that match the build context unless GOOS, GOARCH
+		// or build tags are specified
+		l := loader.New(loader.Options{
+			GOOS:        ctx.String(loader.GOOSFlagName),
+			GOARCH:      ctx.String(loader.GOARCHFlagName),
+			Tags:        ctx.String(loader.TagsFlagName),
+			UseAllFiles: true,
+		})
 		wd, err := dirs.GetwdEvalSymLinks()
 		if err != nil {
 			return errors.Wrapf(err, "Failed to get working directory")
 		}
+		if ctx.Bool(modulesFlagName) {
+			return doVerifyModules(wd, ctx.App.Stdout)
+		}
 		pkgs := ctx.Slice(pkgsFlagName)
+		// the "why" command is handled here because the packages parameter consumes all arguments that are not flags
+		explainWhy := len(pkgs) > 0 && pkgs[0] == whyCommandName
+		if explainWhy {
+			if len(pkgs) < 2 {
+				return errors.Errorf("usage: novendor %s <vendored-package> [packages]", whyCommandName)
+			}
+			pkgs = pkgs[1:]
+		}
 		if ignorePkgs := ctx.StringSlice(ignoreFlagName); !reflect.DeepEqual(ignorePkgs, []string{""}) {
 			pkgs = append(pkgs, ignorePkgs...)
 		}
-		return doNovendor(wd, pkgs, ctx.Bool(projectPkgFlagName), ctx.Bool(fullPathFlagName), ctx.Bool(printPkgInfoFlagName), ctx.App.Stdout)
+		if explainWhy {
+			return doWhy(l, wd, pkgs[0], pkgs[1:], ctx.Bool(projectPkgFlagName), ctx.Bool(fullPathFlagName), ctx.App.Stdout)
+		}
+		var deadPkgsIgnore []string
+		for _, currPath := range ctx.StringSlice(deadPkgsIgnoreName) {
+			if currPath != "" {
+				deadPkgsIgnore = append(deadPkgsIgnore, path.Clean(currPath))
+			}
+		}
+		return doNovendor(l, wd, novendorOptions{
+			pkgPaths:           pkgs,
+			groupPkgsByProject: ctx.Bool(projectPkgFlagName),
+			fullPath:           ctx.Bool(fullPathFlagName),
+			printPkgInfo:       ctx.Bool(printPkgInfoFlagName),
+			reportStale:        ctx.Bool(staleFlagName),
+			checkTests:         ctx.Bool(checkTestsFlagName),
+			allowTestOnly:      ctx.Bool(allowTestOnlyFlagName),
+			reportDead:         ctx.Bool(deadPkgsFlagName),
+			deadPkgsIgnore:     deadPkgsIgnore,
+			printPaths:         ctx.Bool(printPathsFlagName),
+			reportShadowing:    ctx.Bool(shadowingFlagName),
+		}, ctx.App.Stdout)
 	}
 	os.Exit(app.Run(os.Args))
 }
@@ -97,18 +208,199 @@ type pkgWithSrc struct {
 	src string
 }
 
-func doNovendor(projectDir string, pkgPaths []string, groupPkgsByProject, fullPath, printPkgInfo bool, w io.Writer) error {
+// novendorOptions configures the packages that are checked by doNovendor and what is reported.
+type novendorOptions struct {
+	// pkgPaths are the paths of the project packages to check, relative to the project directory. If empty, all of
+	// the non-vendored packages of the project are checked.
+	pkgPaths []string
+	// groupPkgsByProject specifies whether vendored packages are grouped by project so that only the projects that are
+	// entirely unused are reported.
+	groupPkgsByProject bool
+	// fullPath specifies whether the reported packages include the path of the vendor directory.
+	fullPath bool
+	// printPkgInfo specifies whether all of the project and vendored packages are printed before the results.
+	printPkgInfo bool
+	// reportStale specifies whether stale vendored packages are reported.
+	reportStale bool
+	// checkTests specifies whether vendored packages that are only used by test code are reported separately.
+	checkTests bool
+	// allowTestOnly specifies whether vendored packages that are only used by test code do not cause a failure.
+	allowTestOnly bool
+	// reportDead specifies whether project packages that are not reachable from a main package or a package with tests
+	// are reported.
+	reportDead bool
+	// deadPkgsIgnore are the paths (relative to the project directory) of project packages that are not reported as
+	// dead.
+	deadPkgsIgnore []string
+	// printPaths specifies whether the directories that can be deleted to remove the unused vendored packages are
+	// printed instead of the packages.
+	printPaths bool
+	// reportShadowing specifies whether vendored packages that shadow other packages are reported.
+	reportShadowing bool
+}
+
+// doNovendor uses the provided loader to check the project in projectDir for unused vendored packages (and for the
+// other problems specified by the provided options) and prints the results to w.
+func doNovendor(l *loader.Loader, projectDir string, opts novendorOptions, w io.Writer) error {
+	gopath, pkgsToProcess, err := getPkgsToProcess(projectDir, opts.pkgPaths)
+	if err != nil {
+		return err
+	}
+
+	allProjectPkgs, allVendoredPkgs, err := getPackageInfo(l, projectDir, pkgsToProcess)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get package information")
+	}
+	if opts.printPkgInfo {
+		projectPkgOutput := []string{fmt.Sprintf("All project packages (%d):", len(allProjectPkgs))}
+		for pkg := range allProjectPkgs {
+			projectPkgOutput = append(projectPkgOutput, pkg)
+		}
+		sort.Strings(projectPkgOutput)
+		fmt.Fprintln(w, strings.Join(projectPkgOutput, "\n\t"))
+
+		vendoredPkgOutput := []string{fmt.Sprintf("All vendored packages (%d):", len(allVendoredPkgs))}
+		for pkg := range allVendoredPkgs {
+			vendoredPkgOutput = append(vendoredPkgOutput, pkg)
+		}
+		sort.Strings(vendoredPkgOutput)
+		fmt.Fprintln(w, strings.Join(vendoredPkgOutput, "\n\t"))
+	}
+
+	var prodProjectPkgs map[string]bool
+	if opts.checkTests {
+		prodProjectPkgs, err = getProjectImports(l, projectDir, pkgsToProcess, false)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get package information")
+		}
+	}
+
+	var stalePkgs map[string]string
+	if opts.reportStale {
+		stalePkgs, err = getStaleVendoredPkgs(l, allProjectPkgs, allVendoredPkgs)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to determine stale packages")
+		}
+		// packages that are stale are reported in the stale category rather than as unused
+		usedPkgs := make(map[string]bool)
+		for k, v := range allProjectPkgs {
+			usedPkgs[k] = v
+		}
+		for k := range stalePkgs {
+			usedPkgs[k] = true
+			if prodProjectPkgs != nil {
+				prodProjectPkgs[k] = true
+			}
+		}
+		allProjectPkgs = usedPkgs
+	}
+
+	if opts.printPaths {
+		unusedPkgs, err := getUnusedVendoredPkgs(allProjectPkgs, allVendoredPkgs, opts.groupPkgsByProject, true)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to determine unused packages")
+		}
+		deletableDirs, err := getDeletableVendorDirs(projectDir, gopath, unusedPkgs, allProjectPkgs, allVendoredPkgs, opts.groupPkgsByProject)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to determine deletable directories")
+		}
+		if len(deletableDirs) > 0 {
+			fmt.Fprintln(w, strings.Join(deletableDirs, "\n"))
+			return fmt.Errorf("")
+		}
+		return nil
+	}
+
+	unusedPkgs, err := getUnusedVendoredPkgs(allProjectPkgs, allVendoredPkgs, opts.groupPkgsByProject, opts.fullPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to determine unused packages")
+	}
+	if len(unusedPkgs) > 0 {
+		fmt.Fprintln(w, strings.Join(unusedPkgs, "\n"))
+	}
+
+	var testOnlyPkgs []string
+	if opts.checkTests {
+		// packages that are unused when only production code is considered but used when test code is considered
+		// are only used by tests
+		unusedByProdPkgs, err := getUnusedVendoredPkgs(prodProjectPkgs, allVendoredPkgs, opts.groupPkgsByProject, opts.fullPath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to determine packages only used by tests")
+		}
+		unusedPkgsSet := make(map[string]bool)
+		for _, pkg := range unusedPkgs {
+			unusedPkgsSet[pkg] = true
+		}
+		for _, pkg := range unusedByProdPkgs {
+			if !unusedPkgsSet[pkg] {
+				testOnlyPkgs = append(testOnlyPkgs, pkg)
+			}
+		}
+	}
+	if len(testOnlyPkgs) > 0 {
+		testOnlyOutput := append([]string{fmt.Sprintf("Vendored packages only used by tests (%d):", len(testOnlyPkgs))}, testOnlyPkgs...)
+		fmt.Fprintln(w, strings.Join(testOnlyOutput, "\n\t"))
+	}
+	if len(stalePkgs) > 0 {
+		staleOutput := []string{fmt.Sprintf("Stale vendored packages (%d):", len(stalePkgs))}
+		for pkg, reason := range stalePkgs {
+			if !opts.fullPath {
+				_, pkg = vendorutil.SplitVendorPath(pkg)
+			}
+			staleOutput = append(staleOutput, fmt.Sprintf("%s: %s", pkg, reason))
+		}
+		sort.Strings(staleOutput[1:])
+		fmt.Fprintln(w, strings.Join(staleOutput, "\n\t"))
+	}
+	var shadowingPkgs map[string]string
+	if opts.reportShadowing {
+		projectImportPath, err := filepath.Rel(path.Join(gopath, "src"), projectDir)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to determine import path of %s", projectDir)
+		}
+		shadowingPkgs = getShadowingVendoredPkgs(filepath.ToSlash(projectImportPath), allVendoredPkgs)
+	}
+	if len(shadowingPkgs) > 0 {
+		shadowingOutput := []string{fmt.Sprintf("Shadowing vendored packages (%d):", len(shadowingPkgs))}
+		for pkg, reason := range shadowingPkgs {
+			shadowingOutput = append(shadowingOutput, fmt.Sprintf("%s: %s", pkg, reason))
+		}
+		sort.Strings(shadowingOutput[1:])
+		fmt.Fprintln(w, strings.Join(shadowingOutput, "\n\t"))
+	}
+	var deadPkgs []string
+	if opts.reportDead {
+		deadPkgs, err = getDeadProjectPkgs(l, projectDir, pkgsToProcess, matcher.Path(opts.deadPkgsIgnore...))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to determine dead project packages")
+		}
+	}
+	if len(deadPkgs) > 0 {
+		deadOutput := append([]string{fmt.Sprintf("Dead project packages (%d):", len(deadPkgs))}, deadPkgs...)
+		fmt.Fprintln(w, strings.Join(deadOutput, "\n\t"))
+	}
+	if len(unusedPkgs) > 0 || len(stalePkgs) > 0 || len(shadowingPkgs) > 0 || len(deadPkgs) > 0 || (len(testOnlyPkgs) > 0 && !opts.allowTestOnly) {
+		return fmt.Errorf("")
+	}
+
+	return nil
+}
+
+// getPkgsToProcess verifies that projectDir is an absolute path within $GOPATH/src and returns the value of $GOPATH
+// along with the packages of the project at the provided paths (relative to projectDir). If no paths are provided, all
+// of the non-vendored packages in projectDir are returned.
+func getPkgsToProcess(projectDir string, pkgPaths []string) (gopath string, pkgsToProcess []pkgWithSrc, err error) {
 	if !path.IsAbs(projectDir) {
-		return errors.Errorf("projectDir %s must be an absolute path", projectDir)
+		return "", nil, errors.Errorf("projectDir %s must be an absolute path", projectDir)
 	}
 
-	gopath := os.Getenv("GOPATH")
+	gopath = os.Getenv("GOPATH")
 	if gopath == "" {
-		return errors.Errorf("GOPATH environment variable must be set")
+		return "", nil, errors.Errorf("GOPATH environment variable must be set")
 	}
 
 	if relPath, err := filepath.Rel(path.Join(gopath, "src"), projectDir); err != nil || strings.HasPrefix(relPath, "../") {
-		return errors.Errorf("Project directory %s must be a subdirectory of $GOPATH/src (%s)", projectDir, path.Join(gopath, "src"))
+		return "", nil, errors.Errorf("Project directory %s must be a subdirectory of $GOPATH/src (%s)", projectDir, path.Join(gopath, "src"))
 	}
 
 	if len(pkgPaths) == 0 {
@@ -116,73 +408,97 @@ func doNovendor(projectDir string, pkgPaths []string, groupPkgsByProject, fullPa
 		matcher := matcher.Any(pkgpath.DefaultGoPkgExcludeMatcher(), matcher.Name("vendor"))
 		pkgs, err := pkgpath.PackagesInDir(projectDir, matcher)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to list packages")
+			return "", nil, errors.Wrapf(err, "Failed to list packages")
 		}
 
 		pkgPaths, err = pkgs.Paths(pkgpath.Relative)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to convert package paths")
+			return "", nil, errors.Wrapf(err, "Failed to convert package paths")
 		}
 	}
 
-	pkgsToProcess := make([]pkgWithSrc, len(pkgPaths))
+	pkgsToProcess = make([]pkgWithSrc, len(pkgPaths))
 	for i, pkgPath := range pkgPaths {
 		pkgsToProcess[i] = pkgWithSrc{
 			pkg: ".",
 			src: path.Join(projectDir, pkgPath),
 		}
 	}
+	return gopath, pkgsToProcess, nil
+}
 
-	allProjectPkgs, allVendoredPkgs, err := getPackageInfo(projectDir, pkgsToProcess)
+func getPackageInfo(l *loader.Loader, projectDir string, pkgsToProcess []pkgWithSrc) (allProjectPkgs map[string]bool, allVendoredPkgs map[string]bool, err error) {
+	allProjectPkgs, err = getProjectImports(l, projectDir, pkgsToProcess, true)
 	if err != nil {
-		return errors.Wrapf(err, "Failed to get package information")
-	}
-	if printPkgInfo {
-		projectPkgOutput := []string{fmt.Sprintf("All project packages (%d):", len(allProjectPkgs))}
-		for pkg := range allProjectPkgs {
-			projectPkgOutput = append(projectPkgOutput, pkg)
-		}
-		sort.Strings(projectPkgOutput)
-		fmt.Fprintln(w, strings.Join(projectPkgOutput, "\n\t"))
-
-		vendoredPkgOutput := []string{fmt.Sprintf("All vendored packages (%d):", len(allVendoredPkgs))}
-		for pkg := range allVendoredPkgs {
-			vendoredPkgOutput = append(vendoredPkgOutput, pkg)
-		}
-		sort.Strings(vendoredPkgOutput)
-		fmt.Fprintln(w, strings.Join(vendoredPkgOutput, "\n\t"))
+		return nil, nil, err
 	}
 
-	unusedPkgs, err := getUnusedVendoredPkgs(allProjectPkgs, allVendoredPkgs, groupPkgsByProject, fullPath)
+	allVendoredPkgs, err = getAllVendoredPkgs(l, projectDir)
 	if err != nil {
-		return errors.Wrapf(err, "Failed to determine unused packages")
-	}
-	if len(unusedPkgs) > 0 {
-		fmt.Fprintln(w, strings.Join(unusedPkgs, "\n"))
-		return fmt.Errorf("")
+		return nil, nil, err
 	}
 
-	return nil
+	return allProjectPkgs, allVendoredPkgs, err
 }
 
-func getPackageInfo(projectDir string, pkgsToProcess []pkgWithSrc) (allProjectPkgs map[string]bool, allVendoredPkgs map[string]bool, err error) {
-	allProjectPkgs = make(map[string]bool)
+// getProjectImports returns all of the packages that are imported by the provided packages either directly or
+// transitively. If includeTests is true, the imports of the test files of the provided packages are considered as well.
+func getProjectImports(l *loader.Loader, projectDir string, pkgsToProcess []pkgWithSrc, includeTests bool) (map[string]bool, error) {
+	projectPkgs := make(map[string]bool)
 	for _, currPkg := range pkgsToProcess {
-		imps, err := getAllImports(currPkg.pkg, currPkg.src, projectDir, make(map[string]bool), true)
+		imps, err := getAllImports(l, currPkg.pkg, currPkg.src, projectDir, make(map[string]bool), includeTests)
 		if err != nil {
-			return nil, nil, errors.Wrapf(err, "failed to get all imports for %s", currPkg.pkg)
+			return nil, errors.Wrapf(err, "failed to get all imports for %s", currPkg.pkg)
 		}
 		for k, v := range imps {
-			allProjectPkgs[k] = v
+			projectPkgs[k] = v
 		}
 	}
+	return projectPkgs, nil
+}
 
-	allVendoredPkgs, err = getAllVendoredPkgs(projectDir)
-	if err != nil {
-		return nil, nil, err
+// getDeadProjectPkgs returns the sorted import paths of the provided project packages that are dead. The main packages,
+// the packages that have test files and the packages matched by ignore (relative to projectDir) are the roots of the
+// project, and a project package is dead if it is not a root and is not imported by any root either directly or
+// transitively (including through the test files of the roots).
+func getDeadProjectPkgs(l *loader.Loader, projectDir string, pkgsToProcess []pkgWithSrc, ignore matcher.Matcher) ([]string, error) {
+	var roots []pkgWithSrc
+	candidates := make(map[string]bool)
+	for _, currPkg := range pkgsToProcess {
+		pkgs, err := getPkgsInDir(l, currPkg.pkg, currPkg.src, make(map[string]bool))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get packages in directory %s", currPkg.src)
+		}
+		isRoot := false
+		if rel, err := filepath.Rel(projectDir, currPkg.src); err == nil && ignore.Match(rel) {
+			isRoot = true
+		}
+		for _, pkg := range pkgs {
+			if pkg.Name == "main" || len(pkg.TestGoFiles) > 0 || len(pkg.XTestGoFiles) > 0 {
+				isRoot = true
+			}
+		}
+		if isRoot {
+			roots = append(roots, currPkg)
+			continue
+		}
+		for _, pkg := range pkgs {
+			candidates[pkg.ImportPath] = true
+		}
 	}
 
-	return allProjectPkgs, allVendoredPkgs, err
+	reachablePkgs, err := getProjectImports(l, projectDir, roots, true)
+	if err != nil {
+		return nil, err
+	}
+	var deadPkgs []string
+	for pkg := range candidates {
+		if !reachablePkgs[pkg] {
+			deadPkgs = append(deadPkgs, pkg)
+		}
+	}
+	sort.Strings(deadPkgs)
+	return deadPkgs, nil
 }
 
 func getUnusedVendoredPkgs(allProjectPkgs, allVendoredPkgs map[string]bool, groupPkgsByProject, fullPath bool) ([]string, error) {
@@ -191,18 +507,18 @@ func getUnusedVendoredPkgs(allProjectPkgs, allVendoredPkgs map[string]bool, grou
 		// do package-level grouping
 		allProjectPkgsGrouped := make(map[string]bool)
 		for k := range allProjectPkgs {
-			vendorPath, nonVendorFullPath := splitPathOnVendor(k)
-			vendoredRepoOrgProjectPath := path.Join(vendorPath, repoOrgProjectPath(nonVendorFullPath))
-			allProjectPkgsGrouped[vendoredRepoOrgProjectPath] = true
+			vendorPath, nonVendorFullPath := vendorutil.SplitVendorPath(k)
+			vendoredRepoRootPath := path.Join(vendorPath, repoRootPath(nonVendorFullPath))
+			allProjectPkgsGrouped[vendoredRepoRootPath] = true
 		}
 
 		usedKeys := make(map[string]bool)
 		for k := range allVendoredPkgs {
-			vendorPath, nonVendorFullPath := splitPathOnVendor(k)
-			vendoredRepoOrgProjectPath := path.Join(vendorPath, repoOrgProjectPath(nonVendorFullPath))
-			if !allProjectPkgsGrouped[vendoredRepoOrgProjectPath] && !usedKeys[vendoredRepoOrgProjectPath] {
-				unusedVendorPkgs = append(unusedVendorPkgs, vendoredRepoOrgProjectPath)
-				usedKeys[vendoredRepoOrgProjectPath] = true
+			vendorPath, nonVendorFullPath := vendorutil.SplitVendorPath(k)
+			vendoredRepoRootPath := path.Join(vendorPath, repoRootPath(nonVendorFullPath))
+			if !allProjectPkgsGrouped[vendoredRepoRootPath] && !usedKeys[vendoredRepoRootPath] {
+				unusedVendorPkgs = append(unusedVendorPkgs, vendoredRepoRootPath)
+				usedKeys[vendoredRepoRootPath] = true
 			}
 		}
 	} else {
@@ -216,7 +532,7 @@ func getUnusedVendoredPkgs(allProjectPkgs, allVendoredPkgs map[string]bool, grou
 	if !fullPath {
 		// if fullPath is false, remove vendor portion from output
 		for i, pkgName := range unusedVendorPkgs {
-			_, pkgName = splitPathOnVendor(pkgName)
+			_, pkgName = vendorutil.SplitVendorPath(pkgName)
 			unusedVendorPkgs[i] = pkgName
 		}
 	}
@@ -224,56 +540,148 @@ func getUnusedVendoredPkgs(allProjectPkgs, allVendoredPkgs map[string]bool, grou
 	return unusedVendorPkgs, nil
 }
 
-func getAllVendoredPkgs(projectRoot string) (map[string]bool, error) {
-	vendoredPkgs := make(map[string]bool)
-	err := filepath.Walk(projectRoot, func(currPath string, info os.FileInfo, err error) error {
-		if !info.IsDir() {
-			return nil
-		}
+const (
+	staleReasonNotBuildable = "no Go files can be built for any GOOS/GOARCH"
+	staleReasonTestOnly     = "only imported by test files of vendored packages"
+)
 
-		rel, err := filepath.Rel(projectRoot, currPath)
+// getStaleVendoredPkgs returns a map from the import path of every stale vendored package to the reason that it is
+// considered stale. A vendored package is stale if it is used by the project but none of its non-test Go files can be
+// built for any known GOOS/GOARCH combination, or if it is not used by the project and is only imported by the test
+// files of other vendored packages.
+func getStaleVendoredPkgs(l *loader.Loader, allProjectPkgs, allVendoredPkgs map[string]bool) (map[string]string, error) {
+	stalePkgs := make(map[string]string)
+	for vendoredPkg := range allVendoredPkgs {
+		if !allProjectPkgs[vendoredPkg] {
+			continue
+		}
+		pkg, err := doImport(l, vendoredPkg, "", build.FindOnly, nil)
 		if err != nil {
-			return err
+			return nil, errors.Wrapf(err, "failed to find vendored package %s", vendoredPkg)
 		}
-		inVendorDir := false
-		skipDirectory := false
-		for _, currPart := range strings.Split(rel, "/") {
-			if currPart == "vendor" {
-				inVendorDir = true
-				break
+		buildable, err := isBuildable(pkg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if !buildable {
+			stalePkgs[vendoredPkg] = staleReasonNotBuildable
+		}
+	}
+
+	// determine the vendored packages that are imported by the test files of other vendored packages
+	for vendoredPkg := range allVendoredPkgs {
+		pkg, _ := doImport(l, vendoredPkg, "", build.ImportComment, nil)
+		if pkg.ImportPath == "" {
+			continue
+		}
+		for _, currImport := range append(pkg.TestImports, pkg.XTestImports...) {
+			if !strings.Contains(currImport, ".") {
+				continue
 			}
-			if strings.HasPrefix(currPart, ".") {
-				skipDirectory = true
-				break
+			importedPkg, err := doImport(l, currImport, pkg.Dir, build.FindOnly, nil)
+			if err != nil || !allVendoredPkgs[importedPkg.ImportPath] || allProjectPkgs[importedPkg.ImportPath] {
+				continue
 			}
+			stalePkgs[importedPkg.ImportPath] = staleReasonTestOnly
 		}
+	}
+	return stalePkgs, nil
+}
 
-		if skipDirectory || !inVendorDir {
-			return nil
+// getShadowingVendoredPkgs returns a map from the path (relative to the project directory) of every vendored package
+// that shadows another package to the reason that it is considered shadowing. A vendored package shadows a package of
+// the project if its import path is within the import path of the project, in which case imports of the package from
+// the project resolve to the vendored copy rather than to the package of the project itself. A vendored package also
+// shadows the package with the same import path in the nearest vendor directory higher in the tree, since imports of
+// the package from within the directory that contains the lower vendor directory resolve to a different copy than
+// imports from elsewhere in the project.
+func getShadowingVendoredPkgs(projectImportPath string, allVendoredPkgs map[string]bool) map[string]string {
+	relPath := func(pkg string) string {
+		return strings.TrimPrefix(pkg, projectImportPath+"/")
+	}
+	shadowingPkgs := make(map[string]string)
+	for vendoredPkg := range allVendoredPkgs {
+		vendorPath, pkgPath := vendorutil.SplitVendorPath(vendoredPkg)
+		if pkgPath == projectImportPath || strings.HasPrefix(pkgPath, projectImportPath+"/") {
+			shadowingPkgs[relPath(vendoredPkg)] = fmt.Sprintf("shadows package %s of the project", pkgPath)
+			continue
+		}
+		for currDir := path.Dir(path.Dir(vendorPath)); currDir == projectImportPath || strings.HasPrefix(currDir, projectImportPath+"/"); currDir = path.Dir(currDir) {
+			if shadowedPkg := path.Join(currDir, "vendor", pkgPath); allVendoredPkgs[shadowedPkg] {
+				shadowingPkgs[relPath(vendoredPkg)] = fmt.Sprintf("shadows %s", relPath(shadowedPkg))
+				break
+			}
 		}
+	}
+	return shadowingPkgs
+}
 
-		// directory is in a vendor directory: attempt to parse as a package
-		pkg, err := doImport(".", currPath, build.ImportComment, nil)
-		// record import path if package could be parsed and import path is not "." (which can
-		// happen for some directories like testdata which cannot be imported)
-		if err == nil && pkg.ImportPath != "." {
-			vendoredPkgs[pkg.ImportPath] = true
+// isBuildable returns true if at least one of the non-test Go files in the provided directory can be built for at least
+// one of the GOOS/GOARCH combinations known to the loader package.
+func isBuildable(dir string) (bool, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read directory %s", dir)
+	}
+	ctx := build.Default
+	ctx.CgoEnabled = true
+	for _, goos := range loader.KnownOS {
+		ctx.GOOS = goos
+		for _, goarch := range loader.KnownArch {
+			ctx.GOARCH = goarch
+			for _, currFile := range files {
+				if currFile.IsDir() || !strings.HasSuffix(currFile.Name(), ".go") || strings.HasSuffix(currFile.Name(), "_test.go") {
+					continue
+				}
+				if match, err := ctx.MatchFile(dir, currFile.Name()); err == nil && match {
+					return true, nil
+				}
+			}
 		}
-		return nil
-	})
+	}
+	return false, nil
+}
+
+func getAllVendoredPkgs(l *loader.Loader, projectRoot string) (map[string]bool, error) {
+	vendorDirs, err := vendorutil.VendorDirsUnder(projectRoot)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to determine vendored packages")
 	}
+	vendoredPkgs := make(map[string]bool)
+	for _, currVendorDir := range vendorDirs {
+		if err := filepath.Walk(currVendorDir, func(currPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			if currPath != currVendorDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			// directory is in a vendor directory: attempt to parse as a package
+			pkg, err := doImport(l, ".", currPath, build.ImportComment, nil)
+			// record import path if package could be parsed and import path is not "." (which can
+			// happen for some directories like testdata which cannot be imported)
+			if err == nil && pkg.ImportPath != "." {
+				vendoredPkgs[pkg.ImportPath] = true
+			}
+			return nil
+		}); err != nil {
+			return nil, errors.Wrapf(err, "Failed to determine vendored packages")
+		}
+	}
 	return vendoredPkgs, nil
 }
 
 // getAllImports takes an import and returns all of the packages that it imports (excluding standard library packages).
 // Includes all transitive imports and the package of the import itself. Assumes that the import occurs in a package in
 // "srcDir". If the "test" parameter is "true", considers all imports in the test files for the package as well.
-func getAllImports(importPkgPath, srcDir, projectRoot string, examinedImports map[string]bool, includeTests bool) (map[string]bool, error) {
+func getAllImports(l *loader.Loader, importPkgPath, srcDir, projectRoot string, examinedImports map[string]bool, includeTests bool) (map[string]bool, error) {
 	importedPkgs := make(map[string]bool)
 
-	pkgs, err := getPkgsInDir(importPkgPath, srcDir, examinedImports)
+	pkgs, err := getPkgsInDir(l, importPkgPath, srcDir, examinedImports)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get packages in package %s", importPkgPath)
 	}
@@ -300,7 +708,7 @@ func getAllImports(importPkgPath, srcDir, projectRoot string, examinedImports ma
 				continue
 			}
 
-			currImportedPkgs, err := getAllImports(currImport, srcDir, projectRoot, examinedImports, false)
+			currImportedPkgs, err := getAllImports(l, currImport, srcDir, projectRoot, examinedImports, false)
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed to get all imports for %s", currImport)
 			}
@@ -314,7 +722,7 @@ func getAllImports(importPkgPath, srcDir, projectRoot string, examinedImports ma
 	return importedPkgs, nil
 }
 
-func getPkgsInDir(importPkgPath, srcDir string, examinedImports map[string]bool) ([]*build.Package, error) {
+func getPkgsInDir(l *loader.Loader, importPkgPath, srcDir string, examinedImports map[string]bool) ([]*build.Package, error) {
 	if !strings.Contains(importPkgPath, ".") {
 		// if package is a standard package, return empty
 		return nil, nil
@@ -325,7 +733,7 @@ func getPkgsInDir(importPkgPath, srcDir string, examinedImports map[string]bool)
 	for {
 		// ignore error because doImport returns partial object even on error. As long as an ImportPath is present,
 		// proceed with determining imports. Perform the import using the provided ctxIgnoreFiles.
-		pkg, pkgErr := doImport(importPkgPath, srcDir, build.ImportComment, ctxIgnoreFiles)
+		pkg, pkgErr := doImport(l, importPkgPath, srcDir, build.ImportComment, ctxIgnoreFiles)
 		if pkg.ImportPath == "" {
 			break
 		}
@@ -364,7 +772,7 @@ func getPkgsInDir(importPkgPath, srcDir string, examinedImports map[string]bool)
 			break
 		}
 
-		if pkg, _ := doImport(importPkgPath, srcDir, build.ImportComment, combineMaps(ctxIgnoreFiles, invalidFilesMap)); pkg.ImportPath != "" {
+		if pkg, _ := doImport(l, importPkgPath, srcDir, build.ImportComment, combineMaps(ctxIgnoreFiles, invalidFilesMap)); pkg.ImportPath != "" {
 			pkgs = append(pkgs, pkg)
 		}
 
@@ -385,58 +793,159 @@ func combineMaps(m1, m2 map[string]struct{}) map[string]struct{} {
 	return out
 }
 
-// takes the provided input, splits it on the path separator and returns the path up to the last "vendor" directory as
-// the first return value and the path after the last "vendor" directory as the second return value. For example, if
-// "foo/bar/vendor/inner/vendor/github.com/org/repo" is provided as input, the output is ("foo/bar/vendor/inner/vendor",
-// "github.com/org/repo").
-func splitPathOnVendor(pkgPath string) (string, string) {
-	// get last index of "vendor"
-	pathParts := strings.Split(pkgPath, "/")
-	vendorIndex := -1
-	for i := len(pathParts) - 1; i >= 0; i-- {
-		if pathParts[i] == "vendor" {
-			vendorIndex = i
-			break
+// getDeletableVendorDirs returns the paths (relative to projectDir) of the directories that can be deleted to remove
+// the provided unused vendored packages (full import paths as returned by getUnusedVendoredPkgs with the same value of
+// groupPkgsByProject). The directory of each unused package is collapsed to its highest ancestor below its vendor
+// directory that does not contain a package that is used, so deleting the returned directories (along with all of their
+// contents) does not affect the packages that are used. Unused packages whose directories contain a used package cannot
+// be deleted as a whole and are omitted. The returned paths are sorted and none of them contains another.
+func getDeletableVendorDirs(projectDir, gopath string, unusedPkgs []string, allProjectPkgs, allVendoredPkgs map[string]bool, groupPkgsByProject bool) ([]string, error) {
+	unusedSet := make(map[string]bool, len(unusedPkgs))
+	for _, pkg := range unusedPkgs {
+		unusedSet[pkg] = true
+	}
+	isUnused := func(pkg string) bool {
+		if !groupPkgsByProject {
+			return unusedSet[pkg]
+		}
+		vendorPath, nonVendorFullPath := vendorutil.SplitVendorPath(pkg)
+		return vendorPath != "" && unusedSet[path.Join(vendorPath, repoRootPath(nonVendorFullPath))]
+	}
+
+	// directories that contain a used package (including the directories of the used packages themselves)
+	keepDirs := make(map[string]bool)
+	for _, pkgs := range []map[string]bool{allProjectPkgs, allVendoredPkgs} {
+		for pkg := range pkgs {
+			if isUnused(pkg) {
+				continue
+			}
+			for currDir := filepath.Join(gopath, "src", pkg); strings.HasPrefix(currDir, projectDir+"/"); currDir = filepath.Dir(currDir) {
+				keepDirs[currDir] = true
+			}
 		}
 	}
-	return strings.Join(pathParts[:vendorIndex+1], "/"), strings.Join(pathParts[vendorIndex+1:], "/")
+
+	deletableDirs := make(map[string]bool)
+	for _, pkg := range unusedPkgs {
+		currDir := filepath.Join(gopath, "src", pkg)
+		if keepDirs[currDir] {
+			continue
+		}
+		for parentDir := filepath.Dir(currDir); filepath.Base(parentDir) != "vendor" && !keepDirs[parentDir]; parentDir = filepath.Dir(parentDir) {
+			currDir = parentDir
+		}
+		deletableDirs[currDir] = true
+	}
+
+	var sortedDirs []string
+	for currDir := range deletableDirs {
+		sortedDirs = append(sortedDirs, currDir)
+	}
+	sort.Strings(sortedDirs)
+
+	var paths []string
+	prevDir := ""
+	for _, currDir := range sortedDirs {
+		if prevDir != "" && strings.HasPrefix(currDir, prevDir+"/") {
+			continue
+		}
+		prevDir = currDir
+		relPath, err := filepath.Rel(projectDir, currDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to determine path of %s relative to %s", currDir, projectDir)
+		}
+		paths = append(paths, relPath)
+	}
+	return paths, nil
+}
+
+// knownRepoRoots are the import path prefixes of hosts and vanity domains whose repository roots are known along with
+// the number of path elements in the repository root of an import path with the prefix. For example, the repository
+// root of "k8s.io/client-go/rest" is "k8s.io/client-go" (2 elements) and the repository root of
+// "golang.org/x/crypto/ssh/agent" is "golang.org/x/crypto" (3 elements). The prefixes are checked in order, so more
+// specific prefixes must come first.
+var knownRepoRoots = []struct {
+	prefix   string
+	elements int
+}{
+	{"github.com/", 3},
+	{"bitbucket.org/", 3},
+	{"gitlab.com/", 3},
+	{"code.google.com/p/", 3},
+	{"hub.jazz.net/git/", 4},
+	{"golang.org/x/", 3},
+	{"honnef.co/go/", 3},
+	{"gonum.org/v1/", 3},
+	{"k8s.io/", 2},
+	{"sigs.k8s.io/", 2},
+	{"go.uber.org/", 2},
+	{"go.etcd.io/", 2},
+	{"go.opentelemetry.io/", 2},
+	{"google.golang.org/", 2},
+	{"cloud.google.com/", 2},
+	{"go.opencensus.io/", 1},
+	{"gotest.tools/", 1},
 }
 
-// returns the path that contains at most the first 3 elements of the package path. In most schemes, this will
-// correspond to the source repository, organization and project ("gibhub.com/user/repo", "golang.org/x/crypto").
-// If the path is shorter than 3 portions (for example, "gopkg.in/yaml.v2"), the path will be returned as-is. Does not
-// do any semantic analysis, so 3 portions will be returned even if logically the repository is only 2 levels. For
-// example, if "gopkg.in/project/subpackage" is provided as input, the first 3 parts of the path will be returned even
-// though conceptually it represents a subpackage.
-func repoOrgProjectPath(pkgPath string) string {
-	_, pkgPath = splitPathOnVendor(pkgPath)
+// vcsSuffixes are the suffixes of a path element that mark the end of the repository root in an import path that
+// specifies its version control system (for example, "example.org/repo.git/sub").
+var vcsSuffixes = []string{".git", ".hg", ".svn", ".bzr", ".fossil"}
+
+// repoRootPath returns the import path of the root of the repository that contains the package with the provided path
+// (the portion of the path after the last "vendor" directory is used). The repository root is determined offline using
+// the rules that "go get" uses for the hosts it knows about, known vanity domains and the version control suffixes that
+// can be used in import paths. An element that ends in a version control suffix (for example,
+// "example.org/repo.git/sub") ends the root. gopkg.in paths have 2 elements ("gopkg.in/yaml.v2") or 3 elements if the
+// package has a user ("gopkg.in/user/pkg.v1"), and paths on known hosts and vanity domains have the number of elements
+// specified in knownRepoRoots. All other paths use at most the first 3 elements, which corresponds to the repository,
+// organization and project in most schemes. If the path has fewer elements than the root, the path is returned as-is.
+func repoRootPath(pkgPath string) string {
+	_, pkgPath = vendorutil.SplitVendorPath(pkgPath)
 	pathParts := strings.Split(pkgPath, "/")
-	lastIdx := len(pathParts)
-	if lastIdx > 3 {
-		lastIdx = 3
+
+	for i, currPart := range pathParts {
+		if i > 0 && hasVCSSuffix(currPart) {
+			return strings.Join(pathParts[:i+1], "/")
+		}
+	}
+	lastIdx := 3
+	if strings.HasPrefix(pkgPath, "gopkg.in/") {
+		if len(pathParts) > 1 && strings.Contains(pathParts[1], ".v") {
+			lastIdx = 2
+		}
+	} else {
+		for _, currRoot := range knownRepoRoots {
+			if strings.HasPrefix(pkgPath+"/", currRoot.prefix) {
+				lastIdx = currRoot.elements
+				break
+			}
+		}
+	}
+	if lastIdx > len(pathParts) {
+		lastIdx = len(pathParts)
 	}
 	return strings.Join(pathParts[:lastIdx], "/")
 }
 
-// allContext is a build.Context based on build.Default that has "UseAllFiles" set to true. Makes it such that analysis
-// is done on all Go files rather than on just those that match the default build context.
-var allContext = getAllContext()
-
-func getAllContext() build.Context {
-	ctx := build.Default
-	ctx.UseAllFiles = true
-	return ctx
+// hasVCSSuffix returns true if the provided path element ends in one of the version control suffixes.
+func hasVCSSuffix(pathPart string) bool {
+	for _, currSuffix := range vcsSuffixes {
+		if strings.HasSuffix(pathPart, currSuffix) && len(pathPart) > len(currSuffix) {
+			return true
+		}
+	}
+	return false
 }
 
-// doImport performs an "Import" operation. If "ignoreFiles" does not have any entries, it uses "allContext" to do the
-// import. Otherwise, it creates a new "all" context with a custom ReadDir function that ignores files with the names in
-// the provided map.
-func doImport(path, srcDir string, mode build.ImportMode, ignoreFiles map[string]struct{}) (*build.Package, error) {
+// doImport performs an "Import" operation. If "ignoreFiles" does not have any entries, it uses the provided loader to
+// do the import. Otherwise, it creates a copy of the build context of the loader with a custom ReadDir function that
+// ignores files with the names in the provided map.
+func doImport(l *loader.Loader, path, srcDir string, mode build.ImportMode, ignoreFiles map[string]struct{}) (*build.Package, error) {
 	if len(ignoreFiles) == 0 {
-		return allContext.Import(path, srcDir, mode)
+		return l.Import(path, srcDir, mode)
 	}
 
-	ctx := getAllContext()
+	ctx := l.Context()
 	ctx.ReadDir = func(dir string) ([]os.FileInfo, error) {
 		files, err := ioutil.ReadDir(dir)
 		var filesToReturn []os.FileInfo
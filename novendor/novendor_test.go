@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -27,6 +28,8 @@ import (
 	"github.com/nmiyake/pkg/gofiles"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/checks/loader"
 )
 
 func TestNovendor(t *testing.T) {
@@ -510,7 +513,11 @@ package bar; import _ "{{index . "vendor/github.com/org/library/subpackage_linux
 
 func verifyDoMain(t *testing.T, caseNum int, name, dir string, args []string, group, full bool, checkType string, f func(map[string]gofiles.GoFile) []string, files map[string]gofiles.GoFile) {
 	buf := bytes.Buffer{}
-	doMainErr := doNovendor(dir, args, group, full, false, &buf)
+	doMainErr := doNovendor(loader.New(loader.Options{UseAllFiles: true}), dir, novendorOptions{
+		pkgPaths:           args,
+		groupPkgsByProject: group,
+		fullPath:           full,
+	}, &buf)
 	expectedOutput := ""
 	if f != nil {
 		expectedOutput = fmt.Sprintln(strings.Join(f(files), "\n"))
@@ -522,3 +529,529 @@ func verifyDoMain(t *testing.T, caseNum int, name, dir string, args []string, gr
 	}
 	assert.Equal(t, expectedOutput, buf.String(), "Case %d (%s): %s\nOutput:\n%s", caseNum, name, checkType, buf.String())
 }
+
+func TestRepoRootPath(t *testing.T) {
+	for i, currCase := range []struct {
+		pkgPath string
+		want    string
+	}{
+		{"github.com/org/repo/sub/pkg", "github.com/org/repo"},
+		{"github.com/org", "github.com/org"},
+		{"foo/vendor/github.com/org/repo/sub", "github.com/org/repo"},
+		{"golang.org/x/crypto/ssh/agent", "golang.org/x/crypto"},
+		{"k8s.io/client-go/rest", "k8s.io/client-go"},
+		{"sigs.k8s.io/yaml", "sigs.k8s.io/yaml"},
+		{"google.golang.org/grpc/codes", "google.golang.org/grpc"},
+		{"go.opencensus.io/trace", "go.opencensus.io"},
+		{"gopkg.in/yaml.v2", "gopkg.in/yaml.v2"},
+		{"gopkg.in/yaml.v2/subpackage", "gopkg.in/yaml.v2"},
+		{"gopkg.in/user/pkg.v1/sub", "gopkg.in/user/pkg.v1"},
+		{"example.org/repo.git/sub/pkg", "example.org/repo.git"},
+		{"example.org/org/repo/sub", "example.org/org/repo"},
+	} {
+		assert.Equal(t, currCase.want, repoRootPath(currCase.pkgPath), "Case %d: %s", i, currCase.pkgPath)
+	}
+}
+
+func TestNovendorStale(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     `package main; import _ "github.com/org/lib"; import _ "github.com/org/ignored";`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/lib.go",
+			Src:     `package lib`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/lib_test.go",
+			Src:     `package lib; import _ "github.com/org/testlib";`,
+		},
+		{
+			RelPath: "vendor/github.com/org/testlib/testlib.go",
+			Src:     `package testlib`,
+		},
+		{
+			RelPath: "vendor/github.com/org/ignored/ignored.go",
+			Src: `// +build ignore
+
+package ignored`,
+		},
+		{
+			RelPath: "vendor/github.com/org/unused/unused.go",
+			Src:     `package unused`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "github.com/org/testlib\ngithub.com/org/unused\n", buf.String())
+
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		reportStale:        true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `github.com/org/unused
+Stale vendored packages (2):
+	github.com/org/ignored: no Go files can be built for any GOOS/GOARCH
+	github.com/org/testlib: only imported by test files of vendored packages
+`, buf.String())
+}
+
+func TestNovendorShadowing(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	projectImportPath, err := filepath.Rel(path.Join(os.Getenv("GOPATH"), "src"), tmpDir)
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     `package main; import _ "github.com/org/lib"; import _ "` + projectImportPath + `/bar"; import _ "` + projectImportPath + `/sub";`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src:     `package bar`,
+		},
+		{
+			RelPath: "vendor/" + projectImportPath + "/bar/bar.go",
+			Src:     `package bar`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/lib.go",
+			Src:     `package lib`,
+		},
+		{
+			RelPath: "sub/sub.go",
+			Src:     `package sub; import _ "github.com/org/lib"; import _ "github.com/org/other";`,
+		},
+		{
+			RelPath: "sub/vendor/github.com/org/lib/lib.go",
+			Src:     `package lib`,
+		},
+		{
+			RelPath: "sub/vendor/github.com/org/other/other.go",
+			Src:     `package other`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		reportShadowing:    true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, fmt.Sprintf(`Shadowing vendored packages (2):
+	sub/vendor/github.com/org/lib: shadows vendor/github.com/org/lib
+	vendor/%s/bar: shadows package %s/bar of the project
+`, projectImportPath, projectImportPath), buf.String())
+}
+
+func TestNovendorCheckTests(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "foo.go",
+			Src:     `package foo; import _ "github.com/org/lib";`,
+		},
+		{
+			RelPath: "foo_test.go",
+			Src:     `package foo; import _ "github.com/org/assert";`,
+		},
+		{
+			RelPath: "foo_ext_test.go",
+			Src:     `package foo_test; import _ "github.com/org/lib/mock";`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/lib.go",
+			Src:     `package lib`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/mock/mock.go",
+			Src:     `package mock`,
+		},
+		{
+			RelPath: "vendor/github.com/org/assert/assert.go",
+			Src:     `package assert; import _ "github.com/org/difflib";`,
+		},
+		{
+			RelPath: "vendor/github.com/org/difflib/difflib.go",
+			Src:     `package difflib`,
+		},
+		{
+			RelPath: "vendor/github.com/org/unused/unused.go",
+			Src:     `package unused`,
+		},
+	})
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		checkTests:         true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `github.com/org/unused
+Vendored packages only used by tests (2):
+	github.com/org/assert
+	github.com/org/difflib
+`, buf.String())
+
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		checkTests: true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `github.com/org/unused
+Vendored packages only used by tests (3):
+	github.com/org/assert
+	github.com/org/difflib
+	github.com/org/lib/mock
+`, buf.String())
+
+	// packages that are only used by tests do not cause a failure if they are allowed
+	require.NoError(t, os.RemoveAll(path.Join(tmpDir, "vendor/github.com/org/unused")))
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		checkTests:         true,
+		allowTestOnly:      true,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, `Vendored packages only used by tests (2):
+	github.com/org/assert
+	github.com/org/difflib
+`, buf.String())
+
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		checkTests:         true,
+	}, &buf)
+	require.Error(t, err)
+}
+
+func TestNovendorDeadPackages(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "main.go",
+			Src:     `package main; import _ "{{index . "used/used.go"}}";`,
+		},
+		{
+			RelPath: "used/used.go",
+			Src:     `package used`,
+		},
+		{
+			RelPath: "tested/tested.go",
+			Src:     `package tested`,
+		},
+		{
+			RelPath: "tested/tested_test.go",
+			Src:     `package tested; import _ "{{index . "testhelper/testhelper.go"}}";`,
+		},
+		{
+			RelPath: "testhelper/testhelper.go",
+			Src:     `package testhelper`,
+		},
+		{
+			RelPath: "dead/dead.go",
+			Src:     `package dead; import _ "{{index . "deaddep/deaddep.go"}}";`,
+		},
+		{
+			RelPath: "deaddep/deaddep.go",
+			Src:     `package deaddep`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/lib.go",
+			Src:     `package lib; import _ "{{index . "deaddep/deaddep.go"}}";`,
+		},
+	})
+	require.NoError(t, err)
+
+	// dead packages are only reported if requested
+	buf := bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, "github.com/org/lib\n", buf.String())
+
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		reportDead:         true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, fmt.Sprintf(`github.com/org/lib
+Dead project packages (2):
+	%s
+	%s
+`, files["dead/dead.go"].ImportPath, files["deaddep/deaddep.go"].ImportPath), buf.String())
+
+	// ignored packages and their imports are not reported
+	require.NoError(t, os.RemoveAll(path.Join(tmpDir, "vendor")))
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		reportDead:         true,
+		deadPkgsIgnore:     []string{"dead"},
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestNovendorPrintPaths(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	_, err = gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "main.go",
+			Src:     `package main; import _ "github.com/org/used"; import _ "github.com/org/parent/child";`,
+		},
+		{
+			RelPath: "vendor/github.com/org/used/used.go",
+			Src:     `package used`,
+		},
+		{
+			RelPath: "vendor/github.com/org/used/sub/sub.go",
+			Src:     `package sub`,
+		},
+		{
+			RelPath: "vendor/github.com/org/parent/parent.go",
+			Src:     `package parent`,
+		},
+		{
+			RelPath: "vendor/github.com/org/parent/child/child.go",
+			Src:     `package child`,
+		},
+		{
+			RelPath: "vendor/github.com/org/unused/unused.go",
+			Src:     `package unused`,
+		},
+		{
+			RelPath: "vendor/github.com/gone/a/a.go",
+			Src:     `package a`,
+		},
+		{
+			RelPath: "vendor/github.com/gone/b/b.go",
+			Src:     `package b`,
+		},
+		{
+			RelPath: "vendor/golang.org/x/text/text.go",
+			Src:     `package text`,
+		},
+	})
+	require.NoError(t, err)
+
+	// directories are collapsed to the highest ancestor that does not contain a used package
+	buf := bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		printPaths:         true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `vendor/github.com/gone
+vendor/github.com/org/unused
+vendor/golang.org
+`, buf.String())
+
+	// unused packages whose directories contain a used package are omitted
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		printPaths: true,
+	}, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `vendor/github.com/gone
+vendor/github.com/org/unused
+vendor/github.com/org/used/sub
+vendor/golang.org
+`, buf.String())
+
+	// no output if there are no unused packages
+	require.NoError(t, os.RemoveAll(path.Join(tmpDir, "vendor")))
+	buf = bytes.Buffer{}
+	err = doNovendor(loader.New(loader.Options{UseAllFiles: true}), tmpDir, novendorOptions{
+		groupPkgsByProject: true,
+		printPaths:         true,
+	}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestVerifyModules(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	for _, currFile := range []struct {
+		relPath string
+		content string
+	}{
+		{"go.mod", "module github.com/org/project\n\nrequire (\n\tgithub.com/org/lib v1.0.0\n\tgithub.com/org/missing v1.0.0\n)\n"},
+		{"vendor/modules.txt", "# github.com/org/lib v1.0.0\n## explicit\ngithub.com/org/lib\ngithub.com/org/lib/sub\n# github.com/org/removed v1.0.0\n## explicit; go 1.12\n# github.com/org/transitive v1.0.0\ngithub.com/org/transitive\n"},
+		{"vendor/github.com/org/lib/lib.go", "package lib"},
+		{"vendor/github.com/org/lib/sub/sub.go", "package sub"},
+		{"vendor/github.com/org/lib/extra/extra.go", "package extra"},
+		{"vendor/github.com/org/lib/testdata/data.go", "package data"},
+		{"vendor/github.com/org/leftover/leftover.go", "package leftover"},
+	} {
+		require.NoError(t, os.MkdirAll(path.Dir(path.Join(tmpDir, currFile.relPath)), 0755))
+		require.NoError(t, ioutil.WriteFile(path.Join(tmpDir, currFile.relPath), []byte(currFile.content), 0644))
+	}
+
+	buf := bytes.Buffer{}
+	err = doVerifyModules(tmpDir, &buf)
+	require.Error(t, err)
+	assert.Equal(t, `github.com/org/missing: required by go.mod but not listed in vendor/modules.txt
+github.com/org/removed: listed in vendor/modules.txt as explicitly required but not required by go.mod
+vendor/github.com/org/leftover: not listed in vendor/modules.txt
+vendor/github.com/org/lib/extra: not listed in vendor/modules.txt
+vendor/github.com/org/transitive: listed in vendor/modules.txt but does not exist
+`, buf.String())
+}
+
+func TestNovendorWhy(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tmpDir, cleanup, err := dirs.TempDir(wd, "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	files, err := gofiles.Write(tmpDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "main.go",
+			Src:     `package main; import _ "{{index . "foo/foo.go"}}";`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import _ "github.com/org/a"
+`,
+		},
+		{
+			RelPath: "bar/bar_test.go",
+			Src:     `package bar; import _ "github.com/org/testlib";`,
+		},
+		{
+			RelPath: "vendor/github.com/org/a/a.go",
+			Src: `package a
+
+import (
+	_ "fmt"
+	_ "github.com/org/lib/sub"
+)
+`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/sub/sub.go",
+			Src:     `package sub`,
+		},
+		{
+			RelPath: "vendor/github.com/org/lib/unused/unused.go",
+			Src:     `package unused`,
+		},
+		{
+			RelPath: "vendor/github.com/org/testlib/testlib.go",
+			Src:     `package testlib`,
+		},
+		{
+			RelPath: "vendor/github.com/gone/gone.go",
+			Src:     `package gone`,
+		},
+	})
+	require.NoError(t, err)
+	fooPkg := files["foo/foo.go"].ImportPath
+	vendorPath := path.Join(path.Dir(fooPkg), "vendor")
+
+	for i, currCase := range []struct {
+		pkg                string
+		groupPkgsByProject bool
+		fullPath           bool
+		want               string
+	}{
+		{
+			pkg:  "github.com/org/lib/sub",
+			want: fmt.Sprintf("%s (foo/foo.go:3)\ngithub.com/org/a (vendor/github.com/org/a/a.go:5)\ngithub.com/org/lib/sub\n", fooPkg),
+		},
+		{
+			pkg:      path.Join(vendorPath, "github.com/org/lib/sub"),
+			fullPath: true,
+			want:     fmt.Sprintf("%s (foo/foo.go:3)\n%s/github.com/org/a (vendor/github.com/org/a/a.go:5)\n%s/github.com/org/lib/sub\n", fooPkg, vendorPath, vendorPath),
+		},
+		{
+			pkg:                "github.com/org/lib",
+			groupPkgsByProject: true,
+			want:               fmt.Sprintf("%s (foo/foo.go:3)\ngithub.com/org/a (vendor/github.com/org/a/a.go:5)\ngithub.com/org/lib/sub\n", fooPkg),
+		},
+		{
+			pkg:  "github.com/org/testlib",
+			want: fmt.Sprintf("%s (bar/bar_test.go:1)\ngithub.com/org/testlib\n", files["bar/bar_test.go"].ImportPath),
+		},
+		{
+			pkg:  "github.com/org/lib/unused",
+			want: "github.com/org/lib/unused is not used by the project\n",
+		},
+		{
+			pkg:  "github.com/gone",
+			want: "github.com/gone is not used by the project\n",
+		},
+	} {
+		buf := bytes.Buffer{}
+		err := doWhy(loader.New(loader.Options{UseAllFiles: true}), tmpDir, currCase.pkg, nil, currCase.groupPkgsByProject, currCase.fullPath, &buf)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, buf.String(), "Case %d", i)
+	}
+
+	// "project packages" are only matched if packages are grouped by project
+	err = doWhy(loader.New(loader.Options{UseAllFiles: true}), tmpDir, "github.com/org/lib", nil, false, false, &bytes.Buffer{})
+	assert.EqualError(t, err, "github.com/org/lib is not a vendored package")
+}
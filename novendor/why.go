@@ -0,0 +1,196 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"go/token"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/palantir/checks/checks/loader"
+	"github.com/palantir/checks/checks/vendorutil"
+)
+
+// importEdge records that the package "from" imports a package at the position "pos".
+type importEdge struct {
+	from string
+	pos  token.Position
+}
+
+// doWhy prints why the vendored package vendoredPkg is considered used by the project packages at the provided paths
+// (relative to projectDir, or all of the non-vendored packages if no paths are provided). vendoredPkg may be specified
+// with or without the vendor directory prefix and, if groupPkgsByProject is true, may be a "project package", in which
+// case it is used if any of the packages in the project package is used. If the package is used, the shortest chain of
+// imports from a project package to the package is printed one package per line, where each line other than the last
+// contains the file and line of the import of the next package. Otherwise, a line stating that the package is unused is
+// printed. Returns an error if vendoredPkg does not match any vendored package.
+func doWhy(l *loader.Loader, projectDir, vendoredPkg string, pkgPaths []string, groupPkgsByProject, fullPath bool, w io.Writer) error {
+	_, pkgsToProcess, err := getPkgsToProcess(projectDir, pkgPaths)
+	if err != nil {
+		return err
+	}
+	allProjectPkgs, allVendoredPkgs, err := getPackageInfo(l, projectDir, pkgsToProcess)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get package information")
+	}
+
+	targets := matchVendoredPkgs(vendoredPkg, allVendoredPkgs, groupPkgsByProject)
+	if len(targets) == 0 {
+		return errors.Errorf("%s is not a vendored package", vendoredPkg)
+	}
+	usedTargets := make(map[string]bool)
+	for pkg := range targets {
+		if allProjectPkgs[pkg] {
+			usedTargets[pkg] = true
+		}
+	}
+	if len(usedTargets) == 0 {
+		fmt.Fprintf(w, "%s is not used by the project\n", vendoredPkg)
+		return nil
+	}
+
+	chain, positions, err := shortestImportChain(l, projectDir, pkgsToProcess, allProjectPkgs, usedTargets)
+	if err != nil {
+		return err
+	}
+	for i, pkg := range chain {
+		if !fullPath {
+			pkg = vendorutil.StripVendorPrefix(pkg)
+		}
+		if i == len(chain)-1 {
+			fmt.Fprintln(w, pkg)
+			continue
+		}
+		filename := positions[i].Filename
+		if rel, err := filepath.Rel(projectDir, filename); err == nil && !strings.HasPrefix(rel, "../") {
+			filename = rel
+		}
+		fmt.Fprintf(w, "%s (%s:%d)\n", pkg, filename, positions[i].Line)
+	}
+	return nil
+}
+
+// matchVendoredPkgs returns the vendored packages that match the provided package. A vendored package matches if its
+// import path with or without the vendor directory prefix is equal to pkg. If there are no such packages and
+// groupPkgsByProject is true, the vendored packages whose "project package" is pkg match.
+func matchVendoredPkgs(pkg string, allVendoredPkgs map[string]bool, groupPkgsByProject bool) map[string]bool {
+	matches := make(map[string]bool)
+	for vendored := range allVendoredPkgs {
+		if vendored == pkg || vendorutil.StripVendorPrefix(vendored) == pkg {
+			matches[vendored] = true
+		}
+	}
+	if len(matches) > 0 || !groupPkgsByProject {
+		return matches
+	}
+	for vendored := range allVendoredPkgs {
+		vendorPath, nonVendorFullPath := vendorutil.SplitVendorPath(vendored)
+		if root := repoRootPath(nonVendorFullPath); root == pkg || path.Join(vendorPath, root) == pkg {
+			matches[vendored] = true
+		}
+	}
+	return matches
+}
+
+// shortestImportChain returns the shortest chain of imports from one of the provided project packages to one of the
+// target packages along with the positions of the imports in the chain (the position at index i is the import of the
+// package at index i+1 in the package at index i). Imports are resolved in the same manner as getAllImports: the
+// imports of the test files of the project packages are considered and vendored imports are resolved against the last
+// package in the project that was encountered. Only the packages in usedPkgs are traversed. If multiple chains are
+// equally short, the chain that is found first when the packages and their imports are considered in sorted order is
+// returned.
+func shortestImportChain(l *loader.Loader, projectDir string, pkgsToProcess []pkgWithSrc, usedPkgs, targets map[string]bool) ([]string, []token.Position, error) {
+	type queued struct {
+		pkg          *build.Package
+		srcDir       string
+		includeTests bool
+	}
+	var queue []queued
+	edges := make(map[string]*importEdge)
+	for _, currPkg := range pkgsToProcess {
+		pkgs, err := getPkgsInDir(l, currPkg.pkg, currPkg.src, make(map[string]bool))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to get packages in directory %s", currPkg.src)
+		}
+		for _, pkg := range pkgs {
+			if _, ok := edges[pkg.ImportPath]; ok {
+				continue
+			}
+			edges[pkg.ImportPath] = nil
+			queue = append(queue, queued{pkg: pkg, srcDir: pkg.Dir, includeTests: true})
+		}
+	}
+
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+		if targets[curr.pkg.ImportPath] {
+			chain, positions := importChain(curr.pkg.ImportPath, edges)
+			return chain, positions, nil
+		}
+
+		srcDir := curr.srcDir
+		importPos := []map[string][]token.Position{curr.pkg.ImportPos}
+		if rel, err := filepath.Rel(projectDir, curr.pkg.Dir); err == nil && !strings.HasPrefix(rel, "../") {
+			srcDir = curr.pkg.Dir
+			if curr.includeTests {
+				importPos = append(importPos, curr.pkg.TestImportPos, curr.pkg.XTestImportPos)
+			}
+		}
+		for _, currImportPos := range importPos {
+			var imports []string
+			for currImport := range currImportPos {
+				imports = append(imports, currImport)
+			}
+			sort.Strings(imports)
+			for _, currImport := range imports {
+				pkgs, err := getPkgsInDir(l, currImport, srcDir, make(map[string]bool))
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "failed to get packages in package %s", currImport)
+				}
+				for _, pkg := range pkgs {
+					if _, ok := edges[pkg.ImportPath]; ok || !usedPkgs[pkg.ImportPath] {
+						continue
+					}
+					edges[pkg.ImportPath] = &importEdge{
+						from: curr.pkg.ImportPath,
+						pos:  currImportPos[currImport][0],
+					}
+					queue = append(queue, queued{pkg: pkg, srcDir: srcDir})
+				}
+			}
+		}
+	}
+	return nil, nil, errors.Errorf("failed to determine the imports through which the project uses the package")
+}
+
+// importChain returns the chain of imports that ends at the provided package using the provided edges along with the
+// positions of the imports.
+func importChain(pkg string, edges map[string]*importEdge) ([]string, []token.Position) {
+	chain := []string{pkg}
+	var positions []token.Position
+	for edge := edges[pkg]; edge != nil; edge = edges[edge.from] {
+		chain = append([]string{edge.from}, chain...)
+		positions = append([]token.Position{edge.pos}, positions...)
+	}
+	return chain, positions
+}
@@ -9,21 +9,58 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 
+	"golang.org/x/tools/go/analysis/unitchecker"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
 	"github.com/palantir/checks/outparamcheck/outparamcheck"
 )
 
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
+	if isVetInvocation(os.Args[1:]) {
+		unitchecker.Main(outparamcheck.Analyzer)
+	}
+
 	cfgPath := ""
+	printCfg := false
+	inferAnnotated := false
+	fix := false
+	format := ""
+	var bl baseline.Options
 	fset := flag.CommandLine
-	fset.StringVar(&cfgPath, "config", "", "JSON configuration or '@' followed by path to a configuration file (@pathToJsonFile)")
+	fset.StringVar(&cfgPath, "config", "", "YAML or JSON configuration or '@' followed by path to a configuration file (@pathToConfigFile)")
+	fset.BoolVar(&printCfg, "print-config", false, "print the effective configuration (user configuration merged with the defaults) as YAML and exit")
+	fset.BoolVar(&inferAnnotated, "infer", false, "also check calls to the functions in the checked packages that have parameters annotated with '//outparam:' comments")
+	fset.BoolVar(&fix, "fix", false, "fix violations where the argument is addressable by rewriting the argument 'x' as '&x'")
+	fset.StringVar(&format, "format", diagnostic.FormatText, "format of the output. Must be 'text', 'json', 'checkstyle' or 'github'")
+	fset.StringVar(&bl.Path, baseline.FlagName, "", "path to a baseline file: errors recorded in the baseline are not reported")
+	fset.StringVar(&bl.WritePath, baseline.WriteFlagName, "", "path to which a baseline file that records the current errors is written")
 	flag.Parse()
 
-	err := outparamcheck.Run(cfgPath, flag.Args())
+	var err error
+	if printCfg {
+		err = outparamcheck.PrintConfig(cfgPath, os.Stdout)
+	} else {
+		err = outparamcheck.Run(cfgPath, inferAnnotated, fix, format, bl, flag.Args())
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
+
+// isVetInvocation returns true if the provided arguments are those used by "go vet -vettool" to invoke the tool. The
+// "go" tool invokes vet tools with "-V=full" to determine the version of the tool, with "-flags" to determine the flags
+// supported by the tool, and with the path to a ".cfg" file that describes the package to check.
+func isVetInvocation(args []string) bool {
+	for _, arg := range args {
+		if arg == "-V=full" || arg == "-flags" {
+			return true
+		}
+	}
+	return len(args) > 0 && strings.HasSuffix(args[len(args)-1], ".cfg")
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package outparamcheck
+
+import (
+	"fmt"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	configFlagName = "config"
+	inferFlagName  = "infer"
+)
+
+// Analyzer checks that the arguments provided for output parameters are pointers. It can be run using drivers such as
+// "go vet -vettool" or composed with other analyzers. The result of the analyzer is a []OutParamError that contains the
+// violations in the package in order of location.
+//
+// The analyzer is configured using the "config" flag, which is YAML or JSON configuration or '@' followed by the path
+// to a configuration file, and the "infer" flag, which enables checking of calls to functions that have parameters
+// annotated with "//outparam:" comments.
+var Analyzer = newAnalyzer()
+
+func newAnalyzer() *analysis.Analyzer {
+	var cfgParam string
+	var inferAnnotated bool
+
+	a := analyzerWithRun(func(pass *analysis.Pass) (interface{}, error) {
+		cfg, err := effectiveConfig(cfgParam)
+		if err != nil {
+			return nil, err
+		}
+		return runPass(pass, cfg, inferAnnotated)
+	})
+	a.Flags.StringVar(&cfgParam, configFlagName, "", "YAML or JSON configuration or '@' followed by path to a configuration file (@pathToConfigFile)")
+	a.Flags.BoolVar(&inferAnnotated, inferFlagName, false, "also check calls to functions that have parameters annotated with '//outparam:' comments")
+	return a
+}
+
+// newConfiguredAnalyzer returns an analyzer that checks using the provided configuration rather than the configuration
+// specified by its flags, which allows the check to be run with different configurations without modifying the flags of
+// Analyzer.
+func newConfiguredAnalyzer(cfg Config, inferAnnotated bool) *analysis.Analyzer {
+	return analyzerWithRun(func(pass *analysis.Pass) (interface{}, error) {
+		return runPass(pass, cfg, inferAnnotated)
+	})
+}
+
+func analyzerWithRun(run func(*analysis.Pass) (interface{}, error)) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "outparamcheck",
+		Doc: "check that arguments for output parameters are pointers\n\n" +
+			"Functions such as encoding/json.Unmarshal accept output parameters declared as interface{}. " +
+			"This analyzer verifies that the arguments provided for such parameters are of the form &x.",
+		URL:        "https://github.com/palantir/checks/tree/master/outparamcheck",
+		FactTypes:  []analysis.Fact{new(outParamsFact)},
+		ResultType: reflect.TypeOf([]OutParamError(nil)),
+		Run:        run,
+	}
+}
+
+// outParamsFact records the indices of the output parameters of a function that has parameters annotated with
+// "//outparam:" comments.
+type outParamsFact struct {
+	Indices []int
+}
+
+func (*outParamsFact) AFact() {}
+
+func (f *outParamsFact) String() string {
+	return fmt.Sprintf("outParams(%v)", f.Indices)
+}
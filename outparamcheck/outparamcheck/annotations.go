@@ -0,0 +1,115 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package outparamcheck
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// outParamDirective is the prefix of a comment in the documentation of a function that marks parameters of the function
+// as output parameters. The remainder of the comment is a comma-separated list of parameter names. For example:
+//
+//	// Load unmarshals the configuration into the provided value.
+//	//
+//	//outparam:v
+//	func Load(data []byte, v interface{}) error
+const outParamDirective = "//outparam:"
+
+// exportAnnotatedFacts exports an outParamsFact for each function and method declared in the package of the provided
+// pass that has parameters annotated as output parameters using the outParamDirective.
+func exportAnnotatedFacts(pass *analysis.Pass) error {
+	annotated, err := annotatedOutParams(pass.Fset, pass.Files, pass.TypesInfo)
+	if err != nil {
+		return err
+	}
+	for fn, outs := range annotated {
+		pass.ExportObjectFact(fn, &outParamsFact{
+			Indices: outs,
+		})
+	}
+	return nil
+}
+
+// annotatedOutParams returns the indices of the output parameters of the functions and methods declared in the provided
+// files that have parameters annotated as output parameters using the outParamDirective.
+func annotatedOutParams(fset *token.FileSet, files []*ast.File, info *types.Info) (map[*types.Func][]int, error) {
+	annotated := make(map[*types.Func][]int)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Doc == nil {
+				continue
+			}
+			names := outParamNames(funcDecl.Doc)
+			if len(names) == 0 {
+				continue
+			}
+			fn, ok := info.Defs[funcDecl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			outs, err := paramIndices(fn, names)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s annotation at %s", outParamDirective, fset.Position(funcDecl.Pos()))
+			}
+			annotated[fn] = outs
+		}
+	}
+	return annotated, nil
+}
+
+// outParamNames returns the names of the parameters specified by the outParamDirective comments in the provided
+// documentation.
+func outParamNames(doc *ast.CommentGroup) []string {
+	var names []string
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, outParamDirective) {
+			continue
+		}
+		for _, name := range strings.Split(strings.TrimPrefix(comment.Text, outParamDirective), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// paramIndices returns the indices of the parameters of the provided function with the provided names.
+func paramIndices(fn *types.Func, names []string) ([]int, error) {
+	params := fn.Type().(*types.Signature).Params()
+	var outs []int
+	for _, name := range names {
+		idx := -1
+		for i := 0; i < params.Len(); i++ {
+			if params.At(i).Name() == name {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil, errors.Errorf("%s does not have a parameter named %s", fn.Name(), name)
+		}
+		outs = append(outs, idx)
+	}
+	return outs, nil
+}
+
+// calledFunc returns the function or method called by the provided call expression. Returns false if the call is not a
+// static call of a function or method, such as a call of a function value.
+func calledFunc(info *types.Info, call *ast.CallExpr) (*types.Func, bool) {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok {
+		return nil, false
+	}
+	return fn.Origin(), true
+}
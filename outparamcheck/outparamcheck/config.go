@@ -4,13 +4,165 @@
 
 package outparamcheck
 
-// Config stores a map from function name to the argument indices which are output parameters.
-type Config map[string][]int
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Config stores a map from function name to the output parameters of the function. Methods can be specified either as
+// "[receiver type].[method]" or as "([receiver type]).[method]", where the receiver type may be a pointer type (for
+// example, "(*github.com/palantir/example/config.Loader).Load"). Both forms match calls on pointer and non-pointer
+// receivers.
+type Config map[string]OutParams
+
+// OutParams specifies the output parameters of a function. In configuration, it is either a list of argument indices
+// (which is unmarshalled as Args) or an object with "args", "variadic-from", "maps" and "implementations" fields.
+type OutParams struct {
+	// Args are the indices of the arguments that must be pointers.
+	Args []int `yaml:"args,omitempty"`
+	// VariadicFrom is the index of the first of the trailing arguments that must all be pointers, which is used for
+	// functions that collect results into a variable number of destinations. If nil, there are no such arguments.
+	// Arguments passed using "..." are not checked.
+	VariadicFrom *int `yaml:"variadic-from,omitempty"`
+	// Maps are the indices of the arguments that are maps into which results are stored. Maps do not need to be
+	// passed as pointers, but must not be the nil literal.
+	Maps []int `yaml:"maps,omitempty"`
+	// Implementations specifies that the function is a method of an interface type and that calls of the method on
+	// any type that implements the interface (including other interface types) should be checked in addition to
+	// calls made through the interface type itself. The interface is only resolved in packages that import the
+	// package that declares it (directly or indirectly).
+	Implementations bool `yaml:"implementations,omitempty"`
+}
+
+// UnmarshalYAML unmarshals the output parameters from either a list of argument indices or an object with "args",
+// "variadic-from", "maps" and "implementations" fields.
+func (o *OutParams) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var args []int
+	if err := unmarshal(&args); err == nil {
+		*o = OutParams{Args: args}
+		return nil
+	}
+	type outParams OutParams
+	var out outParams
+	if err := unmarshal(&out); err != nil {
+		return errors.Errorf(`out-params must be a list of argument indices or an object with "args", "variadic-from", "maps" and "implementations" fields`)
+	}
+	*o = OutParams(out)
+	return nil
+}
+
+// MarshalYAML marshals the output parameters as a list of argument indices if only Args is specified and as an object
+// otherwise.
+func (o OutParams) MarshalYAML() (interface{}, error) {
+	if o.VariadicFrom == nil && len(o.Maps) == 0 && !o.Implementations {
+		return o.Args, nil
+	}
+	type outParams OutParams
+	return outParams(o), nil
+}
+
+// validate returns an error if any of the argument indices of the output parameters of the function with the provided
+// name are negative.
+func (o OutParams) validate(name string) error {
+	indices := append(append([]int{}, o.Args...), o.Maps...)
+	if o.VariadicFrom != nil {
+		indices = append(indices, *o.VariadicFrom)
+	}
+	for _, i := range indices {
+		if i < 0 {
+			return errors.Errorf("invalid argument index %d for %s: must be non-negative", i, name)
+		}
+	}
+	return nil
+}
+
+// pointerArgs returns the indices of the arguments of a call with the provided number of arguments that must be
+// pointers. If hasEllipsis is true, the last argument is passed using "..." and is not included in the variadic
+// arguments.
+func (o OutParams) pointerArgs(numArgs int, hasEllipsis bool) []int {
+	if o.VariadicFrom == nil {
+		return o.Args
+	}
+	if hasEllipsis {
+		numArgs--
+	}
+	out := append([]int{}, o.Args...)
+	for i := *o.VariadicFrom; i < numArgs; i++ {
+		out = append(out, i)
+	}
+	return out
+}
 
 var defaultCfg = Config(
-	map[string][]int{
-		"encoding/json.Unmarshal":     {1},
-		"encoding/safejson.Unmarshal": {1},
-		"gopkg.in/yaml.v2.Unmarshal":  {1},
+	map[string]OutParams{
+		"encoding/json.Unmarshal":     {Args: []int{1}},
+		"encoding/safejson.Unmarshal": {Args: []int{1}},
+		"gopkg.in/yaml.v2.Unmarshal":  {Args: []int{1}},
 	},
 )
+
+// matches a method key of the form "(*pkg.Type).Method" or "(pkg.Type).Method".
+var methodKeyRegexp = regexp.MustCompile(`^\(\*?([^()]+)\)\.([^.()]+)$`)
+
+// normalizeKey converts a configuration key for a method specified using the "([receiver type]).[method]" form into the
+// "[receiver type].[method]" form used for matching. Keys of any other form are returned unmodified.
+func normalizeKey(key string) string {
+	if match := methodKeyRegexp.FindStringSubmatch(key); match != nil {
+		return match[1] + "." + match[2]
+	}
+	return key
+}
+
+// effectiveConfig returns the configuration specified by the provided parameter merged with the default configuration.
+// The parameter is treated as literal YAML or JSON configuration unless it begins with '@', in which case the remainder
+// is treated as the path to a configuration file.
+func effectiveConfig(cfgParam string) (Config, error) {
+	cfg := Config{}
+	if cfgParam != "" {
+		var usrCfg Config
+		var err error
+		if strings.HasPrefix(cfgParam, "@") {
+			usrCfg, err = loadCfgFromPath(cfgParam[1:])
+		} else {
+			usrCfg, err = loadCfg(cfgParam)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to load configuration from parameter %s", cfgParam)
+		}
+		for key, val := range usrCfg {
+			cfg[normalizeKey(key)] = val
+		}
+	}
+	// add default config (values for default will override any user-supplied config for the same keys)
+	for key, val := range defaultCfg {
+		cfg[key] = val
+	}
+	return cfg, nil
+}
+
+func loadCfgFromPath(cfgPath string) (Config, error) {
+	cfgBytes, err := ioutil.ReadFile(cfgPath)
+	if err != nil {
+		return Config{}, errors.Wrapf(err, "failed to read file %s", cfgPath)
+	}
+	return loadCfg(string(cfgBytes))
+}
+
+// loadCfg loads the provided configuration. Because JSON is a subset of YAML, the configuration may be either JSON or
+// YAML.
+func loadCfg(cfgYML string) (Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(cfgYML), &cfg); err != nil {
+		return Config{}, errors.Wrapf(err, "failed to unmarshal configuration %s", cfgYML)
+	}
+	for key, outs := range cfg {
+		if err := outs.validate(key); err != nil {
+			return Config{}, err
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package outparamcheck
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	for i, currCase := range []struct {
+		name     string
+		cfgParam string
+		want     Config
+	}{
+		{
+			name: "empty configuration uses defaults",
+			want: defaultCfg,
+		},
+		{
+			name:     "JSON configuration",
+			cfgParam: `{"github.com/palantir/example/config.Load": [0]}`,
+			want: Config{
+				"encoding/json.Unmarshal":                 {Args: []int{1}},
+				"encoding/safejson.Unmarshal":             {Args: []int{1}},
+				"gopkg.in/yaml.v2.Unmarshal":              {Args: []int{1}},
+				"github.com/palantir/example/config.Load": {Args: []int{0}},
+			},
+		},
+		{
+			name: "YAML configuration with comments, method receivers and multiple out-params",
+			cfgParam: `
+# loads configuration into the provided value
+github.com/palantir/example/config.Load: [0]
+# both out-params of the method must be pointers
+"(*github.com/palantir/example/config.Loader).LoadAll": [1, 2]
+"(github.com/palantir/example/config.Loader).LoadOne": [0]
+`,
+			want: Config{
+				"encoding/json.Unmarshal":                           {Args: []int{1}},
+				"encoding/safejson.Unmarshal":                       {Args: []int{1}},
+				"gopkg.in/yaml.v2.Unmarshal":                        {Args: []int{1}},
+				"github.com/palantir/example/config.Load":           {Args: []int{0}},
+				"github.com/palantir/example/config.Loader.LoadAll": {Args: []int{1, 2}},
+				"github.com/palantir/example/config.Loader.LoadOne": {Args: []int{0}},
+			},
+		},
+		{
+			name: "YAML configuration with variadic and map out-params",
+			cfgParam: `
+"(*github.com/palantir/example/schema.Decoder).DecodeAll":
+  args: [0]
+  variadic-from: 2
+github.com/palantir/example/schema.DecodeMap:
+  maps: [1]
+`,
+			want: Config{
+				"encoding/json.Unmarshal":                              {Args: []int{1}},
+				"encoding/safejson.Unmarshal":                          {Args: []int{1}},
+				"gopkg.in/yaml.v2.Unmarshal":                           {Args: []int{1}},
+				"github.com/palantir/example/schema.Decoder.DecodeAll": {Args: []int{0}, VariadicFrom: intPtr(2)},
+				"github.com/palantir/example/schema.DecodeMap":         {Maps: []int{1}},
+			},
+		},
+		{
+			name: "YAML configuration for interface method that applies to implementations",
+			cfgParam: `
+"(github.com/palantir/example/codec.Decoder).UnmarshalInto":
+  args: [0]
+  implementations: true
+`,
+			want: Config{
+				"encoding/json.Unmarshal":                                 {Args: []int{1}},
+				"encoding/safejson.Unmarshal":                             {Args: []int{1}},
+				"gopkg.in/yaml.v2.Unmarshal":                              {Args: []int{1}},
+				"github.com/palantir/example/codec.Decoder.UnmarshalInto": {Args: []int{0}, Implementations: true},
+			},
+		},
+		{
+			name:     "default configuration overrides user configuration",
+			cfgParam: `{"encoding/json.Unmarshal": [0]}`,
+			want:     defaultCfg,
+		},
+	} {
+		got, err := effectiveConfig(currCase.cfgParam)
+		require.NoError(t, err, "Case %d: %s", i, currCase.name)
+		assert.Equal(t, currCase.want, got, "Case %d: %s", i, currCase.name)
+	}
+}
+
+func TestEffectiveConfigInvalidIndex(t *testing.T) {
+	_, err := effectiveConfig(`{"github.com/palantir/example/config.Load": [-1]}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid argument index -1 for github.com/palantir/example/config.Load")
+}
+
+func TestEffectiveConfigInvalid(t *testing.T) {
+	for i, currCase := range []struct {
+		cfgParam string
+		wantErr  string
+	}{
+		{`{"github.com/palantir/example/config.Load": {"variadic-from": -1}}`, "invalid argument index -1 for github.com/palantir/example/config.Load"},
+		{`{"github.com/palantir/example/config.Load": {"maps": [-2]}}`, "invalid argument index -2 for github.com/palantir/example/config.Load"},
+		{`{"github.com/palantir/example/config.Load": "0"}`, `out-params must be a list of argument indices or an object with "args", "variadic-from", "maps" and "implementations" fields`},
+	} {
+		_, err := effectiveConfig(currCase.cfgParam)
+		require.Error(t, err, "Case %d", i)
+		assert.Contains(t, err.Error(), currCase.wantErr, "Case %d", i)
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	var buf bytes.Buffer
+	err := PrintConfig(`{"(*github.com/palantir/example/config.Loader).Load": [0, 1]}`, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, `encoding/json.Unmarshal:
+- 1
+encoding/safejson.Unmarshal:
+- 1
+github.com/palantir/example/config.Loader.Load:
+- 0
+- 1
+gopkg.in/yaml.v2.Unmarshal:
+- 1
+`, buf.String())
+
+	buf.Reset()
+	err = PrintConfig(`{"github.com/palantir/example/schema.Decode": {"args": [0], "variadic-from": 2, "maps": [1]}}`, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `github.com/palantir/example/schema.Decode:
+  args:
+  - 0
+  variadic-from: 2
+  maps:
+  - 1
+`)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
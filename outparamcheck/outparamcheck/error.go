@@ -11,6 +11,8 @@ import (
 	"strings"
 
 	"github.com/dustin/go-humanize"
+
+	"github.com/palantir/checks/checks/diagnostic"
 )
 
 type OutParamError struct {
@@ -18,6 +20,11 @@ type OutParamError struct {
 	Line     string
 	Method   string
 	Argument int
+	// SuggestedFix is the replacement for the argument that fixes the error. Empty if the argument is not addressable
+	// and cannot be fixed by adding '&'.
+	SuggestedFix string
+	// NilMap is true if the argument is the nil literal provided for an output parameter that is a map.
+	NilMap bool
 }
 
 func (err OutParamError) Error() string {
@@ -34,8 +41,33 @@ func (err OutParamError) Error() string {
 	}
 	line = strings.TrimSpace(line)
 
+	return fmt.Sprintf("%s\t%s  // %s", pos, line, err.message())
+}
+
+// Diagnostic returns the diagnostic for the error.
+func (err OutParamError) Diagnostic() diagnostic.Diagnostic {
+	ruleID := "out-param"
+	if err.NilMap {
+		ruleID = "nil-map-out-param"
+	}
+	return diagnostic.New(err.Pos, "outparamcheck", ruleID, err.message())
+}
+
+// requirement returns the description of the requirement that the argument violates.
+func (err OutParamError) requirement() string {
 	ord := humanize.Ordinal(err.Argument + 1)
-	return fmt.Sprintf("%s\t%s  // %s argument of '%s' requires '&'", pos, line, ord, err.Method)
+	if err.NilMap {
+		return fmt.Sprintf("%s argument of '%s' must be a non-nil map", ord, err.Method)
+	}
+	return fmt.Sprintf("%s argument of '%s' requires '&'", ord, err.Method)
+}
+
+func (err OutParamError) message() string {
+	msg := err.requirement()
+	if err.SuggestedFix != "" {
+		msg += fmt.Sprintf(" (suggested fix: '%s')", err.SuggestedFix)
+	}
+	return msg
 }
 
 type byLocation []OutParamError
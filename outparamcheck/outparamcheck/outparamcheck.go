@@ -6,132 +6,208 @@
 package outparamcheck
 
 import (
-	"encoding/json"
 	"fmt"
 	"go/ast"
-	"go/build"
 	"go/token"
 	"go/types"
+	"io"
 	"io/ioutil"
+	"os"
 	"sort"
 	"strings"
-	"sync"
 
-	"github.com/kisielk/gotool"
 	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
 	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v2"
 
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
+	"github.com/palantir/checks/checks/vendorutil"
 	"github.com/palantir/checks/outparamcheck/exprs"
 )
 
-func Run(cfgParam string, paths []string) error {
-	cfg := Config{}
-	if cfgParam != "" {
-		var usrCfg Config
-		var err error
-		if strings.HasPrefix(cfgParam, "@") {
-			usrCfg, err = loadCfgFromPath(cfgParam[1:])
-		} else {
-			usrCfg, err = loadCfg(cfgParam)
-		}
-		if err != nil {
-			return errors.Wrapf(err, "Failed to load configuration from parameter %s", cfgParam)
-		}
-		for key, val := range usrCfg {
-			cfg[key] = val
-		}
+// Run checks the packages that match the provided patterns using the check implemented by Analyzer. Packages are
+// loaded using go/packages, so patterns such as "./..." and packages in modules are supported, the test variants of
+// the packages are checked and build tags can be specified using the GOFLAGS environment variable. If inferAnnotated is
+// true, the functions and methods in the checked packages that have parameters annotated as output parameters using
+// "//outparam:" comments are checked in addition to the configured functions. If fix is true, violations where the
+// argument is an addressable expression are fixed by rewriting the argument "x" as "&x" and only the violations that
+// cannot be fixed are reported. The violations are printed to standard out in the provided format: the "text" format
+// prints each violation along with the source line on which it occurs, while the other formats print the violations as
+// diagnostics. Violations that are suppressed by the baseline are not reported.
+func Run(cfgParam string, inferAnnotated, fix bool, format string, bl baseline.Options, paths []string) error {
+	if err := diagnostic.ValidateFormat(format); err != nil {
+		return err
 	}
-	// add default config (values for default will override any user-supplied config for the same keys)
-	for key, val := range defaultCfg {
-		cfg[key] = val
+	// verify configuration before loading packages
+	cfg, err := effectiveConfig(cfgParam)
+	if err != nil {
+		return err
 	}
 
-	prog, err := load(paths)
+	pkgs, err := load(paths)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	errs := run(prog, cfg)
+	results, err := analyze(pkgs, cfg, inferAnnotated)
+	if err != nil {
+		return err
+	}
+
+	var errs []OutParamError
+	var fixes []OutParamError
+	for _, currErr := range results {
+		if fix && currErr.SuggestedFix != "" {
+			fixes = append(fixes, currErr)
+			continue
+		}
+		errs = append(errs, currErr)
+	}
+	if err := applyFixes(fixes); err != nil {
+		return err
+	}
+	errs, err = filterErrors(errs, bl)
+	if err != nil {
+		return err
+	}
+	if err := reportErrors(errs, format, os.Stdout); err != nil {
+		return err
+	}
 	if len(errs) > 0 {
-		reportErrors(errs)
 		return fmt.Errorf("%s; the parameters listed above require the use of '&', for example f(&x) instead of f(x)",
 			plural(len(errs), "error", "errors"))
 	}
 	return nil
 }
 
-func run(prog *loader.Program, cfg Config) []OutParamError {
-	var errs []OutParamError
-	var mut sync.Mutex // guards errs
-	var wg sync.WaitGroup
-	for _, pkgInfo := range prog.InitialPackages() {
-		if pkgInfo.Pkg.Path() == "unsafe" { // not a real package
-			continue
-		}
-
-		wg.Add(1)
-
-		go func(pkgInfo *loader.PackageInfo) {
-			defer wg.Done()
-			v := &visitor{
-				prog:   prog,
-				pkg:    pkgInfo,
-				lines:  map[string][]string{},
-				errors: []OutParamError{},
-				cfg:    cfg,
-			}
-			for _, astFile := range pkgInfo.Files {
-				exprs.Walk(v, astFile)
-			}
-			mut.Lock()
-			defer mut.Unlock()
-			errs = append(errs, v.errors...)
-		}(pkgInfo)
+// PrintConfig prints the effective configuration for the provided configuration parameter as YAML. The effective
+// configuration is the user-supplied configuration merged with the default configuration.
+func PrintConfig(cfgParam string, w io.Writer) error {
+	cfg, err := effectiveConfig(cfgParam)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal configuration")
 	}
-	wg.Wait()
-	return errs
+	if _, err := w.Write(out); err != nil {
+		return errors.Wrapf(err, "failed to write configuration")
+	}
+	return nil
 }
 
-func loadCfgFromPath(cfgPath string) (Config, error) {
-	cfgBytes, err := ioutil.ReadFile(cfgPath)
+func load(paths []string) ([]*packages.Package, error) {
+	loadcfg := &packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(loadcfg, paths...)
 	if err != nil {
-		return Config{}, errors.Wrapf(err, "failed to read file %s", cfgPath)
+		return nil, errors.Wrapf(err, "failed to load packages")
+	}
+	var loadErrs []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			loadErrs = append(loadErrs, err.Error())
+		}
+	})
+	if len(loadErrs) > 0 {
+		return nil, errors.Errorf("failed to load packages:\n%s", strings.Join(loadErrs, "\n"))
 	}
-	return loadCfg(string(cfgBytes))
+	return pkgs, nil
 }
 
-func loadCfg(cfgJSON string) (Config, error) {
-	var cfg Config
-	if err := json.Unmarshal([]byte(cfgJSON), &cfg); err != nil {
-		return Config{}, errors.Wrapf(err, "failed to unmarshal json %s", cfgJSON)
+// analyze runs the check on the provided packages using an analyzer that is configured with the provided configuration
+// and returns the violations.
+func analyze(pkgs []*packages.Package, cfg Config, inferAnnotated bool) ([]OutParamError, error) {
+	graph, err := checker.Analyze([]*analysis.Analyzer{newConfiguredAnalyzer(cfg, inferAnnotated)}, pkgs, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run analysis")
 	}
-	return cfg, nil
-}
 
-func load(paths []string) (*loader.Program, error) {
-	loadcfg := loader.Config{
-		Build: &build.Default,
+	var errs []OutParamError
+	// files in packages with tests are analyzed as part of both the package and its test variant
+	seen := make(map[string]struct{})
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return nil, errors.Wrapf(act.Err, "failed to analyze package %s", act.Package.PkgPath)
+		}
+		for _, currErr := range act.Result.([]OutParamError) {
+			key := fmt.Sprintf("%s:%d", currErr.Pos, currErr.Argument)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			errs = append(errs, currErr)
+		}
 	}
-	includeTests := true
-	rest, err := loadcfg.FromArgs(gotool.ImportPaths(paths), includeTests)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not parse arguments")
+	return errs, nil
+}
+
+// run runs the check on the initial packages of the provided program using the provided configuration and returns the
+// violations. The packages of the program are loaded again using go/packages and checked in the same manner as Run.
+//
+// Deprecated: run is retained for compatibility with callers of the loader.Program-based API and will be removed in the
+// next release. Use Run instead.
+func run(prog *loader.Program, cfg Config) ([]OutParamError, error) {
+	var patterns []string
+	for _, pkgInfo := range prog.Imported {
+		patterns = append(patterns, pkgInfo.Pkg.Path())
 	}
-	if len(rest) > 0 {
-		return nil, errors.Errorf("unhandled extra arguments: %v", rest)
+	for _, pkgInfo := range prog.Created {
+		// packages created from files do not have an import path that go/packages can resolve
+		for _, file := range pkgInfo.Files {
+			patterns = append(patterns, "file="+prog.Fset.Position(file.Pos()).Filename)
+		}
 	}
-	prog, err := loadcfg.Load()
+	sort.Strings(patterns)
+	pkgs, err := load(patterns)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return prog, nil
+	return analyze(pkgs, cfg, false)
+}
+
+// runPass runs the check on the package of the provided pass. Each violation is reported as a diagnostic on the pass
+// and returned.
+func runPass(pass *analysis.Pass, cfg Config, inferAnnotated bool) ([]OutParamError, error) {
+	if inferAnnotated {
+		if err := exportAnnotatedFacts(pass); err != nil {
+			return nil, err
+		}
+	}
+	v := &visitor{
+		pass:           pass,
+		lines:          map[string][]string{},
+		errors:         []OutParamError{},
+		cfg:            cfg,
+		inferAnnotated: inferAnnotated,
+		checked:        map[ast.Expr]struct{}{},
+		interfaces:     map[string]*types.Interface{},
+	}
+	for _, astFile := range pass.Files {
+		exprs.Walk(v, astFile)
+	}
+	sort.Sort(byLocation(v.errors))
+	return v.errors, nil
 }
 
 type visitor struct {
-	prog   *loader.Program
-	pkg    *loader.PackageInfo
+	pass   *analysis.Pass
 	lines  map[string][]string
 	errors []OutParamError
 	cfg    Config
+	// if true, calls to functions annotated with "//outparam:" comments are checked
+	inferAnnotated bool
+	// arguments that have already been checked
+	checked map[ast.Expr]struct{}
+	// the interface types for the names of the interfaces of rules that apply to implementations (nil if the
+	// interface could not be resolved)
+	interfaces map[string]*types.Interface
 }
 
 func (v *visitor) Visit(expr ast.Expr) {
@@ -145,15 +221,119 @@ func (v *visitor) Visit(expr ast.Expr) {
 	}
 	for name, outs := range v.cfg {
 		// Suffix-matching so they also apply to vendored packages
-		if strings.HasSuffix(key, name) {
-			for _, i := range outs {
-				arg := call.Args[i]
-				if !isAddr(arg) {
-					v.errorAt(arg.Pos(), method, i)
-				}
+		if strings.HasSuffix(key, name) || (outs.Implementations && v.callsImplementation(call, name)) {
+			v.checkArgs(call, method, outs.pointerArgs(len(call.Args), call.Ellipsis.IsValid()))
+			v.checkMapArgs(call, method, outs.Maps)
+		}
+	}
+	if v.inferAnnotated {
+		if fn, ok := calledFunc(v.pass.TypesInfo, call); ok {
+			var fact outParamsFact
+			if v.pass.ImportObjectFact(fn, &fact) {
+				v.checkArgs(call, method, fact.Indices)
+			}
+		}
+	}
+}
+
+// callsImplementation returns true if the provided call is a call of the interface method specified by the provided
+// configuration key on a receiver whose type implements the interface.
+func (v *visitor) callsImplementation(call *ast.CallExpr, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	selection, ok := v.pass.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return false
+	}
+	dot := strings.LastIndex(name, ".")
+	if dot == -1 || name[dot+1:] != sel.Sel.Name {
+		return false
+	}
+	iface := v.lookupInterface(name[:dot])
+	if iface == nil {
+		return false
+	}
+	recv := selection.Recv()
+	return types.Implements(recv, iface) || types.Implements(types.NewPointer(recv), iface)
+}
+
+// lookupInterface returns the interface type with the provided qualified name (for example,
+// "github.com/palantir/example/codec.Decoder") if it is declared by the package being checked or any of its
+// dependencies. Returns nil if the type cannot be found or is not an interface.
+func (v *visitor) lookupInterface(typeName string) *types.Interface {
+	if iface, ok := v.interfaces[typeName]; ok {
+		return iface
+	}
+	var iface *types.Interface
+	if dot := strings.LastIndex(typeName, "."); dot != -1 {
+		if pkg := findPackage(v.pass.Pkg, typeName[:dot], map[*types.Package]struct{}{}); pkg != nil {
+			if obj, ok := pkg.Scope().Lookup(typeName[dot+1:]).(*types.TypeName); ok {
+				iface, _ = obj.Type().Underlying().(*types.Interface)
 			}
 		}
 	}
+	v.interfaces[typeName] = iface
+	return iface
+}
+
+// findPackage returns the package with the provided import path (ignoring any vendor prefix) from the provided package
+// and its transitive imports. Returns nil if no such package exists.
+func findPackage(pkg *types.Package, pkgPath string, visited map[*types.Package]struct{}) *types.Package {
+	if _, ok := visited[pkg]; ok {
+		return nil
+	}
+	visited[pkg] = struct{}{}
+	if vendorutil.StripVendorPrefix(pkg.Path()) == pkgPath {
+		return pkg
+	}
+	for _, imported := range pkg.Imports() {
+		if found := findPackage(imported, pkgPath, visited); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (v *visitor) checkArgs(call *ast.CallExpr, method string, outs []int) {
+	for _, i := range outs {
+		if i >= len(call.Args) {
+			continue
+		}
+		arg := call.Args[i]
+		if _, ok := v.checked[arg]; ok {
+			continue
+		}
+		v.checked[arg] = struct{}{}
+		if isAddr(arg) {
+			continue
+		}
+		suggestedFix := ""
+		if isAddressable(v.pass.TypesInfo, arg) {
+			suggestedFix = "&" + types.ExprString(arg)
+		}
+		v.errorAt(arg, method, i, suggestedFix, false)
+	}
+}
+
+// checkMapArgs checks that the arguments at the provided indices, which are maps into which results are stored, are
+// not the nil literal.
+func (v *visitor) checkMapArgs(call *ast.CallExpr, method string, maps []int) {
+	for _, i := range maps {
+		if i >= len(call.Args) {
+			continue
+		}
+		arg := call.Args[i]
+		if _, ok := v.checked[arg]; ok {
+			continue
+		}
+		v.checked[arg] = struct{}{}
+		if tv, ok := v.pass.TypesInfo.Types[arg]; !ok || !tv.IsNil() {
+			continue
+		}
+		v.errorAt(arg, method, i, "", true)
+	}
 }
 
 func (v *visitor) keyAndName(call *ast.CallExpr) (key string, name string, ok bool) {
@@ -161,26 +341,26 @@ func (v *visitor) keyAndName(call *ast.CallExpr) (key string, name string, ok bo
 	case *ast.Ident:
 		// Function calls without a selector; this includes calls within the
 		// same package as well as calls into dot-imported packages
-		if def, ok := v.pkg.Uses[target]; ok && def.Pkg() != nil {
+		if def, ok := v.pass.TypesInfo.Uses[target]; ok && def.Pkg() != nil {
 			return fmt.Sprintf("%v.%v", def.Pkg().Path(), target.Name), target.Name, true
 		}
 	case *ast.SelectorExpr:
 		// Function calls into other packages
 		if recv, ok := target.X.(*ast.Ident); ok {
-			if pkg, ok := v.pkg.Uses[recv].(*types.PkgName); ok {
+			if pkg, ok := v.pass.TypesInfo.Uses[recv].(*types.PkgName); ok {
 				return fmt.Sprintf("%v.%v", pkg.Imported().Path(), target.Sel.Name), target.Sel.Name, true
 			}
 		}
 		// Method calls
-		if typ, ok := v.pkg.Types[target.X]; ok {
+		if typ, ok := v.pass.TypesInfo.Types[target.X]; ok {
 			return fmt.Sprintf("%v.%v", typ.Type.String(), target.Sel.Name), target.Sel.Name, true
 		}
 	}
 	return "", "", false
 }
 
-func (v *visitor) errorAt(pos token.Pos, method string, argument int) {
-	position := v.prog.Fset.Position(pos)
+func (v *visitor) errorAt(arg ast.Expr, method string, argument int, suggestedFix string, nilMap bool) {
+	position := v.pass.Fset.Position(arg.Pos())
 	lines, ok := v.lines[position.Filename]
 	if !ok {
 		contents, err := ioutil.ReadFile(position.Filename)
@@ -195,7 +375,32 @@ func (v *visitor) errorAt(pos token.Pos, method string, argument int) {
 	if position.Line-1 < len(lines) {
 		line = strings.TrimSpace(lines[position.Line-1])
 	}
-	v.errors = append(v.errors, OutParamError{position, line, method, argument})
+	outParamErr := OutParamError{
+		Pos:          position,
+		Line:         line,
+		Method:       method,
+		Argument:     argument,
+		SuggestedFix: suggestedFix,
+		NilMap:       nilMap,
+	}
+	v.errors = append(v.errors, outParamErr)
+
+	diag := analysis.Diagnostic{
+		Pos:     arg.Pos(),
+		End:     arg.End(),
+		Message: outParamErr.requirement(),
+	}
+	if suggestedFix != "" {
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: fmt.Sprintf("Replace with '%s'", suggestedFix),
+			TextEdits: []analysis.TextEdit{{
+				Pos:     arg.Pos(),
+				End:     arg.Pos(),
+				NewText: []byte("&"),
+			}},
+		}}
+	}
+	v.pass.Report(diag)
 }
 
 func isAddr(expr ast.Expr) bool {
@@ -215,11 +420,122 @@ func isAddr(expr ast.Expr) bool {
 	}
 }
 
-func reportErrors(errs []OutParamError) {
+// isAddressable returns true if the provided expression is addressable, in which case the '&' operator can be applied
+// to it directly.
+func isAddressable(info *types.Info, expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return isAddressable(info, expr.X)
+	case *ast.Ident:
+		_, ok := info.Uses[expr].(*types.Var)
+		return ok
+	case *ast.SelectorExpr:
+		sel, ok := info.Selections[expr]
+		if !ok {
+			// qualified identifier
+			_, ok := info.Uses[expr.Sel].(*types.Var)
+			return ok
+		}
+		if sel.Kind() != types.FieldVal {
+			return false
+		}
+		// field selections through a pointer are always addressable
+		return sel.Indirect() || isAddressable(info, expr.X)
+	case *ast.IndexExpr:
+		tv, ok := info.Types[expr.X]
+		if !ok {
+			return false
+		}
+		switch typ := tv.Type.Underlying().(type) {
+		case *types.Slice:
+			return true
+		case *types.Array:
+			return isAddressable(info, expr.X)
+		case *types.Pointer:
+			_, ok := typ.Elem().Underlying().(*types.Array)
+			return ok
+		}
+		return false
+	case *ast.StarExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// applyFixes rewrites the arguments of the provided errors in place by adding '&' before them.
+func applyFixes(fixes []OutParamError) error {
+	offsets := make(map[string][]int)
+	for _, fix := range fixes {
+		offsets[fix.Pos.Filename] = append(offsets[fix.Pos.Filename], fix.Pos.Offset)
+	}
+	for filename, fileOffsets := range offsets {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return errors.Wrapf(err, "failed to stat %s", filename)
+		}
+		contents, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", filename)
+		}
+		// apply insertions from the end of the file so that earlier offsets remain valid
+		sort.Sort(sort.Reverse(sort.IntSlice(fileOffsets)))
+		for _, offset := range fileOffsets {
+			contents = append(contents[:offset], append([]byte("&"), contents[offset:]...)...)
+		}
+		if err := ioutil.WriteFile(filename, contents, info.Mode()); err != nil {
+			return errors.Wrapf(err, "failed to write %s", filename)
+		}
+	}
+	return nil
+}
+
+// filterErrors applies the provided baseline options to the provided errors. The files of the errors are recorded in
+// the baseline relative to the working directory.
+func filterErrors(errs []OutParamError, bl baseline.Options) ([]OutParamError, error) {
+	if bl == (baseline.Options{}) {
+		return errs, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get working directory")
+	}
+	var diags []diagnostic.Diagnostic
+	for _, err := range errs {
+		diags = append(diags, err.Diagnostic())
+	}
+	if bl.WritePath != "" {
+		if err := baseline.New(wd, diags).Write(bl.WritePath); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	b, err := baseline.Load(bl.Path, wd)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []OutParamError
+	for i, diag := range diags {
+		if !b.Suppress(diag) {
+			filtered = append(filtered, errs[i])
+		}
+	}
+	return filtered, nil
+}
+
+func reportErrors(errs []OutParamError, format string, w io.Writer) error {
 	sort.Sort(byLocation(errs))
+	if format == diagnostic.FormatText {
+		for _, err := range errs {
+			fmt.Fprintln(w, err)
+		}
+		return nil
+	}
+	var diags []diagnostic.Diagnostic
 	for _, err := range errs {
-		fmt.Println(err)
+		diags = append(diags, err.Diagnostic())
 	}
+	return diagnostic.Print(w, format, diags)
 }
 
 func plural(count int, singular, plural string) string {
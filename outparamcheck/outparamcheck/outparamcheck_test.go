@@ -6,6 +6,7 @@
 package outparamcheck
 
 import (
+	"bytes"
 	"go/ast"
 	"go/importer"
 	"go/parser"
@@ -13,111 +14,240 @@ import (
 	"go/types"
 	"io/ioutil"
 	"os"
+	"path"
 	"testing"
 
+	"github.com/nmiyake/pkg/dirs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis/analysistest"
 	"golang.org/x/tools/go/loader"
+
+	"github.com/palantir/checks/checks/baseline"
+	"github.com/palantir/checks/checks/diagnostic"
 )
 
-const prog = `
-package main
+func TestOutParamCheck(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
 
-import (
-	"encoding/json"
-)
+func TestOutParamCheckAnnotated(t *testing.T) {
+	defer setAnalyzerFlag(t, inferFlagName, "true")()
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "annotated/lib", "annotated/use")
+}
+
+func TestOutParamCheckSuggestedFixes(t *testing.T) {
+	defer setAnalyzerFlag(t, configFlagName, `{"fix.Fill": [0]}`)()
+	results := analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "fix")
+	require.Len(t, results, 1)
+
+	var suggestions []string
+	for _, err := range results[0].Result.([]OutParamError) {
+		suggestions = append(suggestions, err.SuggestedFix)
+	}
+	assert.Equal(t, []string{"&x", "&s.Field", "&arr[0]", "&(x)", "", ""}, suggestions)
+}
+
+func TestOutParamCheckFix(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
+	defer cleanup()
+	require.NoError(t, err)
+
+	fixFile := path.Join(tmpDir, "fix", "fix.go")
+	require.NoError(t, os.Mkdir(path.Dir(fixFile), 0755))
+	require.NoError(t, ioutil.WriteFile(fixFile, []byte(`package fix
+
+type S struct {
+	Field interface{}
+}
+
+func Fill(v interface{}) {}
+
+func get() interface{} { return nil }
 
 func main() {
-	j := []byte("...")
 	var x interface{}
-	json.Unmarshal(j, x)
-	json.Unmarshal(j, &x)
-	json.Unmarshal(j, *&x)
-	json.Unmarshal(j, nil)
+	var s S
+	var arr [1]interface{}
+	m := map[string]interface{}{}
+	Fill(x)
+	Fill(s.Field)
+	Fill(arr[0])
+	Fill((x))
+	Fill(get())
+	Fill(m["key"])
 }
-`
+`), 0644))
 
-func TestOutParamCheck(t *testing.T) {
-	// write program to temp file
-	tmpf, cleanup := writeTempFile(t, prog)
+	// addressable arguments are rewritten in place and only the remaining violations are reported
+	err = Run(`{"fix.Fill": [0]}`, false, true, diagnostic.FormatText, baseline.Options{}, []string{"./" + path.Dir(fixFile)})
+	require.Error(t, err)
+	assert.Equal(t, "2 errors; the parameters listed above require the use of '&', for example f(&x) instead of f(x)", err.Error())
+
+	got, err := ioutil.ReadFile(fixFile)
+	require.NoError(t, err)
+	assert.Equal(t, `package fix
+
+type S struct {
+	Field interface{}
+}
+
+func Fill(v interface{}) {}
+
+func get() interface{} { return nil }
+
+func main() {
+	var x interface{}
+	var s S
+	var arr [1]interface{}
+	m := map[string]interface{}{}
+	Fill(&x)
+	Fill(&s.Field)
+	Fill(&arr[0])
+	Fill(&(x))
+	Fill(get())
+	Fill(m["key"])
+}
+`, string(got))
+}
+
+func TestRunLoaderProgram(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir(".", "")
 	defer cleanup()
+	require.NoError(t, err)
 
-	// parse program
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, tmpf, prog, 0)
+	fixFile := path.Join(tmpDir, "fix", "fix.go")
+	require.NoError(t, os.Mkdir(path.Dir(fixFile), 0755))
+	require.NoError(t, ioutil.WriteFile(fixFile, []byte(`package fix
+
+func Fill(v interface{}) {}
+
+func main() {
+	var x interface{}
+	Fill(x)
+	Fill(&x)
+}
+`), 0644))
+
+	conf := loader.Config{}
+	conf.CreateFromFilenames("fix", fixFile)
+	prog, err := conf.Load()
 	require.NoError(t, err)
 
-	// type information will be populated by type checker
-	info := types.Info{
-		Types: map[ast.Expr]types.TypeAndValue{},
-		Uses:  map[*ast.Ident]types.Object{},
-	}
+	errs, err := run(prog, Config{"fix.Fill": {Args: []int{0}}})
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "Fill(x)", errs[0].Line)
+	assert.Equal(t, "&x", errs[0].SuggestedFix)
+}
+
+func TestOutParamCheckVariadicAndMaps(t *testing.T) {
+	defer setAnalyzerFlag(t, configFlagName, `{"collect.Decode": {"args": [0], "variadic-from": 2, "maps": [1]}}`)()
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "collect")
+}
+
+func TestOutParamCheckImplementations(t *testing.T) {
+	defer setAnalyzerFlag(t, configFlagName, `{"(iface/codec.Decoder).UnmarshalInto": {"args": [0], "implementations": true}}`)()
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "iface/codec", "iface/use")
+}
+
+func TestAnnotatedOutParamsUnknownParam(t *testing.T) {
+	src := `
+package main
 
-	// hypothetical package
-	packagePath := "github.com/palantir/checks/outparamcheck"
-	packageName := "main"
-	pkg := types.NewPackage(packagePath, packageName)
+//outparam:missing
+func Load(v interface{}) {}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "load.go", src, parser.ParseComments)
+	require.NoError(t, err)
 
-	// run type checker
+	info := &types.Info{
+		Defs: map[*ast.Ident]types.Object{},
+	}
 	cfg := &types.Config{
 		Importer: importer.For("gc", nil),
 	}
-	files := []*ast.File{file}
-	err = types.NewChecker(cfg, fset, pkg, &info).Files(files)
+	_, err = cfg.Check("github.com/palantir/checks/outparamcheck/annotated", fset, []*ast.File{file}, info)
 	require.NoError(t, err)
 
-	// assert type information filled in
-	assert.NotEqual(t, 0, len(info.Types))
-	assert.NotEqual(t, 0, len(info.Uses))
-
-	// run out-param checker
-	errs := run(&loader.Program{
-		Fset: fset,
-		Created: []*loader.PackageInfo{{
-			Pkg:   pkg,
-			Files: files,
-			Info:  info,
-		}},
-	}, defaultCfg)
-
-	// there should be one failure
-	expected := []OutParamError{
+	_, err = annotatedOutParams(fset, []*ast.File{file}, info)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Load does not have a parameter named missing")
+}
+
+// setAnalyzerFlag sets the flag with the provided name on Analyzer to the provided value and returns a function that
+// restores the default value of the flag.
+func setAnalyzerFlag(t *testing.T, name, value string) func() {
+	require.NoError(t, Analyzer.Flags.Set(name, value))
+	return func() {
+		require.NoError(t, Analyzer.Flags.Set(name, Analyzer.Flags.Lookup(name).DefValue))
+	}
+}
+
+func TestReportErrors(t *testing.T) {
+	errs := []OutParamError{
 		{
-			Pos: token.Position{
-				Filename: tmpf,
-				Offset:   116,
-				Line:     11,
-				Column:   20,
-			},
-			Line:     `json.Unmarshal(j, x)`,
+			Pos:          token.Position{Filename: "/go/src/foo/foo.go", Line: 12, Column: 24},
+			Line:         "json.Unmarshal(data, v) // comment",
+			Method:       "Unmarshal",
+			Argument:     1,
+			SuggestedFix: "&v",
+		},
+		{
+			Pos:      token.Position{Filename: "/go/src/foo/foo.go", Line: 5, Column: 19},
+			Line:     "yaml.Unmarshal(data, getV())",
 			Method:   "Unmarshal",
 			Argument: 1,
 		},
 	}
-	assert.Equal(t, expected, errs)
-}
-
-func writeTempFile(t *testing.T, contents string) (path string, cleanup func()) {
-	tmpf, err := ioutil.TempFile("", "")
-	require.NoError(t, err, "failed to create temp file")
 
-	cleanup = func() {
-		err = os.Remove(tmpf.Name())
-		assert.NoError(t, err, "failed to remove temp file")
-	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, reportErrors(errs, diagnostic.FormatText, buf))
+	assert.Equal(t, "foo/foo.go:5:19\tyaml.Unmarshal(data, getV())  // 2nd argument of 'Unmarshal' requires '&'\n"+
+		"foo/foo.go:12:24\tjson.Unmarshal(data, v)  // 2nd argument of 'Unmarshal' requires '&' (suggested fix: '&v')\n", buf.String())
+
+	buf = &bytes.Buffer{}
+	require.NoError(t, reportErrors(errs, diagnostic.FormatCheckstyle, buf))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0">
+    <file name="/go/src/foo/foo.go">
+        <error line="5" column="19" severity="error" message="2nd argument of &#39;Unmarshal&#39; requires &#39;&amp;&#39;" source="outparamcheck.out-param"></error>
+        <error line="12" column="24" severity="error" message="2nd argument of &#39;Unmarshal&#39; requires &#39;&amp;&#39; (suggested fix: &#39;&amp;v&#39;)" source="outparamcheck.out-param"></error>
+    </file>
+</checkstyle>
+`, buf.String())
+}
 
-	remove := true
+func TestFilterErrors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
 	defer func() {
-		err = tmpf.Close()
-		assert.NoError(t, err, "failed to close temp file")
-		if remove {
-			cleanup()
-		}
+		_ = os.RemoveAll(tmpDir)
 	}()
 
-	_, err = tmpf.WriteString(contents)
-	require.NoError(t, err, "failed to write temp file")
+	errs := []OutParamError{
+		{
+			Pos:      token.Position{Filename: "foo.go", Line: 5, Column: 19},
+			Method:   "Unmarshal",
+			Argument: 1,
+		},
+		{
+			Pos:      token.Position{Filename: "foo.go", Line: 12, Column: 24},
+			Method:   "Unmarshal",
+			Argument: 1,
+		},
+	}
+	baselineFile := path.Join(tmpDir, "baseline.json")
+	filtered, err := filterErrors(errs[:1], baseline.Options{WritePath: baselineFile})
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+
+	filtered, err = filterErrors(errs, baseline.Options{Path: baselineFile})
+	require.NoError(t, err)
+	assert.Equal(t, errs[1:], filtered)
 
-	remove = false
-	return tmpf.Name(), cleanup
+	filtered, err = filterErrors(errs, baseline.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, errs, filtered)
 }
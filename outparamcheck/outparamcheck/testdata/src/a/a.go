@@ -0,0 +1,18 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package a
+
+import (
+	"encoding/json"
+)
+
+func main() {
+	j := []byte("...")
+	var x interface{}
+	json.Unmarshal(j, x) // want "2nd argument of 'Unmarshal' requires '&'"
+	json.Unmarshal(j, &x)
+	json.Unmarshal(j, *&x)
+	json.Unmarshal(j, nil)
+}
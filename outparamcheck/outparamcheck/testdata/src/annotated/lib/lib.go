@@ -0,0 +1,22 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package lib
+
+type Loader struct{}
+
+// Load loads data into v.
+//
+//outparam:v
+func (l *Loader) Load(data string, v interface{}) {} // want Load:`outParams\(\[1\]\)`
+
+// Fill fills a and b.
+//
+//outparam:a,b
+func Fill(a, b interface{}) {} // want Fill:`outParams\(\[0 1\]\)`
+
+func local() {
+	var x interface{}
+	Fill(x, &x) // want "1st argument of 'Fill' requires '&'"
+}
@@ -0,0 +1,17 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package use
+
+import (
+	"annotated/lib"
+)
+
+func use() {
+	var x, y interface{}
+	l := &lib.Loader{}
+	l.Load("", x) // want "2nd argument of 'Load' requires '&'"
+	l.Load("", &x)
+	lib.Fill(&x, y) // want "2nd argument of 'Fill' requires '&'"
+}
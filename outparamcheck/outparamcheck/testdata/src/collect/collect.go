@@ -0,0 +1,22 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package collect
+
+func Decode(v interface{}, m map[string]interface{}, dests ...interface{}) {}
+
+func main() {
+	var x, y, z interface{}
+	m := map[string]interface{}{}
+	dests := []interface{}{&x, &y}
+	Decode(&x, m)
+	Decode(&x, m, &y, &z)
+	Decode(&x, m, dests...)
+	Decode(x, m)         // want "1st argument of 'Decode' requires '&'"
+	Decode(&x, nil)      // want "2nd argument of 'Decode' must be a non-nil map"
+	Decode(&x, (nil))    // want "2nd argument of 'Decode' must be a non-nil map"
+	Decode(&x, m, &y, z) // want "4th argument of 'Decode' requires '&'"
+	Decode(&x, m, &y, nil)
+	Decode(&x, m, y, z) // want "3rd argument of 'Decode' requires '&'" "4th argument of 'Decode' requires '&'"
+}
@@ -0,0 +1,26 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package fix
+
+type S struct {
+	Field interface{}
+}
+
+func Fill(v interface{}) {}
+
+func get() interface{} { return nil }
+
+func main() {
+	var x interface{}
+	var s S
+	var arr [1]interface{}
+	m := map[string]interface{}{}
+	Fill(x)        // want "1st argument of 'Fill' requires '&'"
+	Fill(s.Field)  // want "1st argument of 'Fill' requires '&'"
+	Fill(arr[0])   // want "1st argument of 'Fill' requires '&'"
+	Fill((x))      // want "1st argument of 'Fill' requires '&'"
+	Fill(get())    // want "1st argument of 'Fill' requires '&'"
+	Fill(m["key"]) // want "1st argument of 'Fill' requires '&'"
+}
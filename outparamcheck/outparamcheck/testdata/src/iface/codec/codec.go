@@ -0,0 +1,13 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package codec
+
+type Decoder interface {
+	UnmarshalInto(dst interface{}) error
+}
+
+func UnmarshalWith(d Decoder, dst interface{}) error {
+	return d.UnmarshalInto(dst) // want "1st argument of 'UnmarshalInto' requires '&'"
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 Palantir Technologies, Inc. All rights reserved.
+// Licensed under the MIT License. See LICENSE in the project root
+// for license information.
+
+package use
+
+import (
+	"iface/codec"
+)
+
+type valueDecoder struct{}
+
+func (valueDecoder) UnmarshalInto(dst interface{}) error { return nil }
+
+type ptrDecoder struct{}
+
+func (*ptrDecoder) UnmarshalInto(dst interface{}) error { return nil }
+
+type otherDecoder struct{}
+
+func (otherDecoder) UnmarshalInto(dst interface{}, n int) error { return nil }
+
+type readDecoder interface {
+	codec.Decoder
+	Read() error
+}
+
+func main() {
+	var x interface{}
+	var d codec.Decoder
+	d.UnmarshalInto(&x)
+	d.UnmarshalInto(x)              // want "1st argument of 'UnmarshalInto' requires '&'"
+	valueDecoder{}.UnmarshalInto(x) // want "1st argument of 'UnmarshalInto' requires '&'"
+	var p ptrDecoder
+	p.UnmarshalInto(x) // want "1st argument of 'UnmarshalInto' requires '&'"
+	var r readDecoder
+	r.UnmarshalInto(x) // want "1st argument of 'UnmarshalInto' requires '&'"
+	var anon interface {
+		UnmarshalInto(interface{}) error
+	}
+	anon.UnmarshalInto(x) // want "1st argument of 'UnmarshalInto' requires '&'"
+
+	// does not implement codec.Decoder
+	otherDecoder{}.UnmarshalInto(x, 0)
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/palantir/checks/ptimports/ptimports"
+)
+
+type ptimportsConfig struct {
+	// Groups specifies the order of the import groups. See ptimports.Options for the supported values.
+	Groups []string `yaml:"groups" json:"groups"`
+
+	// RemoveUnused specifies whether unused imports should be removed and missing imports added. Defaults to true.
+	RemoveUnused *bool `yaml:"remove-unused" json:"remove-unused"`
+
+	// MergeDuplicates specifies whether imports of the same path with different names should be merged.
+	MergeDuplicates bool `yaml:"merge-duplicates" json:"merge-duplicates"`
+
+	// RequireBlankImportComments specifies whether blank imports that do not have a comment should be reported.
+	RequireBlankImportComments bool `yaml:"require-blank-import-comments" json:"require-blank-import-comments"`
+
+	// RelativeImports specifies how relative imports are handled: "report" or "rewrite". If empty, relative imports
+	// are left unmodified.
+	RelativeImports string `yaml:"relative-imports" json:"relative-imports"`
+
+	// SingleImportBlock specifies whether files that declare their imports in multiple import declarations should be
+	// reported.
+	SingleImportBlock bool `yaml:"single-import-block" json:"single-import-block"`
+
+	// SkipDirs specifies the names of the directories that are skipped when directories are processed recursively. If
+	// nil, the default directories are skipped (see defaultSkipDirs).
+	SkipDirs []string `yaml:"skip-dirs" json:"skip-dirs"`
+}
+
+// loadOptions returns the options and the names of the directories to skip specified by the YAML configuration file at
+// the provided path. Returns the default options and nil directories if the path is empty.
+func loadOptions(configPath string) (ptimports.Options, []string, error) {
+	var cfg ptimportsConfig
+	if configPath != "" {
+		yml, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			return ptimports.Options{}, nil, errors.Wrapf(err, "failed to read file %s", configPath)
+		}
+		if err := yaml.Unmarshal(yml, &cfg); err != nil {
+			return ptimports.Options{}, nil, errors.Wrapf(err, "failed to unmarshal YML %s", string(yml))
+		}
+	}
+	return ptimports.Options{
+		Groups:                     cfg.Groups,
+		RemoveUnused:               cfg.RemoveUnused == nil || *cfg.RemoveUnused,
+		MergeDuplicates:            cfg.MergeDuplicates,
+		RequireBlankImportComments: cfg.RequireBlankImportComments,
+		RelativeImports:            cfg.RelativeImports,
+		SingleImportBlock:          cfg.SingleImportBlock,
+	}, cfg.SkipDirs, nil
+}
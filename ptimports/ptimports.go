@@ -24,30 +24,68 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"go/build"
 	"go/scanner"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
 
 	"github.com/palantir/checks/ptimports/ptimports"
 )
 
 var (
-	exitCode = 0
-	list     = flag.Bool("l", false, "list files whose formatting differs from ptimport's")
-	write    = flag.Bool("w", false, "Do not print reformatted sources to standard output. If a file's formatting is different from ptimports's, overwrite it with ptimports's version.")
+	exitCode        = 0
+	list            = flag.Bool("l", false, "list files whose formatting differs from ptimport's")
+	write           = flag.Bool("w", false, "Do not print reformatted sources to standard output. If a file's formatting is different from ptimports's, overwrite it with ptimports's version.")
+	doDiff          = flag.Bool("d", false, "display diffs instead of rewriting files")
+	check           = flag.Bool("c", false, "list files whose formatting differs from ptimports's without rewriting them and exit with a non-zero exit code if there are any")
+	groups          = flag.String("groups", "", "comma-separated order of import groups. Each group is \"std\", \"external\", \"local\" or an import path prefix. Overrides the groups in the configuration file.")
+	removeUnused    = flag.Bool("remove-unused", true, "remove unused imports and add missing imports. Overrides the value in the configuration file.")
+	mergeDuplicates = flag.Bool("merge-duplicates", false, "merge imports of the same path with different names. Overrides the value in the configuration file.")
+	requireComments = flag.Bool("require-blank-import-comments", false, "report blank imports that do not have a comment explaining why they are needed. Overrides the value in the configuration file.")
+	singleImport    = flag.Bool("single-import-block", false, "report files that declare their imports in multiple import declarations (declarations that import \"C\" are exempt). With -w, the declarations are consolidated instead. Overrides the value in the configuration file.")
+	relativeImports = flag.String("relative-imports", "", "handling of relative imports: \"report\" reports them and \"rewrite\" rewrites them to absolute import paths. Overrides the value in the configuration file.")
+	assumeFilename  = flag.String("assume-filename", "", "name of the file used to determine the import groups and to report errors when the source is read from standard input")
+	skipDirsFlag    = flag.String("skip-dirs", "", "comma-separated names of the directories to skip when processing directories recursively (default \"Godeps,testdata,vendor\"). Hidden directories are always skipped. Overrides the value in the configuration file.")
+	parallelism     = flag.Int("parallelism", runtime.NumCPU(), "maximum number of files to process concurrently")
+	config          = flag.String("config", "", "path to a YAML configuration file that specifies the options for processing imports")
+
+	options  ptimports.Options
+	skipDirs []string
+
+	// number of files that were processed and changed when processing directories recursively
+	nRecursiveFiles   int
+	nRecursiveChanged int
+	recursiveMode     bool
+	// true if the formatting of any of the processed files differs from ptimports's
+	anyChanged bool
 )
 
+func init() {
+	flag.BoolVar(check, "check", false, "same as -c")
+}
+
+// defaultSkipDirs are the names of the directories that are skipped when processing directories recursively if the
+// directories are not configured.
+var defaultSkipDirs = []string{"Godeps", "testdata", "vendor"}
+
 func report(err error) {
 	scanner.PrintError(os.Stderr, err)
 	exitCode = 2
 }
 
+// stdinFilename is the name used for source read from standard input if -assume-filename is not specified.
+const stdinFilename = "<standard input>"
+
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: ptimports [flags] [path...]\n")
+	fmt.Fprintf(os.Stderr, "usage: ptimports [flags] [path|package...|-]\n")
 	flag.PrintDefaults()
 	os.Exit(2)
 }
@@ -58,11 +96,13 @@ func isGoFile(f os.FileInfo) bool {
 	return !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
 }
 
-func processFile(filename string, in io.Reader) error {
+// processFile processes the file with the provided name (reading its source from in if it is non-nil) and writes the
+// output determined by the flags to out. Returns true if the formatting of the file changed.
+func processFile(filename string, in io.Reader, out io.Writer) (bool, error) {
 	if in == nil {
 		f, err := os.Open(filename)
 		if err != nil {
-			return err
+			return false, err
 		}
 		defer func() {
 			_ = f.Close()
@@ -72,48 +112,142 @@ func processFile(filename string, in io.Reader) error {
 
 	src, err := ioutil.ReadAll(in)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	res, err := ptimports.Process(filename, src)
+	opts := options
+	if *write {
+		// files that are written are rewritten to declare their imports in a single import declaration
+		opts.SingleImportBlock = false
+	}
+	res, err := ptimports.ProcessWithOptions(filename, src, opts)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	if *list {
-		if !bytes.Equal(src, res) {
-			fmt.Println(filename)
+	changed := !bytes.Equal(src, res)
+	if changed {
+		if *list || *check {
+			fmt.Fprintln(out, filename)
+		}
+		if *write {
+			if err := ioutil.WriteFile(filename, res, 0); err != nil {
+				return false, err
+			}
+		}
+		if *doDiff {
+			data, err := diff(src, res, filename)
+			if err != nil {
+				return false, fmt.Errorf("computing diff: %s", err)
+			}
+			fmt.Fprintf(out, "diff -u %s %s\n", filepath.ToSlash(filename+".orig"), filepath.ToSlash(filename))
+			_, _ = out.Write(data)
 		}
-		return nil
 	}
 
-	if *write {
-		// only write when file changed
-		if !bytes.Equal(src, res) {
-			return ioutil.WriteFile(filename, res, 0)
-		}
-	} else {
+	if !*list && !*write && !*doDiff && !*check {
 		// print regardless of whether they are equal
-		fmt.Print(string(res))
+		fmt.Fprint(out, string(res))
 	}
-	return nil
+	return changed, nil
 }
 
-func visitFile(path string, f os.FileInfo, err error) error {
-	if err == nil && isGoFile(f) {
-		err = processFile(path, nil)
+// processFiles processes the provided files using at most -parallelism concurrent workers. The output of the files is
+// written to standard output and errors are reported in the order of the files, so the output is the same as if the
+// files were processed sequentially. Returns the number of files that were processed successfully and the number of
+// those files whose formatting changed.
+func processFiles(files []string) (int, int) {
+	type result struct {
+		out     bytes.Buffer
+		changed bool
+		err     error
+		done    chan struct{}
 	}
-	if err != nil {
-		report(err)
+	results := make([]*result, len(files))
+	for i := range results {
+		results[i] = &result{
+			done: make(chan struct{}),
+		}
 	}
-	if f.IsDir() && shouldSkipDir(f.Name()) {
-		return filepath.SkipDir
+
+	workers := *parallelism
+	if workers < 1 {
+		workers = 1
 	}
-	return nil
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := range files {
+			indices <- i
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				r := results[idx]
+				r.changed, r.err = processFile(files[idx], nil, &r.out)
+				close(r.done)
+			}
+		}()
+	}
+
+	nProcessed, nChanged := 0, 0
+	for i, r := range results {
+		<-r.done
+		_, _ = os.Stdout.Write(r.out.Bytes())
+		// release the output of the file since all of the results are retained until every file has been processed
+		results[i] = nil
+		if r.err != nil {
+			report(r.err)
+			continue
+		}
+		nProcessed++
+		if r.changed {
+			nChanged++
+			anyChanged = true
+		}
+	}
+	wg.Wait()
+	return nProcessed, nChanged
+}
+
+// goFilesInDir returns the Go files in the provided directory and its subdirectories in lexical order. Directories that
+// are hidden or whose names are in skipDirs are skipped (the provided directory itself is never skipped). Directories
+// that cannot be read are reported and skipped.
+func goFilesInDir(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			report(err)
+			return nil
+		}
+		if f.IsDir() {
+			if path != root && shouldSkipDir(f.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isGoFile(f) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
 }
 
 func shouldSkipDir(name string) bool {
-	return name == "Godeps" || name == "vendor"
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, skipDir := range skipDirs {
+		if name == skipDir {
+			return true
+		}
+	}
+	return false
 }
 
 func main() {
@@ -135,18 +269,213 @@ func gofmtMain() {
 		usage()
 	}
 
+	var err error
+	options, skipDirs, err = loadOptions(*config)
+	if err != nil {
+		report(err)
+		return
+	}
+	if skipDirs == nil {
+		skipDirs = defaultSkipDirs
+	}
+	// flags that are set explicitly override the configuration
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "groups":
+			options.Groups = strings.Split(*groups, ",")
+		case "remove-unused":
+			options.RemoveUnused = *removeUnused
+		case "merge-duplicates":
+			options.MergeDuplicates = *mergeDuplicates
+		case "require-blank-import-comments":
+			options.RequireBlankImportComments = *requireComments
+		case "relative-imports":
+			options.RelativeImports = *relativeImports
+		case "single-import-block":
+			options.SingleImportBlock = *singleImport
+		case "skip-dirs":
+			skipDirs = nil
+			for _, dir := range strings.Split(*skipDirsFlag, ",") {
+				if dir != "" {
+					skipDirs = append(skipDirs, dir)
+				}
+			}
+		}
+	})
+	if err := options.Validate(); err != nil {
+		report(errors.Wrapf(err, "invalid options"))
+		return
+	}
+	if *check && *write {
+		report(errors.New("cannot use -c with -w"))
+		return
+	}
+
 	for _, path := range paths {
-		switch dir, err := os.Stat(path); {
-		case err != nil:
+		if path == "-" {
+			processStdin()
+			continue
+		}
+		processPath(path)
+	}
+	if recursiveMode && *write {
+		fmt.Fprintf(os.Stderr, "ptimports: %d of %d files changed\n", nRecursiveChanged, nRecursiveFiles)
+	}
+	if *check && anyChanged && exitCode == 0 {
+		exitCode = 1
+	}
+}
+
+// processStdin processes the source read from standard input and writes the result to standard output. The file name
+// specified by -assume-filename is used to determine the repository of the source for grouping imports.
+func processStdin() {
+	if *write {
+		report(errors.New("cannot use -w with standard input"))
+		return
+	}
+	filename := *assumeFilename
+	if filename == "" {
+		filename = stdinFilename
+	}
+	changed, err := processFile(filename, os.Stdin, os.Stdout)
+	if err != nil {
+		report(err)
+		return
+	}
+	if changed {
+		anyChanged = true
+	}
+}
+
+// processPath processes the provided argument. If the argument is the path to a file, the file is processed; if it is
+// the path to a directory, all of the Go files in the directory and its subdirectories are processed. Otherwise, the
+// argument is treated as a package: an import path or relative path followed by "/..." processes all of the Go files
+// in the package directory and its subdirectories, while any other import path processes the Go files in the package
+// directory. Directories are processed recursively as described by goFilesInDir and the files are processed
+// concurrently.
+func processPath(path string) {
+	recursive := false
+	if dir, err := os.Stat(path); err == nil {
+		if !dir.IsDir() {
+			processFiles([]string{path})
+			return
+		}
+		recursive = true
+	} else if strings.HasSuffix(path, "/...") {
+		path = strings.TrimSuffix(path, "/...")
+		recursive = true
+	}
+
+	dir, err := pkgDir(path)
+	if err != nil {
+		report(err)
+		return
+	}
+	if recursive {
+		files, err := goFilesInDir(dir)
+		if err != nil {
 			report(err)
-		case dir.IsDir():
-			if err := filepath.Walk(path, visitFile); err != nil {
-				report(err)
-			}
-		default:
-			if err := processFile(path, nil); err != nil {
-				report(err)
-			}
+			return
 		}
+		nProcessed, nChanged := processFiles(files)
+		recursiveMode = true
+		nRecursiveFiles += nProcessed
+		nRecursiveChanged += nChanged
+		return
+	}
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		report(err)
+		return
+	}
+	var files []string
+	for _, fileInfo := range fileInfos {
+		if isGoFile(fileInfo) {
+			files = append(files, filepath.Join(dir, fileInfo.Name()))
+		}
+	}
+	processFiles(files)
+}
+
+// pkgDir returns the directory for the provided path. If the path is an existing directory, it is returned unmodified.
+// Otherwise, the path is treated as an import path and the directory of the package is returned.
+func pkgDir(path string) (string, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return path, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	pkg, err := build.Import(path, wd, build.FindOnly)
+	if err != nil {
+		return "", fmt.Errorf("failed to find package %s: %v", path, err)
+	}
+	return pkg.Dir, nil
+}
+
+// diff returns the unified diff between b1 and b2 computed using the "diff" command. The diff is labeled using the
+// provided filename.
+func diff(b1, b2 []byte, filename string) (data []byte, err error) {
+	f1, err := writeTempFile("", "ptimports", b1)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.Remove(f1)
+	}()
+
+	f2, err := writeTempFile("", "ptimports", b2)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.Remove(f2)
+	}()
+
+	data, err = exec.Command("diff", "-u", f1, f2).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files don't match.
+		// Ignore that failure as long as we get output.
+		return replaceTempFilename(data, filename)
+	}
+	return data, err
+}
+
+func writeTempFile(dir, prefix string, data []byte) (string, error) {
+	file, err := ioutil.TempFile(dir, prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// replaceTempFilename replaces the names of the temporary files in the header of the provided diff with the provided
+// filename ("filename.orig" for the original file), preserving the timestamps.
+func replaceTempFilename(diff []byte, filename string) ([]byte, error) {
+	bs := bytes.SplitN(diff, []byte{'\n'}, 3)
+	if len(bs) < 3 {
+		return nil, fmt.Errorf("got unexpected diff for %s", filename)
+	}
+	// Preserve timestamps.
+	var t0, t1 []byte
+	if i := bytes.LastIndexByte(bs[0], '\t'); i != -1 {
+		t0 = bs[0][i:]
+	}
+	if i := bytes.LastIndexByte(bs[1], '\t'); i != -1 {
+		t1 = bs[1][i:]
 	}
+	// Always print filepath with slash separator.
+	f := filepath.ToSlash(filename)
+	bs[0] = []byte(fmt.Sprintf("--- %s%s", f+".orig", t0))
+	bs[1] = []byte(fmt.Sprintf("+++ %s%s", f, t1))
+	return bytes.Join(bs, []byte{'\n'}), nil
 }
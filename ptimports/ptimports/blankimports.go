@@ -0,0 +1,73 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptimports
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// UncommentedBlankImport is a blank import that does not have a comment.
+type UncommentedBlankImport struct {
+	Pos  token.Position
+	Path string
+}
+
+func (i UncommentedBlankImport) String() string {
+	return fmt.Sprintf("%v: blank import of %s must have a comment explaining why it is needed", i.Pos, i.Path)
+}
+
+// BlankImportsError is the error returned by ProcessWithOptions when RequireBlankImportComments is true and the file
+// contains blank imports that do not have a comment.
+type BlankImportsError struct {
+	Imports []UncommentedBlankImport
+}
+
+func (e *BlankImportsError) Error() string {
+	lines := make([]string, len(e.Imports))
+	for i, imp := range e.Imports {
+		lines[i] = imp.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// uncommentedBlankImports returns the blank imports in the provided file that do not have a doc comment or a trailing
+// comment. The doc comment of an import declaration without parentheses is considered to be the doc comment of its
+// import.
+func uncommentedBlankImports(fset *token.FileSet, f *ast.File) []UncommentedBlankImport {
+	var uncommented []UncommentedBlankImport
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range d.Specs {
+			impSpec := spec.(*ast.ImportSpec)
+			if impSpec.Name == nil || impSpec.Name.Name != "_" {
+				continue
+			}
+			if impSpec.Doc != nil || impSpec.Comment != nil || (!d.Lparen.IsValid() && d.Doc != nil) {
+				continue
+			}
+			uncommented = append(uncommented, UncommentedBlankImport{
+				Pos:  fset.Position(impSpec.Pos()),
+				Path: impSpec.Path.Value,
+			})
+		}
+	}
+	return uncommented
+}
@@ -16,20 +16,32 @@ package ptimports
 
 import (
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
 	"strings"
 
 	"github.com/palantir/pkg/pkgpath"
+	"golang.org/x/mod/modfile"
+
+	"github.com/palantir/checks/checks/vendorutil"
 )
 
+// repoForFile returns the import path prefix (with a trailing "/") of the repository that contains the provided file.
+// If the file is in a Go module, the path of the module is used. Otherwise, the first 3 segments of the path of the
+// file relative to $GOPATH/src are used. The path of a file in a vendor directory is relative to the vendor directory,
+// so vendored files are grouped relative to the repository from which they were vendored.
 func repoForFile(filename string) (string, error) {
 	abs, err := filepath.Abs(filename)
 	if err != nil {
 		return "", err
 	}
+	if modulePath, _, ok := moduleForDir(filepath.Dir(abs)); ok {
+		// append trailing / to prevent matches on repos with superstring names
+		return modulePath + "/", nil
+	}
 	relative := abs
 	if goPathSrcRel, err := pkgpath.NewAbsPkgPath(abs).GoPathSrcRel(); err == nil {
-		relative = goPathSrcRel
+		relative = vendorutil.StripVendorPrefix(goPathSrcRel)
 	}
 	segments := strings.Split(relative, "/")
 	if len(segments) < 3 {
@@ -39,29 +51,108 @@ func repoForFile(filename string) (string, error) {
 	return filepath.Join(segments[:3]...) + "/", nil
 }
 
+// moduleForDir returns the module path declared in the go.mod file in the provided directory or the closest of its
+// parent directories that contains a go.mod file along with the directory that contains the go.mod file. Returns false
+// if no such file exists or if it does not declare a module path.
+func moduleForDir(dir string) (string, string, bool) {
+	for {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			modulePath := modfile.ModulePath(data)
+			return modulePath, dir, modulePath != ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
 type importGrouper interface {
 	importGroup(importPath string) int
 }
 
-func newVendoredGrouper(repoPath string) importGrouper {
-	return vendoredGrouper{repoPath}
+const (
+	// StdLibGroup is the name of the group that contains imports of standard library packages.
+	StdLibGroup = "std"
+	// ExternalGroup is the name of the group that contains the imports that do not belong to any other group.
+	ExternalGroup = "external"
+	// LocalGroup is the name of the group that contains imports of packages in the same repository as the file.
+	LocalGroup = "local"
+)
+
+// DefaultGroups returns the default import groups: standard library imports, followed by external imports, followed by
+// project-local imports.
+func DefaultGroups() []string {
+	return []string{StdLibGroup, ExternalGroup, LocalGroup}
+}
+
+// newGrouper returns a grouper for files in the repository with the provided path that orders import groups as
+// specified by groups. If groups is empty, DefaultGroups is used.
+func newGrouper(repoPath string, groups []string) (importGrouper, error) {
+	if len(groups) == 0 {
+		groups = DefaultGroups()
+	}
+	grouper := vendoredGrouper{
+		repoPath: repoPath,
+		prefixes: make(map[string]int),
+	}
+	builtins := map[string]*int{
+		StdLibGroup:   &grouper.stdLib,
+		ExternalGroup: &grouper.external,
+		LocalGroup:    &grouper.local,
+	}
+	seen := make(map[string]struct{})
+	for i, group := range groups {
+		if group == "" {
+			return nil, fmt.Errorf("import group %d is empty", i)
+		}
+		if _, ok := seen[group]; ok {
+			return nil, fmt.Errorf("import group %q specified more than once", group)
+		}
+		seen[group] = struct{}{}
+		if idx, ok := builtins[group]; ok {
+			*idx = i
+			continue
+		}
+		grouper.prefixes[group] = i
+	}
+	for _, builtin := range DefaultGroups() {
+		if _, ok := seen[builtin]; !ok {
+			return nil, fmt.Errorf("import groups must include %q: %v", builtin, groups)
+		}
+	}
+	return grouper, nil
 }
 
-// vendoredGrouper groups packages by standard library, vendored, an in-repo
-// packages.
 type vendoredGrouper struct {
 	repoPath string
+	stdLib   int
+	external int
+	local    int
+	// key is the import path prefix, value is the index of its group
+	prefixes map[string]int
 }
 
+// importGroup returns the index of the group of the provided import path. Import paths that include the path of a
+// vendor directory are grouped based on the path of the vendored package.
 func (g vendoredGrouper) importGroup(importPath string) int {
+	importPath = vendorutil.StripVendorPrefix(importPath)
 	switch {
 	case inStandardLibrary(importPath):
-		return 0
-	case !g.inThisRepo(importPath):
-		return 1
-	default:
-		return 2
+		return g.stdLib
+	case g.inThisRepo(importPath):
+		return g.local
+	}
+	group := g.external
+	longest := -1
+	for prefix, idx := range g.prefixes {
+		if strings.HasPrefix(importPath, prefix) && len(prefix) > longest {
+			group = idx
+			longest = len(prefix)
+		}
 	}
+	return group
 }
 
 func (g vendoredGrouper) inThisRepo(importPath string) bool {
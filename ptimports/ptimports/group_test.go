@@ -15,13 +15,20 @@
 package ptimports
 
 import (
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 
+	"github.com/nmiyake/pkg/dirs"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVendorGrouper(t *testing.T) {
-	grouper := newVendoredGrouper("github.com/palantir/checks/")
+	grouper, err := newGrouper("github.com/palantir/checks/", nil)
+	require.NoError(t, err)
 
 	for i, currCase := range []struct {
 		path  string
@@ -33,7 +40,108 @@ func TestVendorGrouper(t *testing.T) {
 		{path: "github.com/palantir/pkg/pkgpath", group: 1},
 		{path: "github.com/palantir/checks", group: 2},
 		{path: "github.com/palantir/checks/ptimports", group: 2},
+		{path: "github.com/palantir/checks/vendor/github.com/palantir/pkg/pkgpath", group: 1},
+		{path: "github.com/org/project/vendor/github.com/palantir/checks/ptimports", group: 2},
 	} {
 		assert.Equal(t, currCase.group, grouper.importGroup(currCase.path), "Case %d: %s", i, currCase.path)
 	}
 }
+
+func TestConfiguredGrouper(t *testing.T) {
+	grouper, err := newGrouper("github.com/palantir/checks/", []string{
+		StdLibGroup,
+		ExternalGroup,
+		"github.com/palantir/",
+		"github.com/palantir/pkg/cli",
+		LocalGroup,
+	})
+	require.NoError(t, err)
+
+	for i, currCase := range []struct {
+		path  string
+		group int
+	}{
+		{path: "strings", group: 0},
+		{path: "github.com/stretchr/testify/assert", group: 1},
+		{path: "github.com/palantir/pkg/pkgpath", group: 2},
+		{path: "github.com/palantir/pkg/cli/flag", group: 3},
+		{path: "github.com/palantir/checks/vendor/github.com/palantir/pkg/cli/flag", group: 3},
+		{path: "github.com/palantir/checks/ptimports", group: 4},
+	} {
+		assert.Equal(t, currCase.group, grouper.importGroup(currCase.path), "Case %d: %s", i, currCase.path)
+	}
+}
+
+func TestInvalidGroups(t *testing.T) {
+	for i, currCase := range []struct {
+		groups  []string
+		wantErr string
+	}{
+		{
+			groups:  []string{StdLibGroup, LocalGroup},
+			wantErr: `import groups must include "external": [std local]`,
+		},
+		{
+			groups:  []string{StdLibGroup, ExternalGroup, LocalGroup, ExternalGroup},
+			wantErr: `import group "external" specified more than once`,
+		},
+		{
+			groups:  []string{StdLibGroup, "", ExternalGroup, LocalGroup},
+			wantErr: `import group 1 is empty`,
+		},
+	} {
+		_, err := newGrouper("github.com/palantir/checks/", currCase.groups)
+		assert.EqualError(t, err, currCase.wantErr, "Case %d", i)
+	}
+}
+
+func TestRepoForFile(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	for i, currCase := range []struct {
+		files    map[string]string
+		filename string
+		want     string
+	}{
+		{
+			files: map[string]string{
+				"go.mod":         "module github.com/org/project\n",
+				"foo/bar/bar.go": "package bar\n",
+			},
+			filename: "foo/bar/bar.go",
+			want:     "github.com/org/project/",
+		},
+		{
+			files: map[string]string{
+				"go.mod":     "module github.com/org/project\n",
+				"sub/go.mod": "// nested module\nmodule github.com/org/project/sub\n",
+				"sub/sub.go": "package sub\n",
+			},
+			filename: "sub/sub.go",
+			want:     "github.com/org/project/sub/",
+		},
+	} {
+		projectDir, err := ioutil.TempDir(tmpDir, "")
+		require.NoError(t, err)
+		for currPath, currContent := range currCase.files {
+			require.NoError(t, os.MkdirAll(path.Join(projectDir, path.Dir(currPath)), 0755), "Case %d", i)
+			require.NoError(t, ioutil.WriteFile(path.Join(projectDir, currPath), []byte(currContent), 0644), "Case %d", i)
+		}
+
+		got, err := repoForFile(path.Join(projectDir, currCase.filename))
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, got, "Case %d", i)
+	}
+
+	// outside of a module, the path relative to $GOPATH/src is used
+	got, err := repoForFile("group.go")
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/palantir/checks/", got)
+
+	// vendored files are relative to the vendor directory
+	got, err = repoForFile("../../vendor/github.com/palantir/pkg/pkgpath/packages.go")
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/palantir/pkg/", got)
+}
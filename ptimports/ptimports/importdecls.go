@@ -0,0 +1,69 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptimports
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// ExtraImportDecl is an import declaration in a file that already contains an earlier (non-cgo) import declaration.
+type ExtraImportDecl struct {
+	Pos token.Position
+	// First is the position of the first import declaration in the file.
+	First token.Position
+}
+
+func (d ExtraImportDecl) String() string {
+	return fmt.Sprintf("%v: imports must be declared in a single import declaration (first declared at %v)", d.Pos, d.First)
+}
+
+// MultipleImportDeclsError is the error returned by ProcessWithOptions when SingleImportBlock is true and the file
+// contains multiple import declarations.
+type MultipleImportDeclsError struct {
+	Decls []ExtraImportDecl
+}
+
+func (e *MultipleImportDeclsError) Error() string {
+	lines := make([]string, len(e.Decls))
+	for i, decl := range e.Decls {
+		lines[i] = decl.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// extraImportDecls returns the import declarations in the provided file that follow its first import declaration.
+// Declarations that import "C" are ignored because they must remain separate to keep their cgo preamble.
+func extraImportDecls(fset *token.FileSet, f *ast.File) []ExtraImportDecl {
+	var first token.Position
+	var extra []ExtraImportDecl
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT || isCImportDecl(d) {
+			continue
+		}
+		if !first.IsValid() {
+			first = fset.Position(d.Pos())
+			continue
+		}
+		extra = append(extra, ExtraImportDecl{
+			Pos:   fset.Position(d.Pos()),
+			First: first,
+		})
+	}
+	return extra
+}
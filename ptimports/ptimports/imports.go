@@ -37,19 +37,129 @@ import (
 	"golang.org/x/tools/imports"
 )
 
-// Process formats and adjusts imports for the provided file.
+// Options specifies the options used by ProcessWithOptions.
+type Options struct {
+	// Groups specifies the order of the import groups. Each element is either the name of a builtin group
+	// (StdLibGroup, ExternalGroup or LocalGroup) or an import path prefix, in which case the group contains the
+	// imports whose path starts with the prefix. Each builtin group must appear exactly once. An import that is not
+	// in the standard library or the local repository belongs to the group with the longest matching prefix, or to
+	// ExternalGroup if no prefix matches. If empty, DefaultGroups is used.
+	Groups []string
+
+	// RemoveUnused specifies whether unused imports should be removed before the imports are grouped. As with
+	// goimports, missing imports are also added.
+	RemoveUnused bool
+
+	// MergeDuplicates specifies whether imports of the same path with different names should be merged before the
+	// imports are grouped. Blank imports of a path that is also imported with a name are removed. Otherwise, the
+	// import without a name is kept (or the first import if all of them have names) and references through the
+	// names of the removed imports are rewritten to use the name of the kept import. Duplicates that cannot be merged
+	// safely are left unmodified.
+	MergeDuplicates bool
+
+	// RequireBlankImportComments specifies whether every blank import must have a doc comment or a trailing comment
+	// that explains why it is needed. If true and the file contains blank imports without a comment, the file is not
+	// processed and a *BlankImportsError that lists them is returned.
+	RequireBlankImportComments bool
+
+	// RelativeImports specifies how relative imports (imports whose path starts with "./" or "../") are handled. If
+	// ReportRelativeImports, the file is not processed if it contains relative imports and a *RelativeImportsError that
+	// lists them is returned. If RewriteRelativeImports, relative imports are rewritten to the absolute import path of
+	// the package they refer to (computed from the Go module or $GOPATH/src location of the package) before the
+	// imports are grouped. If empty, relative imports are left unmodified.
+	RelativeImports string
+
+	// SingleImportBlock specifies whether all of the imports of a file must be declared in a single import
+	// declaration. If true and the file contains multiple import declarations (declarations that import "C" are
+	// exempt, since they must remain separate), the file is not processed and a *MultipleImportDeclsError that lists
+	// the declarations after the first is returned. If false, the declarations are consolidated into a single grouped
+	// declaration.
+	SingleImportBlock bool
+}
+
+// Validate returns an error if the options are not valid.
+func (o Options) Validate() error {
+	if _, err := newGrouper("", o.Groups); err != nil {
+		return err
+	}
+	return validateRelativeImports(o.RelativeImports)
+}
+
+// Process formats and adjusts imports for the provided file. Unused imports are removed and missing imports are added
+// before the imports are grouped.
 func Process(filename string, src []byte) ([]byte, error) {
+	return ProcessWithOptions(filename, src, Options{
+		RemoveUnused: true,
+	})
+}
+
+// ProcessWithOptions is like Process, but adjusts and groups the imports as specified by the provided options.
+func ProcessWithOptions(filename string, src []byte, opts Options) ([]byte, error) {
+	if opts.RequireBlankImportComments || opts.RelativeImports == ReportRelativeImports || opts.SingleImportBlock {
+		fileSet := token.NewFileSet()
+		file, _, err := parse(fileSet, filename, src)
+		if err != nil {
+			return nil, err
+		}
+		if opts.RequireBlankImportComments {
+			if uncommented := uncommentedBlankImports(fileSet, file); len(uncommented) > 0 {
+				return nil, &BlankImportsError{
+					Imports: uncommented,
+				}
+			}
+		}
+		if opts.RelativeImports == ReportRelativeImports {
+			if relative := relativeImports(fileSet, file); len(relative) > 0 {
+				return nil, &RelativeImportsError{
+					Imports: relative,
+				}
+			}
+		}
+		if opts.SingleImportBlock {
+			if extra := extraImportDecls(fileSet, file); len(extra) > 0 {
+				return nil, &MultipleImportDeclsError{
+					Decls: extra,
+				}
+			}
+		}
+	}
+
+	if opts.RemoveUnused {
+		var err error
+		src, err = imports.Process(filename, src, &imports.Options{
+			Fragment:  true,
+			Comments:  true,
+			TabIndent: true,
+			TabWidth:  8,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	fileSet := token.NewFileSet()
 	file, adjust, err := parse(fileSet, filename, src)
 	if err != nil {
 		return nil, err
 	}
+	if opts.RelativeImports == RewriteRelativeImports {
+		// rewrite before merging so that a relative import and an absolute import of the same package are merged
+		if err := rewriteRelativeImports(file, filename); err != nil {
+			return nil, err
+		}
+	}
+	if opts.MergeDuplicates {
+		mergeDuplicateImports(fileSet, file, filename)
+	}
 
 	repo, err := repoForFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	grp := newVendoredGrouper(repo)
+	grp, err := newGrouper(repo, opts.Groups)
+	if err != nil {
+		return nil, err
+	}
 
 	cImportsDocs, err := fixImports(fileSet, file, grp)
 	if err != nil {
@@ -102,8 +212,14 @@ func Process(filename string, src []byte) ([]byte, error) {
 		return val
 	})
 
-	// ensure that output is goimports-compliant
-	out, err = imports.Process(filename, out, nil)
+	// ensure that output is goimports-compliant. Imports are only added and removed before grouping so that added
+	// imports are grouped properly.
+	out, err = imports.Process(filename, out, &imports.Options{
+		Comments:   true,
+		TabIndent:  true,
+		TabWidth:   8,
+		FormatOnly: true,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -257,6 +373,7 @@ func addImportSpaces(r io.Reader, breaks []string) []byte {
 	sc := bufio.NewScanner(r)
 	inImports := false
 	done := false
+	var docLines []string
 	for sc.Scan() {
 		s := sc.Text()
 
@@ -271,6 +388,12 @@ func addImportSpaces(r io.Reader, breaks []string) []byte {
 			done = true
 			inImports = false
 		}
+		if inImports && strings.HasPrefix(strings.TrimSpace(s), "//") {
+			// doc comments are written along with the import that follows them so that a space before the import
+			// is placed before its comments
+			docLines = append(docLines, s)
+			continue
+		}
 		if inImports && len(breaks) > 0 {
 			if m := impLine.FindStringSubmatch(s); m != nil {
 				if m[1] == breaks[0] {
@@ -279,9 +402,16 @@ func addImportSpaces(r io.Reader, breaks []string) []byte {
 				}
 			}
 		}
+		for _, docLine := range docLines {
+			fmt.Fprintln(&out, docLine)
+		}
+		docLines = nil
 		if !inImports || s != "" {
 			fmt.Fprintln(&out, s)
 		}
 	}
+	for _, docLine := range docLines {
+		fmt.Fprintln(&out, docLine)
+	}
 	return out.Bytes()
 }
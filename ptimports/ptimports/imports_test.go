@@ -15,8 +15,11 @@
 package ptimports_test
 
 import (
+	"io/ioutil"
+	"path"
 	"testing"
 
+	"github.com/nmiyake/pkg/dirs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -188,3 +191,540 @@ func Print(s string) {
 		assert.Equal(t, tc.want, string(got), "Case %d: %s", i, tc.name)
 	}
 }
+
+func TestPtImportsWithOptions(t *testing.T) {
+	in := `package foo
+
+import "github.com/palantir/checks/ptimports/ptimports"
+import "bytes"
+import "golang.org/x/tools/imports"
+import "github.com/palantir/pkg/pkgpath"
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = ptimports.Process
+	_ = imports.Process
+	_ = pkgpath.PackagesInDir
+}
+`
+	want := `package foo
+
+import (
+	"github.com/palantir/checks/ptimports/ptimports"
+
+	"bytes"
+
+	"github.com/palantir/pkg/pkgpath"
+
+	"golang.org/x/tools/imports"
+)
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = ptimports.Process
+	_ = imports.Process
+	_ = pkgpath.PackagesInDir
+}
+`
+	got, err := ptimports.ProcessWithOptions("test.go", []byte(in), ptimports.Options{
+		Groups: []string{ptimports.LocalGroup, ptimports.StdLibGroup, "github.com/palantir/", ptimports.ExternalGroup},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, string(got))
+
+	_, err = ptimports.ProcessWithOptions("test.go", []byte(in), ptimports.Options{
+		Groups: []string{ptimports.StdLibGroup, ptimports.ExternalGroup},
+	})
+	assert.EqualError(t, err, `import groups must include "local": [std external]`)
+}
+
+func TestPtImportsRemoveUnusedAndMergeDuplicates(t *testing.T) {
+	for i, tc := range []struct {
+		name string
+		opts ptimports.Options
+		in   string
+		want string
+	}{
+		{
+			"Unused imports are kept and missing imports are not added by default",
+			ptimports.Options{},
+			`package foo
+
+import "strings"
+import "bytes"
+
+func Foo() {
+	_ = bytes.Buffer{}
+}
+`,
+			`package foo
+
+import (
+	"bytes"
+	"strings"
+)
+
+func Foo() {
+	_ = bytes.Buffer{}
+}
+`,
+		},
+		{
+			"Unused imports are removed and missing imports are added before grouping",
+			ptimports.Options{
+				RemoveUnused: true,
+			},
+			`package foo
+
+import "strings"
+import "github.com/palantir/checks/ptimports/ptimports"
+
+func Foo() {
+	_ = ptimports.Process
+	_ = bytes.Buffer{}
+}
+`,
+			`package foo
+
+import (
+	"bytes"
+
+	"github.com/palantir/checks/ptimports/ptimports"
+)
+
+func Foo() {
+	_ = ptimports.Process
+	_ = bytes.Buffer{}
+}
+`,
+		},
+		{
+			"Duplicate imports are merged",
+			ptimports.Options{
+				MergeDuplicates: true,
+			},
+			`package foo
+
+import (
+	_ "bytes"
+	f "fmt"
+	"bytes"
+	"fmt"
+)
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = fmt.Sprint()
+	_ = f.Sprint()
+}
+
+func Bar(f Formatter) {
+	_ = f.Format()
+}
+
+type Formatter interface {
+	Format() string
+}
+`,
+			`package foo
+
+import (
+	"bytes"
+	"fmt"
+)
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = fmt.Sprint()
+	_ = fmt.Sprint()
+}
+
+func Bar(f Formatter) {
+	_ = f.Format()
+}
+
+type Formatter interface {
+	Format() string
+}
+`,
+		},
+		{
+			"Duplicate imports are not merged if the name of the kept import is declared in the file",
+			ptimports.Options{
+				MergeDuplicates: true,
+			},
+			`package foo
+
+import (
+	f "fmt"
+	"fmt"
+)
+
+func Foo() {
+	_ = fmt.Sprint()
+	_ = f.Sprint()
+}
+
+func Bar() {
+	fmt := struct{ Sprint func() string }{}
+	_ = fmt.Sprint()
+}
+`,
+			`package foo
+
+import (
+	"fmt"
+	f "fmt"
+)
+
+func Foo() {
+	_ = fmt.Sprint()
+	_ = f.Sprint()
+}
+
+func Bar() {
+	fmt := struct{ Sprint func() string }{}
+	_ = fmt.Sprint()
+}
+`,
+		},
+	} {
+		got, err := ptimports.ProcessWithOptions("test.go", []byte(tc.in), tc.opts)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, string(got), "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestPtImportsComments(t *testing.T) {
+	for i, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"Doc comments and trailing comments remain attached to their imports when regrouping",
+			`package foo
+
+import (
+	// errors doc
+	"github.com/pkg/errors" // trailing errors
+	// fmt doc
+	"fmt" // trailing fmt
+	_ "net/http/pprof" // register pprof handlers
+	"github.com/palantir/checks/ptimports/ptimports" // local
+	// bytes doc
+	// spanning lines
+	"bytes"
+)
+
+func Foo() {
+	_ = fmt.Sprint
+	_ = errors.New
+	_ = ptimports.Process
+	_ = bytes.Buffer{}
+}
+`,
+			`package foo
+
+import (
+	// bytes doc
+	// spanning lines
+	"bytes"
+	// fmt doc
+	"fmt"              // trailing fmt
+	_ "net/http/pprof" // register pprof handlers
+
+	// errors doc
+	"github.com/pkg/errors" // trailing errors
+
+	"github.com/palantir/checks/ptimports/ptimports" // local
+)
+
+func Foo() {
+	_ = fmt.Sprint
+	_ = errors.New
+	_ = ptimports.Process
+	_ = bytes.Buffer{}
+}
+`,
+		},
+		{
+			"Floating comments are attached to the following import",
+			`package foo
+
+import (
+	"os"
+
+	// floating comment
+
+	"bytes"
+	"fmt"
+	// trailing comment
+)
+
+func Foo() {
+	_ = os.Exit
+	_ = fmt.Sprint
+	_ = bytes.Buffer{}
+}
+`,
+			`package foo
+
+import (
+	// floating comment
+	"bytes"
+	"fmt"
+	"os"
+	// trailing comment
+)
+
+func Foo() {
+	_ = os.Exit
+	_ = fmt.Sprint
+	_ = bytes.Buffer{}
+}
+`,
+		},
+	} {
+		got, err := ptimports.Process("test.go", []byte(tc.in))
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, tc.want, string(got), "Case %d: %s", i, tc.name)
+
+		// output should be stable
+		again, err := ptimports.Process("test.go", got)
+		require.NoError(t, err, "Case %d: %s", i, tc.name)
+		assert.Equal(t, string(got), string(again), "Case %d: %s", i, tc.name)
+	}
+}
+
+func TestPtImportsRequireBlankImportComments(t *testing.T) {
+	opts := ptimports.Options{
+		RequireBlankImportComments: true,
+	}
+
+	_, err := ptimports.ProcessWithOptions("test.go", []byte(`package foo
+
+import (
+	// register image formats
+	_ "image/png"
+	_ "image/jpeg" // register image formats
+	_ "net/http/pprof"
+)
+
+// register expvar handlers
+import _ "expvar"
+import _ "embed"
+`), opts)
+	require.Error(t, err)
+	blankErr, ok := err.(*ptimports.BlankImportsError)
+	require.True(t, ok, "unexpected error type %T", err)
+	var paths []string
+	for _, imp := range blankErr.Imports {
+		paths = append(paths, imp.Path)
+	}
+	assert.Equal(t, []string{`"net/http/pprof"`, `"embed"`}, paths)
+	assert.EqualError(t, err, `test.go:7:2: blank import of "net/http/pprof" must have a comment explaining why it is needed
+test.go:12:8: blank import of "embed" must have a comment explaining why it is needed`)
+
+	_, err = ptimports.ProcessWithOptions("test.go", []byte(`package foo
+
+import (
+	_ "net/http/pprof" // register pprof handlers
+)
+`), opts)
+	assert.NoError(t, err)
+}
+
+func TestPtImportsSingleImportBlock(t *testing.T) {
+	opts := ptimports.Options{
+		SingleImportBlock: true,
+	}
+
+	_, err := ptimports.ProcessWithOptions("test.go", []byte(`package foo
+
+import "fmt"
+
+// #include <stdio.h>
+import "C"
+
+import (
+	"os"
+)
+
+import "strings"
+
+var _ = fmt.Sprint
+var _ = os.Exit
+var _ = strings.Join
+`), opts)
+	require.Error(t, err)
+	declsErr, ok := err.(*ptimports.MultipleImportDeclsError)
+	require.True(t, ok, "unexpected error type %T", err)
+	assert.Equal(t, 2, len(declsErr.Decls))
+	assert.EqualError(t, err, `test.go:8:1: imports must be declared in a single import declaration (first declared at test.go:3:1)
+test.go:12:1: imports must be declared in a single import declaration (first declared at test.go:3:1)`)
+
+	for i, src := range []string{
+		`package foo
+
+import (
+	"fmt"
+	"os"
+)
+
+// #include <stdio.h>
+import "C"
+
+var _ = fmt.Sprint
+var _ = os.Exit
+`,
+		`package foo
+
+import "fmt"
+
+var _ = fmt.Sprint
+`,
+	} {
+		_, err = ptimports.ProcessWithOptions("test.go", []byte(src), opts)
+		assert.NoError(t, err, "Case %d", i)
+	}
+}
+
+func TestPtImportsModule(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	err = ioutil.WriteFile(path.Join(tmpDir, "go.mod"), []byte("module example.com/project\n"), 0644)
+	require.NoError(t, err)
+
+	got, err := ptimports.ProcessWithOptions(path.Join(tmpDir, "foo.go"), []byte(`package foo
+
+import "example.com/project/bar"
+import "bytes"
+import "github.com/palantir/checks/ptimports/ptimports"
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = bar.Bar
+	_ = ptimports.Process
+}
+`), ptimports.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, `package foo
+
+import (
+	"bytes"
+
+	"github.com/palantir/checks/ptimports/ptimports"
+
+	"example.com/project/bar"
+)
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = bar.Bar
+	_ = ptimports.Process
+}
+`, string(got))
+}
+
+func TestPtImportsRelativeImports(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	err = ioutil.WriteFile(path.Join(tmpDir, "go.mod"), []byte("module example.com/project\n"), 0644)
+	require.NoError(t, err)
+	filename := path.Join(tmpDir, "foo", "foo.go")
+
+	src := []byte(`package foo
+
+import (
+	"bytes"
+
+	"./baz"
+	"../bar"
+)
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = bar.Bar
+	_ = baz.Baz
+}
+`)
+
+	_, err = ptimports.ProcessWithOptions(filename, src, ptimports.Options{
+		RelativeImports: ptimports.ReportRelativeImports,
+	})
+	require.Error(t, err)
+	_, ok := err.(*ptimports.RelativeImportsError)
+	require.True(t, ok, "unexpected error type %T", err)
+	assert.EqualError(t, err, filename+`:6:2: relative import of "./baz" is not allowed
+`+filename+`:7:2: relative import of "../bar" is not allowed`)
+
+	got, err := ptimports.ProcessWithOptions(filename, src, ptimports.Options{
+		RelativeImports: ptimports.RewriteRelativeImports,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `package foo
+
+import (
+	"bytes"
+
+	"example.com/project/bar"
+	"example.com/project/foo/baz"
+)
+
+func Foo() {
+	_ = bytes.Buffer{}
+	_ = bar.Bar
+	_ = baz.Baz
+}
+`, string(got))
+
+	_, err = ptimports.ProcessWithOptions(filename, src, ptimports.Options{})
+	assert.NoError(t, err)
+}
+
+func TestPtImportsRelativeImportsMergeDuplicates(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	err = ioutil.WriteFile(path.Join(tmpDir, "go.mod"), []byte("module example.com/project\n"), 0644)
+	require.NoError(t, err)
+
+	got, err := ptimports.ProcessWithOptions(path.Join(tmpDir, "foo.go"), []byte(`package foo
+
+import (
+	bar "example.com/project/bar"
+	other "./bar"
+)
+
+func Foo() {
+	_ = bar.Bar
+	_ = other.Bar
+}
+`), ptimports.Options{
+		MergeDuplicates: true,
+		RelativeImports: ptimports.RewriteRelativeImports,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `package foo
+
+import (
+	bar "example.com/project/bar"
+)
+
+func Foo() {
+	_ = bar.Bar
+	_ = bar.Bar
+}
+`, string(got))
+}
+
+func TestOptionsValidateRelativeImports(t *testing.T) {
+	assert.NoError(t, ptimports.Options{RelativeImports: ptimports.RewriteRelativeImports}.Validate())
+	assert.EqualError(t, ptimports.Options{RelativeImports: "fix"}.Validate(), `invalid value for relative imports "fix": must be one of [report rewrite]`)
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptimports
+
+import (
+	"go/ast"
+	"go/build"
+	"go/token"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// mergeDuplicateImports merges the imports in the provided file that import the same path using different names so
+// that every path is imported at most once. Blank imports of a path that is also imported with a name are removed. For
+// other duplicates, the import without an explicit name (or the first import if every import has a name) is kept and
+// the references to the package through the names of the removed imports are rewritten to use the name of the kept
+// import. Paths that are dot-imported are never merged, and paths whose duplicates cannot be merged safely (because the
+// name of a package cannot be determined or the name of the kept import is declared in the file) are left unmodified.
+func mergeDuplicateImports(fset *token.FileSet, f *ast.File, filename string) {
+	var paths []string
+	specsForPath := make(map[string][]*ast.ImportSpec)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || path == "C" {
+			continue
+		}
+		if _, ok := specsForPath[path]; !ok {
+			paths = append(paths, path)
+		}
+		specsForPath[path] = append(specsForPath[path], spec)
+	}
+
+	declared := declaredNames(f)
+	for _, path := range paths {
+		specs := specsForPath[path]
+		if len(specs) < 2 {
+			continue
+		}
+
+		var keep *ast.ImportSpec
+		hasBlank, dotImported := false, false
+		for _, spec := range specs {
+			switch importName(spec) {
+			case "_":
+				hasBlank = true
+			case ".":
+				dotImported = true
+			case "":
+				if keep == nil || keep.Name != nil {
+					keep = spec
+				}
+			default:
+				if keep == nil {
+					keep = spec
+				}
+			}
+		}
+		if keep == nil && !dotImported {
+			// only blank imports: exact duplicates are removed when the imports are sorted
+			continue
+		}
+		if hasBlank {
+			astutil.DeleteNamedImport(fset, f, "_", path)
+		}
+		if dotImported || keep == nil {
+			continue
+		}
+
+		keepName, ok := pkgNameForSpec(keep, path, filename)
+		if !ok {
+			continue
+		}
+		if _, ok := declared[keepName]; ok {
+			continue
+		}
+		renames := make(map[string]bool)
+		for _, spec := range specs {
+			if spec == keep || importName(spec) == "_" || importName(spec) == importName(keep) {
+				continue
+			}
+			name, ok := pkgNameForSpec(spec, path, filename)
+			if !ok {
+				renames = nil
+				break
+			}
+			renames[name] = true
+		}
+		if renames == nil {
+			continue
+		}
+		for _, spec := range specs {
+			if spec == keep || importName(spec) == "_" || importName(spec) == importName(keep) {
+				continue
+			}
+			astutil.DeleteNamedImport(fset, f, importName(spec), path)
+		}
+		renamePkgRefs(f, renames, keepName)
+	}
+}
+
+// pkgNameForSpec returns the name used to refer to the package imported by the provided spec. If the spec does not
+// specify a name, the name of the package is determined by locating the package relative to the directory of filename.
+func pkgNameForSpec(spec *ast.ImportSpec, path, filename string) (string, bool) {
+	if spec.Name != nil {
+		return spec.Name.Name, true
+	}
+	pkg, err := build.Import(path, filepath.Dir(filename), 0)
+	if err != nil || pkg.Name == "" {
+		return "", false
+	}
+	return pkg.Name, true
+}
+
+// declaredNames returns the names of all of the objects declared in the provided file.
+func declaredNames(f *ast.File) map[string]struct{} {
+	names := make(map[string]struct{})
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Obj != nil && ident.Obj.Kind != ast.Pkg {
+			names[ident.Name] = struct{}{}
+		}
+		return true
+	})
+	return names
+}
+
+// renamePkgRefs rewrites the qualified identifiers in the provided file whose package name is in names to use newName.
+// Only identifiers that are not resolved to an object declared in the file are rewritten.
+func renamePkgRefs(f *ast.File, names map[string]bool, newName string) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil && names[ident.Name] {
+			ident.Name = newName
+		}
+		return true
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptimports
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/palantir/pkg/pkgpath"
+)
+
+const (
+	// ReportRelativeImports is the value of Options.RelativeImports that reports relative imports.
+	ReportRelativeImports = "report"
+	// RewriteRelativeImports is the value of Options.RelativeImports that rewrites relative imports to the absolute
+	// import path of the package they refer to.
+	RewriteRelativeImports = "rewrite"
+)
+
+// RelativeImport is an import whose path is relative (starts with "./" or "../").
+type RelativeImport struct {
+	Pos  token.Position
+	Path string
+}
+
+func (i RelativeImport) String() string {
+	return fmt.Sprintf("%v: relative import of %s is not allowed", i.Pos, i.Path)
+}
+
+// RelativeImportsError is the error returned by ProcessWithOptions when RelativeImports is ReportRelativeImports and
+// the file contains relative imports.
+type RelativeImportsError struct {
+	Imports []RelativeImport
+}
+
+func (e *RelativeImportsError) Error() string {
+	lines := make([]string, len(e.Imports))
+	for i, imp := range e.Imports {
+		lines[i] = imp.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateRelativeImports returns an error if the provided value is not a valid value for Options.RelativeImports.
+func validateRelativeImports(relativeImports string) error {
+	switch relativeImports {
+	case "", ReportRelativeImports, RewriteRelativeImports:
+		return nil
+	default:
+		return fmt.Errorf("invalid value for relative imports %q: must be one of %v", relativeImports, []string{ReportRelativeImports, RewriteRelativeImports})
+	}
+}
+
+// relativeImports returns the imports in the provided file whose paths are relative.
+func relativeImports(fset *token.FileSet, f *ast.File) []RelativeImport {
+	var relative []RelativeImport
+	for _, impSpec := range f.Imports {
+		importPath, err := strconv.Unquote(impSpec.Path.Value)
+		if err != nil || !build.IsLocalImport(importPath) {
+			continue
+		}
+		relative = append(relative, RelativeImport{
+			Pos:  fset.Position(impSpec.Pos()),
+			Path: impSpec.Path.Value,
+		})
+	}
+	return relative
+}
+
+// rewriteRelativeImports rewrites the relative imports in the provided file, which was read from filename, to the
+// absolute import paths of the packages they refer to.
+func rewriteRelativeImports(f *ast.File, filename string) error {
+	for _, impSpec := range f.Imports {
+		importPath, err := strconv.Unquote(impSpec.Path.Value)
+		if err != nil || !build.IsLocalImport(importPath) {
+			continue
+		}
+		absImportPath, err := absoluteImportPath(filename, importPath)
+		if err != nil {
+			return err
+		}
+		impSpec.Path.Value = strconv.Quote(absImportPath)
+	}
+	return nil
+}
+
+// absoluteImportPath returns the import path of the package in the directory that the provided relative import path
+// refers to from the directory of the provided file. If the directory is in a Go module, the import path is the module
+// path joined with the path of the directory relative to the module root. Otherwise, it is the path of the directory
+// relative to $GOPATH/src.
+func absoluteImportPath(filename, relImportPath string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(filepath.Dir(abs), filepath.FromSlash(relImportPath))
+	if modulePath, moduleDir, ok := moduleForDir(dir); ok {
+		rel, err := filepath.Rel(moduleDir, dir)
+		if err != nil {
+			return "", err
+		}
+		return path.Join(modulePath, filepath.ToSlash(rel)), nil
+	}
+	if goPathSrcRel, err := pkgpath.NewAbsPkgPath(dir).GoPathSrcRel(); err == nil && goPathSrcRel != "" && goPathSrcRel != "." {
+		return goPathSrcRel, nil
+	}
+	return "", fmt.Errorf("failed to determine import path for relative import %q: %s is not in a Go module or in $GOPATH/src", relImportPath, dir)
+}
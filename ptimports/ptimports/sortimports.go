@@ -22,6 +22,7 @@ package ptimports
 
 import (
 	"bytes"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/printer"
@@ -31,36 +32,54 @@ import (
 )
 
 func fixImports(fset *token.FileSet, f *ast.File, grp importGrouper) (cImportDocs []*ast.CommentGroup, rErr error) {
+	comments, endComments := importComments(f)
 	imports, cImports, cImportsDocs := takeImports(f)
 	if imports == nil || len(imports.Specs) == 0 {
 		return
 	}
 
-	imports.Specs = sortSpecs(fset, f, grp, imports.Specs)
+	// the sorted imports are printed separately and substituted for a placeholder import so that the comments of each
+	// import remain attached to it regardless of its new position
+	specs := sortSpecs(grp, imports.Specs, comments)
+	imports.Specs = []ast.Spec{&ast.ImportSpec{
+		Path: &ast.BasicLit{
+			ValuePos: imports.Specs[0].Pos(),
+			Kind:     token.STRING,
+			Value:    strconv.Quote(placeholderImportPath),
+		},
+	}}
 	fixParens(imports)
 	f.Decls = append(cImports, append([]ast.Decl{imports}, f.Decls...)...)
 
-	var comments []*ast.CommentGroup
-	for _, fileComment := range f.Comments {
-		skip := false
-		for _, cImportComment := range cImportsDocs {
-			if fileComment == cImportComment {
-				skip = true
-				break
-			}
+	skipComments := make(map[*ast.CommentGroup]bool)
+	for _, cImportComment := range cImportsDocs {
+		skipComments[cImportComment] = true
+	}
+	for _, specComment := range comments {
+		for _, g := range specComment.groups() {
+			skipComments[g] = true
 		}
-		if skip {
+	}
+	for _, g := range endComments {
+		skipComments[g] = true
+	}
+	var fileComments []*ast.CommentGroup
+	for _, fileComment := range f.Comments {
+		if skipComments[fileComment] {
 			continue
 		}
-		comments = append(comments, fileComment)
+		fileComments = append(fileComments, fileComment)
 	}
-	f.Comments = comments
+	f.Comments = fileComments
 
 	buf := &bytes.Buffer{}
 	if err := printer.Fprint(buf, fset, f); err != nil {
 		return nil, err
 	}
-	newF, err := parser.ParseFile(fset, f.Name.Name, buf, parser.ParseComments)
+	placeholderLine := []byte("\t" + strconv.Quote(placeholderImportPath) + "\n")
+	out := bytes.Replace(buf.Bytes(), placeholderLine, specsSource(specs, comments, endComments), 1)
+
+	newF, err := parser.ParseFile(fset, f.Name.Name, out, parser.ParseComments)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +87,137 @@ func fixImports(fset *token.FileSet, f *ast.File, grp importGrouper) (cImportDoc
 	return cImportsDocs, nil
 }
 
+// placeholderImportPath is the path of the import that is substituted for the sorted imports when printing a file.
+const placeholderImportPath = "ptimports:placeholder"
+
+// specComments are the comments attached to an import spec.
+type specComments struct {
+	// doc are the comments on the lines before the import
+	doc []*ast.CommentGroup
+	// comment is the comment on the same line as the import
+	comment *ast.CommentGroup
+}
+
+func (c *specComments) groups() []*ast.CommentGroup {
+	groups := append([]*ast.CommentGroup{}, c.doc...)
+	if c.comment != nil {
+		groups = append(groups, c.comment)
+	}
+	return groups
+}
+
+func (c *specComments) empty() bool {
+	return c == nil || len(c.groups()) == 0
+}
+
+// importComments returns the comments attached to each of the specs in the non-cgo import declarations of the provided
+// file. Doc comments and trailing comments are attached to the import they document. Other comments within the
+// parentheses of an import declaration are attached to the following import as documentation or, if there is no
+// following import, are returned as endComments.
+func importComments(f *ast.File) (comments map[*ast.ImportSpec]*specComments, endComments []*ast.CommentGroup) {
+	comments = make(map[*ast.ImportSpec]*specComments)
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			break
+		}
+		if isCImportDecl(d) {
+			continue
+		}
+		attached := make(map[*ast.CommentGroup]bool)
+		for _, spec := range d.Specs {
+			impSpec := spec.(*ast.ImportSpec)
+			c := &specComments{comment: impSpec.Comment}
+			if impSpec.Doc != nil {
+				c.doc = append(c.doc, impSpec.Doc)
+			}
+			for _, g := range c.groups() {
+				attached[g] = true
+			}
+			comments[impSpec] = c
+		}
+		if !d.Lparen.IsValid() || len(d.Specs) == 0 {
+			continue
+		}
+		for _, g := range f.Comments {
+			if g.Pos() < d.Lparen || g.End() > d.Rparen || attached[g] {
+				continue
+			}
+			var next *ast.ImportSpec
+			for _, spec := range d.Specs {
+				if spec.Pos() > g.End() {
+					next = spec.(*ast.ImportSpec)
+					break
+				}
+			}
+			if next == nil {
+				endComments = append(endComments, g)
+				continue
+			}
+			nextComments := comments[next]
+			var doc []*ast.CommentGroup
+			for i, docGroup := range nextComments.doc {
+				if docGroup.Pos() > g.Pos() {
+					doc = append(append(doc, g), nextComments.doc[i:]...)
+					break
+				}
+				doc = append(doc, docGroup)
+			}
+			if len(doc) == len(nextComments.doc) {
+				doc = append(doc, g)
+			}
+			nextComments.doc = doc
+		}
+	}
+	return comments, endComments
+}
+
+// specsSource returns the source for the provided import specs (one per line, indented by a tab) along with their
+// comments followed by the provided end comments.
+func specsSource(specs []ast.Spec, comments map[*ast.ImportSpec]*specComments, endComments []*ast.CommentGroup) []byte {
+	buf := &bytes.Buffer{}
+	for _, spec := range specs {
+		impSpec := spec.(*ast.ImportSpec)
+		c := comments[impSpec]
+		if c == nil {
+			c = &specComments{}
+		}
+		for _, g := range c.doc {
+			writeCommentLines(buf, g)
+		}
+		_ = buf.WriteByte('\t')
+		if impSpec.Name != nil {
+			fmt.Fprintf(buf, "%s ", impSpec.Name.Name)
+		}
+		_, _ = buf.WriteString(impSpec.Path.Value)
+		if c.comment != nil {
+			for _, comment := range c.comment.List {
+				fmt.Fprintf(buf, " %s", comment.Text)
+			}
+		}
+		_ = buf.WriteByte('\n')
+	}
+	for _, g := range endComments {
+		writeCommentLines(buf, g)
+	}
+	return buf.Bytes()
+}
+
+func writeCommentLines(buf *bytes.Buffer, g *ast.CommentGroup) {
+	for _, comment := range g.List {
+		fmt.Fprintf(buf, "\t%s\n", comment.Text)
+	}
+}
+
+func isCImportDecl(d *ast.GenDecl) bool {
+	for _, spec := range d.Specs {
+		if spec.(*ast.ImportSpec).Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
 func takeImports(f *ast.File) (imports *ast.GenDecl, cImports []ast.Decl, cImportDocs []*ast.CommentGroup) {
 	for len(f.Decls) > 0 {
 		d, ok := f.Decls[0].(*ast.GenDecl)
@@ -140,103 +290,31 @@ func importComment(s ast.Spec) string {
 }
 
 // collapse indicates whether prev may be removed, leaving only next.
-func collapse(prev, next ast.Spec) bool {
+func collapse(prev, next ast.Spec, comments map[*ast.ImportSpec]*specComments) bool {
 	if importPath(next) != importPath(prev) || importName(next) != importName(prev) {
 		return false
 	}
-	return prev.(*ast.ImportSpec).Comment == nil
-}
-
-type posSpan struct {
-	Start token.Pos
-	End   token.Pos
+	return comments[prev.(*ast.ImportSpec)].empty()
 }
 
-func sortSpecs(fset *token.FileSet, f *ast.File, grp importGrouper, specs []ast.Spec) []ast.Spec {
-	// Can't short-circuit here even if specs are already sorted,
-	// since they might yet need deduplication.
-	// A lone import, however, may be safely ignored.
-	if len(specs) <= 1 {
-		return specs
-	}
-
-	// Record positions for specs.
-	pos := make([]posSpan, len(specs))
-	for i, s := range specs {
-		pos[i] = posSpan{s.Pos(), s.End()}
-	}
-
-	// Identify comments in this range.
-	// Any comment from pos[0].Start to the final line counts.
-	lastLine := fset.Position(pos[len(pos)-1].End).Line
-	cstart := len(f.Comments)
-	cend := len(f.Comments)
-	for i, g := range f.Comments {
-		if g.Pos() < pos[0].Start {
-			continue
-		}
-		if i < cstart {
-			cstart = i
-		}
-		if fset.Position(g.End()).Line > lastLine {
-			cend = i
-			break
-		}
-	}
-	comments := f.Comments[cstart:cend]
-
-	// Assign each comment to the import spec preceding it.
-	importComment := map[*ast.ImportSpec][]*ast.CommentGroup{}
-	specIndex := 0
-	for _, g := range comments {
-		for specIndex+1 < len(specs) && pos[specIndex+1].Start <= g.Pos() {
-			specIndex++
-		}
-		s := specs[specIndex].(*ast.ImportSpec)
-		importComment[s] = append(importComment[s], g)
-	}
-
-	// Sort the import specs by import path.
-	// Remove duplicates, when possible without data loss.
-	// Reassign the import paths to have the same position sequence.
-	// Reassign each comment to abut the end of its spec.
-	// Sort the comments by new position.
-	sort.Sort(byImportSpec{
-		specs: specs,
+// sortSpecs sorts the provided import specs by group and import path and removes duplicates, when possible without
+// data loss.
+func sortSpecs(grp importGrouper, specs []ast.Spec, comments map[*ast.ImportSpec]*specComments) []ast.Spec {
+	sorted := append([]ast.Spec{}, specs...)
+	sort.Stable(byImportSpec{
+		specs: sorted,
 		grp:   grp,
 	})
 
 	// Dedup. Thanks to our sorting, we can just consider
 	// adjacent pairs of imports.
-	deduped := specs[:0]
-	for i, s := range specs {
-		if i == len(specs)-1 || !collapse(s, specs[i+1]) {
+	var deduped []ast.Spec
+	for i, s := range sorted {
+		if i == len(sorted)-1 || !collapse(s, sorted[i+1], comments) {
 			deduped = append(deduped, s)
-		} else {
-			p := s.Pos()
-			fset.File(p).MergeLine(fset.Position(p).Line)
 		}
 	}
-	specs = deduped
-
-	// Fix up comment positions
-	for i, s := range specs {
-		s := s.(*ast.ImportSpec)
-		if s.Name != nil {
-			s.Name.NamePos = pos[i].Start
-		}
-		s.Path.ValuePos = pos[i].Start
-		s.EndPos = pos[i].End
-		for _, g := range importComment[s] {
-			for _, c := range g.List {
-				c.Slash = pos[i].End
-			}
-		}
-	}
-
-	sort.Sort(byCommentPos(comments))
-
-	return specs
+	return deduped
 }
 
 type byImportSpec struct {
@@ -267,9 +345,3 @@ func (x byImportSpec) Less(i, j int) bool {
 	}
 	return importComment(x.specs[i]) < importComment(x.specs[j])
 }
-
-type byCommentPos []*ast.CommentGroup
-
-func (x byCommentPos) Len() int           { return len(x) }
-func (x byCommentPos) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
-func (x byCommentPos) Less(i, j int) bool { return x[i].Pos() < x[j].Pos() }
@@ -0,0 +1,217 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/nmiyake/pkg/dirs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/palantir/checks/ptimports/ptimports"
+)
+
+func TestDiff(t *testing.T) {
+	before := `package foo
+
+import "fmt"
+import "bytes"
+`
+	after := `package foo
+
+import (
+	"bytes"
+	"fmt"
+)
+`
+	got, err := diff([]byte(before), []byte(after), "foo/foo.go")
+	require.NoError(t, err)
+
+	// remove timestamps
+	got = regexp.MustCompile(`(?m)^((?:---|\+\+\+) \S+)\t.*$`).ReplaceAll(got, []byte("$1"))
+	want := `--- foo/foo.go.orig
++++ foo/foo.go
+@@ -1,4 +1,6 @@
+ package foo
+ 
+-import "fmt"
+-import "bytes"
++import (
++	"bytes"
++	"fmt"
++)
+`
+	assert.Equal(t, want, string(got))
+}
+
+func TestProcessFileCheck(t *testing.T) {
+	*check = true
+	defer func() {
+		*check = false
+	}()
+
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	unformatted := filepath.Join(tmpDir, "unformatted.go")
+	const unformattedSrc = `package foo
+
+import "fmt"
+import "bytes"
+
+var _ = fmt.Sprint(bytes.MinRead)
+`
+	err = ioutil.WriteFile(unformatted, []byte(unformattedSrc), 0644)
+	require.NoError(t, err)
+
+	formatted := filepath.Join(tmpDir, "formatted.go")
+	err = ioutil.WriteFile(formatted, []byte("package foo\n"), 0644)
+	require.NoError(t, err)
+
+	// files whose formatting differs are listed but not rewritten
+	buf := &bytes.Buffer{}
+	changed, err := processFile(unformatted, nil, buf)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, unformatted+"\n", buf.String())
+	got, err := ioutil.ReadFile(unformatted)
+	require.NoError(t, err)
+	assert.Equal(t, unformattedSrc, string(got))
+
+	buf.Reset()
+	changed, err = processFile(formatted, nil, buf)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestPkgDir(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	for i, currCase := range []struct {
+		path string
+		want string
+	}{
+		{path: "ptimports", want: "ptimports"},
+		{path: "github.com/palantir/checks/ptimports/ptimports", want: filepath.Join(wd, "ptimports")},
+		{path: "./ptimports", want: "./ptimports"},
+	} {
+		got, err := pkgDir(currCase.path)
+		require.NoError(t, err, "Case %d", i)
+		assert.Equal(t, currCase.want, got, "Case %d", i)
+	}
+
+	_, err = pkgDir("github.com/palantir/checks/ptimports/nonexistent")
+	assert.Error(t, err)
+}
+
+func TestLoadOptions(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	opts, skipDirs, err := loadOptions("")
+	require.NoError(t, err)
+	assert.Equal(t, ptimports.Options{RemoveUnused: true}, opts)
+	assert.Nil(t, skipDirs)
+
+	cfgFile := filepath.Join(tmpDir, "ptimports.yml")
+	err = ioutil.WriteFile(cfgFile, []byte(`groups:
+  - std
+  - external
+  - github.com/myorg/
+  - local
+remove-unused: false
+merge-duplicates: true
+relative-imports: rewrite
+single-import-block: true
+skip-dirs:
+  - vendor
+  - generated
+`), 0644)
+	require.NoError(t, err)
+
+	opts, skipDirs, err = loadOptions(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, ptimports.Options{
+		Groups:            []string{"std", "external", "github.com/myorg/", "local"},
+		MergeDuplicates:   true,
+		RelativeImports:   ptimports.RewriteRelativeImports,
+		SingleImportBlock: true,
+	}, opts)
+	assert.Equal(t, []string{"vendor", "generated"}, skipDirs)
+}
+
+func TestGoFilesInDir(t *testing.T) {
+	tmpDir, cleanup, err := dirs.TempDir("", "")
+	require.NoError(t, err)
+	defer cleanup()
+
+	for _, currPath := range []string{
+		"foo.go",
+		"foo.txt",
+		"bar/bar.go",
+		"bar/testdata/data.go",
+		"vendor/github.com/org/dep/dep.go",
+		".hidden/hidden.go",
+		"generated/generated.go",
+	} {
+		err := os.MkdirAll(filepath.Join(tmpDir, filepath.Dir(currPath)), 0755)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(tmpDir, currPath), []byte("package foo\n"), 0644)
+		require.NoError(t, err)
+	}
+
+	defer func(orig []string) {
+		skipDirs = orig
+	}(skipDirs)
+	for i, currCase := range []struct {
+		skipDirs []string
+		want     []string
+	}{
+		{
+			skipDirs: defaultSkipDirs,
+			want:     []string{"bar/bar.go", "foo.go", "generated/generated.go"},
+		},
+		{
+			skipDirs: []string{"generated"},
+			want:     []string{"bar/bar.go", "bar/testdata/data.go", "foo.go", "vendor/github.com/org/dep/dep.go"},
+		},
+	} {
+		skipDirs = currCase.skipDirs
+		got, err := goFilesInDir(tmpDir)
+		require.NoError(t, err, "Case %d", i)
+
+		var want []string
+		for _, currPath := range currCase.want {
+			want = append(want, filepath.Join(tmpDir, currPath))
+		}
+		assert.Equal(t, want, got, "Case %d", i)
+	}
+
+	// the provided directory is not skipped even if its name matches
+	skipDirs = defaultSkipDirs
+	got, err := goFilesInDir(filepath.Join(tmpDir, "vendor"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(tmpDir, "vendor/github.com/org/dep/dep.go")}, got)
+}